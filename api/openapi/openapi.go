@@ -0,0 +1,37 @@
+// Package openapi embeds the OpenAPI specification of the HTTP API.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Load parses the specification and validates it against OpenAPI 3.
+func Load(ctx context.Context) (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi spec: %w", err)
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+
+	return doc, nil
+}
+
+// JSON returns the specification encoded as JSON.
+func JSON(ctx context.Context) ([]byte, error) {
+	doc, err := Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
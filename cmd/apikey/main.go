@@ -0,0 +1,161 @@
+// Command apikey manages the API keys clients authenticate with:
+//
+//	apikey -config=./configs/local.yml create -name=billing -permission=read_write -wallets=<id>,<id>
+//	apikey -config=./configs/local.yml create -name=admin -permission=read_write -all-wallets
+//	apikey -config=./configs/local.yml rotate <key id>
+//	apikey -config=./configs/local.yml revoke <key id>
+//
+// The keys create and rotate print can't be shown again, only their hashes are stored.
+// Every change is recorded in the audit log as made by cli:<OS user>.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	apiKeySvc "github.com/passwordhash/asynchronous-wallet/internal/service/apikey"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/audit"
+	apiKeyRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/apikey"
+	auditRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/audit"
+	postgresPkg "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
+)
+
+const usage = `usage: apikey -config=<path> <command> [arguments]
+
+commands:
+  create -name=<name> -permission=read|read_write (-wallets=<id>,... | -all-wallets)
+  rotate <key id>
+  revoke <key id>`
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "apikey:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg := config.MustLoad()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: cliActor()})
+
+	pool, err := postgresPkg.NewPool(ctx, cfg.PG.DSN())
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer pool.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	auditor := audit.New(log, auditRepo.New(pool))
+	svc := apiKeySvc.New(log, apiKeyRepo.New(pool), apiKeySvc.WithAuditor(auditor))
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "create":
+		return create(ctx, svc, args)
+	case "rotate":
+		return rotate(ctx, svc, args)
+	case "revoke":
+		return revoke(ctx, svc, args)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", cmd, usage)
+	}
+}
+
+func create(ctx context.Context, svc *apiKeySvc.Service, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the key, e.g. the client it is issued to")
+	permission := fs.String("permission", string(entity.APIKeyRead), "read or read_write")
+	wallets := fs.String("wallets", "", "comma-separated IDs of the wallets the key may access")
+	allWallets := fs.Bool("all-wallets", false, "let the key access every wallet")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// An empty scope means every wallet, so it must be asked for explicitly
+	// rather than be the result of a forgotten flag.
+	if (*wallets == "") == !*allWallets {
+		return errors.New("exactly one of -wallets and -all-wallets is required")
+	}
+
+	var walletIDs []string
+	if *wallets != "" {
+		for id := range strings.SplitSeq(*wallets, ",") {
+			walletIDs = append(walletIDs, strings.TrimSpace(id))
+		}
+	}
+
+	key, plain, err := svc.Create(ctx, entity.APIKey{
+		Name:       *name,
+		Permission: entity.APIKeyPermission(*permission),
+		WalletIDs:  walletIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	printKey(key, plain)
+	return nil
+}
+
+func rotate(ctx context.Context, svc *apiKeySvc.Service, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: apikey rotate <key id>")
+	}
+
+	key, plain, err := svc.Rotate(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	printKey(key, plain)
+	return nil
+}
+
+func revoke(ctx context.Context, svc *apiKeySvc.Service, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: apikey revoke <key id>")
+	}
+
+	if err := svc.Revoke(ctx, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Println("revoked", args[0])
+	return nil
+}
+
+// cliActor returns the actor key changes are audited as, the OS user running the tool.
+func cliActor() string {
+	u, err := user.Current()
+	if err != nil {
+		return "cli"
+	}
+	return "cli:" + u.Username
+}
+
+func printKey(key *entity.APIKey, plain string) {
+	scope := "all"
+	if !key.AllWallets() {
+		scope = strings.Join(key.WalletIDs, ",")
+	}
+
+	fmt.Printf("id:         %s\n", key.ID)
+	fmt.Printf("name:       %s\n", key.Name)
+	fmt.Printf("permission: %s\n", key.Permission)
+	fmt.Printf("wallets:    %s\n", scope)
+	fmt.Printf("key:        %s\n", plain)
+	fmt.Println("\nStore the key now, it can't be shown again.")
+}
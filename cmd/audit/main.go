@@ -0,0 +1,88 @@
+// Command audit checks the audit log of the service:
+//
+//	audit -config=./configs/local.yml verify
+//
+// verify walks the hash chain from the first record and reports every record that
+// breaks it, and fails if the chain doesn't reach the hash kept in audit_head, which
+// means records were removed from the end of the log. It prints the hash of the
+// newest record; keep a copy elsewhere to also catch a rewritten audit_head. It
+// exits with status 1 if the chain is broken.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/audit"
+	auditRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/audit"
+	postgresPkg "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
+)
+
+const usage = `usage: audit -config=<path> <command>
+
+commands:
+  verify`
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "audit:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg := config.MustLoad()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	ctx := context.Background()
+
+	pool, err := postgresPkg.NewPool(ctx, cfg.PG.DSN())
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer pool.Close()
+
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	svc := audit.New(log, auditRepo.New(pool))
+
+	switch cmd := args[0]; cmd {
+	case "verify":
+		return verify(ctx, svc)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", cmd, usage)
+	}
+}
+
+func verify(ctx context.Context, svc *audit.Service) error {
+	verification, err := svc.Verify(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("records:   %d\n", verification.Records)
+	fmt.Printf("last id:   %d\n", verification.LastID)
+	fmt.Printf("last hash: %s\n", hex.EncodeToString(verification.LastHash))
+	fmt.Printf("head hash: %s\n", hex.EncodeToString(verification.Head))
+
+	if verification.Intact() {
+		fmt.Println("\nThe chain is intact.")
+		return nil
+	}
+
+	fmt.Println()
+	for _, b := range verification.Breaks {
+		fmt.Printf("record %d: %s\n", b.RecordID, b.Reason)
+	}
+
+	return fmt.Errorf("%d breaks in the chain", len(verification.Breaks))
+}
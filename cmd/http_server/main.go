@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -9,10 +10,10 @@ import (
 
 	"github.com/passwordhash/asynchronous-wallet/internal/app"
 	"github.com/passwordhash/asynchronous-wallet/internal/config"
+	"github.com/passwordhash/asynchronous-wallet/internal/tracing"
 )
 
 // TODO: readme
-// TODO: doc api
 
 const shutdownTimeout = 5 * time.Second // max time to wait for graceful shutdown
 
@@ -23,20 +24,40 @@ func main() {
 
 	cfg := config.MustLoad()
 
-	log := config.SetupLogger(cfg.App.Env)
+	// Records logged with a traced context carry its trace and span IDs.
+	log := slog.New(tracing.NewLogHandler(config.SetupLogger(cfg.App.Env).Handler()))
 
 	application := app.New(ctx, log, cfg)
 
 	go application.HTTPSrv.MustRun()
+	go application.GRPCSrv.MustRun()
+	go application.Worker.Run()
+	go application.Relay.Run()
 
 	<-ctx.Done()
 
 	log.Info("received signal stop signal")
 
+	// Readiness fails from now on, while the servers still accept requests,
+	// so load balancers stop routing to this instance before it goes away.
+	application.Health.Shutdown()
+	time.Sleep(cfg.Health.DrainDelay)
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
+	// Closed first, so open event streams end instead of holding up the shutdown.
+	application.Broadcaster.Close()
 	application.HTTPSrv.Stop(shutdownCtx)
+	application.GRPCSrv.Stop(shutdownCtx)
+	// Stopped after the servers, so in-flight requests can still finish their changes.
+	application.Executor.Stop()
+	application.Worker.Stop(shutdownCtx)
+	application.Relay.Stop(shutdownCtx)
+	// Shut down last, so the spans of the stopped components are exported.
+	if err := application.Tracing.Shutdown(shutdownCtx); err != nil {
+		log.Error("failed to shut down tracing", "err", err)
+	}
 
 	log.Info("application stopped gracefully")
 }
@@ -2,17 +2,49 @@ package app
 
 import (
 	"context"
+	"expvar"
 	"log/slog"
+	"os"
 
+	grpcApp "github.com/passwordhash/asynchronous-wallet/internal/app/grpc"
 	httpApp "github.com/passwordhash/asynchronous-wallet/internal/app/http"
+	relayApp "github.com/passwordhash/asynchronous-wallet/internal/app/relay"
+	workerApp "github.com/passwordhash/asynchronous-wallet/internal/app/worker"
 	"github.com/passwordhash/asynchronous-wallet/internal/config"
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/health"
+	"github.com/passwordhash/asynchronous-wallet/internal/metrics"
+	"github.com/passwordhash/asynchronous-wallet/internal/publisher"
+	"github.com/passwordhash/asynchronous-wallet/internal/ratelimit"
+	apiKeySvc "github.com/passwordhash/asynchronous-wallet/internal/service/apikey"
+	auditSvc "github.com/passwordhash/asynchronous-wallet/internal/service/audit"
 	walletSvc "github.com/passwordhash/asynchronous-wallet/internal/service/wallet"
+	webhookSvc "github.com/passwordhash/asynchronous-wallet/internal/service/webhook"
+	apiKeyRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/apikey"
+	auditRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/audit"
+	outboxRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/outbox"
 	walletRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/wallet"
+	webhookRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/webhook"
+	"github.com/passwordhash/asynchronous-wallet/internal/token"
+	"github.com/passwordhash/asynchronous-wallet/internal/tracing"
+	"github.com/passwordhash/asynchronous-wallet/migrations"
+	"github.com/passwordhash/asynchronous-wallet/pkg/broadcast"
+	"github.com/passwordhash/asynchronous-wallet/pkg/executor"
 	postgresPkg "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
 )
 
 type App struct {
-	HTTPSrv *httpApp.App
+	HTTPSrv  *httpApp.App
+	GRPCSrv  *grpcApp.App
+	Worker   *workerApp.App
+	Relay    *relayApp.App
+	Executor *executor.Executor
+	// Broadcaster feeds the balance change streams.
+	Broadcaster *broadcast.Broadcaster[entity.BalanceChange]
+	Tracing     *tracing.Tracing
+	// Health turns the readiness probe unready once shutdown begins.
+	Health *health.Health
 }
 
 func New(
@@ -20,26 +52,198 @@ func New(
 	log *slog.Logger,
 	cfg *config.Config,
 ) *App {
-	pgPool, err := postgresPkg.NewPool(ctx, cfg.PG.DSN())
+	defaultCurrency := entity.Currency(cfg.Wallet.DefaultCurrency)
+	if !defaultCurrency.IsSupported() {
+		panic("unsupported default wallet currency: " + cfg.Wallet.DefaultCurrency)
+	}
+
+	appTracing, err := tracing.New(ctx, cfg.Tracing)
+	if err != nil {
+		panic("failed to set up tracing: " + err.Error())
+	}
+
+	pgPool, err := postgresPkg.NewPool(ctx, cfg.PG.DSN(),
+		postgresPkg.WithMaxConns(cfg.PG.MaxConns),
+		postgresPkg.WithTracerProvider(appTracing.TracerProvider()),
+	)
 	if err != nil {
 		panic("failed to create postgres pool: " + err.Error())
 	}
 
-	walletRepository := walletRepo.New(pgPool)
+	schemaVersion, err := migrations.Version()
+	if err != nil {
+		panic("failed to get schema version: " + err.Error())
+	}
+
+	appHealth := health.New(cfg.Health.CheckTimeout)
+	appHealth.AddCheck("postgres", health.Postgres(pgPool))
+	appHealth.AddCheck("migrations", health.Migrations(pgPool, schemaVersion))
+
+	appMetrics := metrics.New()
+	appMetrics.RegisterPool(pgPool)
+
+	auditService := auditSvc.New(
+		log.WithGroup("audit_service"),
+		auditRepo.New(pgPool),
+	)
+
+	walletRepository := walletRepo.New(
+		pgPool,
+		walletRepo.WithMetrics(appMetrics),
+		walletRepo.WithDefaultOverdraftLimit(cfg.Wallet.DefaultOverdraftLimit),
+		walletRepo.WithDefaultCurrency(defaultCurrency),
+		walletRepo.WithAudit(auditService.Stamp),
+		walletRepo.WithMaxQueuedAttempts(cfg.Operations.MaxAttempts),
+	)
+
+	walletExecutor := executor.New(cfg.Executor.Workers, cfg.Executor.QueueSize)
+	expvar.Publish("wallet_executor", expvar.Func(func() any {
+		return walletExecutor.Stats()
+	}))
+	appMetrics.RegisterExecutor(walletExecutor)
+
+	balanceBroadcaster := broadcast.New[entity.BalanceChange](cfg.Stream.History, cfg.Stream.BufferSize, cfg.Stream.Retention,
+		broadcast.WithGapTimeout(cfg.Stream.GapTimeout),
+	)
 
 	walletService := walletSvc.New(
 		log.WithGroup("wallet_service"),
 		walletRepository,
+		walletSvc.WithHoldTTL(cfg.Holds.DefaultTTL, cfg.Holds.MaxTTL),
+		walletSvc.WithExecutor(walletExecutor),
+		walletSvc.WithBroadcaster(balanceBroadcaster),
+		walletSvc.WithMetrics(appMetrics),
+		walletSvc.WithTracerProvider(appTracing.TracerProvider()),
+	)
+
+	webhookService := webhookSvc.New(
+		log.WithGroup("webhook_service"),
+		webhookRepo.New(pgPool),
+		webhookSvc.WithRetryPolicy(cfg.Webhooks.MaxAttempts, cfg.Webhooks.BaseBackoff, cfg.Webhooks.MaxBackoff),
+		webhookSvc.WithTimeout(cfg.Webhooks.Timeout),
+		webhookSvc.WithAuditor(auditService),
+	)
+
+	apiKeyService := apiKeySvc.New(
+		log.WithGroup("api_key_service"),
+		apiKeyRepo.New(pgPool),
+		apiKeySvc.WithAuditor(auditService),
 	)
 
+	// Left nil unless enabled, so that only API keys are accepted.
+	var tokenVerifier auth.TokenVerifier
+	if cfg.JWT.Enabled {
+		verifier, err := token.New(ctx, cfg.JWT)
+		if err != nil {
+			panic("failed to set up jwt verification: " + err.Error())
+		}
+		tokenVerifier = verifier
+	}
+
+	rateLimiter, err := ratelimit.New(log.WithGroup("rate_limit"), cfg.RateLimit, pgPool)
+	if err != nil {
+		panic("failed to set up rate limiting: " + err.Error())
+	}
+
 	httpSrv := httpApp.New(
 		ctx,
 		log,
 		cfg.HTTP,
 		walletService,
+		webhookService,
+		apiKeyService,
+		tokenVerifier,
+		rateLimiter,
+		appMetrics,
+		appTracing,
+		appHealth,
+	)
+
+	grpcSrv := grpcApp.New(
+		ctx,
+		log,
+		cfg.GRPC,
+		walletService,
+		apiKeyService,
+		rateLimiter,
+	)
+
+	worker := workerApp.New(
+		ctx,
+		log.WithGroup("worker"),
+		workerApp.Job{
+			Name:     "idempotency_cleanup",
+			Interval: cfg.Idempotency.CleanupInterval,
+			Run: func(ctx context.Context) error {
+				return walletService.CleanupIdempotencyKeys(ctx, cfg.Idempotency.Retention)
+			},
+		},
+		workerApp.Job{
+			Name:     "hold_expiry",
+			Interval: cfg.Holds.SweepInterval,
+			Run:      walletService.ReleaseExpiredHolds,
+		},
+		workerApp.Job{
+			Name:     "operation_queue",
+			Interval: cfg.Operations.PollInterval,
+			Run:      walletService.ProcessQueuedOperations,
+		},
+		workerApp.Job{
+			Name:     "webhook_delivery",
+			Interval: cfg.Webhooks.PollInterval,
+			Run:      webhookService.DeliverDue,
+		},
+		workerApp.Job{
+			Name:     "rate_limit_cleanup",
+			Interval: cfg.RateLimit.CleanupInterval,
+			Run:      rateLimiter.Cleanup,
+		},
+		workerApp.Job{
+			Name:     "audit_sequencer",
+			Interval: cfg.Audit.SequenceInterval,
+			Run:      auditService.Sequence,
+		},
+	)
+
+	relay := relayApp.New(
+		ctx,
+		log.WithGroup("relay"),
+		outboxRepo.New(pgPool,
+			outboxRepo.WithRetryPolicy(cfg.Outbox.MaxAttempts, cfg.Outbox.BaseBackoff, cfg.Outbox.MaxBackoff),
+		),
+		// Events are also fanned out to the subscribed webhooks.
+		publisher.NewMulti(mustPublisher(log, cfg.Outbox), webhookService),
+		cfg.Outbox.PollInterval,
+		cfg.Outbox.BatchSize,
 	)
 
 	return &App{
-		HTTPSrv: httpSrv,
+		HTTPSrv:     httpSrv,
+		GRPCSrv:     grpcSrv,
+		Worker:      worker,
+		Relay:       relay,
+		Executor:    walletExecutor,
+		Broadcaster: balanceBroadcaster,
+		Tracing:     appTracing,
+		Health:      appHealth,
+	}
+}
+
+// mustPublisher returns the publisher wallet events are delivered with and panics
+// if the configured one is unknown or can't be opened.
+func mustPublisher(log *slog.Logger, cfg config.OutboxConfig) publisher.Publisher {
+	switch cfg.Publisher {
+	case "log":
+		return publisher.NewLog(log.WithGroup("events"))
+	case "stdout":
+		return publisher.NewWriter(os.Stdout)
+	case "file":
+		p, err := publisher.NewFile(cfg.FilePath)
+		if err != nil {
+			panic("failed to create file publisher: " + err.Error())
+		}
+		return p
+	default:
+		panic("unknown outbox publisher: " + cfg.Publisher)
 	}
 }
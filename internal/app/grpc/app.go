@@ -0,0 +1,239 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	walletHandler "github.com/passwordhash/asynchronous-wallet/internal/handler/grpc/wallet"
+	"github.com/passwordhash/asynchronous-wallet/internal/ratelimit"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/audit"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	walletSvc "github.com/passwordhash/asynchronous-wallet/internal/service/wallet"
+	walletv1 "github.com/passwordhash/asynchronous-wallet/pkg/api/wallet/v1"
+)
+
+// apiKeyMetadata is the metadata key calls pass their API key in, the gRPC
+// counterpart of the X-API-Key header.
+const apiKeyMetadata = "x-api-key"
+
+// apiKeyContextKey is the context key of the API key an authenticated call was made with.
+type apiKeyContextKey struct{}
+
+// requestIDMetadata is the metadata key of the request ID changes are audited with,
+// the counterpart of the X-Request-ID header.
+const requestIDMetadata = "x-request-id"
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*entity.APIKey, error)
+}
+
+type App struct {
+	log           *slog.Logger
+	walletSvc     *walletSvc.Service
+	authenticator Authenticator
+	rateLimiter   *ratelimit.RateLimiter
+
+	port int
+
+	server *grpc.Server
+	health *health.Server
+}
+
+func New(
+	_ context.Context,
+	log *slog.Logger,
+	cfg config.GRPCConfig,
+	walletSvc *walletSvc.Service,
+	authenticator Authenticator,
+	rateLimiter *ratelimit.RateLimiter,
+) *App {
+	a := &App{
+		log:           log,
+		walletSvc:     walletSvc,
+		authenticator: authenticator,
+		rateLimiter:   rateLimiter,
+
+		port: cfg.Port,
+
+		health: health.NewServer(),
+	}
+
+	// Calls are limited after authentication, so they are counted against their client.
+	a.server = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		a.recovery,
+		a.authenticate,
+		rateLimiter.UnaryInterceptor(clientID),
+	))
+
+	walletv1.RegisterWalletServiceServer(a.server, walletHandler.New(a.walletSvc))
+	healthpb.RegisterHealthServer(a.server, a.health)
+	reflection.Register(a.server)
+
+	return a
+}
+
+// MustRun starts the gRPC server and panics if it fails to start.
+func (a *App) MustRun() {
+	if err := a.Run(); err != nil {
+		panic("failed to run gRPC server: " + err.Error())
+	}
+}
+
+// Run starts the gRPC server and listens on the specified port.
+// It returns nil once the server is stopped.
+func (a *App) Run() error {
+	const op = "grpcapp.Run"
+
+	log := a.log.With(
+		slog.String("op", op),
+		slog.Int("port", a.port),
+	)
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("Starting gRPC server")
+
+	return a.server.Serve(l)
+}
+
+// Stop gracefully stops the gRPC server. If the in-flight calls don't finish
+// before ctx is done, they are cancelled.
+func (a *App) Stop(ctx context.Context) {
+	const op = "grpcapp.Stop"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("Stopping gRPC server")
+
+	// Health checks report NOT_SERVING while the server drains.
+	a.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		a.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("gRPC server stopped gracefully")
+	case <-ctx.Done():
+		a.server.Stop()
+		log.Error("Failed to gracefully stop gRPC server", slog.Any("error", ctx.Err()))
+	}
+}
+
+// recovery turns a panic in a handler into an Internal error instead of
+// crashing the process.
+func (a *App) recovery(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.log.Error("panic in gRPC handler",
+				slog.String("method", info.FullMethod),
+				slog.Any("panic", r),
+				slog.String("stack", string(debug.Stack())),
+			)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// authenticate checks the API key of wallet service calls the way the HTTP API
+// does: a missing or invalid key is Unauthenticated, a read-only key for Deposit
+// or Withdraw and a wallet outside the scope of the key are PermissionDenied.
+// Health and reflection calls need no key.
+func (a *App) authenticate(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/"+walletv1.WalletService_ServiceDesc.ServiceName+"/") {
+		return handler(ctx, req)
+	}
+
+	var plain string
+	if values := metadata.ValueFromIncomingContext(ctx, apiKeyMetadata); len(values) > 0 {
+		plain = values[0]
+	}
+	if plain == "" {
+		return nil, status.Error(codes.Unauthenticated, "API key is required")
+	}
+
+	key, err := a.authenticator.Authenticate(ctx, plain)
+	if errors.Is(err, svcErr.ErrInvalidAPIKey) {
+		return nil, status.Error(codes.Unauthenticated, "API key is invalid or revoked")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+
+	write := info.FullMethod == walletv1.WalletService_Deposit_FullMethodName ||
+		info.FullMethod == walletv1.WalletService_Withdraw_FullMethodName
+	if write && !key.CanWrite() {
+		return nil, status.Error(codes.PermissionDenied, "API key is read-only")
+	}
+
+	if r, ok := req.(interface{ GetWalletId() string }); !ok || !key.AllowsWallet(r.GetWalletId()) {
+		return nil, status.Error(codes.PermissionDenied, "API key has no access to the wallet")
+	}
+
+	ctx = context.WithValue(ctx, apiKeyContextKey{}, key)
+
+	return handler(withActor(ctx, key), req)
+}
+
+// clientID returns the client of an authenticated call, the way the HTTP API names
+// it, or an empty string for calls that need no key.
+func clientID(ctx context.Context) string {
+	if key, ok := ctx.Value(apiKeyContextKey{}).(*entity.APIKey); ok {
+		return "key:" + key.ID
+	}
+	return ""
+}
+
+// withActor returns a copy of ctx whose changes are audited as made with the key
+// from the address of the peer, under the request ID of the call or a new one.
+func withActor(ctx context.Context, key *entity.APIKey) context.Context {
+	actor := audit.Actor{ID: "key:" + key.ID}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			actor.SourceIP = host
+		}
+	}
+
+	requestID := uuid.NewString()
+	if values := metadata.ValueFromIncomingContext(ctx, requestIDMetadata); len(values) > 0 &&
+		values[0] != "" && len(values[0]) <= audit.MaxRequestIDLength {
+		requestID = values[0]
+	}
+
+	return audit.WithRequestID(audit.WithActor(ctx, actor), requestID)
+}
@@ -3,25 +3,57 @@ package httpapp
 import (
 	"context"
 	"errors"
+	"expvar"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
+	"github.com/passwordhash/asynchronous-wallet/api/openapi"
 	"github.com/passwordhash/asynchronous-wallet/internal/config"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	ledgerHandler "github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/ledger"
 	walletHandler "github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/wallet"
+	webhookHandler "github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/webhook"
+	docsHandler "github.com/passwordhash/asynchronous-wallet/internal/handler/docs"
+	healthHandler "github.com/passwordhash/asynchronous-wallet/internal/handler/health"
+	"github.com/passwordhash/asynchronous-wallet/internal/metrics"
+	"github.com/passwordhash/asynchronous-wallet/internal/ratelimit"
+	apiKeySvc "github.com/passwordhash/asynchronous-wallet/internal/service/apikey"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/audit"
 	walletSvc "github.com/passwordhash/asynchronous-wallet/internal/service/wallet"
+	webhookSvc "github.com/passwordhash/asynchronous-wallet/internal/service/webhook"
+	"github.com/passwordhash/asynchronous-wallet/internal/tracing"
 )
 
-type App struct {
-	log       *slog.Logger
-	walletSvc *walletSvc.Service
+// walletService is the wallet service as used by the wallet and ledger handlers
+// and to check the owners of wallets.
+type walletService interface {
+	walletHandler.WalletService
+	ledgerHandler.LedgerService
+	auth.Owners
+}
 
-	port         int
-	readTimeout  time.Duration
-	writeTimeout time.Duration
+type App struct {
+	log        *slog.Logger
+	walletSvc  *walletSvc.Service
+	webhookSvc *webhookSvc.Service
+	apiKeySvc  *apiKeySvc.Service
+	// verifier is nil if bearer tokens aren't accepted.
+	verifier    auth.TokenVerifier
+	rateLimiter *ratelimit.RateLimiter
+	metrics     *metrics.Metrics
+	tracing     *tracing.Tracing
+	prober      healthHandler.Prober
+
+	port           int
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	trustedProxies []string
 
 	server *http.Server
 }
@@ -31,14 +63,29 @@ func New(
 	log *slog.Logger,
 	cfg config.HttpConfig,
 	walletSvc *walletSvc.Service,
+	webhookSvc *webhookSvc.Service,
+	apiKeySvc *apiKeySvc.Service,
+	verifier auth.TokenVerifier,
+	rateLimiter *ratelimit.RateLimiter,
+	metrics *metrics.Metrics,
+	tracing *tracing.Tracing,
+	prober healthHandler.Prober,
 ) *App {
 	return &App{
-		log:       log,
-		walletSvc: walletSvc,
-
-		port:         cfg.Port,
-		readTimeout:  cfg.ReadTimeout,
-		writeTimeout: cfg.WriteTimeout,
+		log:         log,
+		walletSvc:   walletSvc,
+		webhookSvc:  webhookSvc,
+		apiKeySvc:   apiKeySvc,
+		verifier:    verifier,
+		rateLimiter: rateLimiter,
+		metrics:     metrics,
+		tracing:     tracing,
+		prober:      prober,
+
+		port:           cfg.Port,
+		readTimeout:    cfg.ReadTimeout,
+		writeTimeout:   cfg.WriteTimeout,
+		trustedProxies: cfg.TrustedProxies,
 	}
 }
 
@@ -59,23 +106,19 @@ func (a *App) Run() error {
 		slog.Int("port", a.port),
 	)
 
-	walletHlr := walletHandler.New(a.walletSvc)
-
-	app := gin.New()
-	app.Use(gin.Recovery())
-
-	app.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
-
-	api := app.Group("/api")
-	v1 := api.Group("/v1")
+	spec, err := openapi.JSON(context.Background())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-	walletHlr.RegisterRoutes(v1)
+	router, err := newRouter(a.walletSvc, a.webhookSvc, a.apiKeySvc, a.verifier, a.rateLimiter, spec, a.metrics, a.tracing, a.prober, a.trustedProxies)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	srv := &http.Server{
 		Addr:         ":" + strconv.Itoa(a.port),
-		Handler:      app,
+		Handler:      router,
 		ReadTimeout:  a.readTimeout,
 		WriteTimeout: a.writeTimeout,
 	}
@@ -101,3 +144,89 @@ func (a *App) Stop(ctx context.Context) {
 		log.Info("HTTP server stopped gracefully")
 	}
 }
+
+// newRouter returns the router with every route of the HTTP API. The client IP of
+// a request is taken from X-Forwarded-For only if it comes from one of the trusted proxies.
+func newRouter(
+	walletSvc walletService,
+	webhookSvc webhookHandler.WebhookService,
+	authenticator auth.Authenticator,
+	verifier auth.TokenVerifier,
+	rateLimiter *ratelimit.RateLimiter,
+	spec []byte,
+	metrics *metrics.Metrics,
+	tracing *tracing.Tracing,
+	prober healthHandler.Prober,
+	trustedProxies []string,
+) (*gin.Engine, error) {
+	walletHlr := walletHandler.New(walletSvc)
+	ledgerHlr := ledgerHandler.New(walletSvc)
+	webhookHlr := webhookHandler.New(webhookSvc)
+	docsHlr := docsHandler.New(spec)
+	healthHlr := healthHandler.New(prober)
+
+	app := gin.New()
+	// gin trusts every proxy by default, so any client could set the IP it is audited with.
+	if err := app.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	// Tracing goes first, so the spans cover the time of the other middleware.
+	// Recovery goes after the metrics, so requests that panicked are counted with
+	// the 500 it responds with.
+	app.Use(tracing.HTTPMiddleware(), metrics.HTTPMiddleware(), gin.Recovery(), requestID())
+
+	var authOpts []auth.Option
+	if verifier != nil {
+		authOpts = append(authOpts, auth.WithTokens(verifier, walletSvc))
+	}
+	authenticate := auth.Middleware(authenticator, authOpts...)
+
+	// Liveness at /livez and readiness with the result of every check at /readyz.
+	healthHlr.RegisterRoutes(app)
+	// Runtime and executor metrics, e.g. wallet_executor.queueDepths. They include the
+	// command line and memory statistics of the process, so only keys with access
+	// to all wallets may read them.
+	app.GET("/debug/vars",
+		rateLimiter.IPMiddleware(),
+		authenticate,
+		auth.RequireAllWallets(),
+		gin.WrapH(expvar.Handler()),
+	)
+	app.GET("/metrics", gin.WrapH(metrics.Handler()))
+	// OpenAPI specification at /openapi.json and Swagger UI at /swagger/.
+	docsHlr.RegisterRoutes(app)
+
+	api := app.Group("/api")
+	// Limited by source IP before authentication, so failed authentications are
+	// limited too, and after it, so requests are counted against their client.
+	v1 := api.Group("/v1",
+		rateLimiter.IPMiddleware(),
+		authenticate,
+		rateLimiter.HTTPMiddleware(),
+	)
+
+	walletHlr.RegisterRoutes(v1)
+	ledgerHlr.RegisterRoutes(v1)
+	webhookHlr.RegisterRoutes(v1)
+
+	return app, nil
+}
+
+// requestIDHeader is the header of the ID a request is audited under.
+const requestIDHeader = "X-Request-ID"
+
+// requestID takes the request ID from the [requestIDHeader] header of the request,
+// or generates one if it is missing or too long. The ID is returned in the same
+// header and the changes of the request are audited under it.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > audit.MaxRequestIDLength {
+			id = uuid.NewString()
+		}
+
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(audit.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
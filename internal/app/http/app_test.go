@@ -0,0 +1,1222 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/api/openapi"
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/health"
+	"github.com/passwordhash/asynchronous-wallet/internal/metrics"
+	"github.com/passwordhash/asynchronous-wallet/internal/ratelimit"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	"github.com/passwordhash/asynchronous-wallet/internal/tracing"
+	"github.com/passwordhash/asynchronous-wallet/pkg/broadcast"
+)
+
+const (
+	testWalletID      = "123e4567-e89b-12d3-a456-426614174000"
+	testOtherWalletID = "123e4567-e89b-12d3-a456-426614174001"
+	testHoldID        = "223e4567-e89b-12d3-a456-426614174000"
+	testTransactionID = "323e4567-e89b-12d3-a456-426614174000"
+	testOperationID   = "423e4567-e89b-12d3-a456-426614174000"
+	testWebhookID     = "523e4567-e89b-12d3-a456-426614174000"
+	testDeliveryID    = "623e4567-e89b-12d3-a456-426614174000"
+	testEventID       = "723e4567-e89b-12d3-a456-426614174000"
+
+	testAdminKey  = "wlt_admin"
+	testReaderKey = "wlt_reader"
+	testScopedKey = "wlt_scoped"
+
+	testUserToken   = "user-token"
+	testUserSubject = "user-1"
+)
+
+var testTime = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// loadSpec loads the specification with additional properties disallowed in every
+// object schema that lists its properties, so a field the handlers add without
+// documenting it fails the validation.
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	doc, err := openapi.Load(context.Background())
+	require.NoError(t, err)
+
+	visited := make(map[*openapi3.Schema]bool)
+	for _, ref := range doc.Components.Schemas {
+		disallowAdditionalProperties(ref, visited)
+	}
+
+	return doc
+}
+
+func disallowAdditionalProperties(ref *openapi3.SchemaRef, visited map[*openapi3.Schema]bool) {
+	if ref == nil || ref.Value == nil || visited[ref.Value] {
+		return
+	}
+	schema := ref.Value
+	visited[schema] = true
+
+	if len(schema.Properties) > 0 {
+		disallowed := false
+		schema.AdditionalPropertiesAllowed = &disallowed
+	}
+	for _, prop := range schema.Properties {
+		disallowAdditionalProperties(prop, visited)
+	}
+	disallowAdditionalProperties(schema.Items, visited)
+}
+
+// newTestTracing returns tracing that records no spans.
+func newTestTracing(t *testing.T) *tracing.Tracing {
+	t.Helper()
+
+	tr, err := tracing.New(context.Background(), config.TracingConfig{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+
+	return tr
+}
+
+// newTestRateLimiter returns a rate limiter with the backend that allows a single
+// request for every client and wallet.
+func newTestRateLimiter(t *testing.T, backend string) *ratelimit.RateLimiter {
+	t.Helper()
+
+	limiter, err := ratelimit.New(slog.New(slog.NewTextHandler(io.Discard, nil)), config.RateLimitConfig{
+		Backend:     backend,
+		IPRate:      1,
+		IPBurst:     10,
+		ClientRate:  1,
+		ClientBurst: 1,
+		WalletRate:  1,
+		WalletBurst: 1,
+	}, nil)
+	require.NoError(t, err)
+
+	return limiter
+}
+
+func TestRouter_RoutesMatchSpec(t *testing.T) {
+	doc := loadSpec(t)
+	router, err := newRouter(&fakeWalletService{}, &fakeWebhookService{}, fakeAuthenticator{}, fakeVerifier{}, newTestRateLimiter(t, ratelimit.BackendNone), nil, metrics.New(), newTestTracing(t), health.New(time.Second), nil)
+	require.NoError(t, err)
+
+	pathParam := regexp.MustCompile(`:(\w+)`)
+
+	var routes []string
+	for _, r := range router.Routes() {
+		if !strings.HasPrefix(r.Path, "/api/") {
+			continue
+		}
+		routes = append(routes, r.Method+" "+pathParam.ReplaceAllString(r.Path, "{$1}"))
+	}
+
+	var documented []string
+	for path, item := range doc.Paths {
+		for method := range item.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+
+	assert.ElementsMatch(t, documented, routes)
+}
+
+func TestRouter_ErrorCodesMatchSpec(t *testing.T) {
+	doc := loadSpec(t)
+
+	var documented []string
+	for _, code := range doc.Components.Schemas["ErrorCode"].Value.Enum {
+		documented = append(documented, code.(string))
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "../../handler/api/v1/response/response.go", nil, 0)
+	require.NoError(t, err)
+
+	var codes []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for i, name := range spec.Names {
+			if !strings.HasPrefix(name.Name, "ErrCode") {
+				continue
+			}
+			code, err := strconv.Unquote(spec.Values[i].(*ast.BasicLit).Value)
+			require.NoError(t, err)
+			codes = append(codes, code)
+		}
+		return true
+	})
+
+	assert.ElementsMatch(t, documented, codes)
+}
+
+func TestRouter_ResponsesMatchSpec(t *testing.T) {
+	doc := loadSpec(t)
+	specRouter, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		header map[string]string
+		err    error
+		// invalidRequest is set when the request itself violates the spec.
+		invalidRequest bool
+		// rateLimited sends the request twice, so the second one exceeds the rate limit.
+		rateLimited bool
+		wantStatus  int
+	}{
+		{
+			name:       "Deposit",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "deposit", "amount": 100}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Withdraw with idempotency key",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "withdraw", "amount": 100, "currency": "RUB"}`,
+			header:     map[string]string{"Idempotency-Key": "key"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Deposit in async mode",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "deposit", "amount": 100}`,
+			header:     map[string]string{"Prefer": "respond-async"},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:           "Operation with invalid body",
+			method:         http.MethodPost,
+			target:         "/api/v1/wallet",
+			body:           `{"walletId": "` + testWalletID + `", "operationType": "deposit"}`,
+			invalidRequest: true,
+			wantStatus:     http.StatusBadRequest,
+		},
+		{
+			name:       "Withdraw with insufficient funds",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "withdraw", "amount": 100}`,
+			err:        svcErr.ErrInsufficientFunds,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Deposit to busy wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "deposit", "amount": 100}`,
+			err:        svcErr.ErrBusy,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Transfer",
+			method:     http.MethodPost,
+			target:     "/api/v1/transfers",
+			body:       `{"fromWalletId": "` + testWalletID + `", "toWalletId": "` + testOtherWalletID + `", "amount": 100, "currency": "RUB"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Transfer with currency mismatch",
+			method:     http.MethodPost,
+			target:     "/api/v1/transfers",
+			body:       `{"fromWalletId": "` + testWalletID + `", "toWalletId": "` + testOtherWalletID + `", "amount": 100, "currency": "EUR"}`,
+			err:        svcErr.ErrCurrencyMismatch,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Create wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets",
+			body:       `{"id": "` + testWalletID + `", "overdraftLimit": 0, "currency": "RUB"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Create existing wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets",
+			body:       `{"id": "` + testWalletID + `"}`,
+			err:        svcErr.ErrWalletAlreadyExists,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Get wallet",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testWalletID,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Get unknown wallet",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testWalletID,
+			err:        svcErr.ErrWalletNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "List transactions",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testWalletID + "/transactions?type=reversal&limit=10&from=2025-01-01T00:00:00Z",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Watch unknown wallet",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testWalletID + "/events",
+			err:        svcErr.ErrWalletNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Create hold",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets/" + testWalletID + "/holds",
+			body:       `{"amount": 100, "ttlSeconds": 60}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Get hold",
+			method:     http.MethodGet,
+			target:     "/api/v1/holds/" + testHoldID,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Capture hold",
+			method:     http.MethodPost,
+			target:     "/api/v1/holds/" + testHoldID + "/capture",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Capture more than the hold",
+			method:     http.MethodPost,
+			target:     "/api/v1/holds/" + testHoldID + "/capture",
+			body:       `{"amount": 1000}`,
+			err:        svcErr.ErrHoldAmountExceeded,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Release inactive hold",
+			method:     http.MethodPost,
+			target:     "/api/v1/holds/" + testHoldID + "/release",
+			err:        svcErr.ErrHoldNotActive,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Get operation",
+			method:     http.MethodGet,
+			target:     "/api/v1/operations/" + testOperationID,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Deposit to frozen wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "deposit", "amount": 100}`,
+			err:        svcErr.ErrWalletFrozen,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Freeze wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets/" + testWalletID + "/freeze",
+			body:       `{"reason": "suspicious activity"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "Freeze wallet without reason",
+			method:         http.MethodPost,
+			target:         "/api/v1/wallets/" + testWalletID + "/freeze",
+			body:           `{}`,
+			invalidRequest: true,
+			wantStatus:     http.StatusBadRequest,
+		},
+		{
+			name:       "Unfreeze active wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets/" + testWalletID + "/unfreeze",
+			body:       `{"reason": "checked"}`,
+			err:        svcErr.ErrWalletStatusTransition,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Close wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets/" + testWalletID + "/close",
+			body:       `{"reason": "customer request", "sweepToWalletId": "` + testOtherWalletID + `"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Close wallet with balance",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets/" + testWalletID + "/close",
+			body:       `{"reason": "customer request"}`,
+			err:        svcErr.ErrWalletNotEmpty,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Close wallet with key for the wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets/" + testWalletID + "/close",
+			body:       `{"reason": "customer request"}`,
+			header:     map[string]string{auth.APIKeyHeader: testScopedKey},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Assign wallet owner",
+			method:     http.MethodPut,
+			target:     "/api/v1/wallets/" + testWalletID + "/owner",
+			body:       `{"ownerId": "` + testUserSubject + `"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "Assign wallet owner without owner",
+			method:         http.MethodPut,
+			target:         "/api/v1/wallets/" + testWalletID + "/owner",
+			body:           `{}`,
+			invalidRequest: true,
+			wantStatus:     http.StatusBadRequest,
+		},
+		{
+			name:       "Assign wallet owner with key for the wallet",
+			method:     http.MethodPut,
+			target:     "/api/v1/wallets/" + testWalletID + "/owner",
+			body:       `{"ownerId": "` + testUserSubject + `"}`,
+			header:     map[string]string{auth.APIKeyHeader: testScopedKey},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Assign owner of own wallet with bearer token",
+			method:     http.MethodPut,
+			target:     "/api/v1/wallets/" + testWalletID + "/owner",
+			body:       `{"ownerId": "someone-else"}`,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer " + testUserToken},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Reverse transaction",
+			method:     http.MethodPost,
+			target:     "/api/v1/transactions/" + testTransactionID + "/reverse",
+			body:       `{"amount": 30}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Reverse transfer",
+			method:     http.MethodPost,
+			target:     "/api/v1/transactions/" + testTransactionID + "/reverse",
+			err:        svcErr.ErrNotReversible,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Get trial balance",
+			method:     http.MethodGet,
+			target:     "/api/v1/ledger/trial-balance",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Get trial balance with failing service",
+			method:     http.MethodGet,
+			target:     "/api/v1/ledger/trial-balance",
+			err:        assert.AnError,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Create webhook",
+			method:     http.MethodPost,
+			target:     "/api/v1/webhooks",
+			body:       `{"url": "https://example.com/hook", "eventTypes": ["WalletDebited"], "walletIds": ["` + testWalletID + `"]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Get webhook",
+			method:     http.MethodGet,
+			target:     "/api/v1/webhooks/" + testWebhookID,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Delete webhook",
+			method:     http.MethodDelete,
+			target:     "/api/v1/webhooks/" + testWebhookID,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "List deliveries",
+			method:     http.MethodGet,
+			target:     "/api/v1/webhooks/" + testWebhookID + "/deliveries?status=dead&limit=10",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Get delivery",
+			method:     http.MethodGet,
+			target:     "/api/v1/webhooks/" + testWebhookID + "/deliveries/" + testDeliveryID,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Redeliver",
+			method:     http.MethodPost,
+			target:     "/api/v1/webhooks/" + testWebhookID + "/deliveries/" + testDeliveryID + "/redeliver",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Redeliver pending delivery",
+			method:     http.MethodPost,
+			target:     "/api/v1/webhooks/" + testWebhookID + "/deliveries/" + testDeliveryID + "/redeliver",
+			err:        svcErr.ErrWebhookDeliveryNotDead,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:           "Without API key",
+			method:         http.MethodGet,
+			target:         "/api/v1/wallets/" + testWalletID,
+			header:         map[string]string{auth.APIKeyHeader: ""},
+			invalidRequest: true,
+			wantStatus:     http.StatusUnauthorized,
+		},
+		{
+			name:       "With revoked API key",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testWalletID,
+			header:     map[string]string{auth.APIKeyHeader: "wlt_revoked"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Read with read-only key",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testWalletID,
+			header:     map[string]string{auth.APIKeyHeader: testReaderKey},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Withdraw with read-only key",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "withdraw", "amount": 100}`,
+			header:     map[string]string{auth.APIKeyHeader: testReaderKey},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Withdraw with key for the wallet",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "withdraw", "amount": 100}`,
+			header:     map[string]string{auth.APIKeyHeader: testScopedKey},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Transfer to a wallet outside the key scope",
+			method:     http.MethodPost,
+			target:     "/api/v1/transfers",
+			body:       `{"fromWalletId": "` + testWalletID + `", "toWalletId": "` + testOtherWalletID + `", "amount": 100}`,
+			header:     map[string]string{auth.APIKeyHeader: testScopedKey},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Capture hold of a wallet in the key scope",
+			method:     http.MethodPost,
+			target:     "/api/v1/holds/" + testHoldID + "/capture",
+			header:     map[string]string{auth.APIKeyHeader: testScopedKey},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Get wallet in the key scope by uppercase ID",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + strings.ToUpper(testWalletID),
+			header:     map[string]string{auth.APIKeyHeader: testScopedKey},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Get wallet outside the key scope",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testOtherWalletID,
+			header:     map[string]string{auth.APIKeyHeader: testScopedKey},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Get webhook with key limited to some wallets",
+			method:     http.MethodGet,
+			target:     "/api/v1/webhooks/" + testWebhookID,
+			header:     map[string]string{auth.APIKeyHeader: testScopedKey},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Get own wallet with bearer token",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testWalletID,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer " + testUserToken},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Withdraw from own wallet with bearer token",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallet",
+			body:       `{"walletId": "` + testWalletID + `", "operationType": "withdraw", "amount": 100}`,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer " + testUserToken},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Transfer from own wallet to another user with bearer token",
+			method:     http.MethodPost,
+			target:     "/api/v1/transfers",
+			body:       `{"fromWalletId": "` + testWalletID + `", "toWalletId": "` + testOtherWalletID + `", "amount": 100}`,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer " + testUserToken},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Transfer from wallet of another user with bearer token",
+			method:     http.MethodPost,
+			target:     "/api/v1/transfers",
+			body:       `{"fromWalletId": "` + testOtherWalletID + `", "toWalletId": "` + testWalletID + `", "amount": 100}`,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer " + testUserToken},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Create wallet with bearer token",
+			method:     http.MethodPost,
+			target:     "/api/v1/wallets",
+			body:       `{"id": "` + testOtherWalletID + `"}`,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer " + testUserToken},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Get wallet of another user with bearer token",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testOtherWalletID,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer " + testUserToken},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Get webhook with bearer token",
+			method:     http.MethodGet,
+			target:     "/api/v1/webhooks/" + testWebhookID,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer " + testUserToken},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:        "Rate limited",
+			method:      http.MethodGet,
+			target:      "/api/v1/wallets/" + testWalletID,
+			rateLimited: true,
+			wantStatus:  http.StatusTooManyRequests,
+		},
+		{
+			name:       "With invalid bearer token",
+			method:     http.MethodGet,
+			target:     "/api/v1/wallets/" + testWalletID,
+			header:     map[string]string{auth.APIKeyHeader: "", "Authorization": "Bearer expired-token"},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := ratelimit.BackendNone
+			if tt.rateLimited {
+				backend = ratelimit.BackendMemory
+			}
+			router, err := newRouter(&fakeWalletService{err: tt.err}, &fakeWebhookService{err: tt.err}, fakeAuthenticator{}, fakeVerifier{}, newTestRateLimiter(t, backend), nil, metrics.New(), newTestTracing(t), health.New(time.Second), nil)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			req.Header.Set(auth.APIKeyHeader, testAdminKey)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			route, pathParams, err := specRouter.FindRoute(req)
+			require.NoError(t, err)
+
+			reqInput := &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			}
+			if !tt.invalidRequest {
+				require.NoError(t, openapi3filter.ValidateRequest(context.Background(), reqInput))
+			}
+
+			if tt.rateLimited {
+				router.ServeHTTP(httptest.NewRecorder(), req.Clone(req.Context()))
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+
+			respInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: reqInput,
+				Status:                 rec.Code,
+				Header:                 rec.Header(),
+				Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+			}
+			respInput.SetBodyBytes(rec.Body.Bytes())
+
+			assert.NoError(t, openapi3filter.ValidateResponse(context.Background(), respInput), rec.Body.String())
+		})
+	}
+}
+
+func TestRouter_RequestID(t *testing.T) {
+	router, err := newRouter(&fakeWalletService{}, &fakeWebhookService{}, fakeAuthenticator{}, fakeVerifier{}, newTestRateLimiter(t, ratelimit.BackendNone), nil, metrics.New(), newTestTracing(t), health.New(time.Second), nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		requestID string
+		want      string
+	}{
+		{name: "Given", requestID: "test-request-id", want: "test-request-id"},
+		{name: "Missing"},
+		{name: "Too long", requestID: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+			if tt.requestID != "" {
+				req.Header.Set(requestIDHeader, tt.requestID)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(requestIDHeader)
+			if tt.want != "" {
+				assert.Equal(t, tt.want, got)
+			} else {
+				assert.NoError(t, uuid.Validate(got), "expected a generated request ID")
+			}
+		})
+	}
+}
+
+func TestRouter_DebugVars(t *testing.T) {
+	router, err := newRouter(&fakeWalletService{}, &fakeWebhookService{}, fakeAuthenticator{}, fakeVerifier{}, newTestRateLimiter(t, ratelimit.BackendNone), nil, metrics.New(), newTestTracing(t), health.New(time.Second), nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{name: "Without credentials", wantStatus: http.StatusUnauthorized},
+		{name: "Admin key", header: map[string]string{auth.APIKeyHeader: testAdminKey}, wantStatus: http.StatusOK},
+		{name: "Scoped key", header: map[string]string{auth.APIKeyHeader: testScopedKey}, wantStatus: http.StatusForbidden},
+		{name: "Bearer token", header: map[string]string{"Authorization": "Bearer " + testUserToken}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestRouter_PanicsAreCounted(t *testing.T) {
+	m := metrics.New()
+	router, err := newRouter(&fakeWalletService{}, &fakeWebhookService{}, fakeAuthenticator{}, fakeVerifier{}, newTestRateLimiter(t, ratelimit.BackendNone), nil, m, newTestTracing(t), health.New(time.Second), nil)
+	require.NoError(t, err)
+
+	router.GET("/panic", func(*gin.Context) {
+		panic("test panic")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `wallet_http_requests_total{method="GET",route="/panic",status="500"} 1`)
+	assert.Contains(t, rec.Body.String(), `wallet_http_request_duration_seconds_count{method="GET",route="/panic",status="500"} 1`)
+}
+
+func TestRouter_IdempotencyKeys(t *testing.T) {
+	walletSvc := &idempotentWalletService{keys: make(map[[2]string]*entity.IdempotencyKey)}
+	router, err := newRouter(walletSvc, &fakeWebhookService{}, fakeAuthenticator{}, fakeVerifier{}, newTestRateLimiter(t, ratelimit.BackendNone), nil, metrics.New(), newTestTracing(t), health.New(time.Second), nil)
+	require.NoError(t, err)
+
+	deposit := func(apiKey, idempotencyKey string, async bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet",
+			strings.NewReader(`{"walletId": "`+testWalletID+`", "operationType": "deposit", "amount": 100}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(auth.APIKeyHeader, apiKey)
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+		if async {
+			req.Header.Set("Prefer", "respond-async")
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Scoped to the client", func(t *testing.T) {
+		rec := deposit(testAdminKey, "1", false)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = deposit(testScopedKey, "1", false)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Empty(t, rec.Header().Get("Idempotent-Replayed"), "another client's response must not be replayed")
+
+		rec = deposit(testAdminKey, "1", false)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("Async mode is fingerprinted", func(t *testing.T) {
+		rec := deposit(testAdminKey, "2", true)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		rec = deposit(testAdminKey, "2", false)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+	})
+}
+
+func TestRouter_TrustedProxies(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		want           string
+	}{
+		{name: "None", want: "192.0.2.1"},
+		{name: "Trusted", trustedProxies: []string{"192.0.2.0/24"}, want: "198.51.100.7"},
+		{name: "Other", trustedProxies: []string{"203.0.113.0/24"}, want: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, err := newRouter(&fakeWalletService{}, &fakeWebhookService{}, fakeAuthenticator{}, fakeVerifier{}, newTestRateLimiter(t, ratelimit.BackendNone), nil, metrics.New(), newTestTracing(t), health.New(time.Second), tt.trustedProxies)
+			require.NoError(t, err)
+
+			router.GET("/client-ip", func(c *gin.Context) {
+				c.String(http.StatusOK, c.ClientIP())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/client-ip", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Body.String())
+		})
+	}
+}
+
+func TestRouter_InvalidTrustedProxies(t *testing.T) {
+	_, err := newRouter(&fakeWalletService{}, &fakeWebhookService{}, fakeAuthenticator{}, fakeVerifier{}, newTestRateLimiter(t, ratelimit.BackendNone), nil, metrics.New(), newTestTracing(t), health.New(time.Second), []string{"not-an-ip"})
+	require.Error(t, err)
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeAuthenticator knows the test keys: a read-write key for all wallets, a
+// read-only one and a read-write one for the test wallet.
+type fakeAuthenticator struct{}
+
+func (fakeAuthenticator) Authenticate(_ context.Context, key string) (*entity.APIKey, error) {
+	switch key {
+	case testAdminKey:
+		return &entity.APIKey{ID: "admin", Permission: entity.APIKeyReadWrite}, nil
+	case testReaderKey:
+		return &entity.APIKey{ID: "reader", Permission: entity.APIKeyRead}, nil
+	case testScopedKey:
+		return &entity.APIKey{ID: "scoped", Permission: entity.APIKeyReadWrite, WalletIDs: []string{testWalletID}}, nil
+	default:
+		return nil, svcErr.ErrInvalidAPIKey
+	}
+}
+
+// fakeVerifier accepts the test token of the test user, who owns the test wallet.
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(_ context.Context, token string) (string, error) {
+	if token != testUserToken {
+		return "", errors.New("invalid token")
+	}
+	return testUserSubject, nil
+}
+
+// fakeWalletService returns err from every method, or fixed entities if err is nil.
+type fakeWalletService struct {
+	err error
+}
+
+func (f *fakeWalletService) Deposit(context.Context, string, entity.Money, ...entity.OperationOption) error {
+	return f.err
+}
+
+func (f *fakeWalletService) Withdraw(context.Context, string, entity.Money, ...entity.OperationOption) error {
+	return f.err
+}
+
+func (f *fakeWalletService) Transfer(context.Context, string, string, entity.Money, ...entity.OperationOption) error {
+	return f.err
+}
+
+func (f *fakeWalletService) Create(context.Context, entity.Wallet) (*entity.Wallet, error) {
+	return f.wallet()
+}
+
+func (f *fakeWalletService) Wallet(context.Context, string) (*entity.Wallet, error) {
+	return f.wallet()
+}
+
+func (f *fakeWalletService) FreezeWallet(context.Context, string, string) (*entity.Wallet, error) {
+	return f.statusWallet(entity.WalletStatusFrozen, "suspicious activity")
+}
+
+func (f *fakeWalletService) UnfreezeWallet(context.Context, string, string) (*entity.Wallet, error) {
+	return f.statusWallet(entity.WalletStatusActive, "checked")
+}
+
+func (f *fakeWalletService) CloseWallet(context.Context, string, string, string) (*entity.Wallet, error) {
+	return f.statusWallet(entity.WalletStatusClosed, "customer request")
+}
+
+func (f *fakeWalletService) AssignOwner(_ context.Context, _ string, ownerID string) (*entity.Wallet, error) {
+	wallet, err := f.wallet()
+	if err != nil {
+		return nil, err
+	}
+	wallet.OwnerID = ownerID
+	return wallet, nil
+}
+
+func (f *fakeWalletService) Transactions(context.Context, string, entity.TransactionQuery) (*entity.TransactionPage, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.TransactionPage{
+		Transactions: []entity.Transaction{
+			{
+				ID:            testTransactionID,
+				WalletID:      testWalletID,
+				Amount:        30,
+				OperationType: entity.OperationReversal,
+				BalanceAfter:  1000,
+				ReferenceID:   testOperationID,
+				CreatedAt:     testTime,
+			},
+		},
+		NextCursor: "cursor",
+	}, nil
+}
+
+func (f *fakeWalletService) IdempotencyKey(context.Context, string, string, string) (*entity.IdempotencyKey, error) {
+	return nil, svcErr.ErrIdempotencyKeyNotFound
+}
+
+func (f *fakeWalletService) CreateHold(context.Context, string, entity.Money, time.Duration) (*entity.Hold, error) {
+	return f.hold()
+}
+
+func (f *fakeWalletService) Hold(context.Context, string) (*entity.Hold, error) {
+	return f.hold()
+}
+
+func (f *fakeWalletService) CaptureHold(context.Context, string, entity.Money) (*entity.Hold, error) {
+	return f.hold()
+}
+
+func (f *fakeWalletService) ReleaseHold(context.Context, string) (*entity.Hold, error) {
+	return f.hold()
+}
+
+func (f *fakeWalletService) Enqueue(
+	context.Context,
+	string,
+	string,
+	entity.OperationType,
+	entity.Money,
+	...entity.OperationOption,
+) error {
+	return f.err
+}
+
+// idempotentWalletService stores the idempotency keys of deposits and queued
+// operations in memory and checks them the way the wallet service does.
+type idempotentWalletService struct {
+	fakeWalletService
+	keys map[[2]string]*entity.IdempotencyKey
+}
+
+func (f *idempotentWalletService) store(opts []entity.OperationOption) error {
+	key := entity.NewOperationOptions(opts...).IdempotencyKey
+	if key == nil {
+		return nil
+	}
+	if _, ok := f.keys[[2]string{key.ClientID, key.Key}]; ok {
+		return svcErr.ErrIdempotencyKeyReused
+	}
+	f.keys[[2]string{key.ClientID, key.Key}] = key
+	return nil
+}
+
+func (f *idempotentWalletService) Deposit(_ context.Context, _ string, _ entity.Money, opts ...entity.OperationOption) error {
+	return f.store(opts)
+}
+
+func (f *idempotentWalletService) Enqueue(
+	_ context.Context,
+	_ string,
+	_ string,
+	_ entity.OperationType,
+	_ entity.Money,
+	opts ...entity.OperationOption,
+) error {
+	return f.store(opts)
+}
+
+func (f *idempotentWalletService) IdempotencyKey(_ context.Context, clientID, key, fingerprint string) (*entity.IdempotencyKey, error) {
+	stored, ok := f.keys[[2]string{clientID, key}]
+	if !ok {
+		return nil, svcErr.ErrIdempotencyKeyNotFound
+	}
+	if stored.Fingerprint != fingerprint {
+		return nil, svcErr.ErrIdempotencyKeyMismatch
+	}
+	return stored, nil
+}
+
+func (f *fakeWalletService) QueuedOperation(context.Context, string) (*entity.QueuedOperation, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.QueuedOperation{
+		ID: testOperationID,
+		Operation: entity.Operation{
+			WalletID: testWalletID,
+			Amount:   entity.NewMoney(-100, entity.CurrencyRUB),
+			Type:     entity.OperationWithdraw,
+		},
+		Status:        entity.QueuedOperationFailed,
+		FailureReason: "insufficient funds",
+		CreatedAt:     testTime,
+		UpdatedAt:     testTime,
+	}, nil
+}
+
+func (f *fakeWalletService) Reverse(context.Context, string, entity.Money, ...entity.OperationOption) (*entity.Transaction, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.Transaction{ID: testTransactionID}, nil
+}
+
+func (f *fakeWalletService) WatchBalance(
+	context.Context,
+	string,
+	string,
+) (*broadcast.Subscription[entity.BalanceChange], *entity.Wallet, error) {
+	return nil, nil, f.err
+}
+
+func (f *fakeWalletService) TrialBalance(context.Context) (*entity.TrialBalance, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.TrialBalance{
+		SystemAccounts: []entity.AccountBalance{
+			{
+				AccountID: entity.AccountCashIn,
+				Code:      "cash_in",
+				Kind:      entity.AccountKindSystem,
+				Balance:   entity.NewMoney(-1000, entity.CurrencyRUB),
+			},
+		},
+		WalletLiabilities: []entity.Money{entity.NewMoney(900, entity.CurrencyRUB)},
+		Totals:            []entity.Money{entity.NewMoney(-100, entity.CurrencyRUB)},
+		Mismatches: []entity.BalanceMismatch{
+			{
+				WalletID:      testWalletID,
+				CachedBalance: entity.NewMoney(1000, entity.CurrencyRUB),
+				LedgerBalance: entity.NewMoney(900, entity.CurrencyRUB),
+			},
+		},
+	}, nil
+}
+
+func (f *fakeWalletService) IsWalletOwner(_ context.Context, walletID, subject string) (bool, error) {
+	return walletID == testWalletID && subject == testUserSubject, nil
+}
+
+func (f *fakeWalletService) wallet() (*entity.Wallet, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	overdraftLimit := int64(0)
+	return &entity.Wallet{
+		ID:             testWalletID,
+		Balance:        entity.NewMoney(1000, entity.CurrencyRUB),
+		Held:           100,
+		Status:         entity.WalletStatusActive,
+		OverdraftLimit: &overdraftLimit,
+		OwnerID:        testUserSubject,
+		CreatedAt:      testTime,
+		UpdatedAt:      testTime,
+	}, nil
+}
+
+func (f *fakeWalletService) statusWallet(status entity.WalletStatus, reason string) (*entity.Wallet, error) {
+	wallet, err := f.wallet()
+	if err != nil {
+		return nil, err
+	}
+	wallet.Status = status
+	wallet.StatusReason = reason
+	wallet.StatusChangedAt = &testTime
+	return wallet, nil
+}
+
+func (f *fakeWalletService) hold() (*entity.Hold, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.Hold{
+		ID:             testHoldID,
+		WalletID:       testWalletID,
+		Amount:         entity.NewMoney(100, entity.CurrencyRUB),
+		CapturedAmount: 100,
+		Status:         entity.HoldStatusCaptured,
+		TransactionID:  testTransactionID,
+		ExpiresAt:      testTime,
+		CreatedAt:      testTime,
+		UpdatedAt:      testTime,
+	}, nil
+}
+
+// fakeWebhookService returns err from every method, or fixed entities if err is nil.
+type fakeWebhookService struct {
+	err error
+}
+
+func (f *fakeWebhookService) Create(context.Context, entity.Webhook) (*entity.Webhook, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.Webhook{
+		ID:         testWebhookID,
+		URL:        "https://example.com/hook",
+		Secret:     "0123456789abcdef",
+		EventTypes: []entity.EventType{entity.EventWalletDebited},
+		WalletIDs:  []string{testWalletID},
+		Active:     true,
+		CreatedAt:  testTime,
+	}, nil
+}
+
+func (f *fakeWebhookService) Webhook(context.Context, string) (*entity.Webhook, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &entity.Webhook{
+		ID:        testWebhookID,
+		URL:       "https://example.com/hook",
+		Active:    true,
+		CreatedAt: testTime,
+	}, nil
+}
+
+func (f *fakeWebhookService) Delete(context.Context, string) error {
+	return f.err
+}
+
+func (f *fakeWebhookService) Deliveries(context.Context, entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []entity.WebhookDelivery{f.delivery()}, nil
+}
+
+func (f *fakeWebhookService) Delivery(context.Context, string, string) (*entity.WebhookDelivery, []entity.WebhookAttempt, error) {
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	delivery := f.delivery()
+	attempts := []entity.WebhookAttempt{
+		{
+			DeliveryID:  testDeliveryID,
+			StatusCode:  500,
+			Duration:    120 * time.Millisecond,
+			AttemptedAt: testTime,
+		},
+		{
+			DeliveryID:  testDeliveryID,
+			Error:       "connection refused",
+			Duration:    time.Millisecond,
+			AttemptedAt: testTime,
+		},
+	}
+	return &delivery, attempts, nil
+}
+
+func (f *fakeWebhookService) Redeliver(context.Context, string, string) (*entity.WebhookDelivery, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	delivery := f.delivery()
+	delivery.Status = entity.WebhookDeliveryPending
+	return &delivery, nil
+}
+
+func (f *fakeWebhookService) delivery() entity.WebhookDelivery {
+	return entity.WebhookDelivery{
+		ID:             testDeliveryID,
+		WebhookID:      testWebhookID,
+		EventID:        testEventID,
+		EventType:      entity.EventWalletDebited,
+		Payload:        []byte(`{"id": "` + testEventID + `", "type": "WalletDebited"}`),
+		Status:         entity.WebhookDeliveryDead,
+		Attempts:       8,
+		NextAttemptAt:  testTime,
+		LastStatusCode: 500,
+		LastError:      "unexpected status code",
+		CreatedAt:      testTime,
+		UpdatedAt:      testTime,
+	}
+}
@@ -0,0 +1,119 @@
+package relayapp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	outboxRepo "github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/outbox"
+)
+
+// Publisher delivers wallet events to downstream services. It may be called again
+// with an event it already delivered.
+type Publisher interface {
+	Publish(ctx context.Context, event entity.Event) error
+}
+
+type Outbox interface {
+	Dispatch(ctx context.Context, limit int, publish outboxRepo.PublishFunc) (int, error)
+}
+
+// App relays events from the outbox to the publisher.
+type App struct {
+	log       *slog.Logger
+	outbox    Outbox
+	publisher Publisher
+
+	interval  time.Duration
+	batchSize int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(
+	_ context.Context,
+	log *slog.Logger,
+	outbox Outbox,
+	publisher Publisher,
+	interval time.Duration,
+	batchSize int,
+) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &App{
+		log:       log,
+		outbox:    outbox,
+		publisher: publisher,
+
+		interval:  interval,
+		batchSize: batchSize,
+
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// Run relays events until the relay is stopped. A full batch is followed by the
+// next one right away; otherwise the relay waits for the interval.
+func (a *App) Run() {
+	const op = "relayapp.Run"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("Starting outbox relay")
+
+	defer close(a.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		sent, err := a.outbox.Dispatch(a.ctx, a.batchSize, a.publisher.Publish)
+		if err != nil && a.ctx.Err() == nil {
+			log.Error("Failed to relay events", slog.Int("sent", sent), slog.Any("error", err))
+		}
+
+		if sent == a.batchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(a.interval)
+		}
+	}
+}
+
+// Stop stops the relay, waits for the running batch to finish and closes the
+// publisher if it needs closing.
+func (a *App) Stop(ctx context.Context) {
+	const op = "relayapp.Stop"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("Stopping outbox relay")
+
+	a.cancel()
+
+	select {
+	case <-a.done:
+		log.Info("Outbox relay stopped gracefully")
+	case <-ctx.Done():
+		log.Error("Failed to gracefully stop outbox relay", slog.Any("error", ctx.Err()))
+		return
+	}
+
+	if closer, ok := a.publisher.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Error("Failed to close publisher", slog.Any("error", err))
+		}
+	}
+}
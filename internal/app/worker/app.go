@@ -0,0 +1,102 @@
+package workerapp
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is a background task that is run periodically.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type App struct {
+	log  *slog.Logger
+	jobs []Job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(
+	_ context.Context,
+	log *slog.Logger,
+	jobs ...Job,
+) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &App{
+		log:  log,
+		jobs: jobs,
+
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Run starts all jobs and blocks until the worker is stopped.
+func (a *App) Run() {
+	const op = "workerapp.Run"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("Starting background worker", slog.Int("jobs", len(a.jobs)))
+
+	for _, job := range a.jobs {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.runJob(job)
+		}()
+	}
+
+	<-a.ctx.Done()
+}
+
+// Stop stops all jobs and waits for running ones to finish.
+func (a *App) Stop(ctx context.Context) {
+	const op = "workerapp.Stop"
+
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("Stopping background worker")
+
+	a.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("Background worker stopped gracefully")
+	case <-ctx.Done():
+		log.Error("Failed to gracefully stop background worker", slog.Any("error", ctx.Err()))
+	}
+}
+
+// runJob runs the job every interval until the worker is stopped.
+func (a *App) runJob(job Job) {
+	log := a.log.With(slog.String("job", job.Name))
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job.Run(a.ctx); err != nil && a.ctx.Err() == nil {
+				log.Error("Background job failed", slog.Any("error", err))
+			}
+		}
+	}
+}
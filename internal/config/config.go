@@ -10,9 +10,23 @@ import (
 )
 
 type Config struct {
-	App  AppConfig      `yaml:"app"`
-	HTTP HttpConfig     `yaml:"http"`
-	PG   PostgresConfig `yaml:"postgres"`
+	App         AppConfig         `yaml:"app"`
+	HTTP        HttpConfig        `yaml:"http"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	PG          PostgresConfig    `yaml:"postgres"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Wallet      WalletConfig      `yaml:"wallet"`
+	Holds       HoldsConfig       `yaml:"holds"`
+	Operations  OperationsConfig  `yaml:"operations"`
+	Executor    ExecutorConfig    `yaml:"executor"`
+	Outbox      OutboxConfig      `yaml:"outbox"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Stream      StreamConfig      `yaml:"stream"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Health      HealthConfig      `yaml:"health"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Audit       AuditConfig       `yaml:"audit"`
 }
 
 type AppConfig struct {
@@ -23,6 +37,14 @@ type HttpConfig struct {
 	Port         int           `env:"PORT" yaml:"port" env-required:"true"`
 	WriteTimeout time.Duration `env:"WRITE_TIMEOUT" yaml:"write_timeout" env-default:"10"`
 	ReadTimeout  time.Duration `env:"READ_TIMEOUT" yaml:"read_timeout" env-default:"10"`
+	// TrustedProxies are the addresses or CIDRs of the proxies whose X-Forwarded-For
+	// header is trusted for the client IP of a request. Empty means none, so the IP
+	// is the remote address of the connection.
+	TrustedProxies []string `env:"HTTP_TRUSTED_PROXIES" yaml:"trusted_proxies" env-separator:","`
+}
+
+type GRPCConfig struct {
+	Port int `env:"GRPC_PORT" yaml:"port" env-default:"9090"`
 }
 
 type PostgresConfig struct {
@@ -36,6 +58,144 @@ type PostgresConfig struct {
 	MaxConns int32 `env:"POSTGRES_MAX_CONNS" yaml:"max_conns" env-required:"true"`
 }
 
+type IdempotencyConfig struct {
+	// Retention is how long idempotency keys are kept before they are cleaned up.
+	Retention       time.Duration `env:"IDEMPOTENCY_RETENTION" yaml:"retention" env-default:"24h"`
+	CleanupInterval time.Duration `env:"IDEMPOTENCY_CLEANUP_INTERVAL" yaml:"cleanup_interval" env-default:"1h"`
+}
+
+type WalletConfig struct {
+	// DefaultOverdraftLimit is how far below zero the balance of a wallet without
+	// its own overdraft limit may go.
+	DefaultOverdraftLimit int64 `env:"WALLET_DEFAULT_OVERDRAFT_LIMIT" yaml:"default_overdraft_limit" env-default:"0"`
+	// DefaultCurrency is the currency of wallets created without one: RUB, EUR or USD.
+	DefaultCurrency string `env:"WALLET_DEFAULT_CURRENCY" yaml:"default_currency" env-default:"RUB"`
+}
+
+type HoldsConfig struct {
+	// DefaultTTL is the TTL of holds created without one.
+	DefaultTTL time.Duration `env:"HOLDS_DEFAULT_TTL" yaml:"default_ttl" env-default:"15m"`
+	MaxTTL     time.Duration `env:"HOLDS_MAX_TTL" yaml:"max_ttl" env-default:"168h"`
+	// SweepInterval is how often holds whose TTL has passed are released.
+	SweepInterval time.Duration `env:"HOLDS_SWEEP_INTERVAL" yaml:"sweep_interval" env-default:"1m"`
+}
+
+type OperationsConfig struct {
+	// PollInterval is how often operations accepted in async mode are picked up.
+	PollInterval time.Duration `env:"OPERATIONS_POLL_INTERVAL" yaml:"poll_interval" env-default:"1s"`
+	// MaxAttempts is how many times an operation that fails with an unexpected error is tried before it's marked failed.
+	MaxAttempts int `env:"OPERATIONS_MAX_ATTEMPTS" yaml:"max_attempts" env-default:"5"`
+}
+
+type AuditConfig struct {
+	// SequenceInterval is how often the records staged by changes are chained into the audit log.
+	SequenceInterval time.Duration `env:"AUDIT_SEQUENCE_INTERVAL" yaml:"sequence_interval" env-default:"1s"`
+}
+
+type ExecutorConfig struct {
+	// Workers is the number of goroutines balance changes are spread over by wallet ID.
+	// Each of them holds at most one database connection, so it should stay below
+	// postgres.max_conns to leave connections for reads.
+	Workers int `env:"EXECUTOR_WORKERS" yaml:"workers" env-default:"8"`
+	// QueueSize is how many changes may wait for a worker before requests are rejected with 503.
+	QueueSize int `env:"EXECUTOR_QUEUE_SIZE" yaml:"queue_size" env-default:"64"`
+}
+
+type OutboxConfig struct {
+	// Publisher is where wallet events are delivered: log, stdout or file.
+	Publisher string `env:"OUTBOX_PUBLISHER" yaml:"publisher" env-default:"log"`
+	// FilePath is the file events are appended to by the file publisher.
+	FilePath     string        `env:"OUTBOX_FILE_PATH" yaml:"file_path" env-default:"events.jsonl"`
+	PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" yaml:"poll_interval" env-default:"1s"`
+	BatchSize    int           `env:"OUTBOX_BATCH_SIZE" yaml:"batch_size" env-default:"100"`
+	// MaxAttempts is how many times an event is published before it is moved to the dead state.
+	MaxAttempts int `env:"OUTBOX_MAX_ATTEMPTS" yaml:"max_attempts" env-default:"10"`
+	// BaseBackoff is the delay before the second attempt. It doubles with every
+	// further attempt up to MaxBackoff.
+	BaseBackoff time.Duration `env:"OUTBOX_BASE_BACKOFF" yaml:"base_backoff" env-default:"1s"`
+	MaxBackoff  time.Duration `env:"OUTBOX_MAX_BACKOFF" yaml:"max_backoff" env-default:"5m"`
+}
+
+type WebhooksConfig struct {
+	// MaxAttempts is how many times a delivery is attempted before it is moved to the dead-letter state.
+	MaxAttempts int `env:"WEBHOOKS_MAX_ATTEMPTS" yaml:"max_attempts" env-default:"8"`
+	// BaseBackoff is the delay before the second attempt. It doubles with every
+	// further attempt up to MaxBackoff.
+	BaseBackoff time.Duration `env:"WEBHOOKS_BASE_BACKOFF" yaml:"base_backoff" env-default:"5s"`
+	MaxBackoff  time.Duration `env:"WEBHOOKS_MAX_BACKOFF" yaml:"max_backoff" env-default:"1h"`
+	// Timeout is how long a webhook has to respond before the attempt fails.
+	Timeout      time.Duration `env:"WEBHOOKS_TIMEOUT" yaml:"timeout" env-default:"10s"`
+	PollInterval time.Duration `env:"WEBHOOKS_POLL_INTERVAL" yaml:"poll_interval" env-default:"1s"`
+}
+
+type StreamConfig struct {
+	// History is how many recent balance changes of a watched wallet are kept for
+	// clients resuming with Last-Event-ID.
+	History int `env:"STREAM_HISTORY" yaml:"history" env-default:"100"`
+	// BufferSize is how many changes may wait for a slow client before its stream is closed.
+	BufferSize int `env:"STREAM_BUFFER_SIZE" yaml:"buffer_size" env-default:"16"`
+	// Retention is how long the history of a wallet is kept after its last client disconnected.
+	Retention time.Duration `env:"STREAM_RETENTION" yaml:"retention" env-default:"5m"`
+	// GapTimeout is how long changes of a wallet wait for an earlier change that is
+	// published late before they are streamed without it.
+	GapTimeout time.Duration `env:"STREAM_GAP_TIMEOUT" yaml:"gap_timeout" env-default:"1s"`
+}
+
+type TracingConfig struct {
+	// Exporter is where spans are sent: otlp, stdout or none.
+	Exporter string `env:"TRACING_EXPORTER" yaml:"exporter" env-default:"none"`
+	// Endpoint is the host:port of the OTLP/HTTP collector.
+	Endpoint string `env:"TRACING_ENDPOINT" yaml:"endpoint" env-default:"localhost:4318"`
+	Insecure bool   `env:"TRACING_INSECURE" yaml:"insecure" env-default:"false"`
+	// SampleRatio is the share of new traces that are recorded. Requests with a
+	// traceparent header follow the sampling decision of the caller.
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" yaml:"sample_ratio" env-default:"1"`
+}
+
+type JWTConfig struct {
+	// Enabled accepts bearer tokens of end users next to API keys.
+	Enabled bool `env:"JWT_ENABLED" yaml:"enabled" env-default:"false"`
+	// JWKSURL is where the keys tokens are signed with are fetched from and refreshed.
+	// One of JWKSURL, JWKSFile and StaticKey must be set.
+	JWKSURL string `env:"JWT_JWKS_URL" yaml:"jwks_url"`
+	// JWKSFile is a JWK Set read once on startup, for deployments without access to the issuer.
+	JWKSFile string `env:"JWT_JWKS_FILE" yaml:"jwks_file"`
+	// StaticKey is a shared HS256 secret, for tests and local development without an identity provider.
+	StaticKey string `env:"JWT_STATIC_KEY" yaml:"static_key"`
+	Issuer    string `env:"JWT_ISSUER" yaml:"issuer"`
+	Audience  string `env:"JWT_AUDIENCE" yaml:"audience"`
+	// Leeway is the clock skew allowed when checking the expiry and not-before times.
+	Leeway time.Duration `env:"JWT_LEEWAY" yaml:"leeway" env-default:"30s"`
+}
+
+type RateLimitConfig struct {
+	// Backend is where the token buckets are kept: memory for a single instance,
+	// postgres to share them between instances, or none to turn rate limiting off.
+	Backend string `env:"RATE_LIMIT_BACKEND" yaml:"backend" env-default:"memory"`
+	// IPRate and IPBurst limit the requests from a single source IP, counted before
+	// authentication so requests with bad credentials are limited too.
+	IPRate  float64 `env:"RATE_LIMIT_IP_RATE" yaml:"ip_rate" env-default:"200"`
+	IPBurst int     `env:"RATE_LIMIT_IP_BURST" yaml:"ip_burst" env-default:"400"`
+	// ClientRate is how many requests per second an API key or end user may make on
+	// average, ClientBurst how many of them at once.
+	ClientRate  float64 `env:"RATE_LIMIT_CLIENT_RATE" yaml:"client_rate" env-default:"100"`
+	ClientBurst int     `env:"RATE_LIMIT_CLIENT_BURST" yaml:"client_burst" env-default:"200"`
+	// WalletRate and WalletBurst limit the requests for a single wallet, whichever clients make them.
+	WalletRate  float64 `env:"RATE_LIMIT_WALLET_RATE" yaml:"wallet_rate" env-default:"20"`
+	WalletBurst int     `env:"RATE_LIMIT_WALLET_BURST" yaml:"wallet_burst" env-default:"40"`
+	// CleanupInterval is how often buckets that have been idle long enough to be full again are removed.
+	CleanupInterval time.Duration `env:"RATE_LIMIT_CLEANUP_INTERVAL" yaml:"cleanup_interval" env-default:"1m"`
+}
+
+type HealthConfig struct {
+	// CheckTimeout is how long the readiness checks may take before they fail.
+	CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" yaml:"check_timeout" env-default:"2s"`
+	// DrainDelay is how long the service stays up after it turned unready on shutdown,
+	// so load balancers notice it before the servers stop accepting requests.
+	// It should be longer than the period of the readiness probe.
+	DrainDelay time.Duration `env:"HEALTH_DRAIN_DELAY" yaml:"drain_delay" env-default:"5s"`
+}
+
 func (p PostgresConfig) DSN() string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
 		p.Username,
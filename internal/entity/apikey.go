@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKeyPermission string
+
+const (
+	APIKeyRead      APIKeyPermission = "read"
+	APIKeyReadWrite APIKeyPermission = "read_write"
+)
+
+// IsValid reports whether p is a known permission.
+func (p APIKeyPermission) IsValid() bool {
+	return p == APIKeyRead || p == APIKeyReadWrite
+}
+
+// APIKey grants a client access to the API. Only the hash of the key is stored.
+type APIKey struct {
+	ID   string
+	Name string
+	// Hash is the SHA-256 hash of the key.
+	Hash       []byte
+	Permission APIKeyPermission
+	// WalletIDs limits the wallets the key may access; empty means all wallets.
+	WalletIDs []string
+	CreatedAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
+
+// AllWallets reports whether the key may access every wallet.
+func (k *APIKey) AllWallets() bool {
+	return len(k.WalletIDs) == 0
+}
+
+// AllowsWallet reports whether the key may access the wallet. IDs are compared in
+// their canonical form, so every spelling of a wallet ID the service accepts, e.g.
+// uppercase or braced, is allowed. An invalid ID is never allowed by a scoped key.
+func (k *APIKey) AllowsWallet(walletID string) bool {
+	if k.AllWallets() {
+		return true
+	}
+
+	id, err := uuid.Parse(walletID)
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(k.WalletIDs, func(allowed string) bool {
+		parsed, err := uuid.Parse(allowed)
+		return err == nil && parsed == id
+	})
+}
+
+// CanWrite reports whether the key may change wallets, not only read them.
+func (k *APIKey) CanWrite() bool {
+	return k.Permission == APIKeyReadWrite
+}
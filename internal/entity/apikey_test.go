@@ -0,0 +1,39 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+func TestAPIKeyAllowsWallet(t *testing.T) {
+	t.Parallel()
+
+	const walletID = "123e4567-e89b-12d3-a456-426614174000"
+
+	tests := []struct {
+		name      string
+		walletIDs []string
+		walletID  string
+		expected  bool
+	}{
+		{name: "All wallets", walletID: "wallet-id", expected: true},
+		{name: "Scoped", walletIDs: []string{walletID}, walletID: walletID, expected: true},
+		{name: "Uppercase", walletIDs: []string{walletID}, walletID: "123E4567-E89B-12D3-A456-426614174000", expected: true},
+		{name: "Braced", walletIDs: []string{walletID}, walletID: "{" + walletID + "}", expected: true},
+		{name: "Other wallet", walletIDs: []string{walletID}, walletID: "223e4567-e89b-12d3-a456-426614174000"},
+		{name: "Invalid ID", walletIDs: []string{walletID}, walletID: "wallet-id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			key := &entity.APIKey{WalletIDs: tt.walletIDs}
+
+			assert.Equal(t, tt.expected, key.AllowsWallet(tt.walletID))
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package entity
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditWalletCreate      AuditAction = "wallet.create"
+	AuditWalletFreeze      AuditAction = "wallet.freeze"
+	AuditWalletUnfreeze    AuditAction = "wallet.unfreeze"
+	AuditWalletClose       AuditAction = "wallet.close"
+	AuditWalletAssignOwner AuditAction = "wallet.assign_owner"
+	// AuditBalanceChange prefixes the operation type of a balance change, e.g. wallet.deposit.
+	AuditBalanceChange AuditAction = "wallet."
+	AuditEnqueue       AuditAction = "operation.enqueue"
+	AuditHoldCreate    AuditAction = "hold.create"
+	AuditHoldRelease   AuditAction = "hold.release"
+
+	AuditWebhookCreate    AuditAction = "webhook.create"
+	AuditWebhookDelete    AuditAction = "webhook.delete"
+	AuditWebhookRedeliver AuditAction = "webhook.redeliver"
+
+	AuditAPIKeyCreate AuditAction = "apikey.create"
+	AuditAPIKeyRotate AuditAction = "apikey.rotate"
+	AuditAPIKeyRevoke AuditAction = "apikey.revoke"
+)
+
+// AuditBalanceAction returns the action of a balance change of the operation type.
+func AuditBalanceAction(operationType OperationType) AuditAction {
+	return AuditBalanceChange + AuditAction(operationType)
+}
+
+// AuditStatusAction returns the action of a wallet moving to the status.
+func AuditStatusAction(status WalletStatus) AuditAction {
+	switch status {
+	case WalletStatusFrozen:
+		return AuditWalletFreeze
+	case WalletStatusClosed:
+		return AuditWalletClose
+	default:
+		return AuditWalletUnfreeze
+	}
+}
+
+// AuditActorSystem is the actor of changes the service makes on its own, e.g.
+// applying queued operations.
+const AuditActorSystem = "system"
+
+// AuditRecord is an entry of the append-only audit log. Actor is the API key,
+// end user or tool that made the change, e.g. key:<id> or user:<subject>.
+// ResourceID is the transaction, hold, webhook or API key the action is about.
+// BalanceBefore and BalanceAfter are only set for balance changes.
+// Hash covers every other field, PrevHash included, so the records form a chain.
+type AuditRecord struct {
+	ID            int64
+	Action        AuditAction
+	Actor         string
+	SourceIP      string
+	RequestID     string
+	WalletID      string
+	ResourceID    string
+	BalanceBefore *int64
+	BalanceAfter  *int64
+	CreatedAt     time.Time
+	PrevHash      []byte
+	Hash          []byte
+}
+
+// ComputeHash returns the SHA-256 hash of the record. CreatedAt is hashed in UTC
+// with microsecond precision, the precision PostgreSQL keeps.
+func (r AuditRecord) ComputeHash() []byte {
+	h := sha256.New()
+
+	// Every field is prefixed with its length, so moving bytes between fields
+	// changes the hash.
+	write := func(b []byte) {
+		_ = binary.Write(h, binary.BigEndian, uint32(len(b)))
+		h.Write(b)
+	}
+	writeInt := func(v *int64) {
+		if v == nil {
+			write(nil)
+			return
+		}
+		write(strconv.AppendInt(nil, *v, 10))
+	}
+
+	write(r.PrevHash)
+	write([]byte(r.Action))
+	write([]byte(r.Actor))
+	write([]byte(r.SourceIP))
+	write([]byte(r.RequestID))
+	write([]byte(r.WalletID))
+	write([]byte(r.ResourceID))
+	writeInt(r.BalanceBefore)
+	writeInt(r.BalanceAfter)
+	write([]byte(r.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)))
+
+	return h.Sum(nil)
+}
+
+// AuditBreak is a record that breaks the audit chain.
+type AuditBreak struct {
+	RecordID int64
+	Reason   string
+}
+
+// AuditVerification is the result of walking the audit chain. LastHash is the
+// hash of the newest record and Head the hash kept in audit_head when the walk
+// started. Comparing LastHash with a copy kept outside the database also reveals
+// records removed together with a rewritten audit_head.
+type AuditVerification struct {
+	Records  int64
+	LastID   int64
+	LastHash []byte
+	Head     []byte
+	Breaks   []AuditBreak
+}
+
+// Intact reports whether no record breaks the chain.
+func (v AuditVerification) Intact() bool {
+	return len(v.Breaks) == 0
+}
+
+// VerifyNext checks the record against the previous one and adds it to the
+// verification. The first record must have an empty PrevHash.
+func (v *AuditVerification) VerifyNext(r AuditRecord) {
+	if !bytes.Equal(r.PrevHash, v.LastHash) {
+		v.Breaks = append(v.Breaks, AuditBreak{
+			RecordID: r.ID,
+			Reason:   "previous hash doesn't match, records before it were removed or reordered",
+		})
+	}
+	if !bytes.Equal(r.Hash, r.ComputeHash()) {
+		v.Breaks = append(v.Breaks, AuditBreak{
+			RecordID: r.ID,
+			Reason:   "hash doesn't match, the record was modified",
+		})
+	}
+
+	v.Records++
+	v.LastID = r.ID
+	v.LastHash = r.Hash
+}
@@ -0,0 +1,103 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+// chain returns n linked records.
+func chain(n int) []entity.AuditRecord {
+	records := make([]entity.AuditRecord, n)
+
+	var prev []byte
+	for i := range records {
+		before, after := int64(i*100), int64((i+1)*100)
+		records[i] = entity.AuditRecord{
+			ID:            int64(i + 1),
+			Action:        entity.AuditBalanceAction(entity.OperationDeposit),
+			Actor:         "key:test-key-id",
+			SourceIP:      "192.0.2.1",
+			RequestID:     "test-request-id",
+			WalletID:      "test-wallet-id",
+			BalanceBefore: &before,
+			BalanceAfter:  &after,
+			CreatedAt:     time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC),
+			PrevHash:      prev,
+		}
+		records[i].Hash = records[i].ComputeHash()
+		prev = records[i].Hash
+	}
+
+	return records
+}
+
+func TestAuditVerification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		tamper     func(records []entity.AuditRecord) []entity.AuditRecord
+		wantBreaks []int64
+	}{
+		{
+			name:   "Intact",
+			tamper: func(records []entity.AuditRecord) []entity.AuditRecord { return records },
+		},
+		{
+			name: "Modified",
+			tamper: func(records []entity.AuditRecord) []entity.AuditRecord {
+				after := int64(1_000_000)
+				records[1].BalanceAfter = &after
+				return records
+			},
+			wantBreaks: []int64{2},
+		},
+		{
+			name: "Removed",
+			tamper: func(records []entity.AuditRecord) []entity.AuditRecord {
+				return append(records[:1], records[2:]...)
+			},
+			wantBreaks: []int64{3},
+		},
+		{
+			name: "Reordered",
+			tamper: func(records []entity.AuditRecord) []entity.AuditRecord {
+				records[1], records[2] = records[2], records[1]
+				return records
+			},
+			wantBreaks: []int64{3, 2, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var v entity.AuditVerification
+			for _, r := range tt.tamper(chain(4)) {
+				v.VerifyNext(r)
+			}
+
+			var breaks []int64
+			for _, b := range v.Breaks {
+				breaks = append(breaks, b.RecordID)
+			}
+			require.Equal(t, tt.wantBreaks, breaks)
+			require.Equal(t, len(tt.wantBreaks) == 0, v.Intact())
+		})
+	}
+}
+
+func TestAuditRecordComputeHash_TimeZone(t *testing.T) {
+	t.Parallel()
+
+	r := chain(1)[0]
+	local := r
+	local.CreatedAt = r.CreatedAt.In(time.FixedZone("UTC+3", 3*60*60))
+
+	require.Equal(t, r.ComputeHash(), local.ComputeHash())
+}
@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+// BalanceChange is a committed change of a wallet balance, streamed to clients
+// watching the wallet.
+type BalanceChange struct {
+	WalletID      string
+	TransactionID string
+	OperationType OperationType
+	// Amount is signed like [Transaction.Amount].
+	Amount       int64
+	BalanceAfter int64
+	// Sequence is the [Transaction.Sequence] of the change.
+	Sequence   int64
+	OccurredAt time.Time
+}
+
+// NewBalanceChange returns the balance change recorded by the transaction.
+func NewBalanceChange(t *Transaction) BalanceChange {
+	return BalanceChange{
+		WalletID:      t.WalletID,
+		TransactionID: t.ID,
+		OperationType: t.OperationType,
+		Amount:        t.Amount,
+		BalanceAfter:  t.BalanceAfter,
+		Sequence:      t.Sequence,
+		OccurredAt:    t.CreatedAt,
+	}
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+type EventType string
+
+const (
+	EventWalletCreated  EventType = "WalletCreated"
+	EventWalletCredited EventType = "WalletCredited"
+	EventWalletDebited  EventType = "WalletDebited"
+)
+
+// Event is a wallet domain event. It is written to the outbox in the same database
+// transaction as the change it describes and published to downstream services later.
+type Event struct {
+	ID       string
+	Type     EventType
+	WalletID string
+	// Payload is the JSON encoded event data.
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// IsKnown reports whether events of the type are written to the outbox.
+func (t EventType) IsKnown() bool {
+	switch t {
+	case EventWalletCreated, EventWalletCredited, EventWalletDebited:
+		return true
+	default:
+		return false
+	}
+}
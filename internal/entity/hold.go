@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "active"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusReleased HoldStatus = "released"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
+// Hold reserves funds of a wallet. An active hold reduces the available balance
+// of the wallet, but not its ledger balance, until it is captured, released or expires.
+type Hold struct {
+	ID       string
+	WalletID string
+	Amount   Money
+	// CapturedAmount is the part of the hold charged from the wallet. The rest is
+	// released when the hold is captured.
+	CapturedAmount int64
+	Status         HoldStatus
+	// TransactionID is the wallet transaction written when the hold is captured.
+	TransactionID string
+	// Transaction is that transaction. It is only set on the hold returned by the
+	// capture itself.
+	Transaction *Transaction
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// IsActive reports whether the hold still reserves funds at the given time.
+func (h Hold) IsActive(now time.Time) bool {
+	return h.Status == HoldStatusActive && now.Before(h.ExpiresAt)
+}
+
+// HoldCapture describes a charge of a hold.
+type HoldCapture struct {
+	HoldID string
+	// Amount is the amount to charge. If it is zero, the whole hold is charged.
+	// If its currency is empty, the amount is in the hold currency.
+	Amount Money
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// IdempotencyKey is a client supplied key stored together with a fingerprint of
+// the request it was first used with and the response that was returned for it.
+// Keys are scoped to the client that sent them, e.g. key:<id> or user:<subject>.
+type IdempotencyKey struct {
+	ClientID    string
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Response    []byte
+	CreatedAt   time.Time
+}
@@ -0,0 +1,97 @@
+package entity
+
+import "time"
+
+type AccountKind string
+
+const (
+	AccountKindWallet AccountKind = "wallet"
+	AccountKindSystem AccountKind = "system"
+)
+
+// System accounts of the ledger. They are created by migrations and are the
+// counterparties of money entering and leaving the service.
+const (
+	AccountCashIn         = "00000000-0000-0000-0000-000000000001"
+	AccountCashOut        = "00000000-0000-0000-0000-000000000002"
+	AccountFees           = "00000000-0000-0000-0000-000000000003"
+	AccountOpeningBalance = "00000000-0000-0000-0000-000000000004"
+)
+
+// Posting is a single line of a journal entry. Amount is signed: a positive amount
+// increases the account balance, a negative one decreases it.
+type Posting struct {
+	AccountID string
+	Amount    int64
+}
+
+// JournalEntry is a set of postings recorded together. The postings of an entry
+// are in the entry currency and always sum to zero, so money is only ever moved
+// between accounts.
+type JournalEntry struct {
+	ID        string
+	Kind      OperationType
+	Currency  Currency
+	Postings  []Posting
+	CreatedAt time.Time
+}
+
+// IsBalanced reports whether the entry has at least two non-zero postings that sum to zero.
+func (e JournalEntry) IsBalanced() bool {
+	if len(e.Postings) < 2 {
+		return false
+	}
+
+	var sum int64
+	for _, p := range e.Postings {
+		if p.Amount == 0 {
+			return false
+		}
+		sum += p.Amount
+	}
+
+	return sum == 0
+}
+
+// AccountBalance is the balance of a ledger account in one currency derived from
+// its postings. System accounts have a balance per currency.
+type AccountBalance struct {
+	AccountID string
+	Code      string
+	Kind      AccountKind
+	Balance   Money
+}
+
+// BalanceMismatch is a wallet whose cached balance differs from the balance
+// derived from the journal.
+type BalanceMismatch struct {
+	WalletID      string
+	CachedBalance Money
+	LedgerBalance Money
+}
+
+// TrialBalance is a snapshot of the ledger used to prove its consistency.
+// Amounts in different currencies are never summed up.
+type TrialBalance struct {
+	// SystemAccounts are balances of the system accounts.
+	SystemAccounts []AccountBalance
+	// WalletLiabilities are the totals of all wallet balances derived from the
+	// journal, one per currency.
+	WalletLiabilities []Money
+	// Totals are the sums of all postings, one per currency. They are zero for a
+	// consistent ledger.
+	Totals []Money
+	// Mismatches are wallets whose cached balance differs from the journal.
+	Mismatches []BalanceMismatch
+}
+
+// IsConsistent reports whether the journal sums to zero in every currency and all
+// cached wallet balances match the journal.
+func (t TrialBalance) IsConsistent() bool {
+	for _, total := range t.Totals {
+		if total.Amount != 0 {
+			return false
+		}
+	}
+	return len(t.Mismatches) == 0
+}
@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrAmountOverflow   = errors.New("amount overflow")
+)
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+const (
+	CurrencyRUB Currency = "RUB"
+	CurrencyEUR Currency = "EUR"
+	CurrencyUSD Currency = "USD"
+)
+
+// minorUnits is the number of digits after the decimal separator of the supported currencies.
+var minorUnits = map[Currency]int{
+	CurrencyRUB: 2,
+	CurrencyEUR: 2,
+	CurrencyUSD: 2,
+}
+
+// IsSupported reports whether wallets can hold money in the currency.
+func (c Currency) IsSupported() bool {
+	_, ok := minorUnits[c]
+	return ok
+}
+
+// MinorUnits returns the number of digits after the decimal separator, e.g. 2 for cents.
+func (c Currency) MinorUnits() int {
+	return minorUnits[c]
+}
+
+// Money is an amount in minor units (cents, kopecks) of a currency.
+// An empty currency means the amount is in the currency of the wallet it is applied to.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// NewMoney returns an amount in minor units of the currency.
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add returns the sum of m and other. It returns [ErrCurrencyMismatch] if the
+// currencies differ and [ErrAmountOverflow] if the sum doesn't fit into int64.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Neg returns the amount with the opposite sign.
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// IsNegative reports whether the amount is below zero.
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// String formats the amount in major units, e.g. "-12.05 EUR".
+func (m Money) String() string {
+	units := m.Currency.MinorUnits()
+	if units == 0 {
+		return strconv.FormatInt(m.Amount, 10) + " " + string(m.Currency)
+	}
+
+	digits := strconv.FormatUint(absUint(m.Amount), 10)
+	if len(digits) <= units {
+		digits = strings.Repeat("0", units-len(digits)+1) + digits
+	}
+
+	var b strings.Builder
+	if m.Amount < 0 {
+		b.WriteByte('-')
+	}
+	b.WriteString(digits[:len(digits)-units])
+	b.WriteByte('.')
+	b.WriteString(digits[len(digits)-units:])
+	b.WriteByte(' ')
+	b.WriteString(string(m.Currency))
+
+	return b.String()
+}
+
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
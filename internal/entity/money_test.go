@@ -0,0 +1,69 @@
+package entity_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+func TestMoneyAdd(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		a, b          entity.Money
+		expected      entity.Money
+		expectedError error
+	}{
+		{
+			name:     "Ok",
+			a:        entity.NewMoney(100, entity.CurrencyEUR),
+			b:        entity.NewMoney(-30, entity.CurrencyEUR),
+			expected: entity.NewMoney(70, entity.CurrencyEUR),
+		},
+		{
+			name:          "CurrencyMismatch",
+			a:             entity.NewMoney(100, entity.CurrencyEUR),
+			b:             entity.NewMoney(100, entity.CurrencyUSD),
+			expectedError: entity.ErrCurrencyMismatch,
+		},
+		{
+			name:          "Overflow",
+			a:             entity.NewMoney(math.MaxInt64, entity.CurrencyRUB),
+			b:             entity.NewMoney(1, entity.CurrencyRUB),
+			expectedError: entity.ErrAmountOverflow,
+		},
+		{
+			name:          "Underflow",
+			a:             entity.NewMoney(math.MinInt64, entity.CurrencyRUB),
+			b:             entity.NewMoney(-1, entity.CurrencyRUB),
+			expectedError: entity.ErrAmountOverflow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sum, err := tt.a.Add(tt.b)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expected, sum, "expected sum to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "12.34 EUR", entity.NewMoney(1234, entity.CurrencyEUR).String())
+	require.Equal(t, "-0.05 USD", entity.NewMoney(-5, entity.CurrencyUSD).String())
+	require.Equal(t, "-92233720368547758.08 RUB", entity.NewMoney(math.MinInt64, entity.CurrencyRUB).String())
+}
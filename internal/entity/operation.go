@@ -0,0 +1,64 @@
+package entity
+
+// Operation describes a single balance change applied to a wallet.
+type Operation struct {
+	WalletID string
+	// Amount is signed: positive values credit the wallet, negative values debit it.
+	// If its currency is empty, the amount is in the wallet currency.
+	Amount Money
+	Type   OperationType
+
+	OperationOptions
+}
+
+// Transfer describes a movement of funds from one wallet to another.
+type Transfer struct {
+	FromWalletID string
+	ToWalletID   string
+	// Amount is positive. If its currency is empty, the amount is in the currency of the wallets.
+	Amount Money
+
+	OperationOptions
+}
+
+// Reversal describes a compensating change for a past wallet transaction.
+type Reversal struct {
+	TransactionID string
+	// Amount is positive. If it is zero, the part of the transaction that is not
+	// reversed yet is reversed. If its currency is empty, the amount is in the wallet currency.
+	Amount Money
+
+	OperationOptions
+}
+
+// TransferResult holds the ledger entries written for a transfer.
+type TransferResult struct {
+	Debit  *Transaction
+	Credit *Transaction
+}
+
+// OperationOptions are optional parameters shared by all balance changes.
+type OperationOptions struct {
+	// IdempotencyKey, if set, is stored in the same database transaction as the
+	// balance change, so a retried request can never be applied twice.
+	IdempotencyKey *IdempotencyKey
+}
+
+// OperationOption customizes [OperationOptions] before a balance change is applied.
+type OperationOption func(*OperationOptions)
+
+// WithIdempotencyKey attaches an idempotency key to the balance change.
+func WithIdempotencyKey(key *IdempotencyKey) OperationOption {
+	return func(o *OperationOptions) {
+		o.IdempotencyKey = key
+	}
+}
+
+// NewOperationOptions applies the options to zero [OperationOptions].
+func NewOperationOptions(opts ...OperationOption) OperationOptions {
+	var o OperationOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
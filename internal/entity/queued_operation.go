@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+type QueuedOperationStatus string
+
+const (
+	QueuedOperationPending   QueuedOperationStatus = "pending"
+	QueuedOperationCompleted QueuedOperationStatus = "completed"
+	QueuedOperationFailed    QueuedOperationStatus = "failed"
+)
+
+// QueuedOperation is a deposit or withdrawal accepted in async mode. It is stored
+// durably when accepted and applied to the wallet later by a background worker.
+type QueuedOperation struct {
+	ID string
+	Operation
+
+	Status QueuedOperationStatus
+	// FailureReason explains why a failed operation was not applied.
+	FailureReason string
+	// TransactionID is the wallet transaction written when the operation is completed.
+	TransactionID string
+	// Transaction is that transaction. It is only set on the operation returned by
+	// the call that applied it.
+	Transaction *Transaction
+	// Attempts is the number of attempts that failed with an unexpected error.
+	Attempts  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,82 @@
+package entity
+
+import "time"
+
+type OperationType string
+
+const (
+	OperationDeposit     OperationType = "deposit"
+	OperationWithdraw    OperationType = "withdraw"
+	OperationTransferOut OperationType = "transfer_out"
+	OperationTransferIn  OperationType = "transfer_in"
+	OperationCapture     OperationType = "capture"
+	OperationReversal    OperationType = "reversal"
+
+	// OperationTransfer is the kind of a journal entry that moves funds between
+	// wallets; its wallet transactions are [OperationTransferOut] and [OperationTransferIn].
+	OperationTransfer OperationType = "transfer"
+)
+
+// IsReversible reports whether transactions of the type can be reversed. Only changes
+// between a wallet and the outside world are reversible; transfers and reversals are not.
+func (t OperationType) IsReversible() bool {
+	switch t {
+	case OperationDeposit, OperationWithdraw, OperationCapture:
+		return true
+	default:
+		return false
+	}
+}
+
+// Transaction is a single ledger record of a change applied to a wallet balance.
+// Amount is signed: positive values credit the wallet, negative values debit it.
+// ReferenceID links the record to a related one, e.g. the credit side of a
+// transfer references its debit side. It is empty if there is no related record.
+// EntryID is the journal entry the change was posted with; it is empty for
+// records created before the double-entry ledger was introduced.
+// Sequence numbers the records of a wallet in commit order, starting at 1.
+type Transaction struct {
+	ID            string
+	WalletID      string
+	Amount        int64
+	OperationType OperationType
+	BalanceAfter  int64
+	ReferenceID   string
+	EntryID       string
+	Sequence      int64
+	CreatedAt     time.Time
+}
+
+// TransactionCursor points at the last transaction of a history page.
+// Transactions are ordered by creation time and ID, newest first.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// TransactionQuery describes a requested page of a wallet transaction history.
+// Zero values mean "no filter"; an empty Cursor requests the first page.
+type TransactionQuery struct {
+	OperationType OperationType
+	From          time.Time // inclusive
+	To            time.Time // exclusive
+	Cursor        string
+	Limit         int
+}
+
+// TransactionFilter is a decoded [TransactionQuery] used by the storage layer.
+type TransactionFilter struct {
+	WalletID      string
+	OperationType OperationType
+	From          time.Time
+	To            time.Time
+	After         *TransactionCursor
+	Limit         int
+}
+
+// TransactionPage is a single page of a wallet transaction history.
+// NextCursor is empty when there are no more transactions.
+type TransactionPage struct {
+	Transactions []Transaction
+	NextCursor   string
+}
@@ -2,9 +2,64 @@ package entity
 
 import "time"
 
+type WalletStatus string
+
+const (
+	WalletStatusActive WalletStatus = "active"
+	// WalletStatusFrozen blocks every balance change of the wallet until it is unfrozen.
+	WalletStatusFrozen WalletStatus = "frozen"
+	// WalletStatusClosed blocks every balance change of the wallet for good.
+	WalletStatusClosed WalletStatus = "closed"
+)
+
+// CanChangeTo reports whether a wallet may move from s to next: active wallets
+// may be frozen, frozen ones unfrozen, and both closed. Closing is final.
+func (s WalletStatus) CanChangeTo(next WalletStatus) bool {
+	switch s {
+	case WalletStatusActive:
+		return next == WalletStatusFrozen || next == WalletStatusClosed
+	case WalletStatusFrozen:
+		return next == WalletStatusActive || next == WalletStatusClosed
+	default:
+		return false
+	}
+}
+
 type Wallet struct {
-	ID        string
-	Balance   int64
+	ID string
+	// Balance is the cached balance of the wallet in the wallet currency.
+	Balance Money
+	// Held is the sum of the active holds of the wallet.
+	Held   int64
+	Status WalletStatus
+	// StatusReason explains the latest status change; empty if the status never changed.
+	StatusReason    string
+	StatusChangedAt *time.Time
+	// OverdraftLimit is how far below zero the balance may go.
+	// If it is nil, the default limit configured for the service is used.
+	OverdraftLimit *int64
+	// OwnerID is the subject of the end user who owns the wallet; empty if nobody does.
+	OwnerID   string
 	UpdatedAt time.Time
 	CreatedAt time.Time
 }
+
+// Available returns the balance that is not reserved by holds.
+func (w Wallet) Available() Money {
+	return NewMoney(w.Balance.Amount-w.Held, w.Balance.Currency)
+}
+
+// Currency returns the currency the wallet holds.
+func (w Wallet) Currency() Currency {
+	return w.Balance.Currency
+}
+
+// WalletStatusChange moves a wallet to Status for Reason. SweepToWalletID is the
+// wallet the balance of a wallet being closed is moved to; without it only a
+// wallet with zero balance can be closed.
+type WalletStatusChange struct {
+	WalletID        string
+	Status          WalletStatus
+	Reason          string
+	SweepToWalletID string
+}
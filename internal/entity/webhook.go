@@ -0,0 +1,72 @@
+package entity
+
+import "time"
+
+// Webhook is an HTTP endpoint that receives wallet events.
+type Webhook struct {
+	ID  string
+	URL string
+	// Secret is the key deliveries are signed with.
+	Secret string
+	// EventTypes limits the events delivered to the webhook; empty means all events.
+	EventTypes []EventType
+	// WalletIDs limits the wallets whose events are delivered; empty means all wallets.
+	WalletIDs []string
+	Active    bool
+	CreatedAt time.Time
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryDead is the dead-letter state of a delivery that failed too many times.
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery is a single event to be delivered to a webhook.
+type WebhookDelivery struct {
+	ID        string
+	WebhookID string
+	EventID   string
+	EventType EventType
+	// Payload is the request body sent to the webhook.
+	Payload       []byte
+	Status        WebhookDeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	// LastStatusCode and LastError describe the latest failed attempt.
+	LastStatusCode int
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// DueWebhookDelivery is a delivery claimed for an attempt together with the
+// endpoint it goes to.
+type DueWebhookDelivery struct {
+	WebhookDelivery
+
+	URL    string
+	Secret string
+}
+
+// WebhookAttempt is the outcome of one attempt to deliver an event.
+type WebhookAttempt struct {
+	DeliveryID string
+	// StatusCode is zero if no response was received.
+	StatusCode  int
+	Error       string
+	Duration    time.Duration
+	AttemptedAt time.Time
+}
+
+// WebhookDeliveryFilter selects deliveries of a webhook, the newest first.
+type WebhookDeliveryFilter struct {
+	WebhookID string
+	// Status is optional.
+	Status WebhookDeliveryStatus
+	Limit  int
+}
@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/audit"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+)
+
+// APIKeyHeader is the header requests pass their API key in.
+const APIKeyHeader = "X-API-Key"
+
+const (
+	// apiKeyContextKey is the key the authenticated API key is stored under in the gin context.
+	apiKeyContextKey = "apiKey"
+	// userContextKey is the key the end user of a bearer token is stored under in the gin context.
+	userContextKey = "user"
+
+	bearerPrefix = "Bearer "
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*entity.APIKey, error)
+}
+
+// TokenVerifier verifies bearer tokens of end users and returns their subject.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (string, error)
+}
+
+// Owners tells whether an end user owns a wallet.
+type Owners interface {
+	IsWalletOwner(ctx context.Context, walletID, subject string) (bool, error)
+}
+
+// user is an end user authenticated with a bearer token. It may only access the
+// wallets it owns.
+type user struct {
+	subject string
+	owners  Owners
+}
+
+type Option func(*options)
+
+type options struct {
+	verifier TokenVerifier
+	owners   Owners
+}
+
+// WithTokens accepts bearer tokens in the Authorization header next to API keys.
+// Requests made with a token may only access the wallets its subject owns.
+func WithTokens(verifier TokenVerifier, owners Owners) Option {
+	return func(o *options) {
+		o.verifier = verifier
+		o.owners = owners
+	}
+}
+
+// Middleware authenticates requests with the API key in the [APIKeyHeader] header
+// or, with [WithTokens], with a bearer token. Requests without a valid key or
+// token get 401. Requests other than GET, HEAD and OPTIONS made with a read-only
+// key get 403. Changes made by authenticated requests are audited as made by
+// their key or end user.
+func Middleware(authenticator Authenticator, opts ...Option) gin.HandlerFunc {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(c *gin.Context) {
+		if header := c.GetHeader("Authorization"); o.verifier != nil && strings.HasPrefix(header, bearerPrefix) {
+			subject, err := o.verifier.Verify(c.Request.Context(), strings.TrimPrefix(header, bearerPrefix))
+			if err != nil {
+				response.Unauthorized(c, "Bearer token is invalid")
+				c.Abort()
+				return
+			}
+
+			c.Set(userContextKey, &user{subject: subject, owners: o.owners})
+			setActor(c)
+			c.Next()
+			return
+		}
+
+		plain := c.GetHeader(APIKeyHeader)
+		if plain == "" {
+			response.Unauthorized(c, "API key is required")
+			c.Abort()
+			return
+		}
+
+		key, err := authenticator.Authenticate(c.Request.Context(), plain)
+		if errors.Is(err, svcErr.ErrInvalidAPIKey) {
+			response.Unauthorized(c, "API key is invalid or revoked")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			response.InternalError(c, "Failed to authenticate request")
+			c.Abort()
+			return
+		}
+
+		if !key.CanWrite() && !isSafeMethod(c.Request.Method) {
+			response.Forbidden(c, "API key is read-only")
+			c.Abort()
+			return
+		}
+
+		c.Set(apiKeyContextKey, key)
+		setActor(c)
+		c.Next()
+	}
+}
+
+// RequireAllWallets rejects requests made with a key limited to some wallets or
+// with a bearer token, for routes that aren't about a single wallet.
+func RequireAllWallets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := apiKey(c); key == nil || !key.AllWallets() {
+			response.Forbidden(c, "API key must have access to all wallets")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Authorize reports whether the API key of the request may access all the wallets,
+// or the end user of the bearer token owns all of them. If not, it responds with 403.
+func Authorize(c *gin.Context, walletIDs ...string) bool {
+	if u := endUser(c); u != nil {
+		return authorizeUser(c, u, walletIDs)
+	}
+
+	key := apiKey(c)
+	if key == nil {
+		response.Forbidden(c, "API key has no access to the wallet")
+		return false
+	}
+
+	for _, id := range walletIDs {
+		if !key.AllowsWallet(id) {
+			response.Forbidden(c, "API key has no access to the wallet")
+			return false
+		}
+	}
+
+	return true
+}
+
+// AuthorizeTransfer reports whether the request may move funds from one wallet to
+// another. End users must own the debited wallet only, so they can pay into the
+// wallets of others; API keys must have access to both wallets. If not, it
+// responds with 403.
+func AuthorizeTransfer(c *gin.Context, fromWalletID, toWalletID string) bool {
+	if u := endUser(c); u != nil {
+		return authorizeUser(c, u, []string{fromWalletID})
+	}
+
+	return Authorize(c, fromWalletID, toWalletID)
+}
+
+// Subject returns the subject of the bearer token the request was authenticated
+// with. It reports false for requests made with an API key.
+func Subject(c *gin.Context) (string, bool) {
+	if u := endUser(c); u != nil {
+		return u.subject, true
+	}
+	return "", false
+}
+
+// ClientID identifies the API key or the end user the request was made with,
+// e.g. to count its requests. It returns an empty string for requests that
+// weren't authenticated.
+func ClientID(c *gin.Context) string {
+	if u := endUser(c); u != nil {
+		return "user:" + u.subject
+	}
+	if key := apiKey(c); key != nil {
+		return "key:" + key.ID
+	}
+	return ""
+}
+
+// setActor makes the services audit the changes of the request as made by its
+// client, from the IP address of the request.
+func setActor(c *gin.Context) {
+	ctx := audit.WithActor(c.Request.Context(), audit.Actor{ID: ClientID(c), SourceIP: c.ClientIP()})
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// authorizeUser checks that the end user owns the wallets. Wallets that don't
+// exist are treated like the wallets of others, so users can't probe for them.
+func authorizeUser(c *gin.Context, u *user, walletIDs []string) bool {
+	for _, id := range walletIDs {
+		owner, err := u.owners.IsWalletOwner(c.Request.Context(), id, u.subject)
+		if err != nil && !errors.Is(err, svcErr.ErrWalletNotFound) && !errors.Is(err, svcErr.ErrInvalidParams) {
+			response.InternalError(c, "Failed to check wallet owner")
+			return false
+		}
+		if !owner {
+			response.Forbidden(c, "Wallet is not owned by the user")
+			return false
+		}
+	}
+
+	return true
+}
+
+// endUser returns the end user the request was authenticated as, or nil.
+func endUser(c *gin.Context) *user {
+	v, ok := c.Get(userContextKey)
+	if !ok {
+		return nil
+	}
+	u, _ := v.(*user)
+	return u
+}
+
+// apiKey returns the API key the request was authenticated with, or nil.
+func apiKey(c *gin.Context) *entity.APIKey {
+	v, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return nil
+	}
+	key, _ := v.(*entity.APIKey)
+	return key
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -0,0 +1,33 @@
+package ledger
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+)
+
+type LedgerService interface {
+	TrialBalance(ctx context.Context) (*entity.TrialBalance, error)
+}
+
+type Handler struct {
+	ledgerSvc LedgerService
+}
+
+func New(
+	ledgerSvc LedgerService,
+) *Handler {
+	return &Handler{
+		ledgerSvc: ledgerSvc,
+	}
+}
+
+func (h *Handler) RegisterRoutes(base *gin.RouterGroup) {
+	ledgerGroup := base.Group("/ledger", auth.RequireAllWallets())
+	{
+		ledgerGroup.GET("/trial-balance", h.trialBalance)
+	}
+}
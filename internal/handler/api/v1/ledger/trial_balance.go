@@ -0,0 +1,77 @@
+package ledger
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type moneyResp struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type accountBalanceResp struct {
+	AccountID string `json:"accountId"`
+	Code      string `json:"code"`
+	Balance   int64  `json:"balance"`
+	Currency  string `json:"currency"`
+}
+
+type balanceMismatchResp struct {
+	WalletID      string `json:"walletId"`
+	CachedBalance int64  `json:"cachedBalance"`
+	LedgerBalance int64  `json:"ledgerBalance"`
+	Currency      string `json:"currency"`
+}
+
+type trialBalanceResp struct {
+	Consistent        bool                  `json:"consistent"`
+	Totals            []moneyResp           `json:"totals"`
+	WalletLiabilities []moneyResp           `json:"walletLiabilities"`
+	SystemAccounts    []accountBalanceResp  `json:"systemAccounts"`
+	Mismatches        []balanceMismatchResp `json:"mismatches"`
+}
+
+func (h *Handler) trialBalance(c *gin.Context) {
+	tb, err := h.ledgerSvc.TrialBalance(c.Request.Context())
+	if err != nil {
+		response.InternalError(c, "Internal server error")
+		return
+	}
+
+	resp := trialBalanceResp{
+		Consistent:        tb.IsConsistent(),
+		Totals:            newMoneyResps(tb.Totals),
+		WalletLiabilities: newMoneyResps(tb.WalletLiabilities),
+		SystemAccounts:    make([]accountBalanceResp, 0, len(tb.SystemAccounts)),
+		Mismatches:        make([]balanceMismatchResp, 0, len(tb.Mismatches)),
+	}
+	for _, a := range tb.SystemAccounts {
+		resp.SystemAccounts = append(resp.SystemAccounts, accountBalanceResp{
+			AccountID: a.AccountID,
+			Code:      a.Code,
+			Balance:   a.Balance.Amount,
+			Currency:  string(a.Balance.Currency),
+		})
+	}
+	for _, m := range tb.Mismatches {
+		resp.Mismatches = append(resp.Mismatches, balanceMismatchResp{
+			WalletID:      m.WalletID,
+			CachedBalance: m.CachedBalance.Amount,
+			LedgerBalance: m.LedgerBalance.Amount,
+			Currency:      string(m.CachedBalance.Currency),
+		})
+	}
+
+	response.Success(c, 200, resp)
+}
+
+func newMoneyResps(amounts []entity.Money) []moneyResp {
+	resps := make([]moneyResp, 0, len(amounts))
+	for _, m := range amounts {
+		resps = append(resps, moneyResp{Amount: m.Amount, Currency: string(m.Currency)})
+	}
+	return resps
+}
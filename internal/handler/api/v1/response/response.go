@@ -2,6 +2,7 @@ package response
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +12,26 @@ const (
 	ErrCodeInternalServer = "INTERNAL_SERVER_ERROR"
 	ErrCodeNotFound       = "NOT_FOUND"
 	ErrCodeValidation     = "VALIDATION_ERROR"
+	ErrCodeConflict       = "CONFLICT"
+	ErrCodeUnavailable    = "SERVICE_UNAVAILABLE"
+	ErrCodeUnauthorized   = "UNAUTHORIZED"
+	ErrCodeForbidden      = "FORBIDDEN"
+	ErrCodeRateLimited    = "RATE_LIMITED"
+
+	ErrCodeIdempotencyKeyMismatch = "IDEMPOTENCY_KEY_MISMATCH"
+	ErrCodeWalletAlreadyExists    = "WALLET_ALREADY_EXISTS"
+	ErrCodeInsufficientFunds      = "INSUFFICIENT_FUNDS"
+	ErrCodeCurrencyMismatch       = "CURRENCY_MISMATCH"
+	ErrCodeBalanceOverflow        = "BALANCE_OVERFLOW"
+	ErrCodeHoldNotActive          = "HOLD_NOT_ACTIVE"
+	ErrCodeHoldAmountExceeded     = "HOLD_AMOUNT_EXCEEDED"
+	ErrCodeNotReversible          = "TRANSACTION_NOT_REVERSIBLE"
+	ErrCodeReversalAmountExceeded = "REVERSAL_AMOUNT_EXCEEDED"
+	ErrCodeDeliveryNotDead        = "WEBHOOK_DELIVERY_NOT_DEAD"
+	ErrCodeWalletFrozen           = "WALLET_FROZEN"
+	ErrCodeWalletClosed           = "WALLET_CLOSED"
+	ErrCodeWalletStatusConflict   = "WALLET_STATUS_CONFLICT"
+	ErrCodeWalletNotEmpty         = "WALLET_NOT_EMPTY"
 )
 
 type Response struct {
@@ -47,6 +68,26 @@ func ValidationError(c *gin.Context, details string) {
 	BadRequest(c, ErrCodeValidation, "Request parameters are invalid", details)
 }
 
+func Unauthorized(c *gin.Context, message string) {
+	c.JSON(http.StatusUnauthorized, Response{
+		Success: false,
+		Error: &Error{
+			Code:    ErrCodeUnauthorized,
+			Message: message,
+		},
+	})
+}
+
+func Forbidden(c *gin.Context, message string) {
+	c.JSON(http.StatusForbidden, Response{
+		Success: false,
+		Error: &Error{
+			Code:    ErrCodeForbidden,
+			Message: message,
+		},
+	})
+}
+
 func NotFound(c *gin.Context, message string) {
 	c.JSON(http.StatusNotFound, Response{
 		Success: false,
@@ -57,6 +98,26 @@ func NotFound(c *gin.Context, message string) {
 	})
 }
 
+func Conflict(c *gin.Context, code, message string) {
+	c.JSON(http.StatusConflict, Response{
+		Success: false,
+		Error: &Error{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+func UnprocessableEntity(c *gin.Context, code, message string) {
+	c.JSON(http.StatusUnprocessableEntity, Response{
+		Success: false,
+		Error: &Error{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
 func InternalError(c *gin.Context, message string) {
 	c.JSON(http.StatusInternalServerError, Response{
 		Success: false,
@@ -66,3 +127,27 @@ func InternalError(c *gin.Context, message string) {
 		},
 	})
 }
+
+// ServiceUnavailable responds with 503 and asks the client to retry after a second.
+func ServiceUnavailable(c *gin.Context, message string) {
+	c.Header("Retry-After", "1")
+	c.JSON(http.StatusServiceUnavailable, Response{
+		Success: false,
+		Error: &Error{
+			Code:    ErrCodeUnavailable,
+			Message: message,
+		},
+	})
+}
+
+// TooManyRequests responds with 429 and asks the client to retry after retryAfter seconds.
+func TooManyRequests(c *gin.Context, retryAfter int, message string) {
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, Response{
+		Success: false,
+		Error: &Error{
+			Code:    ErrCodeRateLimited,
+			Message: message,
+		},
+	})
+}
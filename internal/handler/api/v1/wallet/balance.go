@@ -1,40 +1,68 @@
 package wallet
 
 import (
-	"errors"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
 	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
-	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
 )
 
-type balanceReq struct {
+type walletReq struct {
 	WalletID string `uri:"id" binding:"required"`
 }
 
-type balanceResp struct {
-	WalletID string `json:"walletId"`
-	Balance  int64  `json:"balance"`
+// walletResp reports the current (ledger) balance and the available balance, i.e. the
+// current one minus the funds reserved by active holds. Balance equals Current and is
+// kept for older clients.
+type walletResp struct {
+	WalletID        string     `json:"walletId"`
+	Balance         int64      `json:"balance"`
+	Current         int64      `json:"current"`
+	Available       int64      `json:"available"`
+	Currency        string     `json:"currency"`
+	Status          string     `json:"status"`
+	StatusReason    string     `json:"statusReason,omitempty"`
+	StatusChangedAt *time.Time `json:"statusChangedAt,omitempty"`
+	OverdraftLimit  *int64     `json:"overdraftLimit,omitempty"`
+	OwnerID         string     `json:"ownerId,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
 }
 
-func (h *Handler) balance(c *gin.Context) {
-	var req balanceReq
+func (h *Handler) wallet(c *gin.Context) {
+	var req walletReq
 	if err := c.ShouldBindUri(&req); err != nil {
 		response.ValidationError(c, err.Error())
 		return
 	}
-
-	amount, err := h.walletSvc.Balance(c.Request.Context(), req.WalletID)
-	if errors.Is(err, svcErr.ErrWalletNotFound) {
-		response.NotFound(c, "Wallet not found")
+	if !auth.Authorize(c, req.WalletID) {
+		return
 	}
-	if err != nil {
-		response.BadRequest(c, response.ErrCodeInvalidRequest, "Failed to retrieve balance", err.Error())
+
+	wallet, err := h.walletSvc.Wallet(c.Request.Context(), req.WalletID)
+	if isErr := handleServiceError(c, err); isErr {
 		return
 	}
 
-	response.Success(c, 200, balanceResp{
-		WalletID: req.WalletID,
-		Balance:  amount,
-	})
+	response.Success(c, 200, newWalletResp(wallet))
+}
+
+func newWalletResp(wallet *entity.Wallet) walletResp {
+	return walletResp{
+		WalletID:        wallet.ID,
+		Balance:         wallet.Balance.Amount,
+		Current:         wallet.Balance.Amount,
+		Available:       wallet.Available().Amount,
+		Currency:        string(wallet.Currency()),
+		Status:          string(wallet.Status),
+		StatusReason:    wallet.StatusReason,
+		StatusChangedAt: wallet.StatusChangedAt,
+		OverdraftLimit:  wallet.OverdraftLimit,
+		OwnerID:         wallet.OwnerID,
+		CreatedAt:       wallet.CreatedAt,
+		UpdatedAt:       wallet.UpdatedAt,
+	}
 }
@@ -0,0 +1,45 @@
+package wallet
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type createReq struct {
+	// WalletID is optional: if it is empty, the ID is generated.
+	WalletID string `json:"id" binding:"omitempty,uuid"`
+	// OverdraftLimit is optional: if it is omitted, the default limit applies.
+	OverdraftLimit *int64 `json:"overdraftLimit" binding:"omitempty,min=0"`
+	// Currency is optional: if it is empty, the default currency is used.
+	Currency string `json:"currency" binding:"omitempty,iso4217"`
+}
+
+func (h *Handler) create(c *gin.Context) {
+	var req createReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	// Wallets created by end users belong to them. Otherwise a wallet without an ID
+	// gets a random one, which only keys for all wallets can access.
+	ownerID, isUser := auth.Subject(c)
+	if !isUser && !auth.Authorize(c, req.WalletID) {
+		return
+	}
+
+	wallet, err := h.walletSvc.Create(c.Request.Context(), entity.Wallet{
+		ID:             req.WalletID,
+		Balance:        entity.NewMoney(0, entity.Currency(req.Currency)),
+		OverdraftLimit: req.OverdraftLimit,
+		OwnerID:        ownerID,
+	})
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 201, newWalletResp(wallet))
+}
@@ -0,0 +1,149 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+
+	// heartbeatInterval is how often a comment is sent on an idle stream, so proxies
+	// keep the connection open and clients notice a dead one.
+	heartbeatInterval = 15 * time.Second
+	// reconnectDelay is how long clients wait before reconnecting to a closed stream.
+	reconnectDelay = 3 * time.Second
+
+	snapshotEvent = "snapshot"
+	balanceEvent  = "balance"
+)
+
+type eventsQuery struct {
+	// LastEventID is an alternative to the Last-Event-ID header for the first
+	// connection of an EventSource, which can't set headers.
+	LastEventID string `form:"lastEventId"`
+}
+
+type balanceChangeResp struct {
+	WalletID      string    `json:"walletId"`
+	TransactionID string    `json:"transactionId"`
+	OperationType string    `json:"operationType"`
+	Amount        int64     `json:"amount"`
+	BalanceAfter  int64     `json:"balanceAfter"`
+	Sequence      int64     `json:"sequence"`
+	OccurredAt    time.Time `json:"occurredAt"`
+}
+
+// events streams balance changes of the wallet as Server-Sent Events. A stream
+// that is not resumed starts with a snapshot of the wallet. The stream of a client
+// that doesn't keep up is closed; it resumes from the last received event when
+// the client reconnects.
+func (h *Handler) events(c *gin.Context) {
+	var req walletReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+	if !auth.Authorize(c, req.WalletID) {
+		return
+	}
+
+	var query eventsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	lastEventID := c.GetHeader(lastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = query.LastEventID
+	}
+
+	ctx := c.Request.Context()
+
+	sub, wallet, err := h.walletSvc.WatchBalance(ctx, req.WalletID, lastEventID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+	defer sub.Close()
+
+	// The server write timeout is meant for regular requests; a stream stays open.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds()); err != nil {
+		return
+	}
+
+	if !sub.Resumed {
+		if err := writeEvent(w, sub.Position, snapshotEvent, newWalletResp(wallet)); err != nil {
+			return
+		}
+	}
+	for _, msg := range sub.Missed {
+		if err := writeEvent(w, msg.ID, balanceEvent, newBalanceChangeResp(msg.Value)); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, msg.ID, balanceEvent, newBalanceChangeResp(msg.Value)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
+
+// writeEvent writes a single event with JSON data.
+func writeEvent(w io.Writer, id, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, payload)
+
+	return err
+}
+
+func newBalanceChangeResp(change entity.BalanceChange) balanceChangeResp {
+	return balanceChangeResp{
+		WalletID:      change.WalletID,
+		TransactionID: change.TransactionID,
+		OperationType: string(change.OperationType),
+		Amount:        change.Amount,
+		BalanceAfter:  change.BalanceAfter,
+		Sequence:      change.Sequence,
+		OccurredAt:    change.OccurredAt,
+	}
+}
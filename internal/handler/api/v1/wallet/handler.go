@@ -2,14 +2,35 @@ package wallet
 
 import (
 	"context"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/pkg/broadcast"
 )
 
 type WalletService interface {
-	Deposit(ctx context.Context, walletID string, amount int64) error
-	Withdraw(ctx context.Context, walletID string, amount int64) error
-	Balance(ctx context.Context, walletID string) (int64, error)
+	Deposit(ctx context.Context, walletID string, amount entity.Money, opts ...entity.OperationOption) error
+	Withdraw(ctx context.Context, walletID string, amount entity.Money, opts ...entity.OperationOption) error
+	Transfer(ctx context.Context, fromWalletID, toWalletID string, amount entity.Money, opts ...entity.OperationOption) error
+	Create(ctx context.Context, params entity.Wallet) (*entity.Wallet, error)
+	Wallet(ctx context.Context, walletID string) (*entity.Wallet, error)
+	FreezeWallet(ctx context.Context, walletID, reason string) (*entity.Wallet, error)
+	UnfreezeWallet(ctx context.Context, walletID, reason string) (*entity.Wallet, error)
+	CloseWallet(ctx context.Context, walletID, reason, sweepToWalletID string) (*entity.Wallet, error)
+	AssignOwner(ctx context.Context, walletID, ownerID string) (*entity.Wallet, error)
+	Transactions(ctx context.Context, walletID string, query entity.TransactionQuery) (*entity.TransactionPage, error)
+	IdempotencyKey(ctx context.Context, clientID, key, fingerprint string) (*entity.IdempotencyKey, error)
+	CreateHold(ctx context.Context, walletID string, amount entity.Money, ttl time.Duration) (*entity.Hold, error)
+	Hold(ctx context.Context, holdID string) (*entity.Hold, error)
+	CaptureHold(ctx context.Context, holdID string, amount entity.Money) (*entity.Hold, error)
+	ReleaseHold(ctx context.Context, holdID string) (*entity.Hold, error)
+	Enqueue(ctx context.Context, operationID, walletID string, operationType entity.OperationType, amount entity.Money, opts ...entity.OperationOption) error
+	QueuedOperation(ctx context.Context, operationID string) (*entity.QueuedOperation, error)
+	Reverse(ctx context.Context, transactionID string, amount entity.Money, opts ...entity.OperationOption) (*entity.Transaction, error)
+	WatchBalance(ctx context.Context, walletID, lastEventID string) (*broadcast.Subscription[entity.BalanceChange], *entity.Wallet, error)
 }
 
 type Handler struct {
@@ -30,11 +51,52 @@ func (h *Handler) RegisterRoutes(base *gin.RouterGroup) {
 		walletGroup.POST("", h.operation)
 	}
 
+	transfersGroup := base.Group("/transfers")
+	{
+		transfersGroup.POST("", h.transfer)
+	}
+
 	walletsGroup := base.Group("/wallets")
 	{
+		walletsGroup.POST("", h.create)
+
 		walletIDGroup := walletsGroup.Group("/:id")
 		{
-			walletIDGroup.GET("", h.balance)
+			walletIDGroup.GET("", h.wallet)
+			walletIDGroup.GET("/transactions", h.transactions)
+			walletIDGroup.GET("/events", h.events)
+			walletIDGroup.POST("/holds", h.createHold)
+
+			// Status changes are administrative, so they need a key for all wallets
+			// rather than access to the wallet.
+			walletIDGroup.POST("/freeze", auth.RequireAllWallets(), h.freezeWallet)
+			walletIDGroup.POST("/unfreeze", auth.RequireAllWallets(), h.unfreezeWallet)
+			walletIDGroup.POST("/close", auth.RequireAllWallets(), h.closeWallet)
+			// So is assigning the owner, e.g. of a wallet created with an API key,
+			// whose end user can't access it otherwise.
+			walletIDGroup.PUT("/owner", auth.RequireAllWallets(), h.assignOwner)
 		}
 	}
+
+	holdsGroup := base.Group("/holds")
+	{
+		holdIDGroup := holdsGroup.Group("/:id")
+		{
+			holdIDGroup.GET("", h.hold)
+			holdIDGroup.POST("/capture", h.captureHold)
+			holdIDGroup.POST("/release", h.releaseHold)
+		}
+	}
+
+	operationsGroup := base.Group("/operations")
+	{
+		operationsGroup.GET("/:id", h.queuedOperation)
+	}
+
+	transactionsGroup := base.Group("/transactions")
+	{
+		// The wallet of a transaction is only known once it is locked, so reversals
+		// need a key for all wallets.
+		transactionsGroup.POST("/:id/reverse", auth.RequireAllWallets(), h.reverse)
+	}
 }
@@ -0,0 +1,164 @@
+package wallet
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type holdReq struct {
+	HoldID string `uri:"id" binding:"required"`
+}
+
+type createHoldReq struct {
+	Amount int64 `json:"amount" binding:"required,min=1,gt=0"`
+	// Currency is optional: if it is empty, the amount is in the wallet currency.
+	Currency string `json:"currency" binding:"omitempty,iso4217"`
+	// TTLSeconds is optional: if it is omitted, the default TTL applies.
+	TTLSeconds int64 `json:"ttlSeconds" binding:"omitempty,min=1"`
+}
+
+type captureHoldReq struct {
+	// Amount is optional: if it is omitted, the whole hold is captured.
+	Amount   int64  `json:"amount" binding:"omitempty,min=1"`
+	Currency string `json:"currency" binding:"omitempty,iso4217"`
+}
+
+type holdResp struct {
+	HoldID         string    `json:"holdId"`
+	WalletID       string    `json:"walletId"`
+	Amount         int64     `json:"amount"`
+	CapturedAmount int64     `json:"capturedAmount"`
+	Currency       string    `json:"currency"`
+	Status         string    `json:"status"`
+	TransactionID  string    `json:"transactionId,omitempty"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func (h *Handler) createHold(c *gin.Context) {
+	var uri walletReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	var req createHoldReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+	if !auth.Authorize(c, uri.WalletID) {
+		return
+	}
+
+	hold, err := h.walletSvc.CreateHold(
+		c.Request.Context(),
+		uri.WalletID,
+		entity.NewMoney(req.Amount, entity.Currency(req.Currency)),
+		time.Duration(req.TTLSeconds)*time.Second,
+	)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 201, newHoldResp(hold))
+}
+
+func (h *Handler) hold(c *gin.Context) {
+	var req holdReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	hold, err := h.walletSvc.Hold(c.Request.Context(), req.HoldID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+	if !auth.Authorize(c, hold.WalletID) {
+		return
+	}
+
+	response.Success(c, 200, newHoldResp(hold))
+}
+
+func (h *Handler) captureHold(c *gin.Context) {
+	var uri holdReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	// The body is optional.
+	var req captureHoldReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.ValidationError(c, err.Error())
+		return
+	}
+	if !h.authorizeHold(c, uri.HoldID) {
+		return
+	}
+
+	hold, err := h.walletSvc.CaptureHold(
+		c.Request.Context(),
+		uri.HoldID,
+		entity.NewMoney(req.Amount, entity.Currency(req.Currency)),
+	)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, newHoldResp(hold))
+}
+
+func (h *Handler) releaseHold(c *gin.Context) {
+	var req holdReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	if !h.authorizeHold(c, req.HoldID) {
+		return
+	}
+
+	hold, err := h.walletSvc.ReleaseHold(c.Request.Context(), req.HoldID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, newHoldResp(hold))
+}
+
+// authorizeHold reports whether the API key of the request may access the wallet
+// of the hold. If it may not or the hold can't be retrieved, it responds with an error.
+func (h *Handler) authorizeHold(c *gin.Context, holdID string) bool {
+	hold, err := h.walletSvc.Hold(c.Request.Context(), holdID)
+	if isErr := handleServiceError(c, err); isErr {
+		return false
+	}
+	return auth.Authorize(c, hold.WalletID)
+}
+
+func newHoldResp(hold *entity.Hold) holdResp {
+	return holdResp{
+		HoldID:         hold.ID,
+		WalletID:       hold.WalletID,
+		Amount:         hold.Amount.Amount,
+		CapturedAmount: hold.CapturedAmount,
+		Currency:       string(hold.Amount.Currency),
+		Status:         string(hold.Status),
+		TransactionID:  hold.TransactionID,
+		ExpiresAt:      hold.ExpiresAt,
+		CreatedAt:      hold.CreatedAt,
+		UpdatedAt:      hold.UpdatedAt,
+	}
+}
@@ -0,0 +1,125 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	jsonContentType = "application/json; charset=utf-8"
+)
+
+// idempotent runs the operation and responds with the given success data.
+// If the request carries an Idempotency-Key header, the key is stored together with
+// the response in the same database transaction as the operation. Keys are scoped
+// to the client, so clients can't collide on them. A repeated request of the client
+// with the same key and body replays the stored response; a repeated request with
+// the same key and a different body is rejected with 422.
+func (h *Handler) idempotent(
+	c *gin.Context,
+	req any,
+	statusCode int,
+	data any,
+	run func(opts ...entity.OperationOption) error,
+) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		if isErr := handleServiceError(c, run()); isErr {
+			return
+		}
+		response.Success(c, statusCode, data)
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		response.ValidationError(c, "Idempotency-Key header is too long")
+		return
+	}
+
+	fingerprint, err := fingerprintOf(c, req)
+	if err != nil {
+		response.InternalError(c, "Internal server error")
+		return
+	}
+
+	clientID := auth.ClientID(c)
+	if replayed := h.replay(c, clientID, key, fingerprint); replayed {
+		return
+	}
+
+	body, err := json.Marshal(response.Response{Success: true, Data: data})
+	if err != nil {
+		response.InternalError(c, "Internal server error")
+		return
+	}
+
+	err = run(entity.WithIdempotencyKey(&entity.IdempotencyKey{
+		ClientID:    clientID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		StatusCode:  statusCode,
+		Response:    body,
+	}))
+	// A concurrent request with the same key has committed first.
+	if errors.Is(err, svcErr.ErrIdempotencyKeyReused) {
+		if replayed := h.replay(c, clientID, key, fingerprint); replayed {
+			return
+		}
+	}
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	c.Data(statusCode, jsonContentType, body)
+}
+
+// replay writes the stored response if the client already used the idempotency key.
+// It returns true if a response was written.
+func (h *Handler) replay(c *gin.Context, clientID, key, fingerprint string) bool {
+	stored, err := h.walletSvc.IdempotencyKey(c.Request.Context(), clientID, key, fingerprint)
+	if errors.Is(err, svcErr.ErrIdempotencyKeyNotFound) {
+		return false
+	}
+	if isErr := handleServiceError(c, err); isErr {
+		return true
+	}
+
+	c.Header(idempotencyReplayedHeader, "true")
+	c.Data(stored.StatusCode, jsonContentType, stored.Response)
+
+	return true
+}
+
+// fingerprintOf returns a fingerprint of the bound request. The route is included,
+// so the same key can't be reused across different endpoints, and so is the async
+// mode, so a request sent again without it isn't answered with the 202 of a queued one.
+func fingerprintOf(c *gin.Context, req any) (string, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.FullPath() + "\n"))
+	// Only written in async mode, so the fingerprints of stored synchronous requests don't change.
+	if prefersAsync(c) {
+		h.Write([]byte(respondAsync + "\n"))
+	}
+	h.Write(payload)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
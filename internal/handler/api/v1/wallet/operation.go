@@ -4,6 +4,9 @@ import (
 	"errors"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
 	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
 	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
 )
@@ -17,6 +20,8 @@ type operationReq struct {
 	WalletID      string `json:"walletId" binding:"required,uuid"`
 	OperationType string `json:"operationType" binding:"required,oneof=deposit withdraw"`
 	Amount        int64  `json:"amount" binding:"required,min=1,gt=0"`
+	// Currency is optional: if it is empty, the amount is in the wallet currency.
+	Currency string `json:"currency" binding:"omitempty,iso4217"`
 }
 
 type operationResp struct {
@@ -29,20 +34,41 @@ func (h *Handler) operation(c *gin.Context) {
 		response.ValidationError(c, err.Error())
 		return
 	}
+	if !auth.Authorize(c, req.WalletID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	amount := entity.NewMoney(req.Amount, entity.Currency(req.Currency))
+
+	// In async mode the operation is only queued; the client polls its status.
+	if prefersAsync(c) {
+		operationID := uuid.NewString()
+		accepted := acceptedOperationResp{
+			OperationID: operationID,
+			Status:      string(entity.QueuedOperationPending),
+		}
+
+		c.Header(preferenceAppliedHeader, respondAsync)
+		h.idempotent(c, req, 202, accepted,
+			func(opts ...entity.OperationOption) error {
+				return h.walletSvc.Enqueue(ctx, operationID, req.WalletID,
+					entity.OperationType(req.OperationType), amount, opts...)
+			})
+		return
+	}
 
 	switch req.OperationType {
 	case depositOperation:
-		err := h.walletSvc.Deposit(c.Request.Context(), req.WalletID, req.Amount)
-		if isErr := handleServiceError(c, err); isErr {
-			return
-		}
-		response.Success(c, 200, operationResp{Message: "Deposit successful"})
+		h.idempotent(c, req, 200, operationResp{Message: "Deposit successful"},
+			func(opts ...entity.OperationOption) error {
+				return h.walletSvc.Deposit(ctx, req.WalletID, amount, opts...)
+			})
 	case withdrawOperation:
-		err := h.walletSvc.Withdraw(c.Request.Context(), req.WalletID, req.Amount)
-		if isErr := handleServiceError(c, err); isErr {
-			return
-		}
-		response.Success(c, 200, operationResp{Message: "Withdrawal successful"})
+		h.idempotent(c, req, 200, operationResp{Message: "Withdrawal successful"},
+			func(opts ...entity.OperationOption) error {
+				return h.walletSvc.Withdraw(ctx, req.WalletID, amount, opts...)
+			})
 	default:
 		response.BadRequest(c, response.ErrCodeInvalidRequest, "Invalid operation type", "Must be either 'deposit' or 'withdraw'")
 		return
@@ -59,6 +85,43 @@ func handleServiceError(c *gin.Context, err error) bool {
 		response.ValidationError(c, "Invalid parameters provided")
 	case errors.Is(err, svcErr.ErrWalletNotFound):
 		response.NotFound(c, "Wallet not found")
+	case errors.Is(err, svcErr.ErrOperationNotFound):
+		response.NotFound(c, "Operation not found")
+	case errors.Is(err, svcErr.ErrHoldNotFound):
+		response.NotFound(c, "Hold not found")
+	case errors.Is(err, svcErr.ErrHoldNotActive):
+		response.Conflict(c, response.ErrCodeHoldNotActive, "Hold is already captured, released or expired")
+	case errors.Is(err, svcErr.ErrHoldAmountExceeded):
+		response.UnprocessableEntity(c, response.ErrCodeHoldAmountExceeded, "Amount exceeds the hold amount")
+	case errors.Is(err, svcErr.ErrTransactionNotFound):
+		response.NotFound(c, "Transaction not found")
+	case errors.Is(err, svcErr.ErrNotReversible):
+		response.UnprocessableEntity(c, response.ErrCodeNotReversible, "Only deposits, withdrawals and captures can be reversed")
+	case errors.Is(err, svcErr.ErrReversalAmountExceeded):
+		response.UnprocessableEntity(c, response.ErrCodeReversalAmountExceeded, "Amount exceeds the amount left to reverse")
+	case errors.Is(err, svcErr.ErrInsufficientFunds):
+		response.Conflict(c, response.ErrCodeInsufficientFunds, "Insufficient funds")
+	case errors.Is(err, svcErr.ErrCurrencyMismatch):
+		response.UnprocessableEntity(c, response.ErrCodeCurrencyMismatch, "Currency doesn't match the wallet currency")
+	case errors.Is(err, svcErr.ErrBalanceOverflow):
+		response.UnprocessableEntity(c, response.ErrCodeBalanceOverflow, "Balance would exceed the maximum amount")
+	case errors.Is(err, svcErr.ErrWalletFrozen):
+		response.Conflict(c, response.ErrCodeWalletFrozen, "Wallet is frozen")
+	case errors.Is(err, svcErr.ErrWalletClosed):
+		response.Conflict(c, response.ErrCodeWalletClosed, "Wallet is closed")
+	case errors.Is(err, svcErr.ErrWalletStatusTransition):
+		response.Conflict(c, response.ErrCodeWalletStatusConflict, "Wallet can't change to the status")
+	case errors.Is(err, svcErr.ErrWalletNotEmpty):
+		response.Conflict(c, response.ErrCodeWalletNotEmpty, "Wallet has a balance or active holds")
+	case errors.Is(err, svcErr.ErrWalletAlreadyExists):
+		response.Conflict(c, response.ErrCodeWalletAlreadyExists, "Wallet already exists")
+	case errors.Is(err, svcErr.ErrIdempotencyKeyMismatch):
+		response.UnprocessableEntity(c, response.ErrCodeIdempotencyKeyMismatch,
+			"Idempotency key was already used with a different request")
+	case errors.Is(err, svcErr.ErrIdempotencyKeyReused):
+		response.Conflict(c, response.ErrCodeConflict, "Request with this idempotency key is being processed")
+	case errors.Is(err, svcErr.ErrBusy):
+		response.ServiceUnavailable(c, "Too many concurrent requests for the wallet, retry later")
 	default:
 		response.InternalError(c, "Internal server error")
 	}
@@ -0,0 +1,34 @@
+package wallet
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type ownerReq struct {
+	// OwnerID is the subject of the end user; an empty one leaves the wallet
+	// without an owner.
+	OwnerID *string `json:"ownerId" binding:"required,max=255"`
+}
+
+func (h *Handler) assignOwner(c *gin.Context) {
+	var uri walletReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	var req ownerReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	wallet, err := h.walletSvc.AssignOwner(c.Request.Context(), uri.WalletID, *req.OwnerID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, newWalletResp(wallet))
+}
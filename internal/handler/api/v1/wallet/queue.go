@@ -0,0 +1,88 @@
+package wallet
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+const (
+	preferHeader            = "Prefer"
+	preferenceAppliedHeader = "Preference-Applied"
+
+	// respondAsync is the RFC 7240 preference that switches an operation to async mode.
+	respondAsync = "respond-async"
+)
+
+type queuedOperationReq struct {
+	OperationID string `uri:"id" binding:"required"`
+}
+
+type acceptedOperationResp struct {
+	OperationID string `json:"operationId"`
+	Status      string `json:"status"`
+}
+
+type queuedOperationResp struct {
+	OperationID   string    `json:"operationId"`
+	WalletID      string    `json:"walletId"`
+	OperationType string    `json:"operationType"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	FailureReason string    `json:"failureReason,omitempty"`
+	TransactionID string    `json:"transactionId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func (h *Handler) queuedOperation(c *gin.Context) {
+	var req queuedOperationReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	operation, err := h.walletSvc.QueuedOperation(c.Request.Context(), req.OperationID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+	if !auth.Authorize(c, operation.WalletID) {
+		return
+	}
+
+	amount := operation.Amount.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+
+	response.Success(c, 200, queuedOperationResp{
+		OperationID:   operation.ID,
+		WalletID:      operation.WalletID,
+		OperationType: string(operation.Type),
+		Amount:        amount,
+		Currency:      string(operation.Amount.Currency),
+		Status:        string(operation.Status),
+		FailureReason: operation.FailureReason,
+		TransactionID: operation.TransactionID,
+		CreatedAt:     operation.CreatedAt,
+		UpdatedAt:     operation.UpdatedAt,
+	})
+}
+
+// prefersAsync reports whether the client asked for async mode with a
+// "Prefer: respond-async" header.
+func prefersAsync(c *gin.Context) bool {
+	for _, header := range c.Request.Header.Values(preferHeader) {
+		for _, preference := range strings.Split(header, ",") {
+			if strings.EqualFold(strings.TrimSpace(preference), respondAsync) {
+				return true
+			}
+		}
+	}
+	return false
+}
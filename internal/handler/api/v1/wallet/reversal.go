@@ -0,0 +1,60 @@
+package wallet
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type transactionReq struct {
+	TransactionID string `uri:"id" binding:"required"`
+}
+
+type reverseReq struct {
+	// TransactionID is not part of the body; it is set from the path, so the
+	// idempotency fingerprint covers it.
+	TransactionID string `json:"transactionId"`
+	// Amount is optional: if it is omitted, the rest of the transaction is reversed.
+	Amount   int64  `json:"amount" binding:"omitempty,min=1"`
+	Currency string `json:"currency" binding:"omitempty,iso4217"`
+}
+
+type reverseResp struct {
+	Message       string `json:"message"`
+	TransactionID string `json:"transactionId"`
+	Amount        int64  `json:"amount,omitempty"`
+}
+
+func (h *Handler) reverse(c *gin.Context) {
+	var uri transactionReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	// The body is optional.
+	var req reverseReq
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.ValidationError(c, err.Error())
+		return
+	}
+	req.TransactionID = uri.TransactionID
+
+	ctx := c.Request.Context()
+	amount := entity.NewMoney(req.Amount, entity.Currency(req.Currency))
+
+	resp := reverseResp{
+		Message:       "Reversal successful",
+		TransactionID: req.TransactionID,
+		Amount:        req.Amount,
+	}
+
+	h.idempotent(c, req, 200, resp, func(opts ...entity.OperationOption) error {
+		_, err := h.walletSvc.Reverse(ctx, req.TransactionID, amount, opts...)
+		return err
+	})
+}
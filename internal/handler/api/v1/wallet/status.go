@@ -0,0 +1,81 @@
+package wallet
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type statusReq struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type closeReq struct {
+	Reason string `json:"reason" binding:"required"`
+	// SweepToWalletID is optional: if it is empty, only a wallet with zero balance
+	// can be closed.
+	SweepToWalletID string `json:"sweepToWalletId" binding:"omitempty,uuid"`
+}
+
+func (h *Handler) freezeWallet(c *gin.Context) {
+	var uri walletReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	var req statusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	wallet, err := h.walletSvc.FreezeWallet(c.Request.Context(), uri.WalletID, req.Reason)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, newWalletResp(wallet))
+}
+
+func (h *Handler) unfreezeWallet(c *gin.Context) {
+	var uri walletReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	var req statusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	wallet, err := h.walletSvc.UnfreezeWallet(c.Request.Context(), uri.WalletID, req.Reason)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, newWalletResp(wallet))
+}
+
+func (h *Handler) closeWallet(c *gin.Context) {
+	var uri walletReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	var req closeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	wallet, err := h.walletSvc.CloseWallet(c.Request.Context(), uri.WalletID, req.Reason, req.SweepToWalletID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, newWalletResp(wallet))
+}
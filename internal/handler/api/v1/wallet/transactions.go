@@ -0,0 +1,79 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type transactionsReq struct {
+	WalletID      string    `uri:"id" binding:"required"`
+	OperationType string    `form:"type" binding:"omitempty,oneof=deposit withdraw transfer_out transfer_in capture reversal"`
+	From          time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor        string    `form:"cursor"`
+	Limit         int       `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type transactionResp struct {
+	ID            string    `json:"id"`
+	Amount        int64     `json:"amount"`
+	OperationType string    `json:"operationType"`
+	BalanceAfter  int64     `json:"balanceAfter"`
+	ReferenceID   string    `json:"referenceId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type transactionsResp struct {
+	WalletID     string            `json:"walletId"`
+	Transactions []transactionResp `json:"transactions"`
+	NextCursor   string            `json:"nextCursor,omitempty"`
+}
+
+func (h *Handler) transactions(c *gin.Context) {
+	var req transactionsReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+	if !auth.Authorize(c, req.WalletID) {
+		return
+	}
+
+	page, err := h.walletSvc.Transactions(c.Request.Context(), req.WalletID, entity.TransactionQuery{
+		OperationType: entity.OperationType(req.OperationType),
+		From:          req.From,
+		To:            req.To,
+		Cursor:        req.Cursor,
+		Limit:         req.Limit,
+	})
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	resp := transactionsResp{
+		WalletID:     req.WalletID,
+		Transactions: make([]transactionResp, 0, len(page.Transactions)),
+		NextCursor:   page.NextCursor,
+	}
+	for _, t := range page.Transactions {
+		resp.Transactions = append(resp.Transactions, transactionResp{
+			ID:            t.ID,
+			Amount:        t.Amount,
+			OperationType: string(t.OperationType),
+			BalanceAfter:  t.BalanceAfter,
+			ReferenceID:   t.ReferenceID,
+			CreatedAt:     t.CreatedAt,
+		})
+	}
+
+	response.Success(c, 200, resp)
+}
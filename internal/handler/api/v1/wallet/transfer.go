@@ -0,0 +1,52 @@
+package wallet
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type transferReq struct {
+	FromWalletID string `json:"fromWalletId" binding:"required,uuid"`
+	ToWalletID   string `json:"toWalletId" binding:"required,uuid,nefield=FromWalletID"`
+	Amount       int64  `json:"amount" binding:"required,min=1,gt=0"`
+	// Currency is optional: if it is empty, the amount is in the wallets currency.
+	Currency string `json:"currency" binding:"omitempty,iso4217"`
+}
+
+type transferResp struct {
+	Message      string `json:"message"`
+	FromWalletID string `json:"fromWalletId"`
+	ToWalletID   string `json:"toWalletId"`
+	Amount       int64  `json:"amount"`
+	Currency     string `json:"currency,omitempty"`
+}
+
+func (h *Handler) transfer(c *gin.Context) {
+	var req transferReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+	if !auth.AuthorizeTransfer(c, req.FromWalletID, req.ToWalletID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	resp := transferResp{
+		Message:      "Transfer successful",
+		FromWalletID: req.FromWalletID,
+		ToWalletID:   req.ToWalletID,
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+	}
+
+	h.idempotent(c, req, 200, resp, func(opts ...entity.OperationOption) error {
+		amount := entity.NewMoney(req.Amount, entity.Currency(req.Currency))
+
+		return h.walletSvc.Transfer(ctx, req.FromWalletID, req.ToWalletID, amount, opts...)
+	})
+}
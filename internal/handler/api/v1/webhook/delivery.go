@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+type deliveryReq struct {
+	WebhookID  string `uri:"id" binding:"required"`
+	DeliveryID string `uri:"deliveryId" binding:"required"`
+}
+
+type deliveriesQuery struct {
+	// Status is optional: if it is empty, deliveries in every status are returned.
+	Status string `form:"status" binding:"omitempty,oneof=pending delivered dead"`
+	// Limit is optional: if it is omitted, the default limit applies.
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
+
+type deliveryResp struct {
+	DeliveryID     string          `json:"deliveryId"`
+	WebhookID      string          `json:"webhookId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	LastStatusCode int             `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
+
+type attemptResp struct {
+	StatusCode  int       `json:"statusCode,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"durationMs"`
+	AttemptedAt time.Time `json:"attemptedAt"`
+}
+
+type deliveryWithAttemptsResp struct {
+	deliveryResp
+	AttemptLog []attemptResp `json:"attemptLog"`
+}
+
+type deliveriesResp struct {
+	Deliveries []deliveryResp `json:"deliveries"`
+}
+
+func (h *Handler) deliveries(c *gin.Context) {
+	var uri webhookReq
+	if err := c.ShouldBindUri(&uri); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	var query deliveriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	deliveries, err := h.webhookSvc.Deliveries(c.Request.Context(), entity.WebhookDeliveryFilter{
+		WebhookID: uri.WebhookID,
+		Status:    entity.WebhookDeliveryStatus(query.Status),
+		Limit:     query.Limit,
+	})
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	resp := deliveriesResp{Deliveries: make([]deliveryResp, 0, len(deliveries))}
+	for _, d := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, newDeliveryResp(&d))
+	}
+
+	response.Success(c, 200, resp)
+}
+
+func (h *Handler) delivery(c *gin.Context) {
+	var req deliveryReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	delivery, attempts, err := h.webhookSvc.Delivery(c.Request.Context(), req.WebhookID, req.DeliveryID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	resp := deliveryWithAttemptsResp{
+		deliveryResp: newDeliveryResp(delivery),
+		AttemptLog:   make([]attemptResp, 0, len(attempts)),
+	}
+	for _, a := range attempts {
+		resp.AttemptLog = append(resp.AttemptLog, attemptResp{
+			StatusCode:  a.StatusCode,
+			Error:       a.Error,
+			DurationMs:  a.Duration.Milliseconds(),
+			AttemptedAt: a.AttemptedAt,
+		})
+	}
+
+	response.Success(c, 200, resp)
+}
+
+func (h *Handler) redeliver(c *gin.Context) {
+	var req deliveryReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	delivery, err := h.webhookSvc.Redeliver(c.Request.Context(), req.WebhookID, req.DeliveryID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, newDeliveryResp(delivery))
+}
+
+func newDeliveryResp(delivery *entity.WebhookDelivery) deliveryResp {
+	resp := deliveryResp{
+		DeliveryID:     delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		EventID:        delivery.EventID,
+		EventType:      string(delivery.EventType),
+		Payload:        delivery.Payload,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+	// The next attempt is only meaningful while the delivery is retried.
+	if delivery.Status == entity.WebhookDeliveryPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	return resp
+}
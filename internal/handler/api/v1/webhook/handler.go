@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+)
+
+type WebhookService interface {
+	Create(ctx context.Context, params entity.Webhook) (*entity.Webhook, error)
+	Webhook(ctx context.Context, webhookID string) (*entity.Webhook, error)
+	Delete(ctx context.Context, webhookID string) error
+	Deliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error)
+	Delivery(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, []entity.WebhookAttempt, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, error)
+}
+
+type Handler struct {
+	webhookSvc WebhookService
+}
+
+func New(
+	webhookSvc WebhookService,
+) *Handler {
+	return &Handler{
+		webhookSvc: webhookSvc,
+	}
+}
+
+func (h *Handler) RegisterRoutes(base *gin.RouterGroup) {
+	webhooksGroup := base.Group("/webhooks", auth.RequireAllWallets())
+	{
+		webhooksGroup.POST("", h.create)
+
+		webhookIDGroup := webhooksGroup.Group("/:id")
+		{
+			webhookIDGroup.GET("", h.webhook)
+			webhookIDGroup.DELETE("", h.delete)
+			webhookIDGroup.GET("/deliveries", h.deliveries)
+			webhookIDGroup.GET("/deliveries/:deliveryId", h.delivery)
+			webhookIDGroup.POST("/deliveries/:deliveryId/redeliver", h.redeliver)
+		}
+	}
+}
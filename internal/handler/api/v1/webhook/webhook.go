@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+)
+
+type webhookReq struct {
+	WebhookID string `uri:"id" binding:"required"`
+}
+
+type createWebhookReq struct {
+	URL string `json:"url" binding:"required,url"`
+	// Secret is optional: if it is empty, a random one is generated.
+	Secret string `json:"secret" binding:"omitempty,min=16"`
+	// EventTypes and WalletIDs are optional filters: if one is empty, it doesn't filter events.
+	EventTypes []string `json:"eventTypes" binding:"omitempty,dive,oneof=WalletCreated WalletCredited WalletDebited"`
+	WalletIDs  []string `json:"walletIds" binding:"omitempty,dive,uuid"`
+}
+
+type webhookResp struct {
+	WebhookID string `json:"webhookId"`
+	URL       string `json:"url"`
+	// Secret is only returned when the webhook is created.
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"eventTypes"`
+	WalletIDs  []string  `json:"walletIds"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type deleteWebhookResp struct {
+	Message string `json:"message"`
+}
+
+func (h *Handler) create(c *gin.Context) {
+	var req createWebhookReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	eventTypes := make([]entity.EventType, 0, len(req.EventTypes))
+	for _, t := range req.EventTypes {
+		eventTypes = append(eventTypes, entity.EventType(t))
+	}
+
+	webhook, err := h.webhookSvc.Create(c.Request.Context(), entity.Webhook{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: eventTypes,
+		WalletIDs:  req.WalletIDs,
+	})
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	resp := newWebhookResp(webhook)
+	resp.Secret = webhook.Secret
+
+	response.Success(c, 201, resp)
+}
+
+func (h *Handler) webhook(c *gin.Context) {
+	var req webhookReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	webhook, err := h.webhookSvc.Webhook(c.Request.Context(), req.WebhookID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, newWebhookResp(webhook))
+}
+
+func (h *Handler) delete(c *gin.Context) {
+	var req webhookReq
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	err := h.webhookSvc.Delete(c.Request.Context(), req.WebhookID)
+	if isErr := handleServiceError(c, err); isErr {
+		return
+	}
+
+	response.Success(c, 200, deleteWebhookResp{Message: "Webhook deleted"})
+}
+
+func newWebhookResp(webhook *entity.Webhook) webhookResp {
+	eventTypes := make([]string, 0, len(webhook.EventTypes))
+	for _, t := range webhook.EventTypes {
+		eventTypes = append(eventTypes, string(t))
+	}
+	walletIDs := webhook.WalletIDs
+	if walletIDs == nil {
+		walletIDs = []string{}
+	}
+
+	return webhookResp{
+		WebhookID:  webhook.ID,
+		URL:        webhook.URL,
+		EventTypes: eventTypes,
+		WalletIDs:  walletIDs,
+		Active:     webhook.Active,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
+
+func handleServiceError(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch {
+	case errors.Is(err, svcErr.ErrInvalidParams):
+		response.ValidationError(c, "Invalid parameters provided")
+	case errors.Is(err, svcErr.ErrWebhookNotFound):
+		response.NotFound(c, "Webhook not found")
+	case errors.Is(err, svcErr.ErrWebhookDeliveryNotFound):
+		response.NotFound(c, "Delivery not found")
+	case errors.Is(err, svcErr.ErrWebhookDeliveryNotDead):
+		response.Conflict(c, response.ErrCodeDeliveryNotDead, "Only dead deliveries can be redelivered")
+	default:
+		response.InternalError(c, "Internal server error")
+	}
+
+	return true
+}
@@ -0,0 +1,54 @@
+package docs
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+// swaggerInitializer replaces the initializer shipped with Swagger UI, which
+// loads the Petstore example, so the UI loads the spec of this service.
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// Handler serves the OpenAPI specification and Swagger UI.
+type Handler struct {
+	spec []byte
+}
+
+// New returns a handler serving spec, the JSON encoded specification.
+func New(
+	spec []byte,
+) *Handler {
+	return &Handler{
+		spec: spec,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/openapi.json", h.openAPI)
+	r.GET("/swagger/*filepath", h.swaggerUI)
+}
+
+func (h *Handler) openAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+func (h *Handler) swaggerUI(c *gin.Context) {
+	if c.Param("filepath") == "/swagger-initializer.js" {
+		c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(swaggerInitializer))
+		return
+	}
+
+	http.StripPrefix("/swagger", http.FileServer(swaggerFiles.HTTP)).ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	walletv1 "github.com/passwordhash/asynchronous-wallet/pkg/api/wallet/v1"
+)
+
+type WalletService interface {
+	Deposit(ctx context.Context, walletID string, amount entity.Money, opts ...entity.OperationOption) error
+	Withdraw(ctx context.Context, walletID string, amount entity.Money, opts ...entity.OperationOption) error
+	Wallet(ctx context.Context, walletID string) (*entity.Wallet, error)
+	Transactions(ctx context.Context, walletID string, query entity.TransactionQuery) (*entity.TransactionPage, error)
+}
+
+// Handler implements the gRPC WalletService on top of the wallet service.
+type Handler struct {
+	walletv1.UnimplementedWalletServiceServer
+
+	walletSvc WalletService
+}
+
+func New(
+	walletSvc WalletService,
+) *Handler {
+	return &Handler{
+		walletSvc: walletSvc,
+	}
+}
+
+func (h *Handler) Deposit(ctx context.Context, req *walletv1.DepositRequest) (*walletv1.DepositResponse, error) {
+	amount := entity.NewMoney(req.GetAmount(), entity.Currency(req.GetCurrency()))
+
+	if err := h.walletSvc.Deposit(ctx, req.GetWalletId(), amount); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &walletv1.DepositResponse{}, nil
+}
+
+func (h *Handler) Withdraw(ctx context.Context, req *walletv1.WithdrawRequest) (*walletv1.WithdrawResponse, error) {
+	amount := entity.NewMoney(req.GetAmount(), entity.Currency(req.GetCurrency()))
+
+	if err := h.walletSvc.Withdraw(ctx, req.GetWalletId(), amount); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &walletv1.WithdrawResponse{}, nil
+}
+
+func (h *Handler) GetBalance(ctx context.Context, req *walletv1.GetBalanceRequest) (*walletv1.GetBalanceResponse, error) {
+	wallet, err := h.walletSvc.Wallet(ctx, req.GetWalletId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &walletv1.GetBalanceResponse{
+		WalletId:  wallet.ID,
+		Current:   wallet.Balance.Amount,
+		Available: wallet.Available().Amount,
+		Currency:  string(wallet.Currency()),
+	}, nil
+}
+
+func (h *Handler) ListTransactions(
+	ctx context.Context,
+	req *walletv1.ListTransactionsRequest,
+) (*walletv1.ListTransactionsResponse, error) {
+	query := entity.TransactionQuery{
+		OperationType: entity.OperationType(req.GetOperationType()),
+		Cursor:        req.GetPageToken(),
+		Limit:         int(req.GetPageSize()),
+	}
+	if req.GetFrom() != nil {
+		query.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		query.To = req.GetTo().AsTime()
+	}
+
+	page, err := h.walletSvc.Transactions(ctx, req.GetWalletId(), query)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &walletv1.ListTransactionsResponse{
+		Transactions:  make([]*walletv1.Transaction, 0, len(page.Transactions)),
+		NextPageToken: page.NextCursor,
+	}
+	for _, t := range page.Transactions {
+		resp.Transactions = append(resp.Transactions, &walletv1.Transaction{
+			Id:            t.ID,
+			Amount:        t.Amount,
+			OperationType: string(t.OperationType),
+			BalanceAfter:  t.BalanceAfter,
+			ReferenceId:   t.ReferenceID,
+			CreatedAt:     timestamp(t.CreatedAt),
+		})
+	}
+
+	return resp, nil
+}
+
+// statusError maps service errors to gRPC status errors.
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, svcErr.ErrInvalidParams):
+		return status.Error(codes.InvalidArgument, "invalid parameters provided")
+	case errors.Is(err, svcErr.ErrWalletNotFound):
+		return status.Error(codes.NotFound, "wallet not found")
+	case errors.Is(err, svcErr.ErrInsufficientFunds):
+		return status.Error(codes.FailedPrecondition, "insufficient funds")
+	case errors.Is(err, svcErr.ErrCurrencyMismatch):
+		return status.Error(codes.FailedPrecondition, "currency doesn't match the wallet currency")
+	case errors.Is(err, svcErr.ErrBalanceOverflow):
+		return status.Error(codes.FailedPrecondition, "balance would exceed the maximum amount")
+	case errors.Is(err, svcErr.ErrWalletFrozen):
+		return status.Error(codes.FailedPrecondition, "wallet is frozen")
+	case errors.Is(err, svcErr.ErrWalletClosed):
+		return status.Error(codes.FailedPrecondition, "wallet is closed")
+	case errors.Is(err, svcErr.ErrBusy):
+		return status.Error(codes.Unavailable, "too many concurrent requests for the wallet, retry later")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	healthSvc "github.com/passwordhash/asynchronous-wallet/internal/health"
+)
+
+type Prober interface {
+	Ready(ctx context.Context) healthSvc.Report
+}
+
+// Handler serves the liveness and readiness probes.
+type Handler struct {
+	prober Prober
+}
+
+func New(
+	prober Prober,
+) *Handler {
+	return &Handler{
+		prober: prober,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/livez", h.live)
+	r.GET("/readyz", h.ready)
+	// Kept for the probes configured before /livez existed.
+	r.GET("/health", h.live)
+}
+
+// live reports that the process serves requests. It doesn't check dependencies,
+// so an outage of the database doesn't get the service restarted.
+func (h *Handler) live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ready reports the result of every readiness check, with 503 if the service
+// must not get requests.
+func (h *Handler) ready(c *gin.Context) {
+	report := h.prober.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
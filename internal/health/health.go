@@ -0,0 +1,135 @@
+// Package health reports whether the service is ready to serve requests.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	postgresPkg "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
+)
+
+// Check returns an error if the dependency it checks can't be used.
+type Check func(ctx context.Context) error
+
+const (
+	StatusReady        = "ready"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+
+	CheckOK     = "ok"
+	CheckFailed = "failed"
+)
+
+// CheckResult is the result of one check.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the result of a readiness probe.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Ready reports whether the service is ready to serve requests.
+func (r Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// Health runs the readiness checks of the service.
+type Health struct {
+	timeout      time.Duration
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+// New returns health with no checks. Every check has timeout to complete.
+func New(timeout time.Duration) *Health {
+	return &Health{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// AddCheck adds the check under the name. It must not be called once probes are served.
+func (h *Health) AddCheck(name string, check Check) {
+	h.checks[name] = check
+}
+
+// Shutdown makes the service unready for good, so load balancers stop sending
+// requests to it while the servers drain.
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// Ready runs the checks concurrently. The service is ready if all of them pass
+// and it isn't shutting down.
+func (h *Health) Ready(ctx context.Context) Report {
+	if h.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := Report{
+		Status: StatusReady,
+		Checks: make(map[string]CheckResult, len(h.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range h.checks {
+		wg.Go(func() {
+			result := CheckResult{Status: CheckOK}
+			if err := check(ctx); err != nil {
+				result = CheckResult{Status: CheckFailed, Error: err.Error()}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Checks[name] = result
+			if result.Status != CheckOK {
+				report.Status = StatusNotReady
+			}
+		})
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Pinger is a database that can be pinged, e.g. a pgx pool.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Postgres checks that a connection to the database can be made.
+func Postgres(db Pinger) Check {
+	return func(ctx context.Context) error {
+		return db.Ping(ctx)
+	}
+}
+
+// Migrations checks that the latest migration applied to the database is want
+// and it didn't fail halfway.
+func Migrations(db postgresPkg.Queryer, want int64) Check {
+	return func(ctx context.Context) error {
+		version, dirty, err := postgresPkg.SchemaVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version != want {
+			return fmt.Errorf("schema version is %d, want %d", version, want)
+		}
+		return nil
+	}
+}
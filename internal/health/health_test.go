@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReady(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		wantStatus string
+		wantChecks map[string]CheckResult
+	}{
+		{
+			name:       "All checks pass",
+			checks:     map[string]Check{"postgres": ok, "migrations": ok},
+			wantStatus: StatusReady,
+			wantChecks: map[string]CheckResult{
+				"postgres":   {Status: CheckOK},
+				"migrations": {Status: CheckOK},
+			},
+		},
+		{
+			name: "Check fails",
+			checks: map[string]Check{
+				"postgres":   ok,
+				"migrations": func(context.Context) error { return errors.New("schema version is 13, want 14") },
+			},
+			wantStatus: StatusNotReady,
+			wantChecks: map[string]CheckResult{
+				"postgres":   {Status: CheckOK},
+				"migrations": {Status: CheckFailed, Error: "schema version is 13, want 14"},
+			},
+		},
+		{
+			name:       "Check times out",
+			checks:     map[string]Check{"postgres": slow},
+			wantStatus: StatusNotReady,
+			wantChecks: map[string]CheckResult{
+				"postgres": {Status: CheckFailed, Error: context.DeadlineExceeded.Error()},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(10 * time.Millisecond)
+			for name, check := range tt.checks {
+				h.AddCheck(name, check)
+			}
+
+			report := h.Ready(context.Background())
+
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Equal(t, tt.wantChecks, report.Checks)
+		})
+	}
+}
+
+func TestReady_ShuttingDown(t *testing.T) {
+	h := New(time.Second)
+	h.AddCheck("postgres", func(context.Context) error {
+		t.Error("check must not run once shutdown began")
+		return nil
+	})
+
+	h.Shutdown()
+	report := h.Ready(context.Background())
+
+	assert.Equal(t, StatusShuttingDown, report.Status)
+	assert.False(t, report.Ready())
+}
+
+func TestMigrations(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT version, dirty FROM schema_migrations LIMIT 1`)
+
+	tests := []struct {
+		name    string
+		version int64
+		dirty   bool
+		wantErr string
+	}{
+		{name: "Latest version", version: 14},
+		{name: "Older version", version: 13, wantErr: "schema version is 13, want 14"},
+		{name: "Dirty", version: 14, dirty: true, wantErr: "migration 14 is dirty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+			require.NoError(t, err)
+			defer mock.Close()
+
+			mock.ExpectQuery(query).WillReturnRows(
+				pgxmock.NewRows([]string{"version", "dirty"}).AddRow(tt.version, tt.dirty))
+
+			err = Migrations(mock, 14)(context.Background())
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/passwordhash/asynchronous-wallet/pkg/executor"
+)
+
+// executorCollector reports the statistics of the balance change executor on
+// every scrape, so full queues and rejected changes can be alerted on.
+type executorCollector struct {
+	executor *executor.Executor
+
+	queueDepth *prometheus.Desc
+	completed  *prometheus.Desc
+	rejected   *prometheus.Desc
+}
+
+// RegisterExecutor registers the statistics of the executor.
+func (m *Metrics) RegisterExecutor(e *executor.Executor) {
+	m.registry.MustRegister(newExecutorCollector(e))
+}
+
+func newExecutorCollector(e *executor.Executor) *executorCollector {
+	return &executorCollector{
+		executor: e,
+
+		queueDepth: prometheus.NewDesc(prometheus.BuildFQName(namespace, "executor", "queue_depth"),
+			"Number of balance changes waiting in the queue of the worker.", []string{"worker"}, nil),
+		completed: prometheus.NewDesc(prometheus.BuildFQName(namespace, "executor", "completed_total"),
+			"Number of balance changes the workers ran.", nil, nil),
+		rejected: prometheus.NewDesc(prometheus.BuildFQName(namespace, "executor", "rejected_total"),
+			"Number of balance changes rejected because the queue of their worker was full.", nil, nil),
+	}
+}
+
+func (c *executorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueDepth
+	ch <- c.completed
+	ch <- c.rejected
+}
+
+func (c *executorCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.executor.Stats()
+
+	for i, depth := range stats.QueueDepths {
+		ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(depth), strconv.Itoa(i))
+	}
+	ch <- prometheus.MustNewConstMetric(c.completed, prometheus.CounterValue, float64(stats.Completed))
+	ch <- prometheus.MustNewConstMetric(c.rejected, prometheus.CounterValue, float64(stats.Rejected))
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that didn't match any route, so unknown paths
+// don't create a series each.
+const unmatchedRoute = "unmatched"
+
+// HTTPMiddleware counts requests and observes their latency by the route
+// template, e.g. /api/v1/wallets/:id, rather than the requested path.
+func (m *Metrics) HTTPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		m.httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.httpRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,87 @@
+// Package metrics exposes Prometheus metrics of the service: HTTP requests,
+// balance operations, database transactions, the connection pool and the
+// balance change executor.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wallet"
+
+// Metrics holds the collectors of the service in its own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+
+	operations *prometheus.CounterVec
+
+	lockWait        *prometheus.HistogramVec
+	transactionTime *prometheus.HistogramVec
+}
+
+// New returns metrics registered in a new registry together with the Go runtime
+// and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests by route and status.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of HTTP requests by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "operations_total",
+			Help:      "Number of deposits and withdrawals by result: success or the error class.",
+		}, []string{"operation", "result"}),
+
+		lockWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "repository",
+			Name:      "lock_wait_seconds",
+			Help:      "Time spent locking the wallet row of a balance operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation"}),
+		transactionTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "repository",
+			Name:      "transaction_duration_seconds",
+			Help:      "Time of the database transaction of a balance operation, commit included.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.operations,
+		m.lockWait,
+		m.transactionTime,
+	)
+
+	return m
+}
+
+// Handler returns the handler serving the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	"github.com/passwordhash/asynchronous-wallet/pkg/executor"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := New()
+
+	router := gin.New()
+	router.Use(m.HTTPMiddleware())
+	router.GET("/wallets/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/wallets/1", "/wallets/2", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/wallets/:id", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpRequestDuration))
+}
+
+func TestOperationCompleted(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantResult string
+	}{
+		{
+			name:       "Success",
+			wantResult: "success",
+		},
+		{
+			name:       "Insufficient funds",
+			err:        svcErr.ErrInsufficientFunds,
+			wantResult: "insufficient_funds",
+		},
+		{
+			name:       "Frozen wallet",
+			err:        svcErr.ErrWalletFrozen,
+			wantResult: "wallet_frozen",
+		},
+		{
+			name:       "Closed wallet",
+			err:        fmt.Errorf("op: %w", svcErr.ErrWalletClosed),
+			wantResult: "wallet_closed",
+		},
+		{
+			name:       "Wrapped busy error",
+			err:        fmt.Errorf("op: %w", svcErr.ErrBusy),
+			wantResult: "busy",
+		},
+		{
+			name:       "Unknown error",
+			err:        assert.AnError,
+			wantResult: "internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+
+			m.OperationCompleted(entity.OperationWithdraw, tt.err)
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.operations.WithLabelValues("withdraw", tt.wantResult)))
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.OperationCompleted(entity.OperationDeposit, nil)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `wallet_operations_total{operation="deposit",result="success"} 1`)
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
+
+func TestRegisterExecutor(t *testing.T) {
+	exec := executor.New(1, 1)
+	t.Cleanup(exec.Stop)
+
+	m := New()
+	m.RegisterExecutor(exec)
+
+	// The first task keeps the worker busy, the second one fills its queue and the
+	// third one is rejected.
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_ = exec.Do(context.Background(), "key", func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	go func() {
+		_ = exec.Do(context.Background(), "key", func(context.Context) error { return nil })
+	}()
+	require.Eventually(t, func() bool { return exec.Stats().QueueDepths[0] == 1 }, time.Second, time.Millisecond)
+	require.ErrorIs(t, exec.Do(context.Background(), "key", func(context.Context) error { return nil }), executor.ErrQueueFull)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	close(release)
+
+	assert.Contains(t, rec.Body.String(), `wallet_executor_queue_depth{worker="0"} 1`)
+	assert.Contains(t, rec.Body.String(), `wallet_executor_rejected_total 1`)
+	assert.Contains(t, rec.Body.String(), `wallet_executor_completed_total 0`)
+}
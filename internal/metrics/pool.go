@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reports the statistics of a pgx connection pool on every scrape.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	totalConns       *prometheus.Desc
+	maxConns         *prometheus.Desc
+	acquireCount     *prometheus.Desc
+	waitCount        *prometheus.Desc
+	waitDuration     *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+}
+
+// RegisterPool registers the statistics of the pool.
+func (m *Metrics) RegisterPool(pool *pgxpool.Pool) {
+	m.registry.MustRegister(newPoolCollector(pool))
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		pool: pool,
+
+		acquiredConns: desc("acquired_connections", "Number of connections currently in use."),
+		idleConns:     desc("idle_connections", "Number of idle connections."),
+		totalConns:    desc("total_connections", "Number of open connections."),
+		maxConns:      desc("max_connections", "Maximum size of the pool."),
+		acquireCount:  desc("acquires_total", "Number of successful acquires."),
+		waitCount: desc("waits_total",
+			"Number of acquires that waited for a connection because the pool was empty."),
+		waitDuration: desc("wait_duration_seconds_total",
+			"Time acquires waited for a connection because the pool was empty."),
+		canceledAcquires: desc("canceled_acquires_total", "Number of acquires canceled by their context."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.canceledAcquires
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+)
+
+const resultSuccess = "success"
+
+// errorClasses maps service errors to the result label of failed operations.
+var errorClasses = []struct {
+	err   error
+	class string
+}{
+	{svcErr.ErrInvalidParams, "invalid_params"},
+	{svcErr.ErrWalletNotFound, "wallet_not_found"},
+	{svcErr.ErrInsufficientFunds, "insufficient_funds"},
+	{svcErr.ErrCurrencyMismatch, "currency_mismatch"},
+	{svcErr.ErrBalanceOverflow, "balance_overflow"},
+	{svcErr.ErrWalletFrozen, "wallet_frozen"},
+	{svcErr.ErrWalletClosed, "wallet_closed"},
+	{svcErr.ErrWalletStatusTransition, "wallet_status_conflict"},
+	{svcErr.ErrWalletNotEmpty, "wallet_not_empty"},
+	{svcErr.ErrIdempotencyKeyReused, "idempotency_conflict"},
+	{svcErr.ErrBusy, "busy"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "canceled"},
+}
+
+// OperationCompleted counts a deposit or withdrawal by its result.
+func (m *Metrics) OperationCompleted(operationType entity.OperationType, err error) {
+	m.operations.WithLabelValues(string(operationType), errorClass(err)).Inc()
+}
+
+// ObserveLockWait records how long the wallet row lock of an operation took.
+func (m *Metrics) ObserveLockWait(operationType entity.OperationType, d time.Duration) {
+	m.lockWait.WithLabelValues(string(operationType)).Observe(d.Seconds())
+}
+
+// ObserveTransaction records how long the database transaction of an operation took.
+func (m *Metrics) ObserveTransaction(operationType entity.OperationType, d time.Duration) {
+	m.transactionTime.WithLabelValues(string(operationType)).Observe(d.Seconds())
+}
+
+func errorClass(err error) string {
+	if err == nil {
+		return resultSuccess
+	}
+	for _, c := range errorClasses {
+		if errors.Is(err, c.err) {
+			return c.class
+		}
+	}
+	return "internal"
+}
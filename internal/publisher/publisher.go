@@ -0,0 +1,177 @@
+// Package publisher delivers wallet domain events from the outbox without a broker.
+// Delivery is at-least-once, so consumers should skip events whose id they have seen.
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+// envelope is the format events are written in.
+type envelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	WalletID  string          `json:"walletId"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func newEnvelope(event entity.Event) envelope {
+	return envelope{
+		ID:        event.ID,
+		Type:      string(event.Type),
+		WalletID:  event.WalletID,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	}
+}
+
+// Encode returns the JSON representation of the event that publishers write.
+func Encode(event entity.Event) ([]byte, error) {
+	data, err := json.Marshal(newEnvelope(event))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	return data, nil
+}
+
+// Publisher delivers a single event.
+type Publisher interface {
+	Publish(ctx context.Context, event entity.Event) error
+}
+
+// Multi publishes events to several publishers in order.
+type Multi struct {
+	publishers []Publisher
+}
+
+func NewMulti(publishers ...Publisher) *Multi {
+	return &Multi{
+		publishers: publishers,
+	}
+}
+
+// Publish publishes the event to every publisher. It stops at the first error,
+// so the event is published to all of them again on retry.
+func (p *Multi) Publish(ctx context.Context, event entity.Event) error {
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the publishers that need it.
+func (p *Multi) Close() error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		if closer, ok := publisher.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Log publishes events to a structured logger.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{
+		log: log,
+	}
+}
+
+// Publish logs the event at info level.
+func (p *Log) Publish(ctx context.Context, event entity.Event) error {
+	p.log.InfoContext(ctx, "wallet event",
+		slog.String("eventID", event.ID),
+		slog.String("type", string(event.Type)),
+		slog.String("walletID", event.WalletID),
+		slog.String("data", string(event.Payload)),
+	)
+
+	return nil
+}
+
+// Writer publishes events as JSON lines to an io.Writer, e.g. os.Stdout.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: w,
+	}
+}
+
+// Publish writes the event as a single JSON line.
+func (p *Writer) Publish(_ context.Context, event entity.Event) error {
+	line, err := Encode(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+
+	return nil
+}
+
+// File publishes events as JSON lines appended to a file. Every event is synced
+// to disk before Publish returns, so an event marked as sent is not lost on a crash.
+type File struct {
+	writer *Writer
+	f      *os.File
+}
+
+// NewFile opens the file for appending, creating it if needed.
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open events file: %w", err)
+	}
+
+	return &File{
+		writer: NewWriter(f),
+		f:      f,
+	}, nil
+}
+
+// Publish appends the event to the file and syncs it.
+func (p *File) Publish(ctx context.Context, event entity.Event) error {
+	if err := p.writer.Publish(ctx, event); err != nil {
+		return err
+	}
+
+	if err := p.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync events file: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the file.
+func (p *File) Close() error {
+	return p.f.Close()
+}
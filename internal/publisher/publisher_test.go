@@ -0,0 +1,80 @@
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+func TestFile_Publish(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	events := []entity.Event{
+		{ID: "event-1", Type: entity.EventWalletCredited, WalletID: "test-wallet-id", Payload: []byte(`{"amount":100}`), CreatedAt: createdAt},
+		{ID: "event-2", Type: entity.EventWalletDebited, WalletID: "test-wallet-id", Payload: []byte(`{"amount":50}`), CreatedAt: createdAt},
+	}
+
+	p, err := NewFile(path)
+	require.NoError(t, err, "expected no error")
+	for _, event := range events {
+		require.NoError(t, p.Publish(t.Context(), event), "expected no error")
+	}
+	require.NoError(t, p.Close(), "expected no error")
+
+	// Reopening appends to the file.
+	p, err = NewFile(path)
+	require.NoError(t, err, "expected no error")
+	require.NoError(t, p.Publish(t.Context(), events[0]), "expected no error")
+	require.NoError(t, p.Close(), "expected no error")
+
+	f, err := os.Open(path)
+	require.NoError(t, err, "expected no error")
+	defer f.Close()
+
+	var lines []envelope
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e envelope
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e), "expected a JSON line")
+		lines = append(lines, e)
+	}
+
+	require.Len(t, lines, 3, "expected every published event to be written")
+	require.Equal(t, "event-2", lines[1].ID, "expected event ID to match")
+	require.Equal(t, "WalletDebited", lines[1].Type, "expected event type to match")
+	require.Equal(t, createdAt, lines[1].CreatedAt, "expected creation time to match")
+	require.JSONEq(t, `{"amount":50}`, string(lines[1].Data), "expected event data to match")
+}
+
+type failingPublisher struct{ err error }
+
+func (p failingPublisher) Publish(context.Context, entity.Event) error { return p.err }
+
+func TestMulti_Publish(t *testing.T) {
+	t.Parallel()
+
+	publishErr := errors.New("publish error")
+	event := entity.Event{ID: "event-1", Type: entity.EventWalletCreated, WalletID: "test-wallet-id", Payload: []byte(`{}`)}
+
+	var first, last bytes.Buffer
+	p := NewMulti(NewWriter(&first), failingPublisher{err: publishErr}, NewWriter(&last))
+
+	err := p.Publish(t.Context(), event)
+
+	require.ErrorIs(t, err, publishErr, "expected error to match")
+	require.NotEmpty(t, first.String(), "expected the event to reach publishers before the failing one")
+	require.Empty(t, last.String(), "expected publishers after the failing one to be skipped")
+}
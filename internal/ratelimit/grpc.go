@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryInterceptor counts every gRPC call against the same buckets HTTPMiddleware
+// counts requests against: the bucket of its client and the bucket of the wallet
+// of the request, so throttled clients can't switch transports to get around the
+// limits. clientID returns the client of the call, e.g. key:<id>; calls without
+// one, such as health checks, aren't limited. It must run after the call was
+// authenticated. A call over any of the limits fails with ResourceExhausted and a
+// retry-after header with the seconds until the next call is allowed.
+func (l *RateLimiter) UnaryInterceptor(clientID func(ctx context.Context) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		client := clientID(ctx)
+		if l.store == nil || client == "" {
+			return handler(ctx, req)
+		}
+
+		keys := []bucketKey{{key: "client:" + client, limit: l.client}}
+		if r, ok := req.(interface{ GetWalletId() string }); ok {
+			for _, id := range canonicalWalletIDs([]string{r.GetWalletId()}) {
+				keys = append(keys, bucketKey{key: "wallet:" + id, limit: l.wallet})
+			}
+		}
+
+		res, err := l.takeAll(ctx, keys)
+		if err != nil {
+			// An outage of the store must not take the API down with it.
+			l.log.ErrorContext(ctx, "failed to check rate limit", "err", err)
+			return handler(ctx, req)
+		}
+
+		if !res.Allowed {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(ceilSeconds(res.RetryAfter))))
+			return nil, status.Error(codes.ResourceExhausted, "too many requests, retry later")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/auth"
+	"github.com/passwordhash/asynchronous-wallet/internal/handler/api/v1/response"
+)
+
+// walletRoute prefixes the routes with the wallet ID in the path.
+const walletRoute = "/api/v1/wallets/:id"
+
+// shownKey is the context key of the result whose headers are set on the response.
+const shownKey = "ratelimit.shown"
+
+// walletBody holds the wallet IDs of request bodies, e.g. of operations and transfers.
+type walletBody struct {
+	WalletID     string `json:"walletId"`
+	FromWalletID string `json:"fromWalletId"`
+	ToWalletID   string `json:"toWalletId"`
+}
+
+// IPMiddleware counts every request against the bucket of its source IP, the
+// client address gin resolves through the trusted proxies. It runs before the
+// request is authenticated, so requests with bad credentials are limited too and
+// can't be used to guess API keys or load the authenticator. A request over the
+// limit gets 429.
+func (l *RateLimiter) IPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.store == nil {
+			c.Next()
+			return
+		}
+
+		l.check(c, []bucketKey{{key: "ip:" + c.ClientIP(), limit: l.ip}})
+	}
+}
+
+// HTTPMiddleware counts every request against the bucket of its client and the
+// buckets of the wallets it is about: the wallet in the path of /wallets/:id routes
+// and the walletId, fromWalletId and toWalletId of the body. It must run after the
+// request was authenticated. A request over any of the limits gets 429.
+//
+// The headers of the bucket with the fewest requests remaining are set on every
+// response: RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset, the seconds
+// until the bucket is full again.
+func (l *RateLimiter) HTTPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.store == nil {
+			c.Next()
+			return
+		}
+
+		keys := []bucketKey{{key: "client:" + auth.ClientID(c), limit: l.client}}
+		for _, id := range walletIDs(c) {
+			keys = append(keys, bucketKey{key: "wallet:" + id, limit: l.wallet})
+		}
+
+		l.check(c, keys)
+	}
+}
+
+// check counts the request against the buckets and aborts it with 429 if it is
+// over any of the limits. Otherwise the headers of the bucket with the fewest
+// requests remaining are set, including the buckets of earlier checks.
+func (l *RateLimiter) check(c *gin.Context, keys []bucketKey) {
+	ctx := c.Request.Context()
+
+	res, err := l.takeAll(ctx, keys)
+	if err != nil {
+		// An outage of the store must not take the API down with it.
+		l.log.ErrorContext(ctx, "failed to check rate limit", "err", err)
+		c.Next()
+		return
+	}
+
+	if !res.Allowed {
+		setHeaders(c, res)
+		response.TooManyRequests(c, ceilSeconds(res.RetryAfter), "Too many requests, retry later")
+		c.Abort()
+		return
+	}
+	if prev, ok := c.Get(shownKey); ok && prev.(Result).Remaining < res.Remaining {
+		res = prev.(Result)
+	}
+
+	c.Set(shownKey, res)
+	setHeaders(c, res)
+	c.Next()
+}
+
+// walletIDs returns the IDs of the wallets the request is about. The body is
+// read and put back for the handler.
+func walletIDs(c *gin.Context) []string {
+	var ids []string
+	if strings.HasPrefix(c.FullPath(), walletRoute) {
+		ids = append(ids, c.Param("id"))
+	}
+
+	if c.Request.Method == http.MethodPost && c.ContentType() == gin.MIMEJSON && c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req walletBody
+		if err == nil && json.Unmarshal(body, &req) == nil {
+			ids = append(ids, req.WalletID, req.FromWalletID, req.ToWalletID)
+		}
+	}
+
+	return canonicalWalletIDs(ids)
+}
+
+func setHeaders(c *gin.Context, res Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
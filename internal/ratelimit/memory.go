@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore keeps the buckets in memory, so every instance limits on its own.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	tokens, res := take(limit.refill(b.tokens, now.Sub(b.updatedAt)), limit)
+	b.tokens, b.updatedAt = tokens, now
+
+	return res, nil
+}
+
+func (s *MemoryStore) Cleanup(_ context.Context, idle time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, b := range s.buckets {
+		if now.Sub(b.updatedAt) > idle {
+			delete(s.buckets, key)
+		}
+	}
+
+	return nil
+}
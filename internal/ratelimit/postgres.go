@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// PostgresStore keeps the buckets in the rate_limit_buckets table, so the limits
+// hold across instances.
+type PostgresStore struct {
+	db DB
+}
+
+func NewPostgresStore(db DB) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+	}
+}
+
+// Take refills and takes from the bucket in a single statement. The row lock
+// makes concurrent requests with the same key take their tokens one by one. A
+// new bucket is created full before, since FOR UPDATE can't lock a row that
+// doesn't exist yet and the first requests for a key would all be allowed.
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	const op = "ratelimit.PostgresStore.Take"
+
+	createQuery := `INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO NOTHING`
+
+	if _, err := s.db.Exec(ctx, createQuery, key, float64(limit.Burst)); err != nil {
+		return Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `WITH bucket AS (
+			SELECT LEAST($2::float8, tokens + EXTRACT(EPOCH FROM NOW() - updated_at) * $3::float8) AS tokens
+			FROM rate_limit_buckets WHERE key = $1 FOR UPDATE
+		), refilled AS (
+			SELECT COALESCE((SELECT tokens FROM bucket), $2::float8) AS tokens
+		)
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+		SELECT $1, CASE WHEN tokens >= 1 THEN tokens - 1 ELSE tokens END, NOW() FROM refilled
+		ON CONFLICT (key) DO UPDATE SET tokens = EXCLUDED.tokens, updated_at = EXCLUDED.updated_at
+		RETURNING (SELECT tokens FROM refilled)`
+
+	var tokens float64
+	if err := s.db.QueryRow(ctx, query, key, float64(limit.Burst), limit.Rate).Scan(&tokens); err != nil {
+		return Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, res := take(tokens, limit)
+
+	return res, nil
+}
+
+func (s *PostgresStore) Cleanup(ctx context.Context, idle time.Duration) error {
+	const op = "ratelimit.PostgresStore.Cleanup"
+
+	query := `DELETE FROM rate_limit_buckets WHERE updated_at < $1`
+
+	if _, err := s.db.Exec(ctx, query, time.Now().Add(-idle)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+// Package ratelimit limits the rate of API requests per source IP, per client and
+// per wallet with token buckets kept in memory or in PostgreSQL.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+)
+
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+	BackendNone     = "none"
+)
+
+// Limit is a token bucket holding up to Burst requests and refilled at Rate
+// requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// fillTime returns how long an empty bucket takes to fill up.
+func (l Limit) fillTime() time.Duration {
+	return seconds(float64(l.Burst) / l.Rate)
+}
+
+// refill returns the tokens of a bucket that had tokens elapsed ago.
+func (l Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	return math.Min(float64(l.Burst), tokens+elapsed.Seconds()*l.Rate)
+}
+
+// Result is the state of a bucket after a request was counted against it.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit int
+	// Remaining is how many more requests are allowed right now.
+	Remaining int
+	// RetryAfter is how long until the next request is allowed; zero if it is allowed now.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// take takes a token for a request from a bucket with tokens. It returns the
+// tokens left and the result of the request.
+func take(tokens float64, limit Limit) (float64, Result) {
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(tokens),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+
+	return tokens, res
+}
+
+// Store keeps the token buckets.
+type Store interface {
+	// Take counts a request against the bucket with the key.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Cleanup removes the buckets that haven't been used for idle.
+	Cleanup(ctx context.Context, idle time.Duration) error
+}
+
+// RateLimiter limits the requests from every source IP, of every client and for
+// every wallet.
+type RateLimiter struct {
+	log    *slog.Logger
+	store  Store
+	ip     Limit
+	client Limit
+	wallet Limit
+}
+
+// New returns a rate limiter keeping its buckets in the configured backend. db is
+// only used by the postgres backend. With the none backend nothing is limited.
+func New(log *slog.Logger, cfg config.RateLimitConfig, db DB) (*RateLimiter, error) {
+	const op = "ratelimit.New"
+
+	l := &RateLimiter{
+		log:    log,
+		ip:     Limit{Rate: cfg.IPRate, Burst: cfg.IPBurst},
+		client: Limit{Rate: cfg.ClientRate, Burst: cfg.ClientBurst},
+		wallet: Limit{Rate: cfg.WalletRate, Burst: cfg.WalletBurst},
+	}
+
+	switch cfg.Backend {
+	case BackendNone:
+		return l, nil
+	case BackendMemory:
+		l.store = NewMemoryStore()
+	case BackendPostgres:
+		l.store = NewPostgresStore(db)
+	default:
+		return nil, fmt.Errorf("%s: unknown backend %q", op, cfg.Backend)
+	}
+
+	for _, limit := range []Limit{l.ip, l.client, l.wallet} {
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			return nil, fmt.Errorf("%s: rates must be positive and bursts at least 1", op)
+		}
+	}
+
+	return l, nil
+}
+
+// Cleanup removes the buckets that are full again, which behave like missing ones.
+func (l *RateLimiter) Cleanup(ctx context.Context) error {
+	if l.store == nil {
+		return nil
+	}
+
+	return l.store.Cleanup(ctx, max(l.ip.fillTime(), l.client.fillTime(), l.wallet.fillTime()))
+}
+
+// bucketKey is a bucket a request is counted against.
+type bucketKey struct {
+	key   string
+	limit Limit
+}
+
+// takeAll counts a request against the buckets. It stops at the first bucket the
+// request is over the limit of and returns its result; otherwise it returns the
+// result of the bucket with the fewest requests remaining.
+func (l *RateLimiter) takeAll(ctx context.Context, keys []bucketKey) (Result, error) {
+	var shown Result
+	for i, k := range keys {
+		res, err := l.store.Take(ctx, k.key, k.limit)
+		if err != nil {
+			return Result{}, fmt.Errorf("bucket %s: %w", k.key, err)
+		}
+
+		if !res.Allowed {
+			return res, nil
+		}
+		if i == 0 || res.Remaining < shown.Remaining {
+			shown = res
+		}
+	}
+
+	return shown, nil
+}
+
+// canonicalWalletIDs returns the distinct canonical forms of the wallet IDs.
+// Requests with invalid IDs fail validation anyway; skipping them keeps made-up
+// IDs from creating buckets. Valid ones are canonicalized, so the spellings of a
+// wallet ID share its bucket.
+func canonicalWalletIDs(ids []string) []string {
+	canonical := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if parsed, err := uuid.Parse(id); err == nil {
+			canonical = append(canonical, parsed.String())
+		}
+	}
+	slices.Sort(canonical)
+
+	return slices.Compact(canonical)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,256 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+	walletv1 "github.com/passwordhash/asynchronous-wallet/pkg/api/wallet/v1"
+)
+
+const testWalletID = "123e4567-e89b-12d3-a456-426614174000"
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 2, Burst: 2}
+
+	res, err := store.Take(t.Context(), "key", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 500 * time.Millisecond}, res)
+
+	res, _ = store.Take(t.Context(), "key", limit)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Second}, res)
+
+	res, _ = store.Take(t.Context(), "key", limit)
+	assert.Equal(t, Result{Allowed: false, Limit: 2, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: time.Second}, res)
+
+	res, _ = store.Take(t.Context(), "other-key", limit)
+	assert.True(t, res.Allowed, "buckets must be independent")
+
+	now = now.Add(500 * time.Millisecond)
+	res, _ = store.Take(t.Context(), "key", limit)
+	assert.True(t, res.Allowed, "a token must be refilled")
+}
+
+func TestMemoryStore_Cleanup(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	_, _ = store.Take(t.Context(), "idle", Limit{Rate: 1, Burst: 1})
+	now = now.Add(2 * time.Second)
+	_, _ = store.Take(t.Context(), "active", Limit{Rate: 1, Burst: 1})
+
+	require.NoError(t, store.Cleanup(t.Context(), time.Second))
+
+	assert.NotContains(t, store.buckets, "idle")
+	assert.Contains(t, store.buckets, "active")
+}
+
+func TestPostgresStore_Take(t *testing.T) {
+	tests := []struct {
+		name     string
+		refilled float64
+		want     Result
+	}{
+		{
+			name:     "Allowed",
+			refilled: 2.5,
+			want:     Result{Allowed: true, Limit: 4, Remaining: 1, Reset: 2500 * time.Millisecond},
+		},
+		{
+			name:     "Denied",
+			refilled: 0.5,
+			want:     Result{Allowed: false, Limit: 4, Remaining: 0, RetryAfter: 500 * time.Millisecond, Reset: 3500 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+			require.NoError(t, err)
+			defer mock.Close()
+
+			mock.ExpectExec(`INSERT INTO rate_limit_buckets .* ON CONFLICT \(key\) DO NOTHING`).
+				WithArgs("key", 4.0).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			mock.ExpectQuery(`WITH bucket AS .* FOR UPDATE.*INSERT INTO rate_limit_buckets .* ON CONFLICT \(key\) DO UPDATE`).
+				WithArgs("key", 4.0, 1.0).
+				WillReturnRows(pgxmock.NewRows([]string{"tokens"}).AddRow(tt.refilled))
+
+			res, err := NewPostgresStore(mock).Take(t.Context(), "key", Limit{Rate: 1, Burst: 4})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), config.RateLimitConfig{
+		Backend:     BackendMemory,
+		IPRate:      1,
+		IPBurst:     10,
+		ClientRate:  1,
+		ClientBurst: 10,
+		WalletRate:  1,
+		WalletBurst: 1,
+	}, nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(limiter.HTTPMiddleware())
+	router.POST("/api/v1/wallet", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	router.GET("/api/v1/wallets/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet",
+			strings.NewReader(`{"walletId": "`+testWalletID+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), testWalletID, "the handler must get the body")
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"), "the wallet bucket has the fewest requests remaining")
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = post()
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"code":"RATE_LIMITED"`)
+
+	// The wallet in the path shares the bucket of the wallet in the body.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+testWalletID, nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// So does another spelling of the same wallet ID.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+strings.ToUpper(testWalletID), nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/urn:uuid:"+testWalletID, nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func TestIPMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), config.RateLimitConfig{
+		Backend:     BackendMemory,
+		IPRate:      1,
+		IPBurst:     1,
+		ClientRate:  1,
+		ClientBurst: 10,
+		WalletRate:  1,
+		WalletBurst: 10,
+	}, nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	// Stands in for the authentication, which rejects every request.
+	unauthorized := func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+	router.GET("/api/v1/wallets/:id", limiter.IPMiddleware(), unauthorized)
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+testWalletID, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("192.0.2.1:1234")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = get("192.0.2.1:5678")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "failed authentications must be limited")
+
+	rec = get("192.0.2.2:1234")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "other addresses have their own bucket")
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), config.RateLimitConfig{
+		Backend:     BackendMemory,
+		IPRate:      1,
+		IPBurst:     10,
+		ClientRate:  1,
+		ClientBurst: 10,
+		WalletRate:  1,
+		WalletBurst: 1,
+	}, nil)
+	require.NoError(t, err)
+
+	clientID := func(ctx context.Context) string {
+		client, _ := ctx.Value(clientContextKey{}).(string)
+		return client
+	}
+	interceptor := limiter.UnaryInterceptor(clientID)
+	handler := func(context.Context, any) (any, error) {
+		return "ok", nil
+	}
+	call := func(client, walletID string) error {
+		ctx := context.WithValue(t.Context(), clientContextKey{}, client)
+		_, err := interceptor(ctx, &walletv1.DepositRequest{WalletId: walletID}, &grpc.UnaryServerInfo{}, handler)
+		return err
+	}
+
+	require.NoError(t, call("key:a", testWalletID))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("key:b", strings.ToUpper(testWalletID))),
+		"another spelling of the wallet ID shares its bucket")
+	assert.NoError(t, call("", testWalletID), "calls without a client aren't limited")
+
+	// The wallet bucket is shared with the HTTP API.
+	router := gin.New()
+	router.GET("/api/v1/wallets/:id", limiter.HTTPMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+testWalletID, nil))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+// clientContextKey is the context key of the client in the interceptor test.
+type clientContextKey struct{}
+
+func TestNew_None(t *testing.T) {
+	limiter, err := New(slog.New(slog.NewTextHandler(io.Discard, nil)), config.RateLimitConfig{Backend: BackendNone}, nil)
+	require.NoError(t, err)
+
+	assert.NoError(t, limiter.Cleanup(t.Context()))
+}
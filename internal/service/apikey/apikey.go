@@ -0,0 +1,257 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+//go:generate mockgen -destination=./mocks/mock_repository.go -package=mocks github.com/passwordhash/asynchronous-wallet/internal/service/apikey Repository
+type Repository interface {
+	Create(ctx context.Context, key entity.APIKey) (*entity.APIKey, error)
+	GetByHash(ctx context.Context, hash []byte) (*entity.APIKey, error)
+	Rotate(ctx context.Context, keyID string, hash []byte) (*entity.APIKey, error)
+	Revoke(ctx context.Context, keyID string) error
+}
+
+// Auditor appends the records of completed changes to the audit log.
+type Auditor interface {
+	Record(ctx context.Context, record entity.AuditRecord)
+}
+
+const (
+	// keyPrefix marks the keys of the service, so leaked ones are easy to spot.
+	keyPrefix = "wlt_"
+	// keySize is the number of random bytes in a generated key.
+	keySize = 32
+)
+
+type Service struct {
+	log  *slog.Logger
+	repo Repository
+	// auditor is nil if key changes aren't audited.
+	auditor Auditor
+}
+
+type Option func(*Service)
+
+// WithAuditor records the creation, rotation and revocation of keys in the audit
+// log of the auditor.
+func WithAuditor(auditor Auditor) Option {
+	return func(s *Service) {
+		s.auditor = auditor
+	}
+}
+
+func New(
+	log *slog.Logger,
+	repo Repository,
+	opts ...Option,
+) *Service {
+	s := &Service{
+		log:  log,
+		repo: repo,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Create creates an API key with the name, permission and wallet IDs of params.
+// It returns the key itself, which isn't stored and can't be shown again.
+func (s *Service) Create(ctx context.Context, params entity.APIKey) (*entity.APIKey, string, error) {
+	const op = "service.apikey.Create"
+
+	log := s.log.With(
+		"op", op,
+		"name", params.Name,
+		"permission", params.Permission,
+	)
+
+	if err := validateKey(params); err != nil {
+		log.Warn("invalid parameters", "err", err)
+
+		return nil, "", svcErr.ErrInvalidParams
+	}
+
+	plain, hash := newKey()
+
+	// Stored in the canonical form the wallet service uses.
+	walletIDs := make([]string, 0, len(params.WalletIDs))
+	for _, id := range params.WalletIDs {
+		walletIDs = append(walletIDs, uuid.MustParse(id).String())
+	}
+
+	key, err := s.repo.Create(ctx, entity.APIKey{
+		Name:       params.Name,
+		Hash:       hash,
+		Permission: params.Permission,
+		WalletIDs:  walletIDs,
+	})
+	if err != nil {
+		log.Error("failed to create api key", "err", err)
+
+		return nil, "", err
+	}
+
+	s.audit(ctx, entity.AuditAPIKeyCreate, key.ID)
+
+	log.Info("api key created", "keyID", key.ID)
+
+	return key, plain, nil
+}
+
+// Rotate replaces the key with a new one, keeping its name and scope. The old key
+// stops working right away. It returns the new key, which can't be shown again.
+func (s *Service) Rotate(ctx context.Context, keyID string) (*entity.APIKey, string, error) {
+	const op = "service.apikey.Rotate"
+
+	log := s.log.With(
+		"op", op,
+		"keyID", keyID,
+	)
+
+	if uuid.Validate(keyID) != nil {
+		log.Warn("invalid api key ID format")
+
+		return nil, "", svcErr.ErrInvalidParams
+	}
+
+	plain, hash := newKey()
+
+	key, err := s.repo.Rotate(ctx, keyID, hash)
+	if err != nil {
+		return nil, "", keyError(log, err, "failed to rotate api key")
+	}
+
+	s.audit(ctx, entity.AuditAPIKeyRotate, keyID)
+
+	log.Info("api key rotated")
+
+	return key, plain, nil
+}
+
+// Revoke revokes the key for good.
+func (s *Service) Revoke(ctx context.Context, keyID string) error {
+	const op = "service.apikey.Revoke"
+
+	log := s.log.With(
+		"op", op,
+		"keyID", keyID,
+	)
+
+	if uuid.Validate(keyID) != nil {
+		log.Warn("invalid api key ID format")
+
+		return svcErr.ErrInvalidParams
+	}
+
+	if err := s.repo.Revoke(ctx, keyID); err != nil {
+		return keyError(log, err, "failed to revoke api key")
+	}
+
+	s.audit(ctx, entity.AuditAPIKeyRevoke, keyID)
+
+	log.Info("api key revoked")
+
+	return nil
+}
+
+// Authenticate returns the API key a request was made with. If the key doesn't
+// exist or is revoked, it returns [svcErr.ErrInvalidAPIKey].
+func (s *Service) Authenticate(ctx context.Context, plain string) (*entity.APIKey, error) {
+	const op = "service.apikey.Authenticate"
+
+	log := s.log.With("op", op)
+
+	// Keys that can't have been issued by the service don't need a lookup.
+	if !strings.HasPrefix(plain, keyPrefix) {
+		log.WarnContext(ctx, "malformed api key")
+
+		return nil, svcErr.ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetByHash(ctx, hashKey(plain))
+	if errors.Is(err, repoErr.ErrAPIKeyNotFound) {
+		log.WarnContext(ctx, "unknown or revoked api key")
+
+		return nil, svcErr.ErrInvalidAPIKey
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to get api key", "err", err)
+
+		return nil, err
+	}
+
+	log.DebugContext(ctx, "api key authenticated", "keyID", key.ID)
+
+	return key, nil
+}
+
+// audit records the action on the key, if the service has an auditor.
+func (s *Service) audit(ctx context.Context, action entity.AuditAction, keyID string) {
+	if s.auditor == nil {
+		return
+	}
+	s.auditor.Record(ctx, entity.AuditRecord{
+		Action:     action,
+		ResourceID: keyID,
+	})
+}
+
+// keyError maps repository errors of API key operations to service errors.
+func keyError(log *slog.Logger, err error, msg string) error {
+	if errors.Is(err, repoErr.ErrAPIKeyNotFound) {
+		log.Warn("api key not found", "err", err)
+		return svcErr.ErrAPIKeyNotFound
+	}
+
+	log.Error(msg, "err", err)
+	return err
+}
+
+func validateKey(key entity.APIKey) error {
+	if strings.TrimSpace(key.Name) == "" {
+		return errors.New("name is required")
+	}
+	if !key.Permission.IsValid() {
+		return errors.New("unknown permission " + string(key.Permission))
+	}
+	for _, id := range key.WalletIDs {
+		if err := uuid.Validate(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newKey returns a random key and its hash.
+func newKey() (string, []byte) {
+	b := make([]byte, keySize)
+	_, _ = rand.Read(b)
+
+	plain := keyPrefix + hex.EncodeToString(b)
+
+	return plain, hashKey(plain)
+}
+
+// hashKey returns the SHA-256 hash of the key. A plain hash is enough, since the
+// keys are random rather than chosen by people.
+func hashKey(plain string) []byte {
+	sum := sha256.Sum256([]byte(plain))
+	return sum[:]
+}
@@ -0,0 +1,249 @@
+package apikey_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/apikey"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/apikey/mocks"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	testKeyID    = "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	testWalletID = "22222222-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+)
+
+func setupTest(t *testing.T) (*apikey.Service, *mocks.MockRepository) {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockRepository(ctrl)
+
+	service := apikey.New(log, mockRepo)
+
+	return service, mockRepo
+}
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		params        entity.APIKey
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name: "Ok",
+			params: entity.APIKey{
+				Name:       "billing",
+				Permission: entity.APIKeyReadWrite,
+				WalletIDs:  []string{testWalletID},
+			},
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, k entity.APIKey) (*entity.APIKey, error) {
+						assert.Len(t, k.Hash, sha256.Size, "expected the key to be stored hashed")
+						assert.Equal(t, []string{testWalletID}, k.WalletIDs)
+						k.ID = testKeyID
+						return &k, nil
+					})
+			},
+		},
+		{
+			name: "Wallet ID in another spelling",
+			params: entity.APIKey{
+				Name:       "billing",
+				Permission: entity.APIKeyRead,
+				WalletIDs:  []string{"{" + strings.ToUpper(testWalletID) + "}"},
+			},
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, k entity.APIKey) (*entity.APIKey, error) {
+						assert.Equal(t, []string{testWalletID}, k.WalletIDs, "expected the canonical wallet ID")
+						k.ID = testKeyID
+						return &k, nil
+					})
+			},
+		},
+		{
+			name:          "Missing name",
+			params:        entity.APIKey{Name: " ", Permission: entity.APIKeyRead},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Unknown permission",
+			params:        entity.APIKey{Name: "billing", Permission: "admin"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name: "Invalid wallet ID",
+			params: entity.APIKey{
+				Name:       "billing",
+				Permission: entity.APIKeyRead,
+				WalletIDs:  []string{"wallet-id"},
+			},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			_, plain, err := service.Create(t.Context(), tt.params)
+
+			require.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError == nil {
+				assert.True(t, strings.HasPrefix(plain, "wlt_"), "expected the key to be returned")
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		keyID         string
+		repoErr       error
+		expectedError error
+	}{
+		{name: "Ok", keyID: testKeyID},
+		{name: "Not found", keyID: testKeyID, repoErr: repoErr.ErrAPIKeyNotFound, expectedError: svcErr.ErrAPIKeyNotFound},
+		{name: "Invalid ID", keyID: "key-id", expectedError: svcErr.ErrInvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			if tt.expectedError != svcErr.ErrInvalidParams {
+				mockRepo.EXPECT().Rotate(gomock.Any(), tt.keyID, gomock.Len(sha256.Size)).
+					Return(&entity.APIKey{ID: tt.keyID}, tt.repoErr)
+			}
+
+			_, _, err := service.Rotate(t.Context(), tt.keyID)
+
+			require.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	t.Parallel()
+
+	service, mockRepo := setupTest(t)
+
+	mockRepo.EXPECT().Revoke(gomock.Any(), testKeyID).Return(repoErr.ErrAPIKeyNotFound)
+
+	err := service.Revoke(t.Context(), testKeyID)
+
+	require.ErrorIs(t, err, svcErr.ErrAPIKeyNotFound)
+}
+
+// auditRecorder records the records passed to Auditor.Record.
+type auditRecorder struct {
+	records []entity.AuditRecord
+}
+
+func (r *auditRecorder) Record(_ context.Context, record entity.AuditRecord) {
+	r.records = append(r.records, record)
+}
+
+func TestRevoke_RecordsAudit(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+	recorder := &auditRecorder{}
+	service := apikey.New(log, mockRepo, apikey.WithAuditor(recorder))
+
+	mockRepo.EXPECT().Revoke(gomock.Any(), testKeyID).Return(nil)
+	mockRepo.EXPECT().Revoke(gomock.Any(), testKeyID).Return(repoErr.ErrAPIKeyNotFound)
+
+	require.NoError(t, service.Revoke(t.Context(), testKeyID))
+	require.ErrorIs(t, service.Revoke(t.Context(), testKeyID), svcErr.ErrAPIKeyNotFound)
+
+	require.Equal(t, []entity.AuditRecord{
+		{Action: entity.AuditAPIKeyRevoke, ResourceID: testKeyID},
+	}, recorder.records, "expected only the completed revocation to be recorded")
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	const plain = "wlt_0123456789abcdef"
+	hash := sha256.Sum256([]byte(plain))
+
+	tests := []struct {
+		name          string
+		plain         string
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name:  "Ok",
+			plain: plain,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByHash(gomock.Any(), hash[:]).
+					Return(&entity.APIKey{ID: testKeyID}, nil)
+			},
+		},
+		{
+			name:  "Unknown or revoked key",
+			plain: plain,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByHash(gomock.Any(), hash[:]).
+					Return(nil, repoErr.ErrAPIKeyNotFound)
+			},
+			expectedError: svcErr.ErrInvalidAPIKey,
+		},
+		{
+			name:          "Malformed key",
+			plain:         "0123456789abcdef",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			key, err := service.Authenticate(t.Context(), tt.plain)
+
+			require.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError == nil {
+				assert.Equal(t, testKeyID, key.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+//go:generate mockgen -destination=./mocks/mock_repository.go -package=mocks github.com/passwordhash/asynchronous-wallet/internal/service/audit Repository
+type Repository interface {
+	Stage(ctx context.Context, record entity.AuditRecord) error
+	Sequence(ctx context.Context, limit int) (int, error)
+	Records(ctx context.Context, afterID int64, limit int) ([]entity.AuditRecord, error)
+	Head(ctx context.Context) ([]byte, error)
+}
+
+const (
+	// verifyBatchSize is the number of records read at a time while verifying the chain.
+	verifyBatchSize = 1000
+	// sequenceBatchSize is the number of staged records chained in one transaction.
+	sequenceBatchSize = 1000
+)
+
+type Service struct {
+	log  *slog.Logger
+	repo Repository
+}
+
+func New(
+	log *slog.Logger,
+	repo Repository,
+) *Service {
+	return &Service{
+		log:  log,
+		repo: repo,
+	}
+}
+
+// Stamp returns the record with the actor, source IP and request ID of ctx. Changes
+// made outside of a request are recorded as made by [entity.AuditActorSystem].
+func (s *Service) Stamp(ctx context.Context, record entity.AuditRecord) entity.AuditRecord {
+	record.Actor = entity.AuditActorSystem
+	if actor, ok := actorFrom(ctx); ok {
+		record.Actor = actor.ID
+		record.SourceIP = actor.SourceIP
+	}
+	record.RequestID = RequestID(ctx)
+
+	return record
+}
+
+// Record stages the record of a completed change, stamped with ctx, to be chained
+// into the audit log by [Service.Sequence]. It is for changes that aren't staged in
+// their own transaction; the change is already done, so a record that can't be
+// staged is only logged.
+func (s *Service) Record(ctx context.Context, record entity.AuditRecord) {
+	const op = "service.audit.Record"
+
+	record = s.Stamp(ctx, record)
+
+	log := s.log.With(
+		"op", op,
+		"action", record.Action,
+		"actor", record.Actor,
+		"requestID", record.RequestID,
+		"walletID", record.WalletID,
+		"resourceID", record.ResourceID,
+	)
+
+	// The change was made for the caller, so a canceled request must not skip its record.
+	if err := s.repo.Stage(context.WithoutCancel(ctx), record); err != nil {
+		log.ErrorContext(ctx, "failed to stage audit record", "err", err)
+		return
+	}
+
+	log.DebugContext(ctx, "audit record staged")
+}
+
+// Sequence chains the staged records into the audit log in the order they were
+// staged, until none are left.
+func (s *Service) Sequence(ctx context.Context) error {
+	const op = "service.audit.Sequence"
+
+	log := s.log.With("op", op)
+
+	for {
+		count, err := s.repo.Sequence(ctx, sequenceBatchSize)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to sequence audit records", "err", err)
+
+			return err
+		}
+		if count > 0 {
+			log.DebugContext(ctx, "audit records sequenced", "count", count)
+		}
+
+		if count < sequenceBatchSize {
+			return nil
+		}
+	}
+}
+
+// Verify walks the audit log from the first record and reports every record that
+// breaks the hash chain. The chain must also reach the hash kept in audit_head,
+// otherwise records were removed from its end. The head is read before the walk,
+// so records appended meanwhile don't count as a mismatch.
+func (s *Service) Verify(ctx context.Context) (*entity.AuditVerification, error) {
+	const op = "service.audit.Verify"
+
+	log := s.log.With("op", op)
+
+	head, err := s.repo.Head(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to read audit head", "err", err)
+
+		return nil, err
+	}
+
+	verification := entity.AuditVerification{Head: head}
+	headReached := len(head) == 0
+	for {
+		records, err := s.repo.Records(ctx, verification.LastID, verifyBatchSize)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to read audit records", "err", err, "afterID", verification.LastID)
+
+			return nil, err
+		}
+
+		for _, record := range records {
+			verification.VerifyNext(record)
+			headReached = headReached || bytes.Equal(record.Hash, head)
+		}
+
+		if len(records) < verifyBatchSize {
+			break
+		}
+	}
+
+	if !headReached {
+		verification.Breaks = append(verification.Breaks, entity.AuditBreak{
+			RecordID: verification.LastID,
+			Reason:   "no record has the hash of audit_head, records after it were removed",
+		})
+	}
+
+	if !verification.Intact() {
+		log.WarnContext(ctx, "audit chain is broken", "breaks", len(verification.Breaks))
+	} else {
+		log.InfoContext(ctx, "audit chain verified", "records", verification.Records)
+	}
+
+	return &verification, nil
+}
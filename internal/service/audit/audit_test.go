@@ -0,0 +1,261 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/audit"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/audit/mocks"
+)
+
+const testWalletID = "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+
+func setupTest(t *testing.T) (*audit.Service, *mocks.MockRepository) {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockRepository(ctrl)
+
+	service := audit.New(log, mockRepo)
+
+	return service, mockRepo
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		ctx      func() context.Context
+		expected entity.AuditRecord
+	}{
+		{
+			name: "WithActor",
+			ctx: func() context.Context {
+				ctx := audit.WithActor(context.Background(), audit.Actor{ID: "key:test-key-id", SourceIP: "192.0.2.1"})
+				return audit.WithRequestID(ctx, "test-request-id")
+			},
+			expected: entity.AuditRecord{
+				Action:    entity.AuditWalletCreate,
+				Actor:     "key:test-key-id",
+				SourceIP:  "192.0.2.1",
+				RequestID: "test-request-id",
+				WalletID:  testWalletID,
+			},
+		},
+		{
+			name: "WithoutActor",
+			ctx:  context.Background,
+			expected: entity.AuditRecord{
+				Action:   entity.AuditWalletCreate,
+				Actor:    entity.AuditActorSystem,
+				WalletID: testWalletID,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			mockRepo.EXPECT().Stage(gomock.Any(), tt.expected).Return(nil)
+
+			service.Record(tt.ctx(), entity.AuditRecord{Action: entity.AuditWalletCreate, WalletID: testWalletID})
+		})
+	}
+}
+
+func TestRecord_Canceled(t *testing.T) {
+	t.Parallel()
+
+	service, mockRepo := setupTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockRepo.EXPECT().Stage(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ entity.AuditRecord) error {
+			assert.NoError(t, ctx.Err(), "expected the record to be staged after the request is canceled")
+			return nil
+		})
+
+	service.Record(ctx, entity.AuditRecord{Action: entity.AuditWalletCreate})
+}
+
+func TestSequence(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError bool
+	}{
+		{
+			name: "UntilNoneLeft",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				gomock.InOrder(
+					mock.EXPECT().Sequence(gomock.Any(), 1000).Return(1000, nil),
+					mock.EXPECT().Sequence(gomock.Any(), 1000).Return(3, nil),
+				)
+			},
+		},
+		{
+			name: "NothingStaged",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Sequence(gomock.Any(), 1000).Return(0, nil)
+			},
+		},
+		{
+			name: "RepositoryError",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Sequence(gomock.Any(), 1000).Return(0, errors.New("db error"))
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			err := service.Sequence(context.Background())
+
+			if tt.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	first := entity.AuditRecord{ID: 1, Action: entity.AuditWalletCreate, Actor: "key:test-key-id", PrevHash: []byte{}}
+	first.Hash = first.ComputeHash()
+	second := entity.AuditRecord{ID: 2, Action: entity.AuditAPIKeyRevoke, Actor: "cli:admin", PrevHash: first.Hash}
+	second.Hash = second.ComputeHash()
+
+	tampered := second
+	tampered.Actor = "cli:someone-else"
+
+	tests := []struct {
+		name          string
+		mockBehavior  func(mock *mocks.MockRepository)
+		expected      *entity.AuditVerification
+		expectedError bool
+	}{
+		{
+			name: "Intact",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Head(gomock.Any()).Return(second.Hash, nil)
+				mock.EXPECT().Records(gomock.Any(), int64(0), gomock.Any()).
+					Return([]entity.AuditRecord{first, second}, nil)
+			},
+			expected: &entity.AuditVerification{Records: 2, LastID: 2, LastHash: second.Hash, Head: second.Hash},
+		},
+		{
+			name: "Appended during the walk",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Head(gomock.Any()).Return(first.Hash, nil)
+				mock.EXPECT().Records(gomock.Any(), int64(0), gomock.Any()).
+					Return([]entity.AuditRecord{first, second}, nil)
+			},
+			expected: &entity.AuditVerification{Records: 2, LastID: 2, LastHash: second.Hash, Head: first.Hash},
+		},
+		{
+			name: "Tampered",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Head(gomock.Any()).Return(second.Hash, nil)
+				mock.EXPECT().Records(gomock.Any(), int64(0), gomock.Any()).
+					Return([]entity.AuditRecord{first, tampered}, nil)
+			},
+			expected: &entity.AuditVerification{
+				Records:  2,
+				LastID:   2,
+				LastHash: second.Hash,
+				Head:     second.Hash,
+				Breaks: []entity.AuditBreak{
+					{RecordID: 2, Reason: "hash doesn't match, the record was modified"},
+				},
+			},
+		},
+		{
+			name: "Truncated",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Head(gomock.Any()).Return(second.Hash, nil)
+				mock.EXPECT().Records(gomock.Any(), int64(0), gomock.Any()).
+					Return([]entity.AuditRecord{first}, nil)
+			},
+			expected: &entity.AuditVerification{
+				Records:  1,
+				LastID:   1,
+				LastHash: first.Hash,
+				Head:     second.Hash,
+				Breaks: []entity.AuditBreak{
+					{RecordID: 1, Reason: "no record has the hash of audit_head, records after it were removed"},
+				},
+			},
+		},
+		{
+			name: "Empty",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Head(gomock.Any()).Return([]byte{}, nil)
+				mock.EXPECT().Records(gomock.Any(), int64(0), gomock.Any()).Return(nil, nil)
+			},
+			expected: &entity.AuditVerification{Head: []byte{}},
+		},
+		{
+			name: "HeadError",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Head(gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			expectedError: true,
+		},
+		{
+			name: "RepositoryError",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Head(gomock.Any()).Return(second.Hash, nil)
+				mock.EXPECT().Records(gomock.Any(), int64(0), gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			verification, err := service.Verify(context.Background())
+
+			if tt.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, verification)
+		})
+	}
+}
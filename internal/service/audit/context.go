@@ -0,0 +1,43 @@
+package audit
+
+import "context"
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIDKey
+)
+
+// MaxRequestIDLength is the longest request ID a client may set; longer ones are
+// replaced with a generated ID.
+const MaxRequestIDLength = 128
+
+// Actor is who makes the changes in a context: the API key, end user or tool,
+// e.g. key:<id>, and the IP address the request came from, if any.
+type Actor struct {
+	ID       string
+	SourceIP string
+}
+
+// WithActor returns a copy of ctx whose changes are recorded as made by the actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// WithRequestID returns a copy of ctx whose changes are recorded with the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID of ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// actorFrom returns the actor of ctx, if any.
+func actorFrom(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey).(Actor)
+	return actor, ok
+}
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/passwordhash/asynchronous-wallet/internal/service/audit (interfaces: Repository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=./mocks/mock_repository.go -package=mocks github.com/passwordhash/asynchronous-wallet/internal/service/audit Repository
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/passwordhash/asynchronous-wallet/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Head mocks base method.
+func (m *MockRepository) Head(ctx context.Context) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Head", ctx)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Head indicates an expected call of Head.
+func (mr *MockRepositoryMockRecorder) Head(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Head", reflect.TypeOf((*MockRepository)(nil).Head), ctx)
+}
+
+// Records mocks base method.
+func (m *MockRepository) Records(ctx context.Context, afterID int64, limit int) ([]entity.AuditRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Records", ctx, afterID, limit)
+	ret0, _ := ret[0].([]entity.AuditRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Records indicates an expected call of Records.
+func (mr *MockRepositoryMockRecorder) Records(ctx, afterID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Records", reflect.TypeOf((*MockRepository)(nil).Records), ctx, afterID, limit)
+}
+
+// Sequence mocks base method.
+func (m *MockRepository) Sequence(ctx context.Context, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sequence", ctx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sequence indicates an expected call of Sequence.
+func (mr *MockRepositoryMockRecorder) Sequence(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sequence", reflect.TypeOf((*MockRepository)(nil).Sequence), ctx, limit)
+}
+
+// Stage mocks base method.
+func (m *MockRepository) Stage(ctx context.Context, record entity.AuditRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stage", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stage indicates an expected call of Stage.
+func (mr *MockRepositoryMockRecorder) Stage(ctx, record any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stage", reflect.TypeOf((*MockRepository)(nil).Stage), ctx, record)
+}
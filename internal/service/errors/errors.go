@@ -3,7 +3,39 @@ package svcErr
 import "errors"
 
 var (
-	ErrWalletNotFound = errors.New("wallet not found")
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrWalletAlreadyExists = errors.New("wallet already exists")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrCurrencyMismatch    = errors.New("currency doesn't match the wallet currency")
+	ErrBalanceOverflow     = errors.New("balance overflow")
+
+	ErrWalletFrozen           = errors.New("wallet is frozen")
+	ErrWalletClosed           = errors.New("wallet is closed")
+	ErrWalletStatusTransition = errors.New("wallet can't change to the status")
+	ErrWalletNotEmpty         = errors.New("wallet balance or holds are not zero")
 
 	ErrInvalidParams = errors.New("invalid parameters provided")
+	ErrBusy          = errors.New("too many concurrent requests for the wallet")
+
+	ErrTransactionNotFound    = errors.New("transaction not found")
+	ErrNotReversible          = errors.New("transaction can't be reversed")
+	ErrReversalAmountExceeded = errors.New("amount exceeds the amount left to reverse")
+
+	ErrOperationNotFound = errors.New("operation not found")
+
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrWebhookDeliveryNotDead  = errors.New("webhook delivery is not dead")
+
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is not active")
+	ErrHoldAmountExceeded = errors.New("amount exceeds the hold amount")
+
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was used with a different request")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was used by a concurrent request")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidAPIKey is returned for a key that doesn't exist or is revoked.
+	ErrInvalidAPIKey = errors.New("invalid api key")
 )
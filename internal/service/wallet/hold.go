@@ -0,0 +1,230 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/pkg/executor"
+)
+
+// expiredHoldsBatchSize is the maximum number of holds released by one sweep.
+const expiredHoldsBatchSize = 100
+
+// CreateHold reserves funds of the wallet for ttl. If ttl is zero, the default TTL is used.
+// The hold reduces the available balance of the wallet until it is captured, released
+// or expires.
+func (s *Service) CreateHold(
+	ctx context.Context,
+	walletID string,
+	amount entity.Money,
+	ttl time.Duration,
+) (*entity.Hold, error) {
+	const op = "service.wallet.CreateHold"
+
+	log := s.log.With(
+		"op", op,
+		"walletID", walletID,
+		"amount", amount.Amount,
+		"currency", amount.Currency,
+		"ttl", ttl,
+	)
+
+	if ttl == 0 {
+		ttl = s.defaultHoldTTL
+	}
+
+	walletID, err := validate(walletID, amount)
+	if err != nil {
+		log.Error("invalid parameters", "err", err)
+
+		return nil, svcErr.ErrInvalidParams
+	}
+	if ttl < 0 || ttl > s.maxHoldTTL {
+		log.Warn("invalid hold ttl")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	var hold *entity.Hold
+	err = s.serialize(ctx, walletID, func(ctx context.Context) error {
+		var err error
+		hold, err = s.repo.CreateHold(ctx, entity.Hold{
+			WalletID:  walletID,
+			Amount:    amount,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, holdError(log, err, "failed to create hold")
+	}
+
+	log.Info("hold created", "holdID", hold.ID)
+
+	return hold, nil
+}
+
+// Hold returns the hold with the given ID.
+func (s *Service) Hold(ctx context.Context, holdID string) (*entity.Hold, error) {
+	const op = "service.wallet.Hold"
+
+	log := s.log.With(
+		"op", op,
+		"holdID", holdID,
+	)
+
+	if uuid.Validate(holdID) != nil {
+		log.Warn("invalid hold ID format")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	hold, err := s.repo.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, holdError(log, err, "failed to get hold")
+	}
+
+	log.Info("hold retrieved")
+
+	return hold, nil
+}
+
+// CaptureHold charges the hold. If amount is zero, the whole hold is charged;
+// otherwise the rest of the hold is released.
+func (s *Service) CaptureHold(ctx context.Context, holdID string, amount entity.Money) (*entity.Hold, error) {
+	const op = "service.wallet.CaptureHold"
+
+	log := s.log.With(
+		"op", op,
+		"holdID", holdID,
+		"amount", amount.Amount,
+		"currency", amount.Currency,
+	)
+
+	if uuid.Validate(holdID) != nil || amount.Amount < 0 ||
+		(amount.Currency != "" && !amount.Currency.IsSupported()) {
+		log.Warn("invalid parameters")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	hold, err := s.repo.CaptureHold(ctx, entity.HoldCapture{
+		HoldID: holdID,
+		Amount: amount,
+	})
+	if err != nil {
+		return nil, holdError(log, err, "failed to capture hold")
+	}
+
+	s.notify(hold.Transaction)
+
+	log.Info("hold captured", "capturedAmount", hold.CapturedAmount)
+
+	return hold, nil
+}
+
+// ReleaseHold releases the funds reserved by the hold.
+func (s *Service) ReleaseHold(ctx context.Context, holdID string) (*entity.Hold, error) {
+	const op = "service.wallet.ReleaseHold"
+
+	log := s.log.With(
+		"op", op,
+		"holdID", holdID,
+	)
+
+	if uuid.Validate(holdID) != nil {
+		log.Warn("invalid hold ID format")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	hold, err := s.repo.ReleaseHold(ctx, holdID)
+	if err != nil {
+		return nil, holdError(log, err, "failed to release hold")
+	}
+
+	log.Info("hold released")
+
+	return hold, nil
+}
+
+// ReleaseExpiredHolds releases the funds reserved by holds whose TTL has passed.
+// Each hold is released in its own transaction, so a failure doesn't stop the sweep.
+func (s *Service) ReleaseExpiredHolds(ctx context.Context) error {
+	const op = "service.wallet.ReleaseExpiredHolds"
+
+	log := s.log.With("op", op)
+
+	ids, err := s.repo.ExpiredHoldIDs(ctx, time.Now(), expiredHoldsBatchSize)
+	if err != nil {
+		log.Error("failed to get expired holds", "err", err)
+
+		return err
+	}
+
+	var released int
+	var errs []error
+	for _, id := range ids {
+		_, err := s.repo.ExpireHold(ctx, id)
+		// The hold was captured or released after it had been selected.
+		if errors.Is(err, repoErr.ErrHoldNotActive) || errors.Is(err, repoErr.ErrHoldNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Error("failed to release expired hold", "holdID", id, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+		released++
+	}
+
+	log.Info("expired holds released", "count", released)
+
+	return errors.Join(errs...)
+}
+
+// holdError maps repository errors of hold operations to service errors.
+func holdError(log *slog.Logger, err error, msg string) error {
+	switch {
+	case errors.Is(err, executor.ErrQueueFull):
+		log.Warn("wallet queue is full", "err", err)
+		return svcErr.ErrBusy
+	case errors.Is(err, repoErr.ErrWalletNotFound):
+		log.Warn("wallet not found", "err", err)
+		return svcErr.ErrWalletNotFound
+	case errors.Is(err, repoErr.ErrHoldNotFound):
+		log.Warn("hold not found", "err", err)
+		return svcErr.ErrHoldNotFound
+	case errors.Is(err, repoErr.ErrHoldNotActive):
+		log.Warn("hold is not active", "err", err)
+		return svcErr.ErrHoldNotActive
+	case errors.Is(err, repoErr.ErrHoldAmountExceeded):
+		log.Warn("amount exceeds the hold amount", "err", err)
+		return svcErr.ErrHoldAmountExceeded
+	case errors.Is(err, repoErr.ErrInsufficientFunds):
+		log.Warn("insufficient funds", "err", err)
+		return svcErr.ErrInsufficientFunds
+	case errors.Is(err, repoErr.ErrCurrencyMismatch):
+		log.Warn("currency mismatch", "err", err)
+		return svcErr.ErrCurrencyMismatch
+	case errors.Is(err, repoErr.ErrBalanceOverflow):
+		log.Warn("balance overflow", "err", err)
+		return svcErr.ErrBalanceOverflow
+	case errors.Is(err, repoErr.ErrWalletFrozen):
+		log.Warn("wallet is frozen", "err", err)
+		return svcErr.ErrWalletFrozen
+	case errors.Is(err, repoErr.ErrWalletClosed):
+		log.Warn("wallet is closed", "err", err)
+		return svcErr.ErrWalletClosed
+	default:
+		log.Error(msg, "err", err)
+		return err
+	}
+}
@@ -0,0 +1,65 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+// IdempotencyKey returns the idempotency key stored for the client if it was used
+// with a request with the same fingerprint. If the key is unknown, it returns [svcErr.ErrIdempotencyKeyNotFound];
+// if it was used with a different request, it returns [svcErr.ErrIdempotencyKeyMismatch].
+func (s *Service) IdempotencyKey(ctx context.Context, clientID, key, fingerprint string) (*entity.IdempotencyKey, error) {
+	const op = "service.wallet.IdempotencyKey"
+
+	log := s.log.With(
+		"op", op,
+		"clientID", clientID,
+		"idempotencyKey", key,
+	)
+
+	stored, err := s.repo.GetIdempotencyKey(ctx, clientID, key)
+	if errors.Is(err, repoErr.ErrIdempotencyKeyNotFound) {
+		return nil, svcErr.ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		log.Error("failed to get idempotency key", "err", err)
+
+		return nil, err
+	}
+
+	if stored.Fingerprint != fingerprint {
+		log.Warn("idempotency key reused with a different request")
+
+		return nil, svcErr.ErrIdempotencyKeyMismatch
+	}
+
+	log.Info("idempotency key found, replaying response")
+
+	return stored, nil
+}
+
+// CleanupIdempotencyKeys deletes idempotency keys older than the retention window.
+func (s *Service) CleanupIdempotencyKeys(ctx context.Context, retention time.Duration) error {
+	const op = "service.wallet.CleanupIdempotencyKeys"
+
+	log := s.log.With(
+		"op", op,
+		"retention", retention,
+	)
+
+	deleted, err := s.repo.DeleteIdempotencyKeys(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.Error("failed to delete expired idempotency keys", "err", err)
+
+		return err
+	}
+
+	log.Info("expired idempotency keys deleted", "count", deleted)
+
+	return nil
+}
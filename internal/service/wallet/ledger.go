@@ -0,0 +1,33 @@
+package wallet
+
+import (
+	"context"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+// TrialBalance returns balances derived from the journal and reports whether
+// the ledger is consistent with the cached wallet balances.
+func (s *Service) TrialBalance(ctx context.Context) (*entity.TrialBalance, error) {
+	const op = "service.wallet.TrialBalance"
+
+	log := s.log.With("op", op)
+
+	tb, err := s.repo.TrialBalance(ctx)
+	if err != nil {
+		log.Error("failed to get trial balance", "err", err)
+
+		return nil, err
+	}
+
+	if !tb.IsConsistent() {
+		log.Error("ledger is inconsistent",
+			"totals", tb.Totals,
+			"mismatches", len(tb.Mismatches),
+		)
+	} else {
+		log.Info("trial balance retrieved")
+	}
+
+	return tb, nil
+}
@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	entity "github.com/passwordhash/asynchronous-wallet/internal/entity"
 	gomock "go.uber.org/mock/gomock"
@@ -41,6 +42,142 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AssignOwner mocks base method.
+func (m *MockRepository) AssignOwner(ctx context.Context, walletID, ownerID string) (*entity.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignOwner", ctx, walletID, ownerID)
+	ret0, _ := ret[0].(*entity.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignOwner indicates an expected call of AssignOwner.
+func (mr *MockRepositoryMockRecorder) AssignOwner(ctx, walletID, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignOwner", reflect.TypeOf((*MockRepository)(nil).AssignOwner), ctx, walletID, ownerID)
+}
+
+// CaptureHold mocks base method.
+func (m *MockRepository) CaptureHold(ctx context.Context, capture entity.HoldCapture) (*entity.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, capture)
+	ret0, _ := ret[0].(*entity.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockRepositoryMockRecorder) CaptureHold(ctx, capture any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockRepository)(nil).CaptureHold), ctx, capture)
+}
+
+// ChangeStatus mocks base method.
+func (m *MockRepository) ChangeStatus(ctx context.Context, change entity.WalletStatusChange) (*entity.Wallet, *entity.TransferResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeStatus", ctx, change)
+	ret0, _ := ret[0].(*entity.Wallet)
+	ret1, _ := ret[1].(*entity.TransferResult)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ChangeStatus indicates an expected call of ChangeStatus.
+func (mr *MockRepositoryMockRecorder) ChangeStatus(ctx, change any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeStatus", reflect.TypeOf((*MockRepository)(nil).ChangeStatus), ctx, change)
+}
+
+// Create mocks base method.
+func (m *MockRepository) Create(ctx context.Context, wallet entity.Wallet) (*entity.Wallet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, wallet)
+	ret0, _ := ret[0].(*entity.Wallet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRepositoryMockRecorder) Create(ctx, wallet any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, wallet)
+}
+
+// CreateHold mocks base method.
+func (m *MockRepository) CreateHold(ctx context.Context, hold entity.Hold) (*entity.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", ctx, hold)
+	ret0, _ := ret[0].(*entity.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockRepositoryMockRecorder) CreateHold(ctx, hold any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockRepository)(nil).CreateHold), ctx, hold)
+}
+
+// DeleteIdempotencyKeys mocks base method.
+func (m *MockRepository) DeleteIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdempotencyKeys", ctx, createdBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteIdempotencyKeys indicates an expected call of DeleteIdempotencyKeys.
+func (mr *MockRepositoryMockRecorder) DeleteIdempotencyKeys(ctx, createdBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyKeys", reflect.TypeOf((*MockRepository)(nil).DeleteIdempotencyKeys), ctx, createdBefore)
+}
+
+// EnqueueOperation mocks base method.
+func (m *MockRepository) EnqueueOperation(ctx context.Context, operation entity.QueuedOperation) (*entity.QueuedOperation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueOperation", ctx, operation)
+	ret0, _ := ret[0].(*entity.QueuedOperation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueOperation indicates an expected call of EnqueueOperation.
+func (mr *MockRepositoryMockRecorder) EnqueueOperation(ctx, operation any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueOperation", reflect.TypeOf((*MockRepository)(nil).EnqueueOperation), ctx, operation)
+}
+
+// ExpireHold mocks base method.
+func (m *MockRepository) ExpireHold(ctx context.Context, holdID string) (*entity.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHold", ctx, holdID)
+	ret0, _ := ret[0].(*entity.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHold indicates an expected call of ExpireHold.
+func (mr *MockRepositoryMockRecorder) ExpireHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHold", reflect.TypeOf((*MockRepository)(nil).ExpireHold), ctx, holdID)
+}
+
+// ExpiredHoldIDs mocks base method.
+func (m *MockRepository) ExpiredHoldIDs(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpiredHoldIDs", ctx, before, limit)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpiredHoldIDs indicates an expected call of ExpiredHoldIDs.
+func (mr *MockRepositoryMockRecorder) ExpiredHoldIDs(ctx, before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpiredHoldIDs", reflect.TypeOf((*MockRepository)(nil).ExpiredHoldIDs), ctx, before, limit)
+}
+
 // GetByID mocks base method.
 func (m *MockRepository) GetByID(ctx context.Context, walletID string) (*entity.Wallet, error) {
 	m.ctrl.T.Helper()
@@ -56,16 +193,167 @@ func (mr *MockRepositoryMockRecorder) GetByID(ctx, walletID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), ctx, walletID)
 }
 
+// GetHold mocks base method.
+func (m *MockRepository) GetHold(ctx context.Context, holdID string) (*entity.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", ctx, holdID)
+	ret0, _ := ret[0].(*entity.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockRepositoryMockRecorder) GetHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockRepository)(nil).GetHold), ctx, holdID)
+}
+
+// GetIdempotencyKey mocks base method.
+func (m *MockRepository) GetIdempotencyKey(ctx context.Context, clientID, key string) (*entity.IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdempotencyKey", ctx, clientID, key)
+	ret0, _ := ret[0].(*entity.IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdempotencyKey indicates an expected call of GetIdempotencyKey.
+func (mr *MockRepositoryMockRecorder) GetIdempotencyKey(ctx, clientID, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdempotencyKey", reflect.TypeOf((*MockRepository)(nil).GetIdempotencyKey), ctx, clientID, key)
+}
+
+// GetQueuedOperation mocks base method.
+func (m *MockRepository) GetQueuedOperation(ctx context.Context, operationID string) (*entity.QueuedOperation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQueuedOperation", ctx, operationID)
+	ret0, _ := ret[0].(*entity.QueuedOperation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQueuedOperation indicates an expected call of GetQueuedOperation.
+func (mr *MockRepositoryMockRecorder) GetQueuedOperation(ctx, operationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueuedOperation", reflect.TypeOf((*MockRepository)(nil).GetQueuedOperation), ctx, operationID)
+}
+
+// LastSequence mocks base method.
+func (m *MockRepository) LastSequence(ctx context.Context, walletID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastSequence", ctx, walletID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LastSequence indicates an expected call of LastSequence.
+func (mr *MockRepositoryMockRecorder) LastSequence(ctx, walletID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastSequence", reflect.TypeOf((*MockRepository)(nil).LastSequence), ctx, walletID)
+}
+
 // Operation mocks base method.
-func (m *MockRepository) Operation(ctx context.Context, walletID string, amount int64) error {
+func (m *MockRepository) Operation(ctx context.Context, operation entity.Operation) (*entity.Transaction, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Operation", ctx, walletID, amount)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "Operation", ctx, operation)
+	ret0, _ := ret[0].(*entity.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Operation indicates an expected call of Operation.
-func (mr *MockRepositoryMockRecorder) Operation(ctx, walletID, amount any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Operation(ctx, operation any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Operation", reflect.TypeOf((*MockRepository)(nil).Operation), ctx, operation)
+}
+
+// ProcessQueuedOperation mocks base method.
+func (m *MockRepository) ProcessQueuedOperation(ctx context.Context) (*entity.QueuedOperation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProcessQueuedOperation", ctx)
+	ret0, _ := ret[0].(*entity.QueuedOperation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProcessQueuedOperation indicates an expected call of ProcessQueuedOperation.
+func (mr *MockRepositoryMockRecorder) ProcessQueuedOperation(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessQueuedOperation", reflect.TypeOf((*MockRepository)(nil).ProcessQueuedOperation), ctx)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockRepository) ReleaseHold(ctx context.Context, holdID string) (*entity.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", ctx, holdID)
+	ret0, _ := ret[0].(*entity.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockRepositoryMockRecorder) ReleaseHold(ctx, holdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockRepository)(nil).ReleaseHold), ctx, holdID)
+}
+
+// Reverse mocks base method.
+func (m *MockRepository) Reverse(ctx context.Context, reversal entity.Reversal) (*entity.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reverse", ctx, reversal)
+	ret0, _ := ret[0].(*entity.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reverse indicates an expected call of Reverse.
+func (mr *MockRepositoryMockRecorder) Reverse(ctx, reversal any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reverse", reflect.TypeOf((*MockRepository)(nil).Reverse), ctx, reversal)
+}
+
+// Transactions mocks base method.
+func (m *MockRepository) Transactions(ctx context.Context, filter entity.TransactionFilter) ([]entity.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transactions", ctx, filter)
+	ret0, _ := ret[0].([]entity.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transactions indicates an expected call of Transactions.
+func (mr *MockRepositoryMockRecorder) Transactions(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transactions", reflect.TypeOf((*MockRepository)(nil).Transactions), ctx, filter)
+}
+
+// Transfer mocks base method.
+func (m *MockRepository) Transfer(ctx context.Context, transfer entity.Transfer) (*entity.TransferResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, transfer)
+	ret0, _ := ret[0].(*entity.TransferResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockRepositoryMockRecorder) Transfer(ctx, transfer any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockRepository)(nil).Transfer), ctx, transfer)
+}
+
+// TrialBalance mocks base method.
+func (m *MockRepository) TrialBalance(ctx context.Context) (*entity.TrialBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrialBalance", ctx)
+	ret0, _ := ret[0].(*entity.TrialBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrialBalance indicates an expected call of TrialBalance.
+func (mr *MockRepositoryMockRecorder) TrialBalance(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Operation", reflect.TypeOf((*MockRepository)(nil).Operation), ctx, walletID, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrialBalance", reflect.TypeOf((*MockRepository)(nil).TrialBalance), ctx)
 }
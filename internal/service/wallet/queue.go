@@ -0,0 +1,182 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+// queuedOperationsBatchSize is the maximum number of queued operations processed by one run.
+const queuedOperationsBatchSize = 100
+
+// Enqueue accepts a deposit or withdrawal to be applied later by [Service.ProcessQueuedOperations].
+// The operation ID is chosen by the caller, so it can be returned to the client
+// before the operation is stored. The amount is positive for both operation types.
+func (s *Service) Enqueue(
+	ctx context.Context,
+	operationID string,
+	walletID string,
+	operationType entity.OperationType,
+	amount entity.Money,
+	opts ...entity.OperationOption,
+) error {
+	const op = "service.wallet.Enqueue"
+
+	log := s.log.With(
+		"op", op,
+		"operationID", operationID,
+		"walletID", walletID,
+		"operationType", operationType,
+		"amount", amount.Amount,
+		"currency", amount.Currency,
+	)
+
+	walletID, err := validate(walletID, amount)
+	if err != nil {
+		log.Error("invalid parameters", "err", err)
+
+		return svcErr.ErrInvalidParams
+	}
+	if uuid.Validate(operationID) != nil {
+		log.Warn("invalid operation ID format")
+
+		return svcErr.ErrInvalidParams
+	}
+
+	switch operationType {
+	case entity.OperationDeposit:
+	case entity.OperationWithdraw:
+		amount = amount.Neg()
+	default:
+		log.Warn("invalid operation type")
+
+		return svcErr.ErrInvalidParams
+	}
+
+	_, err = s.repo.EnqueueOperation(ctx, entity.QueuedOperation{
+		ID:        operationID,
+		Operation: newOperation(walletID, amount, operationType, opts),
+	})
+	if errors.Is(err, repoErr.ErrWalletNotFound) {
+		log.Warn("wallet not found", "err", err)
+
+		return svcErr.ErrWalletNotFound
+	}
+	if errors.Is(err, repoErr.ErrIdempotencyKeyExists) {
+		log.Warn("idempotency key is already used", "err", err)
+
+		return svcErr.ErrIdempotencyKeyReused
+	}
+	if errors.Is(err, repoErr.ErrCurrencyMismatch) {
+		log.Warn("currency mismatch", "err", err)
+
+		return svcErr.ErrCurrencyMismatch
+	}
+	if errors.Is(err, repoErr.ErrWalletFrozen) {
+		log.Warn("wallet is frozen", "err", err)
+
+		return svcErr.ErrWalletFrozen
+	}
+	if errors.Is(err, repoErr.ErrWalletClosed) {
+		log.Warn("wallet is closed", "err", err)
+
+		return svcErr.ErrWalletClosed
+	}
+	if err != nil {
+		log.Error("failed to queue operation", "err", err)
+
+		return err
+	}
+
+	log.Info("operation queued")
+
+	return nil
+}
+
+// QueuedOperation returns the queued operation with the given ID.
+func (s *Service) QueuedOperation(ctx context.Context, operationID string) (*entity.QueuedOperation, error) {
+	const op = "service.wallet.QueuedOperation"
+
+	log := s.log.With(
+		"op", op,
+		"operationID", operationID,
+	)
+
+	if uuid.Validate(operationID) != nil {
+		log.Warn("invalid operation ID format")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	operation, err := s.repo.GetQueuedOperation(ctx, operationID)
+	if errors.Is(err, repoErr.ErrOperationNotFound) {
+		log.Warn("operation not found", "err", err)
+
+		return nil, svcErr.ErrOperationNotFound
+	}
+	if err != nil {
+		log.Error("failed to get operation", "err", err)
+
+		return nil, err
+	}
+
+	log.Info("operation retrieved")
+
+	return operation, nil
+}
+
+// ProcessQueuedOperations applies pending queued operations, the oldest first, until
+// the queue is empty or the batch size is reached. Each operation is applied in its
+// own transaction; an operation that fails with an unexpected error stays pending
+// and the run stops, so it is retried by the next run. The repository marks it
+// failed once it has used up its attempts, so a poison operation can't stall the queue;
+// the error of its last attempt is only logged.
+func (s *Service) ProcessQueuedOperations(ctx context.Context) error {
+	const op = "service.wallet.ProcessQueuedOperations"
+
+	log := s.log.With("op", op)
+
+	var completed, failed int
+	for range queuedOperationsBatchSize {
+		operation, err := s.repo.ProcessQueuedOperation(ctx)
+		if err != nil && operation != nil {
+			log.Error("queued operation failed after its last attempt",
+				"operationID", operation.ID,
+				"attempts", operation.Attempts,
+				"err", err,
+			)
+			failed++
+			continue
+		}
+		if err != nil {
+			log.Error("failed to process queued operation", "err", err)
+
+			return err
+		}
+		if operation == nil {
+			break
+		}
+
+		if operation.Status == entity.QueuedOperationFailed {
+			log.Warn("queued operation failed",
+				"operationID", operation.ID,
+				"reason", operation.FailureReason,
+			)
+			failed++
+			continue
+		}
+		s.notify(operation.Transaction)
+		completed++
+	}
+
+	if completed > 0 || failed > 0 {
+		log.Info("queued operations processed", "completed", completed, "failed", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+// Reverse writes a compensating transaction for a past deposit, withdrawal or capture.
+// If amount is zero, the part of the transaction that is not reversed yet is reversed;
+// otherwise the transaction is reversed partially.
+func (s *Service) Reverse(
+	ctx context.Context,
+	transactionID string,
+	amount entity.Money,
+	opts ...entity.OperationOption,
+) (*entity.Transaction, error) {
+	const op = "service.wallet.Reverse"
+
+	log := s.log.With(
+		"op", op,
+		"transactionID", transactionID,
+		"amount", amount.Amount,
+		"currency", amount.Currency,
+	)
+
+	if uuid.Validate(transactionID) != nil || amount.Amount < 0 ||
+		(amount.Currency != "" && !amount.Currency.IsSupported()) {
+		log.Warn("invalid parameters")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	transaction, err := s.repo.Reverse(ctx, entity.Reversal{
+		TransactionID:    transactionID,
+		Amount:           amount,
+		OperationOptions: entity.NewOperationOptions(opts...),
+	})
+	if err != nil {
+		return nil, reversalError(log, err)
+	}
+
+	s.notify(transaction)
+
+	log.Info("transaction reversed", "reversalID", transaction.ID, "reversedAmount", transaction.Amount)
+
+	return transaction, nil
+}
+
+// reversalError maps repository errors of reversals to service errors.
+func reversalError(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, repoErr.ErrTransactionNotFound):
+		log.Warn("transaction not found", "err", err)
+		return svcErr.ErrTransactionNotFound
+	case errors.Is(err, repoErr.ErrNotReversible):
+		log.Warn("transaction is not reversible", "err", err)
+		return svcErr.ErrNotReversible
+	case errors.Is(err, repoErr.ErrReversalAmountExceeded):
+		log.Warn("amount exceeds the amount left to reverse", "err", err)
+		return svcErr.ErrReversalAmountExceeded
+	case errors.Is(err, repoErr.ErrIdempotencyKeyExists):
+		log.Warn("idempotency key is already used", "err", err)
+		return svcErr.ErrIdempotencyKeyReused
+	case errors.Is(err, repoErr.ErrWalletNotFound):
+		log.Warn("wallet not found", "err", err)
+		return svcErr.ErrWalletNotFound
+	case errors.Is(err, repoErr.ErrInsufficientFunds):
+		log.Warn("insufficient funds", "err", err)
+		return svcErr.ErrInsufficientFunds
+	case errors.Is(err, repoErr.ErrCurrencyMismatch):
+		log.Warn("currency mismatch", "err", err)
+		return svcErr.ErrCurrencyMismatch
+	case errors.Is(err, repoErr.ErrBalanceOverflow):
+		log.Warn("balance overflow", "err", err)
+		return svcErr.ErrBalanceOverflow
+	case errors.Is(err, repoErr.ErrWalletFrozen):
+		log.Warn("wallet is frozen", "err", err)
+		return svcErr.ErrWalletFrozen
+	case errors.Is(err, repoErr.ErrWalletClosed):
+		log.Warn("wallet is closed", "err", err)
+		return svcErr.ErrWalletClosed
+	default:
+		log.Error("failed to reverse transaction", "err", err)
+		return err
+	}
+}
@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/pkg/executor"
+)
+
+// FreezeWallet freezes an active wallet for the reason. A frozen wallet keeps its
+// balance, but no deposit, withdrawal, transfer or hold can change it.
+func (s *Service) FreezeWallet(ctx context.Context, walletID, reason string) (*entity.Wallet, error) {
+	return s.changeStatus(ctx, "service.wallet.FreezeWallet", entity.WalletStatusChange{
+		WalletID: walletID,
+		Status:   entity.WalletStatusFrozen,
+		Reason:   reason,
+	})
+}
+
+// UnfreezeWallet makes a frozen wallet active again.
+func (s *Service) UnfreezeWallet(ctx context.Context, walletID, reason string) (*entity.Wallet, error) {
+	return s.changeStatus(ctx, "service.wallet.UnfreezeWallet", entity.WalletStatusChange{
+		WalletID: walletID,
+		Status:   entity.WalletStatusActive,
+		Reason:   reason,
+	})
+}
+
+// CloseWallet closes an active or frozen wallet for good. The wallet must have no
+// active holds. A non-zero balance is moved to sweepToWalletID as a transfer; if
+// it is empty, only a wallet with zero balance can be closed and
+// [svcErr.ErrWalletNotEmpty] is returned otherwise.
+func (s *Service) CloseWallet(ctx context.Context, walletID, reason, sweepToWalletID string) (*entity.Wallet, error) {
+	return s.changeStatus(ctx, "service.wallet.CloseWallet", entity.WalletStatusChange{
+		WalletID:        walletID,
+		Status:          entity.WalletStatusClosed,
+		Reason:          reason,
+		SweepToWalletID: sweepToWalletID,
+	})
+}
+
+// changeStatus validates and applies the status change. The sweep of a closed
+// wallet is published like any other transfer.
+func (s *Service) changeStatus(
+	ctx context.Context,
+	op string,
+	change entity.WalletStatusChange,
+) (*entity.Wallet, error) {
+	log := s.log.With(
+		"op", op,
+		"walletID", change.WalletID,
+		"status", change.Status,
+	)
+
+	change.Reason = strings.TrimSpace(change.Reason)
+
+	change, err := validateStatusChange(change)
+	if err != nil {
+		log.Warn("invalid parameters", "err", err)
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	// The sweep debits the wallet, so the change is serialized with the other
+	// changes of its balance.
+	var (
+		wallet *entity.Wallet
+		sweep  *entity.TransferResult
+	)
+	err = s.serialize(ctx, change.WalletID, func(ctx context.Context) error {
+		var err error
+		wallet, sweep, err = s.repo.ChangeStatus(ctx, change)
+		return err
+	})
+	if err != nil {
+		return nil, statusError(log, err)
+	}
+
+	if sweep != nil {
+		s.notify(sweep.Debit)
+		s.notify(sweep.Credit)
+	}
+
+	log.Info("wallet status changed", "reason", change.Reason)
+
+	return wallet, nil
+}
+
+// statusError maps repository errors of status changes to service errors.
+func statusError(log *slog.Logger, err error) error {
+	switch {
+	case errors.Is(err, executor.ErrQueueFull):
+		log.Warn("wallet queue is full", "err", err)
+		return svcErr.ErrBusy
+	case errors.Is(err, repoErr.ErrWalletNotFound):
+		log.Warn("wallet not found", "err", err)
+		return svcErr.ErrWalletNotFound
+	case errors.Is(err, repoErr.ErrWalletStatusTransition):
+		log.Warn("wallet can't change to the status", "err", err)
+		return svcErr.ErrWalletStatusTransition
+	case errors.Is(err, repoErr.ErrSameWallet):
+		log.Warn("wallet can't be swept to itself", "err", err)
+		return svcErr.ErrInvalidParams
+	case errors.Is(err, repoErr.ErrWalletNotEmpty):
+		log.Warn("wallet is not empty", "err", err)
+		return svcErr.ErrWalletNotEmpty
+	case errors.Is(err, repoErr.ErrWalletFrozen):
+		log.Warn("wallet swept to is frozen", "err", err)
+		return svcErr.ErrWalletFrozen
+	case errors.Is(err, repoErr.ErrWalletClosed):
+		log.Warn("wallet swept to is closed", "err", err)
+		return svcErr.ErrWalletClosed
+	case errors.Is(err, repoErr.ErrCurrencyMismatch):
+		log.Warn("currency mismatch", "err", err)
+		return svcErr.ErrCurrencyMismatch
+	case errors.Is(err, repoErr.ErrBalanceOverflow):
+		log.Warn("balance overflow", "err", err)
+		return svcErr.ErrBalanceOverflow
+	default:
+		log.Error("failed to change wallet status", "err", err)
+		return err
+	}
+}
+
+// validateStatusChange checks the status change and returns it with the wallet
+// IDs in their canonical form.
+func validateStatusChange(change entity.WalletStatusChange) (entity.WalletStatusChange, error) {
+	var err error
+	if change.WalletID, err = canonicalID(change.WalletID); err != nil {
+		return change, err
+	}
+	if change.Reason == "" {
+		return change, errors.New("reason is required")
+	}
+	if change.SweepToWalletID == "" {
+		return change, nil
+	}
+	if change.SweepToWalletID, err = canonicalID(change.SweepToWalletID); err != nil {
+		return change, err
+	}
+	if change.SweepToWalletID == change.WalletID {
+		return change, errors.New("wallet can't be swept to itself")
+	}
+
+	return change, nil
+}
@@ -0,0 +1,73 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/pkg/broadcast"
+)
+
+// WatchBalance subscribes to the balance changes of the wallet committed from now on.
+// If lastEventID is the ID of a recent change, the changes after it are returned in
+// the subscription as missed. The wallet is read after subscribing, so its balance
+// is never older than the first change received. The caller must close the subscription.
+func (s *Service) WatchBalance(
+	ctx context.Context,
+	walletID string,
+	lastEventID string,
+) (*broadcast.Subscription[entity.BalanceChange], *entity.Wallet, error) {
+	const op = "service.wallet.WatchBalance"
+
+	log := s.log.With(
+		"op", op,
+		"walletID", walletID,
+		"lastEventID", lastEventID,
+	)
+
+	// Changes are published under the canonical ID, so the subscription must use it too.
+	walletID, err := canonicalID(walletID)
+	if err != nil {
+		log.Warn("invalid wallet ID format")
+
+		return nil, nil, svcErr.ErrInvalidParams
+	}
+	if s.broadcaster == nil {
+		log.Error("balance changes are not broadcast")
+
+		return nil, nil, errors.New("balance changes are not broadcast")
+	}
+
+	sub := s.broadcaster.Subscribe(walletID, lastEventID)
+
+	wallet, err := s.repo.GetByID(ctx, walletID)
+	if errors.Is(err, repoErr.ErrWalletNotFound) {
+		sub.Close()
+		log.Warn("wallet not found", "err", err)
+
+		return nil, nil, svcErr.ErrWalletNotFound
+	}
+	if err != nil {
+		sub.Close()
+		log.Error("failed to get wallet", "err", err)
+
+		return nil, nil, err
+	}
+
+	// Changes are streamed in the order of their sequence, starting after the last
+	// change committed now.
+	sequence, err := s.repo.LastSequence(ctx, walletID)
+	if err != nil {
+		sub.Close()
+		log.Error("failed to get last sequence", "err", err)
+
+		return nil, nil, err
+	}
+	s.broadcaster.SetNextPosition(walletID, sequence+1)
+
+	log.Info("watching wallet balance", "resumed", sub.Resumed, "missed", len(sub.Missed))
+
+	return sub, wallet, nil
+}
@@ -0,0 +1,143 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+const (
+	DefaultTransactionsLimit = 20
+	MaxTransactionsLimit     = 100
+)
+
+// Transactions returns a page of the wallet transaction history, newest first.
+// The returned page contains a cursor for the next page if there is one.
+func (s *Service) Transactions(
+	ctx context.Context,
+	walletID string,
+	query entity.TransactionQuery,
+) (*entity.TransactionPage, error) {
+	const op = "service.wallet.Transactions"
+
+	log := s.log.With(
+		"op", op,
+		"walletID", walletID,
+	)
+
+	walletID, err := canonicalID(walletID)
+	if err != nil {
+		log.Warn("invalid wallet ID format")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = DefaultTransactionsLimit
+	}
+	if limit < 0 || limit > MaxTransactionsLimit {
+		log.Warn("invalid page limit", "limit", query.Limit)
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		log.Warn("invalid date range", "from", query.From, "to", query.To)
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	filter := entity.TransactionFilter{
+		WalletID:      walletID,
+		OperationType: query.OperationType,
+		From:          query.From,
+		To:            query.To,
+		Limit:         limit + 1, // fetch one extra row to know whether there is a next page
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			log.Warn("invalid cursor", "err", err)
+
+			return nil, svcErr.ErrInvalidParams
+		}
+		filter.After = cursor
+	}
+
+	_, err = s.repo.GetByID(ctx, walletID)
+	if errors.Is(err, repoErr.ErrWalletNotFound) {
+		log.Warn("wallet not found", "err", err)
+
+		return nil, svcErr.ErrWalletNotFound
+	}
+	if err != nil {
+		log.Error("failed to get wallet", "err", err)
+
+		return nil, err
+	}
+
+	txs, err := s.repo.Transactions(ctx, filter)
+	if err != nil {
+		log.Error("failed to get transactions", "err", err)
+
+		return nil, err
+	}
+
+	page := &entity.TransactionPage{Transactions: txs}
+	if len(txs) > limit {
+		page.Transactions = txs[:limit]
+		last := page.Transactions[limit-1]
+		page.NextCursor = encodeCursor(entity.TransactionCursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	log.Info("wallet transactions retrieved", "count", len(page.Transactions))
+
+	return page, nil
+}
+
+// encodeCursor returns an opaque string representation of the cursor.
+func encodeCursor(c entity.TransactionCursor) string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + c.ID
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor previously produced by [encodeCursor].
+func decodeCursor(s string) (*entity.TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+
+	micros, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	if err := uuid.Validate(id); err != nil {
+		return nil, err
+	}
+
+	return &entity.TransactionCursor{
+		CreatedAt: time.UnixMicro(micros).UTC(),
+		ID:        id,
+	}, nil
+}
@@ -0,0 +1,122 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/pkg/executor"
+)
+
+// Transfer moves funds from one wallet to another atomically. Both wallets must
+// hold the same currency, there is no currency conversion.
+func (s *Service) Transfer(
+	ctx context.Context,
+	fromWalletID string,
+	toWalletID string,
+	amount entity.Money,
+	opts ...entity.OperationOption,
+) error {
+	const op = "service.wallet.Transfer"
+
+	log := s.log.With(
+		"op", op,
+		"fromWalletID", fromWalletID,
+		"toWalletID", toWalletID,
+		"amount", amount.Amount,
+		"currency", amount.Currency,
+	)
+
+	fromWalletID, err := validate(fromWalletID, amount)
+	if err != nil {
+		log.ErrorContext(ctx, "invalid parameters", "err", err)
+
+		return svcErr.ErrInvalidParams
+	}
+	toWalletID, err = validate(toWalletID, amount)
+	if err != nil {
+		log.ErrorContext(ctx, "invalid parameters", "err", err)
+
+		return svcErr.ErrInvalidParams
+	}
+	if fromWalletID == toWalletID {
+		log.ErrorContext(ctx, "invalid parameters", "err", "transfer to the same wallet")
+
+		return svcErr.ErrInvalidParams
+	}
+
+	// The debited wallet is the one whose funds are checked, so the transfer is
+	// serialized with the other changes of that wallet.
+	var result *entity.TransferResult
+	err = s.serialize(ctx, fromWalletID, func(ctx context.Context) error {
+		var err error
+		result, err = s.repo.Transfer(ctx, entity.Transfer{
+			FromWalletID:     fromWalletID,
+			ToWalletID:       toWalletID,
+			Amount:           amount,
+			OperationOptions: entity.NewOperationOptions(opts...),
+		})
+		return err
+	})
+	if errors.Is(err, executor.ErrQueueFull) {
+		log.WarnContext(ctx, "wallet queue is full", "err", err)
+
+		return svcErr.ErrBusy
+	}
+	if errors.Is(err, repoErr.ErrWalletNotFound) {
+		log.WarnContext(ctx, "wallet not found", "err", err)
+
+		return svcErr.ErrWalletNotFound
+	}
+	if errors.Is(err, repoErr.ErrSameWallet) {
+		log.WarnContext(ctx, "transfer to the same wallet", "err", err)
+
+		return svcErr.ErrInvalidParams
+	}
+	if errors.Is(err, repoErr.ErrIdempotencyKeyExists) {
+		log.WarnContext(ctx, "idempotency key is already used", "err", err)
+
+		return svcErr.ErrIdempotencyKeyReused
+	}
+	if errors.Is(err, repoErr.ErrInsufficientFunds) {
+		log.WarnContext(ctx, "insufficient funds", "err", err)
+
+		return svcErr.ErrInsufficientFunds
+	}
+	if errors.Is(err, repoErr.ErrCurrencyMismatch) {
+		log.WarnContext(ctx, "currency mismatch", "err", err)
+
+		return svcErr.ErrCurrencyMismatch
+	}
+	if errors.Is(err, repoErr.ErrBalanceOverflow) {
+		log.WarnContext(ctx, "balance overflow", "err", err)
+
+		return svcErr.ErrBalanceOverflow
+	}
+	if errors.Is(err, repoErr.ErrWalletFrozen) {
+		log.WarnContext(ctx, "wallet is frozen", "err", err)
+
+		return svcErr.ErrWalletFrozen
+	}
+	if errors.Is(err, repoErr.ErrWalletClosed) {
+		log.WarnContext(ctx, "wallet is closed", "err", err)
+
+		return svcErr.ErrWalletClosed
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to transfer funds", "err", err)
+
+		return err
+	}
+
+	if result != nil {
+		s.notify(result.Debit)
+		s.notify(result.Credit)
+	}
+
+	log.InfoContext(ctx, "transfer successful")
+
+	return nil
+}
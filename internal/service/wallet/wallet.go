@@ -4,131 +4,556 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/passwordhash/asynchronous-wallet/internal/entity"
 	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
 	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/pkg/broadcast"
+	"github.com/passwordhash/asynchronous-wallet/pkg/executor"
 )
 
 //go:generate mockgen -destination=./mocks/mock_repository.go -package=mocks github.com/passwordhash/asynchronous-wallet/internal/service/wallet Repository
 type Repository interface {
-	Operation(ctx context.Context, walletID string, amount int64) error
+	Create(ctx context.Context, wallet entity.Wallet) (*entity.Wallet, error)
+	Operation(ctx context.Context, operation entity.Operation) (*entity.Transaction, error)
 	GetByID(ctx context.Context, walletID string) (*entity.Wallet, error)
+	ChangeStatus(ctx context.Context, change entity.WalletStatusChange) (*entity.Wallet, *entity.TransferResult, error)
+	AssignOwner(ctx context.Context, walletID, ownerID string) (*entity.Wallet, error)
+	Transfer(ctx context.Context, transfer entity.Transfer) (*entity.TransferResult, error)
+	Transactions(ctx context.Context, filter entity.TransactionFilter) ([]entity.Transaction, error)
+	LastSequence(ctx context.Context, walletID string) (int64, error)
+	TrialBalance(ctx context.Context) (*entity.TrialBalance, error)
+	CreateHold(ctx context.Context, hold entity.Hold) (*entity.Hold, error)
+	GetHold(ctx context.Context, holdID string) (*entity.Hold, error)
+	CaptureHold(ctx context.Context, capture entity.HoldCapture) (*entity.Hold, error)
+	ReleaseHold(ctx context.Context, holdID string) (*entity.Hold, error)
+	ExpireHold(ctx context.Context, holdID string) (*entity.Hold, error)
+	ExpiredHoldIDs(ctx context.Context, before time.Time, limit int) ([]string, error)
+	Reverse(ctx context.Context, reversal entity.Reversal) (*entity.Transaction, error)
+	EnqueueOperation(ctx context.Context, operation entity.QueuedOperation) (*entity.QueuedOperation, error)
+	GetQueuedOperation(ctx context.Context, operationID string) (*entity.QueuedOperation, error)
+	ProcessQueuedOperation(ctx context.Context) (*entity.QueuedOperation, error)
+	GetIdempotencyKey(ctx context.Context, clientID, key string) (*entity.IdempotencyKey, error)
+	DeleteIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int64, error)
 }
 
+// Executor runs tasks with the same key one at a time.
+type Executor interface {
+	Do(ctx context.Context, key string, task executor.Task) error
+}
+
+// Broadcaster fans committed balance changes out to the clients watching a wallet.
+type Broadcaster interface {
+	PublishInOrder(walletID string, sequence int64, change entity.BalanceChange)
+	SetNextPosition(walletID string, sequence int64)
+	Subscribe(walletID, lastID string) *broadcast.Subscription[entity.BalanceChange]
+}
+
+// Metrics counts the results of deposits and withdrawals.
+type Metrics interface {
+	OperationCompleted(operationType entity.OperationType, err error)
+}
+
+const instrumentationName = "github.com/passwordhash/asynchronous-wallet/internal/service/wallet"
+
+const (
+	DefaultHoldTTL = 15 * time.Minute
+	MaxHoldTTL     = 7 * 24 * time.Hour
+)
+
 type Service struct {
-	log  *slog.Logger
-	repo Repository
+	log         *slog.Logger
+	repo        Repository
+	executor    Executor
+	broadcaster Broadcaster
+	metrics     Metrics
+	tracer      trace.Tracer
+
+	defaultHoldTTL time.Duration
+	maxHoldTTL     time.Duration
+}
+
+type Option func(*Service)
+
+// WithHoldTTL sets the TTL of holds created without one and the maximum TTL a hold may have.
+func WithHoldTTL(defaultTTL, maxTTL time.Duration) Option {
+	return func(s *Service) {
+		s.defaultHoldTTL = defaultTTL
+		s.maxHoldTTL = maxTTL
+	}
+}
+
+// WithExecutor routes balance changes through the executor keyed by wallet ID, so
+// concurrent changes of one wallet queue in memory instead of on its row lock.
+func WithExecutor(executor Executor) Option {
+	return func(s *Service) {
+		s.executor = executor
+	}
+}
+
+// WithBroadcaster publishes every committed balance change to the broadcaster
+// under the wallet ID.
+func WithBroadcaster(broadcaster Broadcaster) Option {
+	return func(s *Service) {
+		s.broadcaster = broadcaster
+	}
+}
+
+// WithMetrics counts every synchronous deposit and withdrawal by its result.
+func WithMetrics(metrics Metrics) Option {
+	return func(s *Service) {
+		s.metrics = metrics
+	}
+}
+
+// WithTracerProvider traces deposits, withdrawals and balance reads with a tracer of the provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(s *Service) {
+		s.tracer = provider.Tracer(instrumentationName)
+	}
 }
 
 func New(
 	log *slog.Logger,
 	repo Repository,
+	opts ...Option,
 ) *Service {
-	return &Service{
-		log:  log,
-		repo: repo,
+	s := &Service{
+		log:            log,
+		repo:           repo,
+		tracer:         noop.NewTracerProvider().Tracer(instrumentationName),
+		defaultHoldTTL: DefaultHoldTTL,
+		maxHoldTTL:     MaxHoldTTL,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
-func (s *Service) Deposit(ctx context.Context, walletID string, amount int64) error {
-	const op = "service.wallet.Deposit"
+// Create creates a new wallet with zero balance. If params.ID is empty, a random
+// one is generated. If params.OverdraftLimit is nil, the default limit applies.
+// If the currency of params.Balance is empty, the default currency is used.
+func (s *Service) Create(ctx context.Context, params entity.Wallet) (*entity.Wallet, error) {
+	const op = "service.wallet.Create"
+
+	if params.ID == "" {
+		params.ID = uuid.NewString()
+	}
+
+	log := s.log.With(
+		"op", op,
+		"walletID", params.ID,
+	)
+
+	id, err := canonicalID(params.ID)
+	if err != nil {
+		log.Warn("invalid wallet ID format")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+	params.ID = id
+
+	if params.OverdraftLimit != nil && *params.OverdraftLimit < 0 {
+		log.Warn("invalid overdraft limit", "overdraftLimit", *params.OverdraftLimit)
+
+		return nil, svcErr.ErrInvalidParams
+	}
+	if currency := params.Currency(); currency != "" && !currency.IsSupported() {
+		log.Warn("unsupported currency", "currency", currency)
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	wallet, err := s.repo.Create(ctx, entity.Wallet{
+		ID:             params.ID,
+		Balance:        entity.NewMoney(0, params.Currency()),
+		OverdraftLimit: params.OverdraftLimit,
+		OwnerID:        params.OwnerID,
+	})
+	if errors.Is(err, repoErr.ErrWalletAlreadyExists) {
+		log.Warn("wallet already exists", "err", err)
+
+		return nil, svcErr.ErrWalletAlreadyExists
+	}
+	if err != nil {
+		log.Error("failed to create wallet", "err", err)
+
+		return nil, err
+	}
+
+	log.Info("wallet created")
+
+	return wallet, nil
+}
+
+// IsWalletOwner reports whether the end user with the subject owns the wallet.
+// If the wallet doesn't exist, it returns [svcErr.ErrWalletNotFound].
+func (s *Service) IsWalletOwner(ctx context.Context, walletID, subject string) (bool, error) {
+	const op = "service.wallet.IsWalletOwner"
 
 	log := s.log.With(
 		"op", op,
 		"walletID", walletID,
-		"amount", amount,
 	)
 
-	if err := validate(walletID, amount); err != nil {
-		log.Error("invalid parameters", "err", err)
+	walletID, err := canonicalID(walletID)
+	if err != nil {
+		log.WarnContext(ctx, "invalid wallet ID format")
 
-		return svcErr.ErrInvalidParams
+		return false, svcErr.ErrInvalidParams
 	}
 
-	err := s.repo.Operation(ctx, walletID, amount)
+	wallet, err := s.repo.GetByID(ctx, walletID)
 	if errors.Is(err, repoErr.ErrWalletNotFound) {
-		log.Warn("wallet not found", "err", err)
+		log.WarnContext(ctx, "wallet not found", "err", err)
 
-		return svcErr.ErrWalletNotFound
+		return false, svcErr.ErrWalletNotFound
 	}
 	if err != nil {
-		log.Error("failed to update balance", "err", err)
+		log.ErrorContext(ctx, "failed to get wallet", "err", err)
 
-		return err
+		return false, err
 	}
 
-	log.Info("deposit successful")
+	// A wallet without an owner belongs to nobody, even to a token without a subject.
+	return wallet.OwnerID != "" && wallet.OwnerID == subject, nil
+}
+
+// AssignOwner makes the end user with the subject ownerID the owner of an existing
+// wallet, e.g. of a wallet created with an API key, replacing its current owner.
+// An empty ownerID leaves the wallet without an owner.
+// If the wallet doesn't exist, it returns [svcErr.ErrWalletNotFound].
+func (s *Service) AssignOwner(ctx context.Context, walletID, ownerID string) (*entity.Wallet, error) {
+	const op = "service.wallet.AssignOwner"
 
-	return nil
+	log := s.log.With(
+		"op", op,
+		"walletID", walletID,
+	)
+
+	walletID, err := canonicalID(walletID)
+	if err != nil {
+		log.WarnContext(ctx, "invalid wallet ID format")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	wallet, err := s.repo.AssignOwner(ctx, walletID, strings.TrimSpace(ownerID))
+	if errors.Is(err, repoErr.ErrWalletNotFound) {
+		log.WarnContext(ctx, "wallet not found", "err", err)
+
+		return nil, svcErr.ErrWalletNotFound
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to assign wallet owner", "err", err)
+
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "wallet owner assigned", "ownerID", wallet.OwnerID)
+
+	return wallet, nil
 }
 
-func (s *Service) Withdraw(ctx context.Context, walletID string, amount int64) error {
-	const op = "service.wallet.Withdraw"
+// Wallet returns the wallet with the given ID.
+func (s *Service) Wallet(ctx context.Context, walletID string) (*entity.Wallet, error) {
+	const op = "service.wallet.Wallet"
 
 	log := s.log.With(
 		"op", op,
 		"walletID", walletID,
-		"amount", amount,
 	)
 
-	if err := validate(walletID, amount); err != nil {
-		log.Error("invalid parameters", "err", err)
+	walletID, err := canonicalID(walletID)
+	if err != nil {
+		log.Warn("invalid wallet ID format")
 
-		return svcErr.ErrInvalidParams
+		return nil, svcErr.ErrInvalidParams
 	}
 
-	err := s.repo.Operation(ctx, walletID, -amount)
+	wallet, err := s.repo.GetByID(ctx, walletID)
 	if errors.Is(err, repoErr.ErrWalletNotFound) {
 		log.Warn("wallet not found", "err", err)
 
-		return svcErr.ErrWalletNotFound
+		return nil, svcErr.ErrWalletNotFound
 	}
 	if err != nil {
-		log.Error("failed to update balance", "err", err)
+		log.Error("failed to get wallet", "err", err)
 
+		return nil, err
+	}
+
+	log.Info("wallet retrieved")
+
+	return wallet, nil
+}
+
+func (s *Service) Deposit(
+	ctx context.Context,
+	walletID string,
+	amount entity.Money,
+	opts ...entity.OperationOption,
+) (err error) {
+	const op = "service.wallet.Deposit"
+
+	ctx, span := s.tracer.Start(ctx, op, trace.WithAttributes(
+		attribute.String("wallet.id", walletID),
+		attribute.Int64("amount", amount.Amount),
+		attribute.String("currency", string(amount.Currency)),
+	))
+	defer func() {
+		s.observe(entity.OperationDeposit, err)
+		endSpan(span, err)
+	}()
+
+	log := s.log.With(
+		"op", op,
+		"walletID", walletID,
+		"amount", amount.Amount,
+		"currency", amount.Currency,
+	)
+
+	walletID, err = validate(walletID, amount)
+	if err != nil {
+		log.ErrorContext(ctx, "invalid parameters", "err", err)
+
+		return svcErr.ErrInvalidParams
+	}
+
+	operation := newOperation(walletID, amount, entity.OperationDeposit, opts)
+
+	if err := s.applyOperation(ctx, log, operation); err != nil {
 		return err
 	}
 
-	log.Info("withdrawal successful")
+	log.InfoContext(ctx, "deposit successful")
 
 	return nil
 }
 
-func (s *Service) Balance(ctx context.Context, walletID string) (int64, error) {
+func (s *Service) Withdraw(
+	ctx context.Context,
+	walletID string,
+	amount entity.Money,
+	opts ...entity.OperationOption,
+) (err error) {
+	const op = "service.wallet.Withdraw"
+
+	ctx, span := s.tracer.Start(ctx, op, trace.WithAttributes(
+		attribute.String("wallet.id", walletID),
+		attribute.Int64("amount", amount.Amount),
+		attribute.String("currency", string(amount.Currency)),
+	))
+	defer func() {
+		s.observe(entity.OperationWithdraw, err)
+		endSpan(span, err)
+	}()
+
+	log := s.log.With(
+		"op", op,
+		"walletID", walletID,
+		"amount", amount.Amount,
+		"currency", amount.Currency,
+	)
+
+	walletID, err = validate(walletID, amount)
+	if err != nil {
+		log.ErrorContext(ctx, "invalid parameters", "err", err)
+
+		return svcErr.ErrInvalidParams
+	}
+
+	operation := newOperation(walletID, amount.Neg(), entity.OperationWithdraw, opts)
+
+	if err := s.applyOperation(ctx, log, operation); err != nil {
+		return err
+	}
+
+	log.InfoContext(ctx, "withdrawal successful")
+
+	return nil
+}
+
+func (s *Service) Balance(ctx context.Context, walletID string) (_ entity.Money, err error) {
 	const op = "service.wallet.Balance"
 
+	ctx, span := s.tracer.Start(ctx, op, trace.WithAttributes(attribute.String("wallet.id", walletID)))
+	defer func() { endSpan(span, err) }()
+
 	log := s.log.With(
 		"op", op,
 		"walletID", walletID,
 	)
 
-	if uuid.Validate(walletID) != nil {
-		log.Warn("invalid wallet ID format", "walletID", walletID)
+	walletID, err = canonicalID(walletID)
+	if err != nil {
+		log.WarnContext(ctx, "invalid wallet ID format", "walletID", walletID)
 
-		return 0, svcErr.ErrInvalidParams
+		return entity.Money{}, svcErr.ErrInvalidParams
 	}
 
 	wallet, err := s.repo.GetByID(ctx, walletID)
+	if errors.Is(err, repoErr.ErrWalletNotFound) {
+		log.WarnContext(ctx, "wallet not found", "err", err)
+
+		return entity.Money{}, svcErr.ErrWalletNotFound
+	}
 	if err != nil {
-		log.Error("failed to get balance", "err", err)
+		log.ErrorContext(ctx, "failed to get balance", "err", err)
 
-		return 0, err
+		return entity.Money{}, err
 	}
 
-	log.Info("wallet balance retrieved")
+	log.InfoContext(ctx, "wallet balance retrieved")
 
 	return wallet.Balance, nil
 }
 
-func validate(walletID string, amount int64) error {
-	if uuid.Validate(walletID) != nil {
-		return svcErr.ErrInvalidParams
+// applyOperation applies the operation to the wallet balance and maps repository
+// errors to service errors.
+func (s *Service) applyOperation(ctx context.Context, log *slog.Logger, operation entity.Operation) error {
+	var transaction *entity.Transaction
+	err := s.serialize(ctx, operation.WalletID, func(ctx context.Context) error {
+		var err error
+		transaction, err = s.repo.Operation(ctx, operation)
+		return err
+	})
+	if errors.Is(err, executor.ErrQueueFull) {
+		log.WarnContext(ctx, "wallet queue is full", "err", err)
+
+		return svcErr.ErrBusy
 	}
-	if amount <= 0 {
-		return svcErr.ErrInvalidParams
+	if errors.Is(err, repoErr.ErrWalletNotFound) {
+		log.WarnContext(ctx, "wallet not found", "err", err)
+
+		return svcErr.ErrWalletNotFound
+	}
+	if errors.Is(err, repoErr.ErrIdempotencyKeyExists) {
+		log.WarnContext(ctx, "idempotency key is already used", "err", err)
+
+		return svcErr.ErrIdempotencyKeyReused
+	}
+	if errors.Is(err, repoErr.ErrInsufficientFunds) {
+		log.WarnContext(ctx, "insufficient funds", "err", err)
+
+		return svcErr.ErrInsufficientFunds
+	}
+	if errors.Is(err, repoErr.ErrCurrencyMismatch) {
+		log.WarnContext(ctx, "currency mismatch", "err", err)
+
+		return svcErr.ErrCurrencyMismatch
+	}
+	if errors.Is(err, repoErr.ErrBalanceOverflow) {
+		log.WarnContext(ctx, "balance overflow", "err", err)
+
+		return svcErr.ErrBalanceOverflow
 	}
+	if errors.Is(err, repoErr.ErrWalletFrozen) {
+		log.WarnContext(ctx, "wallet is frozen", "err", err)
+
+		return svcErr.ErrWalletFrozen
+	}
+	if errors.Is(err, repoErr.ErrWalletClosed) {
+		log.WarnContext(ctx, "wallet is closed", "err", err)
+
+		return svcErr.ErrWalletClosed
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to update balance", "err", err)
+
+		return err
+	}
+
+	s.notify(transaction)
+
 	return nil
 }
+
+// serialize runs the task on the executor under the wallet ID. Without an executor
+// the task runs right away.
+func (s *Service) serialize(ctx context.Context, walletID string, task executor.Task) error {
+	if s.executor == nil {
+		return task(ctx)
+	}
+	return s.executor.Do(ctx, walletID, task)
+}
+
+// observe counts the result of the operation.
+func (s *Service) observe(operationType entity.OperationType, err error) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.OperationCompleted(operationType, err)
+}
+
+// endSpan records the error of the traced call, if any, and ends its span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// notify publishes the balance change recorded by the committed transaction.
+// Changes of a wallet are published from the goroutines that made them, e.g. the
+// credit of a transfer from the shard of the debited wallet, so they can arrive out
+// of order; the broadcaster puts them back in the order of their sequence.
+func (s *Service) notify(transaction *entity.Transaction) {
+	if s.broadcaster == nil || transaction == nil {
+		return
+	}
+	s.broadcaster.PublishInOrder(transaction.WalletID, transaction.Sequence, entity.NewBalanceChange(transaction))
+}
+
+func newOperation(
+	walletID string,
+	amount entity.Money,
+	operationType entity.OperationType,
+	opts []entity.OperationOption,
+) entity.Operation {
+	return entity.Operation{
+		WalletID:         walletID,
+		Amount:           amount,
+		Type:             operationType,
+		OperationOptions: entity.NewOperationOptions(opts...),
+	}
+}
+
+// validate checks the wallet ID and the amount of an operation and returns the
+// wallet ID in its canonical form.
+func validate(walletID string, amount entity.Money) (string, error) {
+	walletID, err := canonicalID(walletID)
+	if err != nil {
+		return "", err
+	}
+	if amount.Amount <= 0 {
+		return "", svcErr.ErrInvalidParams
+	}
+	if amount.Currency != "" && !amount.Currency.IsSupported() {
+		return "", svcErr.ErrInvalidParams
+	}
+	return walletID, nil
+}
+
+// canonicalID returns the wallet ID in its canonical lowercase form. UUIDs are
+// case-insensitive, so wallet IDs are only compared, sorted and used as keys in
+// this form: otherwise two spellings of one wallet would pass as two wallets.
+func canonicalID(walletID string) (string, error) {
+	id, err := uuid.Parse(walletID)
+	if err != nil {
+		return "", svcErr.ErrInvalidParams
+	}
+	return id.String(), nil
+}
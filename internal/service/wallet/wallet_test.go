@@ -1,14 +1,21 @@
 package wallet_test
 
 import (
+	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/passwordhash/asynchronous-wallet/internal/entity"
 	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
 	"github.com/passwordhash/asynchronous-wallet/internal/service/wallet"
 	"github.com/passwordhash/asynchronous-wallet/internal/service/wallet/mocks"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/pkg/broadcast"
+	"github.com/passwordhash/asynchronous-wallet/pkg/executor"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -27,57 +34,135 @@ func setupTest(t *testing.T) (*wallet.Service, *mocks.MockRepository) {
 	return service, mockRepo
 }
 
+func rub(amount int64) entity.Money {
+	return entity.NewMoney(amount, entity.CurrencyRUB)
+}
+
 func TestDeposit(t *testing.T) {
 	t.Parallel()
 
 	validUUID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	idemKey := &entity.IdempotencyKey{Key: "key", Fingerprint: "fingerprint", StatusCode: 200}
 
 	tests := []struct {
 		name          string
 		walletID      string
-		amount        int64
+		amount        entity.Money
+		opts          []entity.OperationOption
 		mockBehavior  func(mock *mocks.MockRepository)
 		expectedError error
 	}{
 		{
 			name:     "Ok",
 			walletID: validUUID,
-			amount:   100,
+			amount:   rub(100),
 			mockBehavior: func(mock *mocks.MockRepository) {
-				mock.EXPECT().Operation(gomock.Any(), validUUID, int64(100)).Return(nil)
+				mock.EXPECT().Operation(gomock.Any(), entity.Operation{
+					WalletID: validUUID,
+					Amount:   rub(100),
+					Type:     entity.OperationDeposit,
+				}).Return(&entity.Transaction{}, nil)
 			},
 			expectedError: nil,
 		},
 		{
 			name:          "Invalid uuid format",
 			walletID:      "wallet-id",
-			amount:        100,
+			amount:        rub(100),
 			mockBehavior:  func(mock *mocks.MockRepository) {},
 			expectedError: svcErr.ErrInvalidParams,
 		},
 		{
 			name:          "Amount is zero",
 			walletID:      validUUID,
-			amount:        0,
+			amount:        rub(0),
 			mockBehavior:  func(mock *mocks.MockRepository) {},
 			expectedError: svcErr.ErrInvalidParams,
 		},
 		{
 			name:          "Amount is negative",
 			walletID:      validUUID,
-			amount:        -100,
+			amount:        rub(-100),
 			mockBehavior:  func(mock *mocks.MockRepository) {},
 			expectedError: svcErr.ErrInvalidParams,
 		},
 		{
 			name:     "Wallet not found",
 			walletID: validUUID,
-			amount:   100,
+			amount:   rub(100),
 			mockBehavior: func(mock *mocks.MockRepository) {
-				mock.EXPECT().Operation(gomock.Any(), validUUID, int64(100)).Return(svcErr.ErrWalletNotFound)
+				mock.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrWalletNotFound)
 			},
 			expectedError: svcErr.ErrWalletNotFound,
 		},
+		{
+			name:          "Unsupported currency",
+			walletID:      validUUID,
+			amount:        entity.NewMoney(100, "XXX"),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:     "Currency mismatch",
+			walletID: validUUID,
+			amount:   entity.NewMoney(100, entity.CurrencyEUR),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrCurrencyMismatch)
+			},
+			expectedError: svcErr.ErrCurrencyMismatch,
+		},
+		{
+			name:     "Balance overflow",
+			walletID: validUUID,
+			amount:   rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrBalanceOverflow)
+			},
+			expectedError: svcErr.ErrBalanceOverflow,
+		},
+		{
+			name:     "Wallet frozen",
+			walletID: validUUID,
+			amount:   rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrWalletFrozen)
+			},
+			expectedError: svcErr.ErrWalletFrozen,
+		},
+		{
+			name:     "Wallet closed",
+			walletID: validUUID,
+			amount:   rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrWalletClosed)
+			},
+			expectedError: svcErr.ErrWalletClosed,
+		},
+		{
+			name:     "With idempotency key",
+			walletID: validUUID,
+			amount:   rub(100),
+			opts:     []entity.OperationOption{entity.WithIdempotencyKey(idemKey)},
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Operation(gomock.Any(), entity.Operation{
+					WalletID:         validUUID,
+					Amount:           rub(100),
+					Type:             entity.OperationDeposit,
+					OperationOptions: entity.OperationOptions{IdempotencyKey: idemKey},
+				}).Return(&entity.Transaction{}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "Idempotency key is already used",
+			walletID: validUUID,
+			amount:   rub(100),
+			opts:     []entity.OperationOption{entity.WithIdempotencyKey(idemKey)},
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrIdempotencyKeyExists)
+			},
+			expectedError: svcErr.ErrIdempotencyKeyReused,
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,7 +173,7 @@ func TestDeposit(t *testing.T) {
 
 			tt.mockBehavior(mockRepo)
 
-			err := service.Deposit(t.Context(), tt.walletID, tt.amount)
+			err := service.Deposit(t.Context(), tt.walletID, tt.amount, tt.opts...)
 
 			if tt.expectedError == nil {
 				t.Log(err)
@@ -108,40 +193,53 @@ func TestWithdraw(t *testing.T) {
 	tests := []struct {
 		name          string
 		walletID      string
-		amount        int64
+		amount        entity.Money
 		mockBehavior  func(mock *mocks.MockRepository)
 		expectedError error
 	}{
 		{
 			name:     "Ok",
 			walletID: validUUID,
-			amount:   100,
+			amount:   rub(100),
 			mockBehavior: func(mock *mocks.MockRepository) {
-				mock.EXPECT().Operation(gomock.Any(), validUUID, int64(-100)).Return(nil)
+				mock.EXPECT().Operation(gomock.Any(), entity.Operation{
+					WalletID: validUUID,
+					Amount:   rub(-100),
+					Type:     entity.OperationWithdraw,
+				}).Return(&entity.Transaction{}, nil)
 			},
 			expectedError: nil,
 		},
 		{
 			name:          "Invalid uuid format",
 			walletID:      "wallet-id",
-			amount:        100,
+			amount:        rub(100),
 			mockBehavior:  func(mock *mocks.MockRepository) {},
 			expectedError: svcErr.ErrInvalidParams,
 		},
 		{
 			name:          "Amount is zero",
 			walletID:      validUUID,
-			amount:        0,
+			amount:        rub(0),
 			mockBehavior:  func(mock *mocks.MockRepository) {},
 			expectedError: svcErr.ErrInvalidParams,
 		},
 		{
 			name:          "Amount is negative",
 			walletID:      validUUID,
-			amount:        -100,
+			amount:        rub(-100),
 			mockBehavior:  func(mock *mocks.MockRepository) {},
 			expectedError: svcErr.ErrInvalidParams,
 		},
+		{
+			name:     "Insufficient funds",
+			walletID: validUUID,
+			amount:   rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrInsufficientFunds)
+			},
+			expectedError: svcErr.ErrInsufficientFunds,
+		},
 	}
 
 	for _, tt := range tests {
@@ -174,23 +272,23 @@ func TestBalance(t *testing.T) {
 		walletID        string
 		mockBehavior    func(mock *mocks.MockRepository)
 		expectedError   error
-		expectedBalance int64
+		expectedBalance entity.Money
 	}{
 		{
 			name:     "Ok",
 			walletID: validUUID,
 			mockBehavior: func(mock *mocks.MockRepository) {
-				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(&entity.Wallet{Balance: 100}, nil)
+				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(&entity.Wallet{Balance: rub(100)}, nil)
 			},
 			expectedError:   nil,
-			expectedBalance: 100,
+			expectedBalance: rub(100),
 		},
 		{
 			name:            "Invalid uuid format",
 			walletID:        "wallet-id",
 			mockBehavior:    func(mock *mocks.MockRepository) {},
 			expectedError:   svcErr.ErrInvalidParams,
-			expectedBalance: 0,
+			expectedBalance: entity.Money{},
 		},
 		{
 			name:     "Wallet not found",
@@ -199,7 +297,7 @@ func TestBalance(t *testing.T) {
 				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(nil, svcErr.ErrWalletNotFound)
 			},
 			expectedError:   svcErr.ErrWalletNotFound,
-			expectedBalance: 0,
+			expectedBalance: entity.Money{},
 		},
 	}
 
@@ -224,3 +322,1293 @@ func TestBalance(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactions(t *testing.T) {
+	t.Parallel()
+
+	validUUID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	txID := "22222222-3c3c-5d5d-8e8e-0f0f1a2b3c4d"
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		walletID           string
+		query              entity.TransactionQuery
+		mockBehavior       func(mock *mocks.MockRepository)
+		expectedCount      int
+		expectedNextCursor bool
+		expectedError      error
+	}{
+		{
+			name:     "Last page",
+			walletID: validUUID,
+			query:    entity.TransactionQuery{Limit: 2},
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(&entity.Wallet{ID: validUUID}, nil)
+				mock.EXPECT().Transactions(gomock.Any(), entity.TransactionFilter{WalletID: validUUID, Limit: 3}).
+					Return([]entity.Transaction{{ID: txID, CreatedAt: createdAt}}, nil)
+			},
+			expectedCount: 1,
+		},
+		{
+			name:     "Has next page",
+			walletID: validUUID,
+			query:    entity.TransactionQuery{Limit: 1, OperationType: entity.OperationDeposit},
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(&entity.Wallet{ID: validUUID}, nil)
+				mock.EXPECT().Transactions(gomock.Any(), entity.TransactionFilter{
+					WalletID:      validUUID,
+					OperationType: entity.OperationDeposit,
+					Limit:         2,
+				}).Return([]entity.Transaction{{ID: txID, CreatedAt: createdAt}, {ID: validUUID}}, nil)
+			},
+			expectedCount:      1,
+			expectedNextCursor: true,
+		},
+		{
+			name:          "Invalid uuid format",
+			walletID:      "wallet-id",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Limit is too big",
+			walletID:      validUUID,
+			query:         entity.TransactionQuery{Limit: wallet.MaxTransactionsLimit + 1},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Invalid cursor",
+			walletID:      validUUID,
+			query:         entity.TransactionQuery{Cursor: "not a cursor"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Invalid date range",
+			walletID:      validUUID,
+			query:         entity.TransactionQuery{From: createdAt, To: createdAt},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:     "Wallet not found",
+			walletID: validUUID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(nil, repoErr.ErrWalletNotFound)
+			},
+			expectedError: svcErr.ErrWalletNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			page, err := service.Transactions(t.Context(), tt.walletID, tt.query)
+
+			if tt.expectedError != nil {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, page, "expected page to be nil on error")
+				return
+			}
+
+			require.NoError(t, err, "expected no error")
+			require.Len(t, page.Transactions, tt.expectedCount, "expected transactions count to match")
+			require.Equal(t, tt.expectedNextCursor, page.NextCursor != "", "expected next cursor presence to match")
+		})
+	}
+}
+
+func TestTransactions_CursorRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	validUUID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	txID := "22222222-3c3c-5d5d-8e8e-0f0f1a2b3c4d"
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+	service, mockRepo := setupTest(t)
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), validUUID).Return(&entity.Wallet{ID: validUUID}, nil).Times(2)
+	mockRepo.EXPECT().Transactions(gomock.Any(), entity.TransactionFilter{WalletID: validUUID, Limit: 2}).
+		Return([]entity.Transaction{{ID: txID, CreatedAt: createdAt}, {ID: validUUID}}, nil)
+	mockRepo.EXPECT().Transactions(gomock.Any(), entity.TransactionFilter{
+		WalletID: validUUID,
+		After:    &entity.TransactionCursor{CreatedAt: createdAt, ID: txID},
+		Limit:    2,
+	}).Return(nil, nil)
+
+	page, err := service.Transactions(t.Context(), validUUID, entity.TransactionQuery{Limit: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = service.Transactions(t.Context(), validUUID, entity.TransactionQuery{Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Empty(t, page.NextCursor)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	stored := &entity.IdempotencyKey{
+		Key:         "key",
+		Fingerprint: "fingerprint",
+		StatusCode:  200,
+		Response:    []byte(`{"success":true}`),
+	}
+
+	tests := []struct {
+		name          string
+		fingerprint   string
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedKey   *entity.IdempotencyKey
+		expectedError error
+	}{
+		{
+			name:        "Same request",
+			fingerprint: "fingerprint",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetIdempotencyKey(gomock.Any(), "key:test", "key").Return(stored, nil)
+			},
+			expectedKey: stored,
+		},
+		{
+			name:        "Different request",
+			fingerprint: "other-fingerprint",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetIdempotencyKey(gomock.Any(), "key:test", "key").Return(stored, nil)
+			},
+			expectedError: svcErr.ErrIdempotencyKeyMismatch,
+		},
+		{
+			name:        "Unknown key",
+			fingerprint: "fingerprint",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetIdempotencyKey(gomock.Any(), "key:test", "key").Return(nil, repoErr.ErrIdempotencyKeyNotFound)
+			},
+			expectedError: svcErr.ErrIdempotencyKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			key, err := service.IdempotencyKey(t.Context(), "key:test", "key", tt.fingerprint)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedKey, key, "expected key to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, key, "expected key to be nil on error")
+			}
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	t.Parallel()
+
+	fromUUID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	toUUID := "22222222-3c3c-5d5d-8e8e-0f0f1a2b3c4d"
+
+	tests := []struct {
+		name          string
+		fromWalletID  string
+		toWalletID    string
+		amount        entity.Money
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name:         "Ok",
+			fromWalletID: fromUUID,
+			toWalletID:   toUUID,
+			amount:       rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Transfer(gomock.Any(), entity.Transfer{
+					FromWalletID: fromUUID,
+					ToWalletID:   toUUID,
+					Amount:       rub(100),
+				}).Return(&entity.TransferResult{}, nil)
+			},
+		},
+		{
+			name:          "Same wallet",
+			fromWalletID:  fromUUID,
+			toWalletID:    fromUUID,
+			amount:        rub(100),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Same wallet in different case",
+			fromWalletID:  "AAAAAAAA-2B2B-4C4C-8D8D-0E0E1F2A3B4C",
+			toWalletID:    "aaaaaaaa-2b2b-4c4c-8d8d-0e0e1f2a3b4c",
+			amount:        rub(100),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:         "Upper case IDs",
+			fromWalletID: "11111111-2B2B-4C4C-8D8D-0E0E1F2A3B4C",
+			toWalletID:   "22222222-3C3C-5D5D-8E8E-0F0F1A2B3C4D",
+			amount:       rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Transfer(gomock.Any(), entity.Transfer{
+					FromWalletID: fromUUID,
+					ToWalletID:   toUUID,
+					Amount:       rub(100),
+				}).Return(&entity.TransferResult{}, nil)
+			},
+		},
+		{
+			name:         "Same wallet row",
+			fromWalletID: fromUUID,
+			toWalletID:   toUUID,
+			amount:       rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrSameWallet)
+			},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Invalid uuid format",
+			fromWalletID:  fromUUID,
+			toWalletID:    "wallet-id",
+			amount:        rub(100),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Amount is zero",
+			fromWalletID:  fromUUID,
+			toWalletID:    toUUID,
+			amount:        rub(0),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:         "Wallet not found",
+			fromWalletID: fromUUID,
+			toWalletID:   toUUID,
+			amount:       rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrWalletNotFound)
+			},
+			expectedError: svcErr.ErrWalletNotFound,
+		},
+		{
+			name:         "Currency mismatch",
+			fromWalletID: fromUUID,
+			toWalletID:   toUUID,
+			amount:       entity.NewMoney(100, entity.CurrencyUSD),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrCurrencyMismatch)
+			},
+			expectedError: svcErr.ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			err := service.Transfer(t.Context(), tt.fromWalletID, tt.toWalletID, tt.amount)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	validUUID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	negativeLimit := int64(-1)
+
+	tests := []struct {
+		name           string
+		walletID       string
+		overdraftLimit *int64
+		currency       entity.Currency
+		mockBehavior   func(mock *mocks.MockRepository)
+		expectedError  error
+	}{
+		{
+			name:     "Client chosen ID",
+			walletID: validUUID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Create(gomock.Any(), entity.Wallet{ID: validUUID}).Return(&entity.Wallet{ID: validUUID}, nil)
+			},
+		},
+		{
+			name:     "Generated ID",
+			walletID: "",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Create(gomock.Any(), gomock.Not(entity.Wallet{})).Return(&entity.Wallet{ID: validUUID}, nil)
+			},
+		},
+		{
+			name:     "With currency",
+			walletID: validUUID,
+			currency: entity.CurrencyEUR,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Create(gomock.Any(), entity.Wallet{
+					ID:      validUUID,
+					Balance: entity.NewMoney(0, entity.CurrencyEUR),
+				}).Return(&entity.Wallet{ID: validUUID, Balance: entity.NewMoney(0, entity.CurrencyEUR)}, nil)
+			},
+		},
+		{
+			name:          "Unsupported currency",
+			walletID:      validUUID,
+			currency:      "GBP",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Invalid uuid format",
+			walletID:      "wallet-id",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:           "Negative overdraft limit",
+			walletID:       validUUID,
+			overdraftLimit: &negativeLimit,
+			mockBehavior:   func(mock *mocks.MockRepository) {},
+			expectedError:  svcErr.ErrInvalidParams,
+		},
+		{
+			name:     "Already exists",
+			walletID: validUUID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Create(gomock.Any(), entity.Wallet{ID: validUUID}).Return(nil, repoErr.ErrWalletAlreadyExists)
+			},
+			expectedError: svcErr.ErrWalletAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			wallet, err := service.Create(t.Context(), entity.Wallet{
+				ID:             tt.walletID,
+				Balance:        entity.NewMoney(0, tt.currency),
+				OverdraftLimit: tt.overdraftLimit,
+			})
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.NotNil(t, wallet, "expected wallet to be returned")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, wallet, "expected wallet to be nil on error")
+			}
+		})
+	}
+}
+
+func TestIsWalletOwner(t *testing.T) {
+	t.Parallel()
+
+	validUUID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+
+	tests := []struct {
+		name          string
+		walletID      string
+		subject       string
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedOwner bool
+		expectedError error
+	}{
+		{
+			name:     "Owner",
+			walletID: validUUID,
+			subject:  "user-1",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(&entity.Wallet{ID: validUUID, OwnerID: "user-1"}, nil)
+			},
+			expectedOwner: true,
+		},
+		{
+			name:     "Other owner",
+			walletID: validUUID,
+			subject:  "user-2",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(&entity.Wallet{ID: validUUID, OwnerID: "user-1"}, nil)
+			},
+		},
+		{
+			name:     "No owner",
+			walletID: validUUID,
+			subject:  "",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(&entity.Wallet{ID: validUUID}, nil)
+			},
+		},
+		{
+			name:     "Wallet not found",
+			walletID: validUUID,
+			subject:  "user-1",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), validUUID).Return(nil, repoErr.ErrWalletNotFound)
+			},
+			expectedError: svcErr.ErrWalletNotFound,
+		},
+		{
+			name:          "Invalid uuid format",
+			walletID:      "wallet-id",
+			subject:       "user-1",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			owner, err := service.IsWalletOwner(t.Context(), tt.walletID, tt.subject)
+
+			require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			require.Equal(t, tt.expectedOwner, owner, "expected ownership to match")
+		})
+	}
+}
+
+func TestAssignOwner(t *testing.T) {
+	t.Parallel()
+
+	validUUID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+
+	tests := []struct {
+		name           string
+		walletID       string
+		ownerID        string
+		mockBehavior   func(mock *mocks.MockRepository)
+		expectedWallet *entity.Wallet
+		expectedError  error
+	}{
+		{
+			name:     "Success",
+			walletID: strings.ToUpper(validUUID),
+			ownerID:  " user-1 ",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().AssignOwner(gomock.Any(), validUUID, "user-1").
+					Return(&entity.Wallet{ID: validUUID, OwnerID: "user-1"}, nil)
+			},
+			expectedWallet: &entity.Wallet{ID: validUUID, OwnerID: "user-1"},
+		},
+		{
+			name:     "Wallet not found",
+			walletID: validUUID,
+			ownerID:  "user-1",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().AssignOwner(gomock.Any(), validUUID, "user-1").Return(nil, repoErr.ErrWalletNotFound)
+			},
+			expectedError: svcErr.ErrWalletNotFound,
+		},
+		{
+			name:          "Invalid uuid format",
+			walletID:      "wallet-id",
+			ownerID:       "user-1",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			wallet, err := service.AssignOwner(t.Context(), tt.walletID, tt.ownerID)
+
+			require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			require.Equal(t, tt.expectedWallet, wallet, "expected wallet to match")
+		})
+	}
+}
+
+func TestCreateHold(t *testing.T) {
+	t.Parallel()
+
+	validUUID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+
+	tests := []struct {
+		name          string
+		walletID      string
+		amount        entity.Money
+		ttl           time.Duration
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name:     "Default TTL",
+			walletID: validUUID,
+			amount:   rub(100),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().CreateHold(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ any, hold entity.Hold) (*entity.Hold, error) {
+						require.WithinDuration(t, time.Now().Add(wallet.DefaultHoldTTL), hold.ExpiresAt, time.Minute)
+						return &hold, nil
+					})
+			},
+		},
+		{
+			name:          "TTL exceeds maximum",
+			walletID:      validUUID,
+			amount:        rub(100),
+			ttl:           wallet.MaxHoldTTL + time.Second,
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Amount is zero",
+			walletID:      validUUID,
+			amount:        rub(0),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:     "Insufficient funds",
+			walletID: validUUID,
+			amount:   rub(100),
+			ttl:      time.Minute,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().CreateHold(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrInsufficientFunds)
+			},
+			expectedError: svcErr.ErrInsufficientFunds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			hold, err := service.CreateHold(t.Context(), tt.walletID, tt.amount, tt.ttl)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.NotNil(t, hold, "expected hold to be returned")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, hold, "expected hold to be nil")
+			}
+		})
+	}
+}
+
+func TestCaptureHold(t *testing.T) {
+	t.Parallel()
+
+	validUUID := "33333333-4d4d-4e4e-8f8f-1a1a2b3c4d5e"
+
+	tests := []struct {
+		name          string
+		holdID        string
+		amount        entity.Money
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name:   "Whole hold",
+			holdID: validUUID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().CaptureHold(gomock.Any(), entity.HoldCapture{HoldID: validUUID}).
+					Return(&entity.Hold{ID: validUUID, Status: entity.HoldStatusCaptured}, nil)
+			},
+		},
+		{
+			name:          "Negative amount",
+			holdID:        validUUID,
+			amount:        rub(-1),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:   "Not active",
+			holdID: validUUID,
+			amount: rub(10),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().CaptureHold(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrHoldNotActive)
+			},
+			expectedError: svcErr.ErrHoldNotActive,
+		},
+		{
+			name:   "Amount exceeded",
+			holdID: validUUID,
+			amount: rub(1000),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().CaptureHold(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrHoldAmountExceeded)
+			},
+			expectedError: svcErr.ErrHoldAmountExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			hold, err := service.CaptureHold(t.Context(), tt.holdID, tt.amount)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.NotNil(t, hold, "expected hold to be returned")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+		})
+	}
+}
+
+func TestReleaseExpiredHolds(t *testing.T) {
+	t.Parallel()
+
+	service, mockRepo := setupTest(t)
+
+	mockRepo.EXPECT().ExpiredHoldIDs(gomock.Any(), gomock.Any(), gomock.Any()).Return([]string{"a", "b", "c"}, nil)
+	mockRepo.EXPECT().ExpireHold(gomock.Any(), "a").Return(&entity.Hold{}, nil)
+	// Captured after it had been selected.
+	mockRepo.EXPECT().ExpireHold(gomock.Any(), "b").Return(nil, repoErr.ErrHoldNotActive)
+	mockRepo.EXPECT().ExpireHold(gomock.Any(), "c").Return(&entity.Hold{}, nil)
+
+	err := service.ReleaseExpiredHolds(t.Context())
+
+	require.NoError(t, err, "expected no error")
+}
+
+func TestReverse(t *testing.T) {
+	t.Parallel()
+
+	validUUID := "44444444-5e5e-4f4f-8a8a-1b1b2c3d4e5f"
+
+	tests := []struct {
+		name          string
+		transactionID string
+		amount        entity.Money
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name:          "Whole transaction",
+			transactionID: validUUID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Reverse(gomock.Any(), entity.Reversal{TransactionID: validUUID}).
+					Return(&entity.Transaction{ID: "reversal-id", OperationType: entity.OperationReversal}, nil)
+			},
+		},
+		{
+			name:          "Invalid transaction ID",
+			transactionID: "invalid",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Negative amount",
+			transactionID: validUUID,
+			amount:        rub(-1),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Not found",
+			transactionID: validUUID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Reverse(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrTransactionNotFound)
+			},
+			expectedError: svcErr.ErrTransactionNotFound,
+		},
+		{
+			name:          "Not reversible",
+			transactionID: validUUID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Reverse(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrNotReversible)
+			},
+			expectedError: svcErr.ErrNotReversible,
+		},
+		{
+			name:          "Amount exceeded",
+			transactionID: validUUID,
+			amount:        rub(1000),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Reverse(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrReversalAmountExceeded)
+			},
+			expectedError: svcErr.ErrReversalAmountExceeded,
+		},
+		{
+			name:          "Insufficient funds",
+			transactionID: validUUID,
+			amount:        rub(10),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Reverse(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrInsufficientFunds)
+			},
+			expectedError: svcErr.ErrInsufficientFunds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			transaction, err := service.Reverse(t.Context(), tt.transactionID, tt.amount)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.NotNil(t, transaction, "expected transaction to be returned")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+		})
+	}
+}
+
+func TestEnqueue(t *testing.T) {
+	t.Parallel()
+
+	operationID := "55555555-6f6f-4a4a-8b8b-1c1c2d3e4f5a"
+	walletID := "66666666-7a7a-4b4b-8c8c-1d1d2e3f4a5b"
+
+	tests := []struct {
+		name          string
+		operationID   string
+		operationType entity.OperationType
+		amount        entity.Money
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name:          "Withdraw",
+			operationID:   operationID,
+			operationType: entity.OperationWithdraw,
+			amount:        rub(50),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().EnqueueOperation(gomock.Any(), entity.QueuedOperation{
+					ID: operationID,
+					Operation: entity.Operation{
+						WalletID: walletID,
+						Amount:   rub(-50),
+						Type:     entity.OperationWithdraw,
+					},
+				}).Return(&entity.QueuedOperation{ID: operationID}, nil)
+			},
+		},
+		{
+			name:          "Invalid operation ID",
+			operationID:   "invalid",
+			operationType: entity.OperationDeposit,
+			amount:        rub(50),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Invalid operation type",
+			operationID:   operationID,
+			operationType: entity.OperationCapture,
+			amount:        rub(50),
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Wallet not found",
+			operationID:   operationID,
+			operationType: entity.OperationDeposit,
+			amount:        rub(50),
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().EnqueueOperation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrWalletNotFound)
+			},
+			expectedError: svcErr.ErrWalletNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			err := service.Enqueue(t.Context(), tt.operationID, walletID, tt.operationType, tt.amount)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+		})
+	}
+}
+
+func TestProcessQueuedOperations(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Until queue is empty", func(t *testing.T) {
+		t.Parallel()
+
+		service, mockRepo := setupTest(t)
+
+		gomock.InOrder(
+			mockRepo.EXPECT().ProcessQueuedOperation(gomock.Any()).
+				Return(&entity.QueuedOperation{Status: entity.QueuedOperationCompleted}, nil),
+			mockRepo.EXPECT().ProcessQueuedOperation(gomock.Any()).
+				Return(&entity.QueuedOperation{Status: entity.QueuedOperationFailed, FailureReason: "insufficient funds"}, nil),
+			mockRepo.EXPECT().ProcessQueuedOperation(gomock.Any()).Return(nil, nil),
+		)
+
+		err := service.ProcessQueuedOperations(t.Context())
+
+		require.NoError(t, err, "expected no error")
+	})
+
+	t.Run("Goes on after last attempt", func(t *testing.T) {
+		t.Parallel()
+
+		service, mockRepo := setupTest(t)
+
+		gomock.InOrder(
+			mockRepo.EXPECT().ProcessQueuedOperation(gomock.Any()).
+				Return(&entity.QueuedOperation{Status: entity.QueuedOperationFailed, FailureReason: "internal error"},
+					errors.New("gave up after 5 attempts: query error")),
+			mockRepo.EXPECT().ProcessQueuedOperation(gomock.Any()).Return(nil, nil),
+		)
+
+		err := service.ProcessQueuedOperations(t.Context())
+
+		require.NoError(t, err, "expected no error")
+	})
+
+	t.Run("Stops on error", func(t *testing.T) {
+		t.Parallel()
+
+		service, mockRepo := setupTest(t)
+
+		processErr := errors.New("process error")
+		mockRepo.EXPECT().ProcessQueuedOperation(gomock.Any()).Return(nil, processErr)
+
+		err := service.ProcessQueuedOperations(t.Context())
+
+		require.ErrorIs(t, err, processErr, "expected error to match")
+	})
+}
+
+// fullExecutor rejects every task as if the wallet queue were full.
+type fullExecutor struct{}
+
+func (fullExecutor) Do(context.Context, string, executor.Task) error {
+	return executor.ErrQueueFull
+}
+
+// recordingExecutor runs every task right away and records its key.
+type recordingExecutor struct {
+	keys []string
+}
+
+func (e *recordingExecutor) Do(ctx context.Context, key string, task executor.Task) error {
+	e.keys = append(e.keys, key)
+	return task(ctx)
+}
+
+func TestDeposit_Executor(t *testing.T) {
+	t.Parallel()
+
+	walletID := "77777777-8b8b-4c4c-8d8d-1e1e2f3a4b5c"
+	otherWalletID := "88888888-8b8b-4c4c-8d8d-1e1e2f3a4b5c"
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("Serialized", func(t *testing.T) {
+		t.Parallel()
+
+		mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+		exec := executor.New(2, 10)
+		t.Cleanup(exec.Stop)
+		service := wallet.New(log, mockRepo, wallet.WithExecutor(exec))
+
+		mockRepo.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(&entity.Transaction{}, nil)
+
+		err := service.Deposit(t.Context(), walletID, rub(100))
+
+		require.NoError(t, err, "expected no error")
+		require.Equal(t, int64(1), exec.Stats().Completed, "expected the deposit to run on the executor")
+	})
+
+	t.Run("Canonical key", func(t *testing.T) {
+		t.Parallel()
+
+		mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+		exec := &recordingExecutor{}
+		service := wallet.New(log, mockRepo, wallet.WithExecutor(exec))
+
+		mockRepo.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(&entity.Transaction{}, nil)
+		mockRepo.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(&entity.TransferResult{}, nil)
+
+		require.NoError(t, service.Deposit(t.Context(), strings.ToUpper(walletID), rub(100)))
+		require.NoError(t, service.Transfer(t.Context(), strings.ToUpper(walletID), otherWalletID, rub(100)))
+
+		// Every spelling of a wallet ID must land on the same shard, or changes of one
+		// wallet would run concurrently and wait for the row lock.
+		require.Equal(t, []string{walletID, walletID}, exec.keys, "expected the canonical wallet ID as the key")
+	})
+
+	t.Run("Queue full", func(t *testing.T) {
+		t.Parallel()
+
+		mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+		service := wallet.New(log, mockRepo, wallet.WithExecutor(fullExecutor{}))
+
+		err := service.Deposit(t.Context(), walletID, rub(100))
+
+		require.ErrorIs(t, err, svcErr.ErrBusy, "expected error to match")
+	})
+}
+
+func TestDeposit_BroadcastsBalanceChange(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+	broadcaster := broadcast.New[entity.BalanceChange](10, 10, time.Minute)
+	service := wallet.New(log, mockRepo, wallet.WithBroadcaster(broadcaster))
+
+	walletID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	transaction := &entity.Transaction{
+		ID:            "22222222-2b2b-4c4c-8d8d-0e0e1f2a3b4c",
+		WalletID:      walletID,
+		Amount:        100,
+		OperationType: entity.OperationDeposit,
+		BalanceAfter:  300,
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), walletID).Return(&entity.Wallet{ID: walletID}, nil)
+	mockRepo.EXPECT().LastSequence(gomock.Any(), walletID).Return(int64(0), nil)
+	mockRepo.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(transaction, nil)
+
+	sub, _, err := service.WatchBalance(t.Context(), walletID, "")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	require.NoError(t, service.Deposit(t.Context(), walletID, rub(100)))
+
+	msg := <-sub.C
+	require.Equal(t, entity.NewBalanceChange(transaction), msg.Value, "expected the committed change to be broadcast")
+}
+
+func TestTransfer_BroadcastsInSequenceOrder(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+	broadcaster := broadcast.New[entity.BalanceChange](10, 10, time.Minute, broadcast.WithGapTimeout(time.Hour))
+	service := wallet.New(log, mockRepo, wallet.WithBroadcaster(broadcaster))
+
+	fromWalletID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	toWalletID := "33333333-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	credit := &entity.Transaction{
+		ID:            "22222222-2b2b-4c4c-8d8d-0e0e1f2a3b4c",
+		WalletID:      toWalletID,
+		Amount:        100,
+		OperationType: entity.OperationTransferIn,
+		BalanceAfter:  100,
+		Sequence:      1,
+	}
+	deposit := &entity.Transaction{
+		ID:            "44444444-2b2b-4c4c-8d8d-0e0e1f2a3b4c",
+		WalletID:      toWalletID,
+		Amount:        50,
+		OperationType: entity.OperationDeposit,
+		BalanceAfter:  150,
+		Sequence:      2,
+	}
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), toWalletID).Return(&entity.Wallet{ID: toWalletID}, nil)
+	mockRepo.EXPECT().LastSequence(gomock.Any(), toWalletID).Return(int64(0), nil)
+	mockRepo.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(deposit, nil)
+	mockRepo.EXPECT().Transfer(gomock.Any(), gomock.Any()).
+		Return(&entity.TransferResult{Debit: &entity.Transaction{WalletID: fromWalletID}, Credit: credit}, nil)
+
+	sub, _, err := service.WatchBalance(t.Context(), toWalletID, "")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// The deposit committed after the transfer is published first, e.g. because the
+	// credit is published from the shard of the debited wallet.
+	require.NoError(t, service.Deposit(t.Context(), toWalletID, rub(50)))
+	require.Empty(t, sub.C, "expected the deposit to wait for the earlier credit")
+
+	require.NoError(t, service.Transfer(t.Context(), fromWalletID, toWalletID, rub(100)))
+
+	require.Equal(t, entity.NewBalanceChange(credit), (<-sub.C).Value, "expected the credit first")
+	require.Equal(t, entity.NewBalanceChange(deposit), (<-sub.C).Value, "expected the deposit after it")
+}
+
+func TestWatchBalance(t *testing.T) {
+	t.Parallel()
+
+	walletID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	dbErr := errors.New("db error")
+
+	tests := []struct {
+		name          string
+		walletID      string
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name:     "Ok",
+			walletID: walletID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), walletID).Return(&entity.Wallet{ID: walletID}, nil)
+				mock.EXPECT().LastSequence(gomock.Any(), walletID).Return(int64(7), nil)
+			},
+		},
+		{
+			name:          "Invalid uuid format",
+			walletID:      "wallet-id",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:     "Wallet not found",
+			walletID: walletID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), walletID).Return(nil, repoErr.ErrWalletNotFound)
+			},
+			expectedError: svcErr.ErrWalletNotFound,
+		},
+		{
+			name:     "Last sequence fails",
+			walletID: walletID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().GetByID(gomock.Any(), walletID).Return(&entity.Wallet{ID: walletID}, nil)
+				mock.EXPECT().LastSequence(gomock.Any(), walletID).Return(int64(0), dbErr)
+			},
+			expectedError: dbErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+			broadcaster := broadcast.New[entity.BalanceChange](10, 10, time.Minute)
+			service := wallet.New(log, mockRepo, wallet.WithBroadcaster(broadcaster))
+
+			tt.mockBehavior(mockRepo)
+
+			sub, w, err := service.WatchBalance(t.Context(), tt.walletID, "")
+
+			require.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError != nil {
+				return
+			}
+			defer sub.Close()
+			require.Equal(t, walletID, w.ID, "expected the wallet snapshot")
+			require.False(t, sub.Resumed, "expected a new stream not to be resumed")
+		})
+	}
+}
+
+func TestWatchBalance_CanonicalKey(t *testing.T) {
+	t.Parallel()
+
+	walletID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+	broadcaster := broadcast.New[entity.BalanceChange](10, 10, time.Minute)
+	service := wallet.New(log, mockRepo, wallet.WithBroadcaster(broadcaster))
+
+	mockRepo.EXPECT().GetByID(gomock.Any(), walletID).Return(&entity.Wallet{ID: walletID}, nil)
+	mockRepo.EXPECT().LastSequence(gomock.Any(), walletID).Return(int64(0), nil)
+
+	sub, _, err := service.WatchBalance(t.Context(), strings.ToUpper(walletID), "")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// Changes are published under the wallet ID as stored.
+	broadcaster.Publish(walletID, entity.BalanceChange{WalletID: walletID, Amount: 100})
+
+	select {
+	case msg := <-sub.C:
+		require.Equal(t, int64(100), msg.Value.Amount)
+	default:
+		t.Fatal("expected the change published under the canonical wallet ID")
+	}
+}
+
+// operationsRecorder records the results passed to Metrics.OperationCompleted.
+type operationsRecorder struct {
+	results []error
+}
+
+func (r *operationsRecorder) OperationCompleted(_ entity.OperationType, err error) {
+	r.results = append(r.results, err)
+}
+
+func TestWithdraw_CountsResult(t *testing.T) {
+	t.Parallel()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockRepo := mocks.NewMockRepository(gomock.NewController(t))
+	recorder := &operationsRecorder{}
+	service := wallet.New(log, mockRepo, wallet.WithMetrics(recorder))
+
+	walletID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+
+	mockRepo.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(&entity.Transaction{}, nil)
+	mockRepo.EXPECT().Operation(gomock.Any(), gomock.Any()).Return(nil, repoErr.ErrInsufficientFunds)
+
+	require.NoError(t, service.Withdraw(t.Context(), walletID, rub(100)))
+	require.ErrorIs(t, service.Withdraw(t.Context(), walletID, rub(100)), svcErr.ErrInsufficientFunds)
+	require.ErrorIs(t, service.Withdraw(t.Context(), "invalid", rub(100)), svcErr.ErrInvalidParams)
+
+	require.Equal(t, []error{nil, svcErr.ErrInsufficientFunds, svcErr.ErrInvalidParams}, recorder.results)
+}
+
+func TestCloseWallet(t *testing.T) {
+	t.Parallel()
+
+	walletID := "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	sweepToWalletID := "22222222-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+
+	tests := []struct {
+		name            string
+		walletID        string
+		reason          string
+		sweepToWalletID string
+		mockBehavior    func(mock *mocks.MockRepository)
+		expectedError   error
+	}{
+		{
+			name:            "Ok",
+			walletID:        walletID,
+			reason:          " customer request ",
+			sweepToWalletID: sweepToWalletID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().ChangeStatus(gomock.Any(), entity.WalletStatusChange{
+					WalletID:        walletID,
+					Status:          entity.WalletStatusClosed,
+					Reason:          "customer request",
+					SweepToWalletID: sweepToWalletID,
+				}).Return(&entity.Wallet{ID: walletID, Status: entity.WalletStatusClosed}, nil, nil)
+			},
+		},
+		{
+			name:          "Reason is empty",
+			walletID:      walletID,
+			reason:        "  ",
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:            "Sweep to itself",
+			walletID:        walletID,
+			reason:          "customer request",
+			sweepToWalletID: walletID,
+			mockBehavior:    func(mock *mocks.MockRepository) {},
+			expectedError:   svcErr.ErrInvalidParams,
+		},
+		{
+			name:            "Sweep to itself in different case",
+			walletID:        walletID,
+			reason:          "customer request",
+			sweepToWalletID: "11111111-2B2B-4C4C-8D8D-0E0E1F2A3B4C",
+			mockBehavior:    func(mock *mocks.MockRepository) {},
+			expectedError:   svcErr.ErrInvalidParams,
+		},
+		{
+			name:     "Wallet not empty",
+			walletID: walletID,
+			reason:   "customer request",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().ChangeStatus(gomock.Any(), gomock.Any()).Return(nil, nil, repoErr.ErrWalletNotEmpty)
+			},
+			expectedError: svcErr.ErrWalletNotEmpty,
+		},
+		{
+			name:     "Already closed",
+			walletID: walletID,
+			reason:   "customer request",
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().ChangeStatus(gomock.Any(), gomock.Any()).Return(nil, nil, repoErr.ErrWalletStatusTransition)
+			},
+			expectedError: svcErr.ErrWalletStatusTransition,
+		},
+		{
+			name:            "Sweep to frozen wallet",
+			walletID:        walletID,
+			reason:          "customer request",
+			sweepToWalletID: sweepToWalletID,
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().ChangeStatus(gomock.Any(), gomock.Any()).Return(nil, nil, repoErr.ErrWalletFrozen)
+			},
+			expectedError: svcErr.ErrWalletFrozen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			tt.mockBehavior(mockRepo)
+
+			wallet, err := service.CloseWallet(t.Context(), tt.walletID, tt.reason, tt.sweepToWalletID)
+
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, entity.WalletStatusClosed, wallet.Status, "expected wallet to be closed")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, wallet, "expected wallet to be nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Resolver looks up the IP addresses of the host of a webhook.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// errInternalAddress is returned for webhooks that point into the network of the
+// service, e.g. at the database or a cloud metadata endpoint.
+var errInternalAddress = errors.New("address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't reachable from
+// the internet either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublic reports whether deliveries may be sent to the address. Loopback,
+// private, link-local, multicast and unspecified addresses are internal.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// checkHost resolves the host of the webhook URL and returns [errInternalAddress]
+// if any of its addresses is not public.
+func (s *Service) checkHost(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+
+	addrs := []netip.Addr{}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		addrs, err = s.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+	}
+
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", errInternalAddress, host, addr)
+		}
+	}
+
+	return nil
+}
+
+// checkDialAddress is the [net.Dialer] Control function of the delivery client.
+// It checks the address that is actually dialed, so a host that resolved to a
+// public address when the webhook was created can't be pointed at an internal
+// one later.
+func checkDialAddress(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errInternalAddress, addrPort.Addr())
+	}
+
+	return nil
+}
+
+// newClient returns the client deliveries are sent with by default. It connects
+// only to public addresses and doesn't follow redirects, so the response to a
+// redirect counts as a failed attempt. Proxies from the environment aren't used,
+// since the address of the proxy is all the dialer would see.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDialAddress,
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
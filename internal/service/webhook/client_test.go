@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.215.14", want: true},
+		{addr: "2606:2800:21f:cb07:6820:80da:af6b:8b2c", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "fd00::1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "0.0.0.0"},
+		{addr: "::"},
+		{addr: "224.0.0.1"},
+		{addr: "100.64.0.1"},
+		{addr: "::ffff:127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, isPublic(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestNewClient_RefusesInternalAddress(t *testing.T) {
+	t.Parallel()
+
+	var hits int
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		hits++
+	}))
+	t.Cleanup(receiver.Close)
+
+	_, err := newClient().Get(receiver.URL)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errInternalAddress, "expected the dial to be refused")
+	assert.Zero(t, hits, "expected the receiver not to be reached")
+}
+
+func TestNewClient_DoesNotFollowRedirects(t *testing.T) {
+	t.Parallel()
+
+	client := newClient()
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/hook", nil)
+
+	assert.ErrorIs(t, client.CheckRedirect(req, []*http.Request{req}), http.ErrUseLastResponse)
+}
@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/publisher"
+)
+
+const (
+	HeaderWebhookID = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	// HeaderTimestamp is the Unix time the delivery was signed at. Receivers should
+	// reject deliveries with an old timestamp to prevent replays.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is "sha256=" followed by the hex encoded HMAC-SHA256 of
+	// "<timestamp>.<body>" keyed with the webhook secret.
+	HeaderSignature = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+
+	// dueDeliveriesBatchSize is the maximum number of deliveries attempted by one run.
+	dueDeliveriesBatchSize = 100
+	// leaseMargin is added to the timeout when deliveries are claimed, so a delivery
+	// isn't claimed again while its attempt is still being recorded.
+	leaseMargin = 30 * time.Second
+	// maxErrorLength limits the part of a response body stored as the attempt error.
+	maxErrorLength = 512
+)
+
+// Publish queues the event for every webhook subscribed to it. The event is queued
+// for a webhook only once, so it is safe to publish it again.
+func (s *Service) Publish(ctx context.Context, event entity.Event) error {
+	const op = "service.webhook.Publish"
+
+	log := s.log.With(
+		"op", op,
+		"eventID", event.ID,
+		"type", event.Type,
+	)
+
+	payload, err := publisher.Encode(event)
+	if err != nil {
+		log.Error("failed to encode event", "err", err)
+
+		return err
+	}
+
+	queued, err := s.repo.EnqueueDeliveries(ctx, event, payload)
+	if err != nil {
+		log.Error("failed to queue deliveries", "err", err)
+
+		return err
+	}
+
+	if queued > 0 {
+		log.Info("deliveries queued", "count", queued)
+	}
+
+	return nil
+}
+
+// DeliverDue attempts the pending deliveries whose next attempt is due. A delivery
+// succeeds when the webhook responds with a 2xx status. A failed delivery is retried
+// with exponential backoff and jitter, and moved to the dead-letter state once it has
+// been attempted the maximum number of times.
+func (s *Service) DeliverDue(ctx context.Context) error {
+	const op = "service.webhook.DeliverDue"
+
+	log := s.log.With("op", op)
+
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, dueDeliveriesBatchSize, s.timeout+leaseMargin)
+	if err != nil {
+		log.Error("failed to claim deliveries", "err", err)
+
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+
+		delivered, failed, dead int
+	)
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			status, err := s.deliver(ctx, delivery)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			switch status {
+			case entity.WebhookDeliveryDelivered:
+				delivered++
+			case entity.WebhookDeliveryDead:
+				dead++
+			default:
+				failed++
+			}
+		}()
+	}
+	wg.Wait()
+
+	log.Info("deliveries attempted", "delivered", delivered, "failed", failed, "dead", dead)
+
+	return errors.Join(errs...)
+}
+
+// deliver sends the delivery to its webhook and records the attempt. It returns
+// the status the delivery was moved to.
+func (s *Service) deliver(ctx context.Context, delivery entity.DueWebhookDelivery) (entity.WebhookDeliveryStatus, error) {
+	log := s.log.With(
+		"op", "service.webhook.deliver",
+		"webhookID", delivery.WebhookID,
+		"deliveryID", delivery.ID,
+		"attempt", delivery.Attempts+1,
+	)
+
+	attemptedAt := time.Now()
+	statusCode, sendErr := s.send(ctx, delivery, attemptedAt)
+
+	attempt := entity.WebhookAttempt{
+		DeliveryID:  delivery.ID,
+		StatusCode:  statusCode,
+		Duration:    time.Since(attemptedAt),
+		AttemptedAt: attemptedAt,
+	}
+
+	status := entity.WebhookDeliveryDelivered
+	nextAttemptAt := attemptedAt
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+
+		status = entity.WebhookDeliveryPending
+		if delivery.Attempts+1 >= s.maxAttempts {
+			status = entity.WebhookDeliveryDead
+		}
+		nextAttemptAt = attemptedAt.Add(s.backoff(delivery.Attempts + 1))
+
+		log.Warn("delivery failed", "statusCode", statusCode, "err", sendErr, "status", status)
+	}
+
+	if err := s.repo.RecordAttempt(ctx, attempt, status, nextAttemptAt); err != nil {
+		log.Error("failed to record attempt", "err", err)
+
+		return "", err
+	}
+
+	return status, nil
+}
+
+// send posts the delivery payload to the webhook. It returns the response status
+// code, which is zero if no response was received, and an error unless the status
+// is 2xx.
+func (s *Service) send(ctx context.Context, delivery entity.DueWebhookDelivery, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookID, delivery.WebhookID)
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d: %s",
+			resp.StatusCode, strings.ToValidUTF8(string(body), ""))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the attempt that follows the given one: the base
+// backoff doubled for every previous attempt and capped at the maximum, of which a
+// random half is used, so deliveries failed together aren't retried together.
+func (s *Service) backoff(attempt int) time.Duration {
+	d := s.maxBackoff
+	if shift := attempt - 1; shift < 32 && s.baseBackoff<<shift < s.maxBackoff && s.baseBackoff<<shift > 0 {
+		d = s.baseBackoff << shift
+	}
+	if d <= 1 {
+		return d
+	}
+
+	return d/2 + rand.N(d/2)
+}
+
+// Sign returns the value of the [HeaderSignature] header of a delivery.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,177 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/passwordhash/asynchronous-wallet/internal/service/webhook (interfaces: Repository)
+//
+// Generated by this command:
+//
+//	mockgen -destination=./mocks/mock_repository.go -package=mocks github.com/passwordhash/asynchronous-wallet/internal/service/webhook Repository
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	entity "github.com/passwordhash/asynchronous-wallet/internal/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimDueDeliveries mocks base method.
+func (m *MockRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.DueWebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDueDeliveries", ctx, limit, lease)
+	ret0, _ := ret[0].([]entity.DueWebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDueDeliveries indicates an expected call of ClaimDueDeliveries.
+func (mr *MockRepositoryMockRecorder) ClaimDueDeliveries(ctx, limit, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDueDeliveries", reflect.TypeOf((*MockRepository)(nil).ClaimDueDeliveries), ctx, limit, lease)
+}
+
+// Create mocks base method.
+func (m *MockRepository) Create(ctx context.Context, webhook entity.Webhook) (*entity.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, webhook)
+	ret0, _ := ret[0].(*entity.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRepositoryMockRecorder) Create(ctx, webhook any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, webhook)
+}
+
+// Deactivate mocks base method.
+func (m *MockRepository) Deactivate(ctx context.Context, webhookID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deactivate", ctx, webhookID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Deactivate indicates an expected call of Deactivate.
+func (mr *MockRepositoryMockRecorder) Deactivate(ctx, webhookID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deactivate", reflect.TypeOf((*MockRepository)(nil).Deactivate), ctx, webhookID)
+}
+
+// Deliveries mocks base method.
+func (m *MockRepository) Deliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deliveries", ctx, filter)
+	ret0, _ := ret[0].([]entity.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Deliveries indicates an expected call of Deliveries.
+func (mr *MockRepositoryMockRecorder) Deliveries(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deliveries", reflect.TypeOf((*MockRepository)(nil).Deliveries), ctx, filter)
+}
+
+// EnqueueDeliveries mocks base method.
+func (m *MockRepository) EnqueueDeliveries(ctx context.Context, event entity.Event, payload []byte) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueDeliveries", ctx, event, payload)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueDeliveries indicates an expected call of EnqueueDeliveries.
+func (mr *MockRepositoryMockRecorder) EnqueueDeliveries(ctx, event, payload any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueDeliveries", reflect.TypeOf((*MockRepository)(nil).EnqueueDeliveries), ctx, event, payload)
+}
+
+// GetByID mocks base method.
+func (m *MockRepository) GetByID(ctx context.Context, webhookID string) (*entity.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, webhookID)
+	ret0, _ := ret[0].(*entity.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockRepositoryMockRecorder) GetByID(ctx, webhookID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), ctx, webhookID)
+}
+
+// GetDelivery mocks base method.
+func (m *MockRepository) GetDelivery(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, []entity.WebhookAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelivery", ctx, webhookID, deliveryID)
+	ret0, _ := ret[0].(*entity.WebhookDelivery)
+	ret1, _ := ret[1].([]entity.WebhookAttempt)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetDelivery indicates an expected call of GetDelivery.
+func (mr *MockRepositoryMockRecorder) GetDelivery(ctx, webhookID, deliveryID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelivery", reflect.TypeOf((*MockRepository)(nil).GetDelivery), ctx, webhookID, deliveryID)
+}
+
+// RecordAttempt mocks base method.
+func (m *MockRepository) RecordAttempt(ctx context.Context, attempt entity.WebhookAttempt, status entity.WebhookDeliveryStatus, nextAttemptAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAttempt", ctx, attempt, status, nextAttemptAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAttempt indicates an expected call of RecordAttempt.
+func (mr *MockRepositoryMockRecorder) RecordAttempt(ctx, attempt, status, nextAttemptAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAttempt", reflect.TypeOf((*MockRepository)(nil).RecordAttempt), ctx, attempt, status, nextAttemptAt)
+}
+
+// Redeliver mocks base method.
+func (m *MockRepository) Redeliver(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeliver", ctx, webhookID, deliveryID)
+	ret0, _ := ret[0].(*entity.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Redeliver indicates an expected call of Redeliver.
+func (mr *MockRepositoryMockRecorder) Redeliver(ctx, webhookID, deliveryID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeliver", reflect.TypeOf((*MockRepository)(nil).Redeliver), ctx, webhookID, deliveryID)
+}
@@ -0,0 +1,400 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+//go:generate mockgen -destination=./mocks/mock_repository.go -package=mocks github.com/passwordhash/asynchronous-wallet/internal/service/webhook Repository
+type Repository interface {
+	Create(ctx context.Context, webhook entity.Webhook) (*entity.Webhook, error)
+	GetByID(ctx context.Context, webhookID string) (*entity.Webhook, error)
+	Deactivate(ctx context.Context, webhookID string) error
+	EnqueueDeliveries(ctx context.Context, event entity.Event, payload []byte) (int64, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.DueWebhookDelivery, error)
+	RecordAttempt(
+		ctx context.Context,
+		attempt entity.WebhookAttempt,
+		status entity.WebhookDeliveryStatus,
+		nextAttemptAt time.Time,
+	) error
+	Deliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, []entity.WebhookAttempt, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, error)
+}
+
+// Auditor appends the records of completed changes to the audit log.
+type Auditor interface {
+	Record(ctx context.Context, record entity.AuditRecord)
+}
+
+const (
+	DefaultMaxAttempts = 8
+	DefaultBaseBackoff = 5 * time.Second
+	DefaultMaxBackoff  = time.Hour
+	DefaultTimeout     = 10 * time.Second
+
+	// DefaultDeliveriesLimit is the number of deliveries listed when no limit is given.
+	DefaultDeliveriesLimit = 50
+	MaxDeliveriesLimit     = 500
+
+	// secretSize is the number of random bytes in a generated secret.
+	secretSize = 32
+)
+
+type Service struct {
+	log    *slog.Logger
+	repo   Repository
+	client *http.Client
+	// resolver resolves the hosts of webhooks being created.
+	resolver Resolver
+	// auditor is nil if changes aren't audited.
+	auditor Auditor
+
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	timeout     time.Duration
+}
+
+type Option func(*Service)
+
+// WithHTTPClient sets the client deliveries are sent with. The default client
+// connects only to public addresses and doesn't follow redirects; the client
+// given replaces those checks.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Service) {
+		s.client = client
+	}
+}
+
+// WithResolver sets the resolver the hosts of webhooks are checked to be public with.
+func WithResolver(resolver Resolver) Option {
+	return func(s *Service) {
+		s.resolver = resolver
+	}
+}
+
+// WithRetryPolicy sets how many times a delivery is attempted before it is moved
+// to the dead-letter state, and the bounds of the backoff between the attempts.
+func WithRetryPolicy(maxAttempts int, baseBackoff, maxBackoff time.Duration) Option {
+	return func(s *Service) {
+		s.maxAttempts = maxAttempts
+		s.baseBackoff = baseBackoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// WithTimeout sets how long a webhook has to respond to a delivery.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		s.timeout = timeout
+	}
+}
+
+// WithAuditor records the creation and deletion of webhooks and redeliveries in
+// the audit log of the auditor.
+func WithAuditor(auditor Auditor) Option {
+	return func(s *Service) {
+		s.auditor = auditor
+	}
+}
+
+func New(
+	log *slog.Logger,
+	repo Repository,
+	opts ...Option,
+) *Service {
+	s := &Service{
+		log:         log,
+		repo:        repo,
+		client:      newClient(),
+		resolver:    net.DefaultResolver,
+		maxAttempts: DefaultMaxAttempts,
+		baseBackoff: DefaultBaseBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+		timeout:     DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Create registers a webhook. If params.Secret is empty, a random one is generated.
+// The host of params.URL must resolve to public addresses only.
+// The returned webhook is the only place the generated secret is shown.
+func (s *Service) Create(ctx context.Context, params entity.Webhook) (*entity.Webhook, error) {
+	const op = "service.webhook.Create"
+
+	log := s.log.With(
+		"op", op,
+		"url", params.URL,
+	)
+
+	if err := validateWebhook(params); err != nil {
+		log.Warn("invalid parameters", "err", err)
+
+		return nil, svcErr.ErrInvalidParams
+	}
+	if err := s.checkHost(ctx, params.URL); err != nil {
+		log.Warn("webhook url is not public", "err", err)
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	if params.Secret == "" {
+		params.Secret = newSecret()
+	}
+
+	webhook, err := s.repo.Create(ctx, entity.Webhook{
+		URL:        params.URL,
+		Secret:     params.Secret,
+		EventTypes: params.EventTypes,
+		WalletIDs:  params.WalletIDs,
+	})
+	if err != nil {
+		log.Error("failed to create webhook", "err", err)
+
+		return nil, err
+	}
+
+	s.audit(ctx, entity.AuditWebhookCreate, webhook.ID)
+
+	log.Info("webhook created", "webhookID", webhook.ID)
+
+	return webhook, nil
+}
+
+// Webhook returns the webhook with the given ID.
+func (s *Service) Webhook(ctx context.Context, webhookID string) (*entity.Webhook, error) {
+	const op = "service.webhook.Webhook"
+
+	log := s.log.With(
+		"op", op,
+		"webhookID", webhookID,
+	)
+
+	if uuid.Validate(webhookID) != nil {
+		log.Warn("invalid webhook ID format")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	webhook, err := s.repo.GetByID(ctx, webhookID)
+	if err != nil {
+		return nil, webhookError(log, err, "failed to get webhook")
+	}
+
+	log.Info("webhook retrieved")
+
+	return webhook, nil
+}
+
+// Delete deactivates the webhook: no new events are queued for it. The webhook and
+// its deliveries are kept, so they can still be inspected.
+func (s *Service) Delete(ctx context.Context, webhookID string) error {
+	const op = "service.webhook.Delete"
+
+	log := s.log.With(
+		"op", op,
+		"webhookID", webhookID,
+	)
+
+	if uuid.Validate(webhookID) != nil {
+		log.Warn("invalid webhook ID format")
+
+		return svcErr.ErrInvalidParams
+	}
+
+	if err := s.repo.Deactivate(ctx, webhookID); err != nil {
+		return webhookError(log, err, "failed to deactivate webhook")
+	}
+
+	s.audit(ctx, entity.AuditWebhookDelete, webhookID)
+
+	log.Info("webhook deactivated")
+
+	return nil
+}
+
+// Deliveries returns deliveries of the webhook, the newest first. If filter.Limit
+// is zero, [DefaultDeliveriesLimit] deliveries are returned.
+func (s *Service) Deliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error) {
+	const op = "service.webhook.Deliveries"
+
+	log := s.log.With(
+		"op", op,
+		"webhookID", filter.WebhookID,
+		"status", filter.Status,
+		"limit", filter.Limit,
+	)
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultDeliveriesLimit
+	}
+
+	if uuid.Validate(filter.WebhookID) != nil || filter.Limit < 0 || filter.Limit > MaxDeliveriesLimit {
+		log.Warn("invalid parameters")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+	switch filter.Status {
+	case "", entity.WebhookDeliveryPending, entity.WebhookDeliveryDelivered, entity.WebhookDeliveryDead:
+	default:
+		log.Warn("invalid delivery status")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	// The webhook is looked up first, so an unknown webhook is told apart from
+	// one without deliveries.
+	if _, err := s.repo.GetByID(ctx, filter.WebhookID); err != nil {
+		return nil, webhookError(log, err, "failed to get webhook")
+	}
+
+	deliveries, err := s.repo.Deliveries(ctx, filter)
+	if err != nil {
+		log.Error("failed to get deliveries", "err", err)
+
+		return nil, err
+	}
+
+	log.Info("deliveries retrieved", "count", len(deliveries))
+
+	return deliveries, nil
+}
+
+// Delivery returns the delivery of the webhook together with its attempts.
+func (s *Service) Delivery(
+	ctx context.Context,
+	webhookID string,
+	deliveryID string,
+) (*entity.WebhookDelivery, []entity.WebhookAttempt, error) {
+	const op = "service.webhook.Delivery"
+
+	log := s.log.With(
+		"op", op,
+		"webhookID", webhookID,
+		"deliveryID", deliveryID,
+	)
+
+	if uuid.Validate(webhookID) != nil || uuid.Validate(deliveryID) != nil {
+		log.Warn("invalid ID format")
+
+		return nil, nil, svcErr.ErrInvalidParams
+	}
+
+	delivery, attempts, err := s.repo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, nil, webhookError(log, err, "failed to get delivery")
+	}
+
+	log.Info("delivery retrieved")
+
+	return delivery, attempts, nil
+}
+
+// Redeliver moves a dead delivery back to pending, so it is attempted again with a
+// fresh attempt budget.
+func (s *Service) Redeliver(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, error) {
+	const op = "service.webhook.Redeliver"
+
+	log := s.log.With(
+		"op", op,
+		"webhookID", webhookID,
+		"deliveryID", deliveryID,
+	)
+
+	if uuid.Validate(webhookID) != nil || uuid.Validate(deliveryID) != nil {
+		log.Warn("invalid ID format")
+
+		return nil, svcErr.ErrInvalidParams
+	}
+
+	delivery, err := s.repo.Redeliver(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, webhookError(log, err, "failed to redeliver")
+	}
+
+	s.audit(ctx, entity.AuditWebhookRedeliver, deliveryID)
+
+	log.Info("delivery scheduled for redelivery")
+
+	return delivery, nil
+}
+
+// audit records the action on the webhook or delivery with the ID, if the service
+// has an auditor.
+func (s *Service) audit(ctx context.Context, action entity.AuditAction, resourceID string) {
+	if s.auditor == nil {
+		return
+	}
+	s.auditor.Record(ctx, entity.AuditRecord{
+		Action:     action,
+		ResourceID: resourceID,
+	})
+}
+
+// webhookError maps repository errors of webhook operations to service errors.
+func webhookError(log *slog.Logger, err error, msg string) error {
+	switch {
+	case errors.Is(err, repoErr.ErrWebhookNotFound):
+		log.Warn("webhook not found", "err", err)
+		return svcErr.ErrWebhookNotFound
+	case errors.Is(err, repoErr.ErrWebhookDeliveryNotFound):
+		log.Warn("delivery not found", "err", err)
+		return svcErr.ErrWebhookDeliveryNotFound
+	case errors.Is(err, repoErr.ErrWebhookDeliveryNotDead):
+		log.Warn("delivery is not dead", "err", err)
+		return svcErr.ErrWebhookDeliveryNotDead
+	default:
+		log.Error(msg, "err", err)
+		return err
+	}
+}
+
+func validateWebhook(webhook entity.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https url")
+	}
+
+	for _, t := range webhook.EventTypes {
+		if !t.IsKnown() {
+			return errors.New("unknown event type " + string(t))
+		}
+	}
+	for _, id := range webhook.WalletIDs {
+		if err := uuid.Validate(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newSecret() string {
+	b := make([]byte, secretSize)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
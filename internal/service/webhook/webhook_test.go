@@ -0,0 +1,373 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	svcErr "github.com/passwordhash/asynchronous-wallet/internal/service/errors"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/webhook"
+	"github.com/passwordhash/asynchronous-wallet/internal/service/webhook/mocks"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	testWebhookID  = "11111111-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	testDeliveryID = "22222222-2b2b-4c4c-8d8d-0e0e1f2a3b4c"
+	testSecret     = "test-secret"
+)
+
+func setupTest(t *testing.T, opts ...webhook.Option) (*webhook.Service, *mocks.MockRepository) {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctrl := gomock.NewController(t)
+
+	mockRepo := mocks.NewMockRepository(ctrl)
+
+	service := webhook.New(log, mockRepo, opts...)
+
+	return service, mockRepo
+}
+
+// fakeResolver resolves the hosts of the test webhooks.
+type fakeResolver map[string][]netip.Addr
+
+func (r fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+var testResolver = fakeResolver{
+	"example.com":          {netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("2606:2800:21f:cb07:6820:80da:af6b:8b2c")},
+	"internal.example.com": {netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("10.0.0.5")},
+	"localhost":            {netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("::1")},
+}
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		params        entity.Webhook
+		mockBehavior  func(mock *mocks.MockRepository)
+		expectedError error
+	}{
+		{
+			name: "Ok",
+			params: entity.Webhook{
+				URL:        "https://example.com/hook",
+				Secret:     testSecret,
+				EventTypes: []entity.EventType{entity.EventWalletCredited},
+				WalletIDs:  []string{testWebhookID},
+			},
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Create(gomock.Any(), entity.Webhook{
+					URL:        "https://example.com/hook",
+					Secret:     testSecret,
+					EventTypes: []entity.EventType{entity.EventWalletCredited},
+					WalletIDs:  []string{testWebhookID},
+				}).Return(&entity.Webhook{ID: testWebhookID}, nil)
+			},
+		},
+		{
+			name:   "Secret is generated",
+			params: entity.Webhook{URL: "http://example.com/hook"},
+			mockBehavior: func(mock *mocks.MockRepository) {
+				mock.EXPECT().Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, w entity.Webhook) (*entity.Webhook, error) {
+						assert.Len(t, w.Secret, 64, "expected a generated secret")
+						return &w, nil
+					})
+			},
+		},
+		{
+			name:          "Relative URL",
+			params:        entity.Webhook{URL: "/hook"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Unsupported scheme",
+			params:        entity.Webhook{URL: "ftp://example.com/hook"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Loopback host",
+			params:        entity.Webhook{URL: "http://localhost:5432/hook"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Private address among others",
+			params:        entity.Webhook{URL: "https://internal.example.com/hook"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Link-local IP",
+			params:        entity.Webhook{URL: "http://169.254.169.254/latest/meta-data"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Unspecified IPv6",
+			params:        entity.Webhook{URL: "http://[::]/hook"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name:          "Unresolvable host",
+			params:        entity.Webhook{URL: "https://unknown.example.com/hook"},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name: "Unknown event type",
+			params: entity.Webhook{
+				URL:        "https://example.com/hook",
+				EventTypes: []entity.EventType{"WalletDeleted"},
+			},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+		{
+			name: "Invalid wallet ID",
+			params: entity.Webhook{
+				URL:       "https://example.com/hook",
+				WalletIDs: []string{"wallet-id"},
+			},
+			mockBehavior:  func(mock *mocks.MockRepository) {},
+			expectedError: svcErr.ErrInvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t, webhook.WithResolver(testResolver))
+
+			tt.mockBehavior(mockRepo)
+
+			_, err := service.Create(t.Context(), tt.params)
+
+			require.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		repoErr       error
+		expectedError error
+	}{
+		{name: "Ok"},
+		{name: "Not found", repoErr: repoErr.ErrWebhookDeliveryNotFound, expectedError: svcErr.ErrWebhookDeliveryNotFound},
+		{name: "Not dead", repoErr: repoErr.ErrWebhookDeliveryNotDead, expectedError: svcErr.ErrWebhookDeliveryNotDead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			service, mockRepo := setupTest(t)
+
+			mockRepo.EXPECT().Redeliver(gomock.Any(), testWebhookID, testDeliveryID).
+				Return(&entity.WebhookDelivery{}, tt.repoErr)
+
+			_, err := service.Redeliver(t.Context(), testWebhookID, testDeliveryID)
+
+			require.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	t.Parallel()
+
+	service, mockRepo := setupTest(t)
+
+	event := entity.Event{
+		ID:       "event-1",
+		Type:     entity.EventWalletCredited,
+		WalletID: testWebhookID,
+		Payload:  []byte(`{"amount":100}`),
+	}
+
+	mockRepo.EXPECT().EnqueueDeliveries(gomock.Any(), event, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ entity.Event, payload []byte) (int64, error) {
+			assert.Contains(t, string(payload), `"type":"WalletCredited"`, "expected the event envelope")
+			assert.Contains(t, string(payload), `"data":{"amount":100}`, "expected the event data")
+			return 1, nil
+		})
+
+	require.NoError(t, service.Publish(t.Context(), event))
+}
+
+func TestDeliverDue(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte(`{"id":"event-1","type":"WalletCredited"}`)
+
+	tests := []struct {
+		name           string
+		responseStatus int
+		attempts       int
+		maxAttempts    int
+		expectedStatus entity.WebhookDeliveryStatus
+		expectedRetry  bool
+	}{
+		{
+			name:           "Delivered",
+			responseStatus: http.StatusNoContent,
+			maxAttempts:    3,
+			expectedStatus: entity.WebhookDeliveryDelivered,
+		},
+		{
+			name:           "Failed",
+			responseStatus: http.StatusInternalServerError,
+			attempts:       2,
+			maxAttempts:    5,
+			expectedStatus: entity.WebhookDeliveryPending,
+			expectedRetry:  true,
+		},
+		{
+			name:           "Last attempt failed",
+			responseStatus: http.StatusBadGateway,
+			attempts:       2,
+			maxAttempts:    3,
+			expectedStatus: entity.WebhookDeliveryDead,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var received atomic.Int32
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received.Add(1)
+
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, payload, body, "expected the payload as the body")
+				assert.Equal(t, "WalletCredited", r.Header.Get(webhook.HeaderEvent))
+				assert.Equal(t, testWebhookID, r.Header.Get(webhook.HeaderWebhookID))
+
+				timestamp := r.Header.Get(webhook.HeaderTimestamp)
+				_, err = strconv.ParseInt(timestamp, 10, 64)
+				assert.NoError(t, err, "expected a Unix timestamp")
+				assert.Equal(t, webhook.Sign(testSecret, timestamp, body), r.Header.Get(webhook.HeaderSignature),
+					"expected a valid signature")
+
+				w.WriteHeader(tt.responseStatus)
+			}))
+			t.Cleanup(receiver.Close)
+
+			service, mockRepo := setupTest(t,
+				webhook.WithHTTPClient(receiver.Client()),
+				webhook.WithRetryPolicy(tt.maxAttempts, time.Minute, time.Hour),
+			)
+
+			mockRepo.EXPECT().ClaimDueDeliveries(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return([]entity.DueWebhookDelivery{{
+					WebhookDelivery: entity.WebhookDelivery{
+						ID:        testDeliveryID,
+						WebhookID: testWebhookID,
+						EventType: entity.EventWalletCredited,
+						Payload:   payload,
+						Attempts:  tt.attempts,
+					},
+					URL:    receiver.URL,
+					Secret: testSecret,
+				}}, nil)
+
+			start := time.Now()
+			mockRepo.EXPECT().RecordAttempt(gomock.Any(), gomock.Any(), tt.expectedStatus, gomock.Any()).
+				DoAndReturn(func(
+					_ context.Context,
+					attempt entity.WebhookAttempt,
+					_ entity.WebhookDeliveryStatus,
+					nextAttemptAt time.Time,
+				) error {
+					assert.Equal(t, testDeliveryID, attempt.DeliveryID)
+					assert.Equal(t, tt.responseStatus, attempt.StatusCode, "expected the response status to be recorded")
+					if tt.expectedRetry {
+						// The third attempt backs off 4 minutes, of which at least a half is used.
+						assert.WithinRange(t, nextAttemptAt, start.Add(2*time.Minute), time.Now().Add(4*time.Minute),
+							"expected exponential backoff with jitter")
+					}
+					return nil
+				})
+
+			require.NoError(t, service.DeliverDue(t.Context()))
+			require.EqualValues(t, 1, received.Load(), "expected the receiver to get the delivery once")
+		})
+	}
+}
+
+func TestDeliverDue_NoResponse(t *testing.T) {
+	t.Parallel()
+
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close()
+
+	service, mockRepo := setupTest(t)
+
+	mockRepo.EXPECT().ClaimDueDeliveries(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]entity.DueWebhookDelivery{{
+			WebhookDelivery: entity.WebhookDelivery{ID: testDeliveryID, WebhookID: testWebhookID},
+			URL:             url,
+		}}, nil)
+	mockRepo.EXPECT().RecordAttempt(gomock.Any(), gomock.Any(), entity.WebhookDeliveryPending, gomock.Any()).
+		DoAndReturn(func(_ context.Context, attempt entity.WebhookAttempt, _ entity.WebhookDeliveryStatus, _ time.Time) error {
+			assert.Zero(t, attempt.StatusCode, "expected no status code")
+			assert.NotEmpty(t, attempt.Error, "expected the error to be recorded")
+			return nil
+		})
+
+	require.NoError(t, service.DeliverDue(t.Context()))
+}
+
+func TestDeliverDue_RecordFails(t *testing.T) {
+	t.Parallel()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	t.Cleanup(receiver.Close)
+
+	service, mockRepo := setupTest(t, webhook.WithHTTPClient(receiver.Client()))
+
+	recordErr := errors.New("db error")
+
+	mockRepo.EXPECT().ClaimDueDeliveries(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return([]entity.DueWebhookDelivery{{
+			WebhookDelivery: entity.WebhookDelivery{ID: testDeliveryID, WebhookID: testWebhookID},
+			URL:             receiver.URL,
+		}}, nil)
+	mockRepo.EXPECT().RecordAttempt(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(recordErr)
+
+	require.ErrorIs(t, service.DeliverDue(t.Context()), recordErr)
+}
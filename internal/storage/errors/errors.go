@@ -3,5 +3,36 @@ package repoErr
 import "errors"
 
 var (
-	ErrWalletNotFound = errors.New("wallet not found")
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrWalletAlreadyExists = errors.New("wallet already exists")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrCurrencyMismatch    = errors.New("currency doesn't match the wallet currency")
+	ErrBalanceOverflow     = errors.New("balance overflow")
+	ErrSameWallet          = errors.New("source and destination wallets are the same")
+
+	ErrWalletFrozen           = errors.New("wallet is frozen")
+	ErrWalletClosed           = errors.New("wallet is closed")
+	ErrWalletStatusTransition = errors.New("wallet can't change to the status")
+	ErrWalletNotEmpty         = errors.New("wallet balance or holds are not zero")
+
+	ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+
+	ErrTransactionNotFound    = errors.New("transaction not found")
+	ErrNotReversible          = errors.New("transaction can't be reversed")
+	ErrReversalAmountExceeded = errors.New("amount exceeds the amount left to reverse")
+
+	ErrOperationNotFound = errors.New("operation not found")
+
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrWebhookDeliveryNotDead  = errors.New("webhook delivery is not dead")
+
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is not active")
+	ErrHoldAmountExceeded = errors.New("amount exceeds the hold amount")
+
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key already exists")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
 )
@@ -0,0 +1,119 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/apikey/model"
+)
+
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+type Repository struct {
+	db DB
+}
+
+func New(db DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Create is a method that stores an API key.
+func (r *Repository) Create(ctx context.Context, key entity.APIKey) (*entity.APIKey, error) {
+	const op = "repository.apikey.Create"
+
+	walletIDs := key.WalletIDs
+	if walletIDs == nil {
+		walletIDs = []string{}
+	}
+
+	query := `INSERT INTO api_keys (name, key_hash, permission, wallet_ids)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *`
+
+	created, err := r.queryKey(ctx, query, key.Name, key.Hash, string(key.Permission), walletIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// GetByHash is a method that retrieves the API key with the given hash unless it is revoked.
+// If there is no such key, it returns [repoErr.ErrAPIKeyNotFound].
+func (r *Repository) GetByHash(ctx context.Context, hash []byte) (*entity.APIKey, error) {
+	const op = "repository.apikey.GetByHash"
+
+	key, err := r.queryKey(ctx, `SELECT * FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`, hash)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// Rotate is a method that replaces the hash of an API key that isn't revoked, so
+// the old key stops working right away.
+// If there is no such key, it returns [repoErr.ErrAPIKeyNotFound].
+func (r *Repository) Rotate(ctx context.Context, keyID string, hash []byte) (*entity.APIKey, error) {
+	const op = "repository.apikey.Rotate"
+
+	query := `UPDATE api_keys SET key_hash = $1, rotated_at = NOW()
+		WHERE id = $2 AND revoked_at IS NULL
+		RETURNING *`
+
+	key, err := r.queryKey(ctx, query, hash, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// Revoke is a method that revokes an API key for good.
+// If the key is not found or is already revoked, it returns [repoErr.ErrAPIKeyNotFound].
+func (r *Repository) Revoke(ctx context.Context, keyID string) error {
+	const op = "repository.apikey.Revoke"
+
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, query, keyID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, repoErr.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// queryKey is a helper method that runs a query returning exactly one API key row.
+// If the query returns no rows, it returns [repoErr.ErrAPIKeyNotFound].
+func (r *Repository) queryKey(ctx context.Context, query string, args ...any) (*entity.APIKey, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	key, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[model.APIKey])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repoErr.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key.ToEntity(), nil
+}
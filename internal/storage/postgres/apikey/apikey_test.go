@@ -0,0 +1,117 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+var keyColumns = []string{
+	"id", "name", "key_hash", "permission", "wallet_ids", "created_at", "rotated_at", "revoked_at",
+}
+
+var notRevoked *time.Time
+
+func setupTest(t *testing.T) (pgxmock.PgxPoolIface, *Repository) {
+	t.Helper()
+
+	mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+
+	return mock, New(mock)
+}
+
+func TestGetByHash(t *testing.T) {
+	t.Parallel()
+
+	const getQuery = `SELECT \* FROM api_keys WHERE key_hash = \$1 AND revoked_at IS NULL`
+
+	hash := []byte("test-hash")
+
+	tests := []struct {
+		name          string
+		mockBehavior  func(mock pgxmock.PgxPoolIface)
+		expectedKey   *entity.APIKey
+		expectedError error
+	}{
+		{
+			name: "Success",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(getQuery).
+					WithArgs(hash).
+					WillReturnRows(pgxmock.NewRows(keyColumns).
+						AddRow("test-key-id", "billing", hash, "read", []string{"test-wallet-id"},
+							time.Time{}, notRevoked, notRevoked))
+			},
+			expectedKey: &entity.APIKey{
+				ID:         "test-key-id",
+				Name:       "billing",
+				Hash:       hash,
+				Permission: entity.APIKeyRead,
+				WalletIDs:  []string{"test-wallet-id"},
+			},
+		},
+		{
+			name: "NotFound",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(getQuery).
+					WithArgs(hash).
+					WillReturnRows(pgxmock.NewRows(keyColumns))
+			},
+			expectedError: repoErr.ErrAPIKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			key, err := repo.GetByHash(t.Context(), hash)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			require.Equal(t, tt.expectedKey, key, "expected key to match")
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	t.Parallel()
+
+	const revokeQuery = `UPDATE api_keys SET revoked_at = NOW\(\) WHERE id = \$1 AND revoked_at IS NULL`
+
+	tests := []struct {
+		name          string
+		rowsAffected  int64
+		expectedError error
+	}{
+		{name: "Success", rowsAffected: 1},
+		{name: "NotFoundOrRevoked", rowsAffected: 0, expectedError: repoErr.ErrAPIKeyNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			mock.ExpectExec(revokeQuery).
+				WithArgs("test-key-id").
+				WillReturnResult(pgxmock.NewResult("UPDATE", tt.rowsAffected))
+
+			err := repo.Revoke(t.Context(), "test-key-id")
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+		})
+	}
+}
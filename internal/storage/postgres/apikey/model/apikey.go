@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+type APIKey struct {
+	ID         string     `db:"id"`
+	Name       string     `db:"name"`
+	KeyHash    []byte     `db:"key_hash"`
+	Permission string     `db:"permission"`
+	WalletIDs  []string   `db:"wallet_ids"`
+	CreatedAt  time.Time  `db:"created_at"`
+	RotatedAt  *time.Time `db:"rotated_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+func (k APIKey) ToEntity() *entity.APIKey {
+	return &entity.APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Hash:       k.KeyHash,
+		Permission: entity.APIKeyPermission(k.Permission),
+		WalletIDs:  k.WalletIDs,
+		CreatedAt:  k.CreatedAt,
+		RotatedAt:  k.RotatedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
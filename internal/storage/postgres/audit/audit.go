@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/audit/model"
+)
+
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type Repository struct {
+	db DB
+}
+
+func New(db DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Stage is a method that writes a record to be chained into the audit log by
+// [Repository.Sequence]. CreatedAt is set to the current time.
+func (r *Repository) Stage(ctx context.Context, record entity.AuditRecord) error {
+	const op = "repository.audit.Stage"
+
+	var walletID *string
+	if record.WalletID != "" {
+		walletID = &record.WalletID
+	}
+
+	query := `INSERT INTO audit_pending (action, actor, source_ip, request_id, wallet_id, resource_id,
+			balance_before, balance_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.Exec(ctx, query,
+		string(record.Action), record.Actor, record.SourceIP, record.RequestID, walletID, record.ResourceID,
+		record.BalanceBefore, record.BalanceAfter,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Sequence is a method that moves up to limit staged records to the audit log in
+// the order they were staged and returns how many were moved. Every record is
+// chained to the one before it: PrevHash is set to its hash and Hash is computed
+// over the record. Sequencers are serialized by the lock of the audit_head row,
+// which holds the hash of the newest record, so two records can't be chained to
+// the same predecessor; the changes that stage records don't take that lock.
+func (r *Repository) Sequence(ctx context.Context, limit int) (int, error) {
+	const op = "repository.audit.Sequence"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var prevHash []byte
+	if err := tx.QueryRow(ctx, `SELECT hash FROM audit_head FOR UPDATE`).Scan(&prevHash); err != nil {
+		return 0, fmt.Errorf("%s: failed to lock audit head: %w", op, err)
+	}
+
+	query := `SELECT id, action, actor, source_ip, request_id, wallet_id, resource_id,
+			balance_before, balance_after, created_at
+		FROM audit_pending
+		ORDER BY id
+		LIMIT $1`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pending, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[model.AuditRecord])
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	insertQuery := `INSERT INTO audit_log (action, actor, source_ip, request_id, wallet_id, resource_id,
+			balance_before, balance_after, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	ids := make([]int64, 0, len(pending))
+	for _, staged := range pending {
+		record := staged.ToEntity()
+		record.PrevHash = prevHash
+		record.Hash = record.ComputeHash()
+
+		_, err := tx.Exec(ctx, insertQuery,
+			string(record.Action), record.Actor, record.SourceIP, record.RequestID, staged.WalletID,
+			record.ResourceID, record.BalanceBefore, record.BalanceAfter, record.CreatedAt, record.PrevHash,
+			record.Hash,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("%s: failed to append record %d: %w", op, staged.ID, err)
+		}
+
+		prevHash = record.Hash
+		ids = append(ids, staged.ID)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM audit_pending WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("%s: failed to delete staged records: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE audit_head SET hash = $1`, prevHash); err != nil {
+		return 0, fmt.Errorf("%s: failed to update audit head: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: commit failed: %w", op, err)
+	}
+
+	return len(pending), nil
+}
+
+// Records is a method that returns up to limit records with IDs greater than afterID,
+// in the order they were appended.
+func (r *Repository) Records(ctx context.Context, afterID int64, limit int) ([]entity.AuditRecord, error) {
+	const op = "repository.audit.Records"
+
+	query := `SELECT * FROM audit_log WHERE id > $1 ORDER BY id LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.AuditRecord])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := make([]entity.AuditRecord, 0, len(records))
+	for _, record := range records {
+		result = append(result, record.ToEntity())
+	}
+
+	return result, nil
+}
+
+// Head is a method that returns the hash of the newest record of the audit log,
+// as kept in audit_head by [Repository.Sequence]. It is empty if no record was
+// appended yet.
+func (r *Repository) Head(ctx context.Context) ([]byte, error) {
+	const op = "repository.audit.Head"
+
+	var hash []byte
+	if err := r.db.QueryRow(ctx, `SELECT hash FROM audit_head`).Scan(&hash); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,300 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+const (
+	stageQuery = `INSERT INTO audit_pending \(action, actor, source_ip, request_id, wallet_id, resource_id,\s+` +
+		`balance_before, balance_after\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)`
+	headQuery    = `SELECT hash FROM audit_head FOR UPDATE`
+	pendingQuery = `SELECT id, action, actor, source_ip, request_id, wallet_id, resource_id,\s+` +
+		`balance_before, balance_after, created_at\s+FROM audit_pending\s+ORDER BY id\s+LIMIT \$1`
+	insertQuery = `INSERT INTO audit_log \(action, actor, source_ip, request_id, wallet_id, resource_id,\s+` +
+		`balance_before, balance_after, created_at, prev_hash, hash\)\s+` +
+		`VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10, \$11\)`
+	deleteQuery = `DELETE FROM audit_pending WHERE id = ANY\(\$1\)`
+	updateQuery = `UPDATE audit_head SET hash = \$1`
+)
+
+var pendingColumns = []string{
+	"id", "action", "actor", "source_ip", "request_id", "wallet_id", "resource_id",
+	"balance_before", "balance_after", "created_at",
+}
+
+var auditColumns = []string{
+	"id", "action", "actor", "source_ip", "request_id", "wallet_id", "resource_id",
+	"balance_before", "balance_after", "created_at", "prev_hash", "hash",
+}
+
+func setupTest(t *testing.T) (pgxmock.PgxPoolIface, *Repository) {
+	t.Helper()
+
+	mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+
+	return mock, New(mock)
+}
+
+func anyArgs(n int) []any {
+	args := make([]any, n)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	return args
+}
+
+func TestStage(t *testing.T) {
+	t.Parallel()
+
+	walletID := "test-wallet-id"
+	before, after := int64(100), int64(150)
+
+	record := entity.AuditRecord{
+		Action:        entity.AuditBalanceAction(entity.OperationDeposit),
+		Actor:         "key:test-key-id",
+		SourceIP:      "192.0.2.1",
+		RequestID:     "test-request-id",
+		WalletID:      walletID,
+		ResourceID:    "test-transaction-id",
+		BalanceBefore: &before,
+		BalanceAfter:  &after,
+	}
+
+	tests := []struct {
+		name          string
+		record        entity.AuditRecord
+		mockBehavior  func(mock pgxmock.PgxPoolIface)
+		expectedError bool
+	}{
+		{
+			name:   "Staged",
+			record: record,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectExec(stageQuery).
+					WithArgs(string(record.Action), record.Actor, record.SourceIP, record.RequestID, &walletID,
+						record.ResourceID, &before, &after).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+		},
+		{
+			name: "WithoutWallet",
+			record: entity.AuditRecord{
+				Action:     entity.AuditAPIKeyRevoke,
+				Actor:      "cli:admin",
+				ResourceID: "test-key-id",
+			},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				var noBalance *int64
+				mock.ExpectExec(stageQuery).
+					WithArgs("apikey.revoke", "cli:admin", "", "", (*string)(nil), "test-key-id", noBalance, noBalance).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			},
+		},
+		{
+			name:   "InsertFails",
+			record: record,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectExec(stageQuery).
+					WithArgs(anyArgs(8)...).
+					WillReturnError(errors.New("insert error"))
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			err := repo.Stage(t.Context(), tt.record)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSequence(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	walletID := "test-wallet-id"
+	before, after := int64(100), int64(150)
+	var noBalance *int64
+	prevHash := []byte("previous-hash")
+
+	first := entity.AuditRecord{
+		Action:        entity.AuditBalanceAction(entity.OperationDeposit),
+		Actor:         "key:test-key-id",
+		SourceIP:      "192.0.2.1",
+		RequestID:     "test-request-id",
+		WalletID:      walletID,
+		ResourceID:    "test-transaction-id",
+		BalanceBefore: &before,
+		BalanceAfter:  &after,
+		CreatedAt:     createdAt,
+		PrevHash:      prevHash,
+	}
+	first.Hash = first.ComputeHash()
+
+	second := entity.AuditRecord{
+		Action:     entity.AuditAPIKeyRevoke,
+		Actor:      "cli:admin",
+		ResourceID: "test-key-id",
+		CreatedAt:  createdAt,
+		PrevHash:   first.Hash,
+	}
+	second.Hash = second.ComputeHash()
+
+	pendingRows := func() *pgxmock.Rows {
+		return pgxmock.NewRows(pendingColumns).
+			AddRow(int64(7), string(first.Action), first.Actor, first.SourceIP, first.RequestID, &walletID,
+				first.ResourceID, &before, &after, createdAt).
+			AddRow(int64(9), string(second.Action), second.Actor, "", "", (*string)(nil), second.ResourceID,
+				noBalance, noBalance, createdAt)
+	}
+
+	tests := []struct {
+		name          string
+		mockBehavior  func(mock pgxmock.PgxPoolIface)
+		expectedCount int
+		expectedError bool
+	}{
+		{
+			name: "Chained",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(headQuery).WillReturnRows(pgxmock.NewRows([]string{"hash"}).AddRow(prevHash))
+				mock.ExpectQuery(pendingQuery).WithArgs(10).WillReturnRows(pendingRows())
+				mock.ExpectExec(insertQuery).
+					WithArgs(string(first.Action), first.Actor, first.SourceIP, first.RequestID, &walletID,
+						first.ResourceID, &before, &after, createdAt, prevHash, first.Hash).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(insertQuery).
+					WithArgs(string(second.Action), second.Actor, "", "", (*string)(nil), second.ResourceID,
+						noBalance, noBalance, createdAt, first.Hash, second.Hash).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(deleteQuery).WithArgs([]int64{7, 9}).WillReturnResult(pgxmock.NewResult("DELETE", 2))
+				mock.ExpectExec(updateQuery).WithArgs(second.Hash).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "NothingStaged",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(headQuery).WillReturnRows(pgxmock.NewRows([]string{"hash"}).AddRow([]byte{}))
+				mock.ExpectQuery(pendingQuery).WithArgs(10).WillReturnRows(pgxmock.NewRows(pendingColumns))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name: "InsertFails",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(headQuery).WillReturnRows(pgxmock.NewRows([]string{"hash"}).AddRow(prevHash))
+				mock.ExpectQuery(pendingQuery).WithArgs(10).WillReturnRows(pendingRows())
+				mock.ExpectExec(insertQuery).WithArgs(anyArgs(11)...).WillReturnError(errors.New("insert error"))
+				mock.ExpectRollback()
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			count, err := repo.Sequence(t.Context(), 10)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCount, count)
+		})
+	}
+}
+
+func TestRecords(t *testing.T) {
+	t.Parallel()
+
+	mock, repo := setupTest(t)
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	walletID := "test-wallet-id"
+	var noBalance *int64
+
+	mock.ExpectQuery(`SELECT \* FROM audit_log WHERE id > \$1 ORDER BY id LIMIT \$2`).
+		WithArgs(int64(10), 2).
+		WillReturnRows(pgxmock.NewRows(auditColumns).
+			AddRow(int64(11), "wallet.create", "key:test-key-id", "192.0.2.1", "test-request-id", &walletID, "",
+				noBalance, noBalance, createdAt, []byte("hash-10"), []byte("hash-11")).
+			AddRow(int64(12), "apikey.revoke", "cli:admin", "", "", (*string)(nil), "test-key-id",
+				noBalance, noBalance, createdAt, []byte("hash-11"), []byte("hash-12")))
+
+	records, err := repo.Records(t.Context(), 10, 2)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+	require.NoError(t, err)
+	require.Equal(t, []entity.AuditRecord{
+		{
+			ID:        11,
+			Action:    entity.AuditWalletCreate,
+			Actor:     "key:test-key-id",
+			SourceIP:  "192.0.2.1",
+			RequestID: "test-request-id",
+			WalletID:  walletID,
+			CreatedAt: createdAt,
+			PrevHash:  []byte("hash-10"),
+			Hash:      []byte("hash-11"),
+		},
+		{
+			ID:         12,
+			Action:     entity.AuditAPIKeyRevoke,
+			Actor:      "cli:admin",
+			ResourceID: "test-key-id",
+			CreatedAt:  createdAt,
+			PrevHash:   []byte("hash-11"),
+			Hash:       []byte("hash-12"),
+		},
+	}, records)
+}
+
+func TestHead(t *testing.T) {
+	t.Parallel()
+
+	mock, repo := setupTest(t)
+
+	mock.ExpectQuery(`SELECT hash FROM audit_head`).
+		WillReturnRows(pgxmock.NewRows([]string{"hash"}).AddRow([]byte("hash-12")))
+
+	hash, err := repo.Head(t.Context())
+
+	require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hash-12"), hash)
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+type AuditRecord struct {
+	ID            int64     `db:"id"`
+	Action        string    `db:"action"`
+	Actor         string    `db:"actor"`
+	SourceIP      string    `db:"source_ip"`
+	RequestID     string    `db:"request_id"`
+	WalletID      *string   `db:"wallet_id"`
+	ResourceID    string    `db:"resource_id"`
+	BalanceBefore *int64    `db:"balance_before"`
+	BalanceAfter  *int64    `db:"balance_after"`
+	CreatedAt     time.Time `db:"created_at"`
+	PrevHash      []byte    `db:"prev_hash"`
+	Hash          []byte    `db:"hash"`
+}
+
+func (r AuditRecord) ToEntity() entity.AuditRecord {
+	var walletID string
+	if r.WalletID != nil {
+		walletID = *r.WalletID
+	}
+
+	return entity.AuditRecord{
+		ID:            r.ID,
+		Action:        entity.AuditAction(r.Action),
+		Actor:         r.Actor,
+		SourceIP:      r.SourceIP,
+		RequestID:     r.RequestID,
+		WalletID:      walletID,
+		ResourceID:    r.ResourceID,
+		BalanceBefore: r.BalanceBefore,
+		BalanceAfter:  r.BalanceAfter,
+		CreatedAt:     r.CreatedAt,
+		PrevHash:      r.PrevHash,
+		Hash:          r.Hash,
+	}
+}
@@ -0,0 +1,228 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// PublishFunc delivers a single event. It must be safe to call again with an
+// event it already delivered.
+type PublishFunc func(ctx context.Context, event entity.Event) error
+
+const (
+	DefaultMaxAttempts = 10
+	DefaultBaseBackoff = time.Second
+	DefaultMaxBackoff  = 5 * time.Minute
+)
+
+type Repository struct {
+	db DB
+
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+type Option func(*Repository)
+
+// WithRetryPolicy sets how many times an event is attempted before it is moved to
+// the dead state, and the bounds of the backoff between the attempts.
+func WithRetryPolicy(maxAttempts int, baseBackoff, maxBackoff time.Duration) Option {
+	return func(r *Repository) {
+		r.maxAttempts = maxAttempts
+		r.baseBackoff = baseBackoff
+		r.maxBackoff = maxBackoff
+	}
+}
+
+func New(db DB, opts ...Option) *Repository {
+	r := &Repository{
+		db:          db,
+		maxAttempts: DefaultMaxAttempts,
+		baseBackoff: DefaultBaseBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+type event struct {
+	ID        string    `db:"event_id"`
+	Type      string    `db:"event_type"`
+	WalletID  string    `db:"wallet_id"`
+	Payload   []byte    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+	Attempts  int       `db:"attempts"`
+}
+
+// Dispatch is a method that publishes up to limit due events in the order they
+// were written and marks the published ones as sent. It returns the number of
+// published events.
+// Several relays can share the outbox. A relay first takes a transaction-level
+// advisory lock on the wallets with due events, skipping the wallets another relay
+// holds, and only then reads their events, so the events of a wallet are published
+// by one relay at a time and none overtakes an event another relay is publishing.
+// An event is marked as sent in the same transaction that locked it, only after
+// publish returned: if the process crashes in between, the event is published
+// again, so delivery is at-least-once.
+// If publish fails, the attempt is recorded on the event and it is retried after
+// a backoff that doubles with every attempt; after the last attempt it is moved to
+// the dead state and never claimed again. The later events of its wallet are held
+// back until then, so the events of a wallet stay in order, while the events of
+// other wallets are published. The errors of the failed events are returned
+// together with the number of published events.
+func (r *Repository) Dispatch(ctx context.Context, limit int, publish PublishFunc) (int, error) {
+	const op = "repository.outbox.Dispatch"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// The events are read in a statement after the locks are taken, so its snapshot
+	// includes whatever the relay that held a lock before committed.
+	walletIDs, err := r.lockWallets(ctx, tx, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(walletIDs) == 0 {
+		return 0, nil
+	}
+
+	query := `SELECT event_id, event_type, wallet_id, payload, created_at, attempts FROM outbox o
+		WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+			AND wallet_id = ANY($2)
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox earlier
+				WHERE earlier.wallet_id = o.wallet_id AND earlier.id < o.id
+					AND earlier.sent_at IS NULL AND earlier.failed_at IS NULL
+					AND earlier.next_attempt_at > NOW()
+			)
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE`
+
+	rows, err := tx.Query(ctx, query, limit, walletIDs)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events, err := pgx.CollectRows(rows, pgx.RowToStructByName[event])
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sent := make([]string, 0, len(events))
+	// blocked holds the wallets with a failed event in this batch.
+	blocked := make(map[string]bool)
+	var publishErrs []error
+	for _, e := range events {
+		if blocked[e.WalletID] {
+			continue
+		}
+
+		publishErr := publish(ctx, entity.Event{
+			ID:        e.ID,
+			Type:      entity.EventType(e.Type),
+			WalletID:  e.WalletID,
+			Payload:   e.Payload,
+			CreatedAt: e.CreatedAt,
+		})
+		if publishErr == nil {
+			sent = append(sent, e.ID)
+			continue
+		}
+
+		publishErr = fmt.Errorf("failed to publish event %s: %w", e.ID, publishErr)
+		publishErrs = append(publishErrs, publishErr)
+		blocked[e.WalletID] = true
+
+		if err := r.recordFailure(ctx, tx, e, publishErr); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if len(sent) > 0 {
+		sentQuery := `UPDATE outbox SET sent_at = NOW(), attempts = attempts + 1 WHERE event_id = ANY($1)`
+		if _, err := tx.Exec(ctx, sentQuery, sent); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(publishErrs) > 0 {
+		return len(sent), fmt.Errorf("%s: %w", op, errors.Join(publishErrs...))
+	}
+
+	return len(sent), nil
+}
+
+// lockWallets is a helper method that takes the advisory locks of up to limit
+// wallets with due events, the wallets with the oldest events first, and returns
+// the IDs of the wallets it locked. The locks are held until tx ends.
+func (r *Repository) lockWallets(ctx context.Context, tx pgx.Tx, limit int) ([]string, error) {
+	// The lock is taken outside the subquery, so only the wallets it returns are locked.
+	query := `SELECT wallet_id FROM (
+			SELECT wallet_id FROM outbox
+			WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+			GROUP BY wallet_id
+			ORDER BY MIN(id)
+			LIMIT $1
+		) due
+		WHERE pg_try_advisory_xact_lock(hashtext(wallet_id::text))`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// recordFailure is a helper method that records a failed attempt to publish the
+// event and schedules the next one, or moves the event to the dead state if it was
+// the last attempt.
+func (r *Repository) recordFailure(ctx context.Context, tx pgx.Tx, e event, publishErr error) error {
+	attempts := e.Attempts + 1
+	if attempts >= r.maxAttempts {
+		query := `UPDATE outbox SET attempts = $1, last_error = $2, failed_at = NOW() WHERE event_id = $3`
+
+		_, err := tx.Exec(ctx, query, attempts, publishErr.Error(), e.ID)
+		return err
+	}
+
+	query := `UPDATE outbox SET attempts = $1, last_error = $2, next_attempt_at = $3 WHERE event_id = $4`
+
+	_, err := tx.Exec(ctx, query, attempts, publishErr.Error(), time.Now().Add(r.backoff(attempts)), e.ID)
+	return err
+}
+
+// backoff returns the delay before the attempt after the given one: baseBackoff
+// doubled with every attempt, up to maxBackoff.
+func (r *Repository) backoff(attempt int) time.Duration {
+	if shift := attempt - 1; shift < 32 && r.baseBackoff<<shift < r.maxBackoff && r.baseBackoff<<shift > 0 {
+		return r.baseBackoff << shift
+	}
+
+	return r.maxBackoff
+}
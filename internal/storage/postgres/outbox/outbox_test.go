@@ -0,0 +1,160 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+func TestDispatch(t *testing.T) {
+	t.Parallel()
+
+	const lockQuery = `SELECT wallet_id FROM \(\s+SELECT wallet_id FROM outbox\s+` +
+		`WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW\(\)\s+` +
+		`GROUP BY wallet_id\s+ORDER BY MIN\(id\)\s+LIMIT \$1\s+\) due\s+` +
+		`WHERE pg_try_advisory_xact_lock\(hashtext\(wallet_id::text\)\)`
+	const selectQuery = `SELECT event_id, event_type, wallet_id, payload, created_at, attempts FROM outbox o\s+` +
+		`WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW\(\)\s+` +
+		`AND wallet_id = ANY\(\$2\)\s+` +
+		`AND NOT EXISTS \(.*earlier.next_attempt_at > NOW\(\)\s+\)\s+` +
+		`ORDER BY id\s+LIMIT \$1\s+FOR UPDATE`
+	const sentQuery = `UPDATE outbox SET sent_at = NOW\(\), attempts = attempts \+ 1 WHERE event_id = ANY\(\$1\)`
+	const retryQuery = `UPDATE outbox SET attempts = \$1, last_error = \$2, next_attempt_at = \$3 WHERE event_id = \$4`
+	const deadQuery = `UPDATE outbox SET attempts = \$1, last_error = \$2, failed_at = NOW\(\) WHERE event_id = \$3`
+
+	eventColumns := []string{"event_id", "event_type", "wallet_id", "payload", "created_at", "attempts"}
+	publishErr := errors.New("publish error")
+
+	// expectEvents expects two events of the test wallet, the first one attempted
+	// firstAttempts times, and one event of another wallet.
+	expectEvents := func(mock pgxmock.PgxPoolIface, firstAttempts int) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockQuery).
+			WithArgs(10).
+			WillReturnRows(pgxmock.NewRows([]string{"wallet_id"}).AddRow("test-wallet-id").AddRow("other-wallet-id"))
+		mock.ExpectQuery(selectQuery).
+			WithArgs(10, []string{"test-wallet-id", "other-wallet-id"}).
+			WillReturnRows(pgxmock.NewRows(eventColumns).
+				AddRow("event-1", "WalletCredited", "test-wallet-id", []byte(`{"amount":100}`), time.Time{}, firstAttempts).
+				AddRow("event-2", "WalletDebited", "test-wallet-id", []byte(`{"amount":50}`), time.Time{}, 0).
+				AddRow("event-3", "WalletCredited", "other-wallet-id", []byte(`{"amount":10}`), time.Time{}, 0))
+	}
+
+	tests := []struct {
+		name              string
+		failOn            string
+		maxAttempts       int
+		mockBehavior      func(mock pgxmock.PgxPoolIface)
+		expectedPublished []string
+		expectedError     error
+	}{
+		{
+			name: "AllPublished",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				expectEvents(mock, 0)
+				mock.ExpectExec(sentQuery).
+					WithArgs([]string{"event-1", "event-2", "event-3"}).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+				mock.ExpectCommit()
+			},
+			expectedPublished: []string{"event-1", "event-2", "event-3"},
+		},
+		{
+			name:   "PublishFails",
+			failOn: "event-1",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				expectEvents(mock, 2)
+				mock.ExpectExec(retryQuery).
+					WithArgs(3, pgxmock.AnyArg(), pgxmock.AnyArg(), "event-1").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(sentQuery).
+					WithArgs([]string{"event-3"}).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+			// event-2 waits for event-1 of the same wallet, event-3 of another wallet doesn't.
+			expectedPublished: []string{"event-3"},
+			expectedError:     publishErr,
+		},
+		{
+			name:        "LastAttemptFails",
+			failOn:      "event-1",
+			maxAttempts: 3,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				expectEvents(mock, 2)
+				mock.ExpectExec(deadQuery).
+					WithArgs(3, pgxmock.AnyArg(), "event-1").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(sentQuery).
+					WithArgs([]string{"event-3"}).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+			expectedPublished: []string{"event-3"},
+			expectedError:     publishErr,
+		},
+		{
+			// No due events, or another relay holds the locks of their wallets.
+			name: "Empty",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs(10).
+					WillReturnRows(pgxmock.NewRows([]string{"wallet_id"}))
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+			require.NoError(t, err)
+
+			maxAttempts := tt.maxAttempts
+			if maxAttempts == 0 {
+				maxAttempts = DefaultMaxAttempts
+			}
+			repo := New(mock, WithRetryPolicy(maxAttempts, time.Second, time.Minute))
+
+			tt.mockBehavior(mock)
+
+			var published []string
+			sent, err := repo.Dispatch(t.Context(), 10, func(ctx context.Context, event entity.Event) error {
+				if event.ID == tt.failOn {
+					return publishErr
+				}
+				published = append(published, event.ID)
+				return nil
+			})
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			require.Equal(t, len(tt.expectedPublished), sent, "expected sent count to match")
+			require.Equal(t, tt.expectedPublished, published, "expected published events to match")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	repo := New(nil, WithRetryPolicy(10, time.Second, time.Minute))
+
+	require.Equal(t, time.Second, repo.backoff(1))
+	require.Equal(t, 4*time.Second, repo.backoff(3))
+	require.Equal(t, time.Minute, repo.backoff(7))
+	require.Equal(t, time.Minute, repo.backoff(100))
+}
@@ -0,0 +1,55 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+// stageAudit is a helper method that writes the record of a change to the
+// audit_pending table, if the repository is audited. It is staged in the
+// transaction of the change, so the change is never committed without its record,
+// and is chained into the audit log by the sequencer once the transaction commits.
+func (r *Repository) stageAudit(ctx context.Context, tx pgx.Tx, record entity.AuditRecord) error {
+	if r.auditStamp == nil {
+		return nil
+	}
+
+	record = r.auditStamp(ctx, record)
+
+	var walletID *string
+	if record.WalletID != "" {
+		walletID = &record.WalletID
+	}
+
+	query := `INSERT INTO audit_pending (action, actor, source_ip, request_id, wallet_id, resource_id,
+			balance_before, balance_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := tx.Exec(ctx, query,
+		string(record.Action), record.Actor, record.SourceIP, record.RequestID, walletID, record.ResourceID,
+		record.BalanceBefore, record.BalanceAfter,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to stage %s audit record: %w", record.Action, err)
+	}
+
+	return nil
+}
+
+// stageBalanceAudit is a helper method that stages the record of the balance
+// change of a transaction with the balances before and after it.
+func (r *Repository) stageBalanceAudit(ctx context.Context, tx pgx.Tx, t *entity.Transaction) error {
+	before, after := t.BalanceAfter-t.Amount, t.BalanceAfter
+
+	return r.stageAudit(ctx, tx, entity.AuditRecord{
+		Action:        entity.AuditBalanceAction(t.OperationType),
+		WalletID:      t.WalletID,
+		ResourceID:    t.ID,
+		BalanceBefore: &before,
+		BalanceAfter:  &after,
+	})
+}
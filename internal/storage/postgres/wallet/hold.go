@@ -0,0 +1,357 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/wallet/model"
+	postgresPkg "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
+)
+
+// CreateHold is a method that reserves funds of a wallet until hold.ExpiresAt.
+// The hold reduces the available balance of the wallet, which is checked against the
+// overdraft limit while the wallet row is locked. The ledger balance is not changed.
+// If the wallet does not exist, it returns [repoErr.ErrWalletNotFound].
+// If the hold currency differs from the wallet currency, it returns [repoErr.ErrCurrencyMismatch].
+// If the available balance is not enough, it returns [repoErr.ErrInsufficientFunds].
+// If the wallet is frozen or closed, it returns [repoErr.ErrWalletFrozen] or [repoErr.ErrWalletClosed].
+func (r *Repository) CreateHold(ctx context.Context, hold entity.Hold) (*entity.Hold, error) {
+	const op = "repository.wallet.CreateHold"
+
+	var created *entity.Hold
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		wallet, err := r.getByID(ctx, tx, hold.WalletID, true)
+		if err != nil {
+			return err
+		}
+		if err := checkStatus(wallet); err != nil {
+			return err
+		}
+
+		amount := inWalletCurrency(wallet, hold.Amount)
+		if amount.Currency != wallet.Currency() {
+			return repoErr.ErrCurrencyMismatch
+		}
+
+		held, err := entity.NewMoney(wallet.Held, amount.Currency).Add(amount)
+		if err != nil {
+			return repoErr.ErrBalanceOverflow
+		}
+		wallet.Held = held.Amount
+
+		if err := r.checkFunds(wallet, wallet.Balance); err != nil {
+			return err
+		}
+
+		if err := r.updateHeld(ctx, tx, wallet.ID, wallet.Held); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO holds (wallet_id, amount, currency, expires_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *`
+
+		created, err = r.queryHold(ctx, tx, query, wallet.ID, amount.Amount, string(amount.Currency), hold.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+
+		return r.stageAudit(ctx, tx, entity.AuditRecord{
+			Action:     entity.AuditHoldCreate,
+			WalletID:   created.WalletID,
+			ResourceID: created.ID,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// GetHold is a method that retrieves a hold by its ID.
+// If the hold is not found, it returns [repoErr.ErrHoldNotFound].
+func (r *Repository) GetHold(ctx context.Context, holdID string) (*entity.Hold, error) {
+	const op = "repository.wallet.GetHold"
+
+	hold, err := r.queryHold(ctx, r.db, `SELECT * FROM holds WHERE id = $1`, holdID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, repoErr.ErrHoldNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hold, nil
+}
+
+// CaptureHold is a method that charges a hold. The captured amount is debited from
+// the wallet and posted to the journal against the cash-out account, the rest of
+// the hold is released.
+// If the hold is not found, it returns [repoErr.ErrHoldNotFound].
+// If the hold was already captured, released or has expired, it returns [repoErr.ErrHoldNotActive].
+// If the amount is greater than the hold amount, it returns [repoErr.ErrHoldAmountExceeded].
+// If the wallet is frozen or closed, it returns [repoErr.ErrWalletFrozen] or [repoErr.ErrWalletClosed];
+// the hold stays active and can still be released.
+func (r *Repository) CaptureHold(ctx context.Context, capture entity.HoldCapture) (*entity.Hold, error) {
+	const op = "repository.wallet.CaptureHold"
+
+	var captured *entity.Hold
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		hold, wallet, err := r.lockHold(ctx, tx, capture.HoldID)
+		if err != nil {
+			return err
+		}
+		if !hold.IsActive(time.Now()) {
+			return repoErr.ErrHoldNotActive
+		}
+		if err := checkStatus(wallet); err != nil {
+			return err
+		}
+
+		amount := capture.Amount
+		if amount.Amount == 0 {
+			amount.Amount = hold.Amount.Amount
+		}
+		if amount.Currency == "" {
+			amount.Currency = hold.Amount.Currency
+		}
+		if amount.Currency != hold.Amount.Currency {
+			return repoErr.ErrCurrencyMismatch
+		}
+		if amount.Amount > hold.Amount.Amount {
+			return repoErr.ErrHoldAmountExceeded
+		}
+
+		// The funds were reserved when the hold was created, so the overdraft
+		// limit is not checked again.
+		balance, err := addToBalance(wallet, amount.Neg())
+		if err != nil {
+			return err
+		}
+
+		if err := r.updateBalance(ctx, tx, wallet.ID, balance); err != nil {
+			return err
+		}
+		if err := r.updateHeld(ctx, tx, wallet.ID, wallet.Held-hold.Amount.Amount); err != nil {
+			return err
+		}
+
+		entryID, err := r.postEntry(ctx, tx, entity.JournalEntry{
+			Kind:     entity.OperationCapture,
+			Currency: amount.Currency,
+			Postings: []entity.Posting{
+				{AccountID: wallet.ID, Amount: -amount.Amount},
+				{AccountID: entity.AccountCashOut, Amount: amount.Amount},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		transaction := &entity.Transaction{
+			WalletID:      wallet.ID,
+			Amount:        -amount.Amount,
+			OperationType: entity.OperationCapture,
+			BalanceAfter:  balance.Amount,
+			EntryID:       entryID,
+		}
+		if err := r.recordTransaction(ctx, tx, transaction, amount.Currency); err != nil {
+			return fmt.Errorf("failed to record transaction: %w", err)
+		}
+
+		query := `UPDATE holds
+			SET status = $1, captured_amount = $2, transaction_id = $3, updated_at = NOW()
+			WHERE id = $4
+			RETURNING *`
+
+		captured, err = r.queryHold(ctx, tx, query,
+			string(entity.HoldStatusCaptured), amount.Amount, transaction.ID, hold.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+		captured.Transaction = transaction
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return captured, nil
+}
+
+// ReleaseHold is a method that releases the reserved funds of an active hold.
+// If the hold is not found, it returns [repoErr.ErrHoldNotFound].
+// If the hold was already captured or released, it returns [repoErr.ErrHoldNotActive].
+func (r *Repository) ReleaseHold(ctx context.Context, holdID string) (*entity.Hold, error) {
+	const op = "repository.wallet.ReleaseHold"
+
+	hold, err := r.finishHold(ctx, holdID, entity.HoldStatusReleased, entity.AuditHoldRelease, func(hold *entity.Hold) bool {
+		return hold.Status == entity.HoldStatusActive
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hold, nil
+}
+
+// ExpireHold is a method that releases the reserved funds of an active hold whose
+// TTL has passed and marks it as expired.
+// If the hold is not found, it returns [repoErr.ErrHoldNotFound].
+// If the hold is not active or hasn't expired yet, it returns [repoErr.ErrHoldNotActive].
+func (r *Repository) ExpireHold(ctx context.Context, holdID string) (*entity.Hold, error) {
+	const op = "repository.wallet.ExpireHold"
+
+	hold, err := r.finishHold(ctx, holdID, entity.HoldStatusExpired, "", func(hold *entity.Hold) bool {
+		return hold.Status == entity.HoldStatusActive && !hold.IsActive(time.Now())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hold, nil
+}
+
+// ExpiredHoldIDs is a method that returns up to limit IDs of active holds that
+// expired before the given time, the oldest first.
+func (r *Repository) ExpiredHoldIDs(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	const op = "repository.wallet.ExpiredHoldIDs"
+
+	query := `SELECT id FROM holds
+		WHERE status = 'active' AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// finishHold is a helper method that moves a hold to the final status and releases
+// its reserved funds. canFinish is called with the locked hold; if it returns false,
+// [repoErr.ErrHoldNotActive] is returned and nothing is changed. The change is
+// audited as action, unless it is empty.
+func (r *Repository) finishHold(
+	ctx context.Context,
+	holdID string,
+	status entity.HoldStatus,
+	action entity.AuditAction,
+	canFinish func(hold *entity.Hold) bool,
+) (*entity.Hold, error) {
+	var finished *entity.Hold
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		hold, wallet, err := r.lockHold(ctx, tx, holdID)
+		if err != nil {
+			return err
+		}
+		if !canFinish(hold) {
+			return repoErr.ErrHoldNotActive
+		}
+
+		if err := r.updateHeld(ctx, tx, wallet.ID, wallet.Held-hold.Amount.Amount); err != nil {
+			return err
+		}
+
+		query := `UPDATE holds SET status = $1, updated_at = NOW() WHERE id = $2 RETURNING *`
+
+		finished, err = r.queryHold(ctx, tx, query, string(status), hold.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+
+		if action == "" {
+			return nil
+		}
+
+		return r.stageAudit(ctx, tx, entity.AuditRecord{
+			Action:     action,
+			WalletID:   finished.WalletID,
+			ResourceID: finished.ID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return finished, nil
+}
+
+// lockHold is a helper method that locks a hold and its wallet for update.
+// The wallet is locked first, in the same order as balance operations take their
+// locks, so hold changes can't deadlock with them.
+// If the hold is not found, it returns [repoErr.ErrHoldNotFound].
+func (r *Repository) lockHold(ctx context.Context, tx pgx.Tx, holdID string) (*entity.Hold, *entity.Wallet, error) {
+	var walletID string
+
+	err := tx.QueryRow(ctx, `SELECT wallet_id FROM holds WHERE id = $1`, holdID).Scan(&walletID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, repoErr.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wallet, err := r.getByID(ctx, tx, walletID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hold, err := r.queryHold(ctx, tx, `SELECT * FROM holds WHERE id = $1 FOR UPDATE`, holdID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return hold, wallet, nil
+}
+
+// updateHeld is a helper method that sets the sum of the active holds of a wallet.
+func (r *Repository) updateHeld(ctx context.Context, tx pgx.Tx, walletID string, held int64) error {
+	query := `UPDATE wallets SET held_amount = $1, updated_at = NOW() WHERE id = $2`
+
+	if _, err := tx.Exec(ctx, query, held, walletID); err != nil {
+		return fmt.Errorf("failed to update held amount: %w", err)
+	}
+
+	return nil
+}
+
+// queryHold is a helper method that runs a query returning exactly one hold row.
+// If the query returns no rows, it returns [pgx.ErrNoRows].
+func (r *Repository) queryHold(
+	ctx context.Context,
+	q postgresPkg.Queryer,
+	query string,
+	args ...any,
+) (*entity.Hold, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hold, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[model.Hold])
+	if err != nil {
+		return nil, err
+	}
+
+	return hold.ToEntity(), nil
+}
@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+// GetIdempotencyKey is a method that retrieves an idempotency key stored for the client.
+// If the key is not found, it returns [repoErr.ErrIdempotencyKeyNotFound].
+func (r *Repository) GetIdempotencyKey(ctx context.Context, clientID, key string) (*entity.IdempotencyKey, error) {
+	const op = "repository.wallet.GetIdempotencyKey"
+
+	query := `SELECT client_id, key, fingerprint, status_code, response, created_at FROM idempotency_keys
+		WHERE client_id = $1 AND key = $2`
+
+	rows, err := r.db.Query(ctx, query, clientID, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	res, err := pgx.CollectExactlyOneRow(rows, func(row pgx.CollectableRow) (entity.IdempotencyKey, error) {
+		var k entity.IdempotencyKey
+		err := row.Scan(&k.ClientID, &k.Key, &k.Fingerprint, &k.StatusCode, &k.Response, &k.CreatedAt)
+		return k, err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, repoErr.ErrIdempotencyKeyNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &res, nil
+}
+
+// DeleteIdempotencyKeys is a method that deletes idempotency keys created before
+// the given time. It returns the number of deleted keys.
+func (r *Repository) DeleteIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int64, error) {
+	const op = "repository.wallet.DeleteIdempotencyKeys"
+
+	query := `DELETE FROM idempotency_keys WHERE created_at < $1`
+
+	tag, err := r.db.Exec(ctx, query, createdBefore)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// insertIdempotencyKey is a helper method that stores an idempotency key within a transaction.
+// If the client already used the key, it returns [repoErr.ErrIdempotencyKeyExists].
+func (r *Repository) insertIdempotencyKey(ctx context.Context, tx pgx.Tx, key *entity.IdempotencyKey) error {
+	query := `INSERT INTO idempotency_keys (client_id, key, fingerprint, status_code, response)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, query, key.ClientID, key.Key, key.Fingerprint, key.StatusCode, key.Response)
+	if isUniqueViolation(err) {
+		return repoErr.ErrIdempotencyKeyExists
+	}
+
+	return err
+}
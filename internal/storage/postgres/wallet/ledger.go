@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+// TrialBalance is a method that derives account balances from the journal and
+// compares them with the cached wallet balances. All figures are read from a
+// single snapshot of the database and are grouped by currency.
+func (r *Repository) TrialBalance(ctx context.Context) (*entity.TrialBalance, error) {
+	const op = "repository.wallet.TrialBalance"
+
+	var res entity.TrialBalance
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`); err != nil {
+			return err
+		}
+
+		systemQuery := `SELECT a.id, a.code, a.kind, e.currency, SUM(p.amount)::BIGINT
+			FROM ledger_accounts a
+			JOIN journal_postings p ON p.account_id = a.id
+			JOIN journal_entries e ON e.id = p.entry_id
+			WHERE a.kind = 'system'
+			GROUP BY a.id, a.code, a.kind, e.currency
+			ORDER BY a.code, e.currency`
+
+		rows, err := tx.Query(ctx, systemQuery)
+		if err != nil {
+			return err
+		}
+		res.SystemAccounts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.AccountBalance, error) {
+			var b entity.AccountBalance
+			err := row.Scan(&b.AccountID, &b.Code, &b.Kind, &b.Balance.Currency, &b.Balance.Amount)
+			return b, err
+		})
+		if err != nil {
+			return err
+		}
+
+		liabilitiesQuery := `SELECT e.currency, SUM(p.amount)::BIGINT
+			FROM journal_postings p
+			JOIN ledger_accounts a ON a.id = p.account_id
+			JOIN journal_entries e ON e.id = p.entry_id
+			WHERE a.kind = 'wallet'
+			GROUP BY e.currency
+			ORDER BY e.currency`
+
+		rows, err = tx.Query(ctx, liabilitiesQuery)
+		if err != nil {
+			return err
+		}
+		res.WalletLiabilities, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.Money, error) {
+			var m entity.Money
+			err := row.Scan(&m.Currency, &m.Amount)
+			return m, err
+		})
+		if err != nil {
+			return err
+		}
+
+		mismatchQuery := `SELECT w.id, w.currency, w.balance, COALESCE(SUM(p.amount), 0)::BIGINT
+			FROM wallets w
+			LEFT JOIN journal_postings p ON p.account_id = w.id
+			GROUP BY w.id, w.currency, w.balance
+			HAVING w.balance <> COALESCE(SUM(p.amount), 0)
+			ORDER BY w.id`
+
+		rows, err = tx.Query(ctx, mismatchQuery)
+		if err != nil {
+			return err
+		}
+		res.Mismatches, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (entity.BalanceMismatch, error) {
+			var m entity.BalanceMismatch
+			err := row.Scan(&m.WalletID, &m.CachedBalance.Currency, &m.CachedBalance.Amount, &m.LedgerBalance.Amount)
+			m.LedgerBalance.Currency = m.CachedBalance.Currency
+			return m, err
+		})
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res.Totals = totalsByCurrency(res)
+
+	return &res, nil
+}
+
+// totalsByCurrency sums the wallet liabilities and the system account balances
+// per currency. The totals are sorted by currency.
+func totalsByCurrency(tb entity.TrialBalance) []entity.Money {
+	sums := make(map[entity.Currency]int64)
+	for _, m := range tb.WalletLiabilities {
+		sums[m.Currency] += m.Amount
+	}
+	for _, a := range tb.SystemAccounts {
+		sums[a.Balance.Currency] += a.Balance.Amount
+	}
+
+	totals := make([]entity.Money, 0, len(sums))
+	for _, currency := range slices.Sorted(maps.Keys(sums)) {
+		totals = append(totals, entity.NewMoney(sums[currency], currency))
+	}
+
+	return totals
+}
+
+// postEntry is a helper method that writes a balanced journal entry within a transaction.
+// If the postings don't sum to zero, it returns [repoErr.ErrUnbalancedEntry].
+// The generated entry ID is returned.
+func (r *Repository) postEntry(ctx context.Context, tx pgx.Tx, entry entity.JournalEntry) (string, error) {
+	if !entry.IsBalanced() {
+		return "", repoErr.ErrUnbalancedEntry
+	}
+
+	entryQuery := `INSERT INTO journal_entries (kind, currency) VALUES ($1, $2) RETURNING id`
+
+	if err := tx.QueryRow(ctx, entryQuery, string(entry.Kind), string(entry.Currency)).Scan(&entry.ID); err != nil {
+		return "", fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	accounts := make([]string, 0, len(entry.Postings))
+	amounts := make([]int64, 0, len(entry.Postings))
+	for _, p := range entry.Postings {
+		accounts = append(accounts, p.AccountID)
+		amounts = append(amounts, p.Amount)
+	}
+
+	postingsQuery := `INSERT INTO journal_postings (entry_id, account_id, amount)
+		SELECT $1, unnest($2::UUID[]), unnest($3::BIGINT[])`
+
+	if _, err := tx.Exec(ctx, postingsQuery, entry.ID, accounts, amounts); err != nil {
+		return "", fmt.Errorf("failed to write journal postings: %w", err)
+	}
+
+	return entry.ID, nil
+}
+
+// cashAccount returns the system account that is the counterparty of a wallet
+// deposit (positive amount) or withdrawal (negative amount).
+func cashAccount(amount int64) string {
+	if amount < 0 {
+		return entity.AccountCashOut
+	}
+	return entity.AccountCashIn
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// BalanceChanged is the payload of WalletCredited and WalletDebited events.
+type BalanceChanged struct {
+	TransactionID string `json:"transactionId"`
+	OperationType string `json:"operationType"`
+	// Amount is positive, the event type tells the direction.
+	Amount       int64     `json:"amount"`
+	Currency     string    `json:"currency"`
+	BalanceAfter int64     `json:"balanceAfter"`
+	ReferenceID  string    `json:"referenceId,omitempty"`
+	OccurredAt   time.Time `json:"occurredAt"`
+}
+
+// WalletCreated is the payload of WalletCreated events.
+type WalletCreated struct {
+	Currency       string    `json:"currency"`
+	OverdraftLimit *int64    `json:"overdraftLimit,omitempty"`
+	OccurredAt     time.Time `json:"occurredAt"`
+}
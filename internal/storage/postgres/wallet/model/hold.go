@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+type Hold struct {
+	ID             string    `db:"id"`
+	WalletID       string    `db:"wallet_id"`
+	Amount         int64     `db:"amount"`
+	Currency       string    `db:"currency"`
+	CapturedAmount int64     `db:"captured_amount"`
+	Status         string    `db:"status"`
+	TransactionID  *string   `db:"transaction_id"`
+	ExpiresAt      time.Time `db:"expires_at"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+func (h Hold) ToEntity() *entity.Hold {
+	var transactionID string
+	if h.TransactionID != nil {
+		transactionID = *h.TransactionID
+	}
+
+	return &entity.Hold{
+		ID:             h.ID,
+		WalletID:       h.WalletID,
+		Amount:         entity.NewMoney(h.Amount, entity.Currency(h.Currency)),
+		CapturedAmount: h.CapturedAmount,
+		Status:         entity.HoldStatus(h.Status),
+		TransactionID:  transactionID,
+		ExpiresAt:      h.ExpiresAt,
+		CreatedAt:      h.CreatedAt,
+		UpdatedAt:      h.UpdatedAt,
+	}
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+type QueuedOperation struct {
+	ID            string    `db:"id"`
+	WalletID      string    `db:"wallet_id"`
+	OperationType string    `db:"operation_type"`
+	Amount        int64     `db:"amount"`
+	Currency      string    `db:"currency"`
+	Status        string    `db:"status"`
+	FailureReason *string   `db:"failure_reason"`
+	TransactionID *string   `db:"transaction_id"`
+	Attempts      int       `db:"attempts"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+func (o QueuedOperation) ToEntity() *entity.QueuedOperation {
+	var failureReason, transactionID string
+	if o.FailureReason != nil {
+		failureReason = *o.FailureReason
+	}
+	if o.TransactionID != nil {
+		transactionID = *o.TransactionID
+	}
+
+	return &entity.QueuedOperation{
+		ID: o.ID,
+		Operation: entity.Operation{
+			WalletID: o.WalletID,
+			Amount:   entity.NewMoney(o.Amount, entity.Currency(o.Currency)),
+			Type:     entity.OperationType(o.OperationType),
+		},
+		Status:        entity.QueuedOperationStatus(o.Status),
+		FailureReason: failureReason,
+		TransactionID: transactionID,
+		Attempts:      o.Attempts,
+		CreatedAt:     o.CreatedAt,
+		UpdatedAt:     o.UpdatedAt,
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+type Transaction struct {
+	ID            string    `db:"id"`
+	WalletID      string    `db:"wallet_id"`
+	Amount        int64     `db:"amount"`
+	OperationType string    `db:"operation_type"`
+	BalanceAfter  int64     `db:"balance_after"`
+	ReferenceID   *string   `db:"reference_id"`
+	EntryID       *string   `db:"entry_id"`
+	Sequence      int64     `db:"sequence"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+func (t Transaction) ToEntity() *entity.Transaction {
+	var referenceID, entryID string
+	if t.ReferenceID != nil {
+		referenceID = *t.ReferenceID
+	}
+	if t.EntryID != nil {
+		entryID = *t.EntryID
+	}
+
+	return &entity.Transaction{
+		ID:            t.ID,
+		WalletID:      t.WalletID,
+		Amount:        t.Amount,
+		OperationType: entity.OperationType(t.OperationType),
+		BalanceAfter:  t.BalanceAfter,
+		ReferenceID:   referenceID,
+		EntryID:       entryID,
+		Sequence:      t.Sequence,
+		CreatedAt:     t.CreatedAt,
+	}
+}
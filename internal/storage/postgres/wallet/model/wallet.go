@@ -7,17 +7,39 @@ import (
 )
 
 type Wallet struct {
-	ID        string    `db:"id"`
-	Balance   int64     `db:"balance"`
-	UpdatedAt time.Time `db:"updated_at"`
-	CreateAt  time.Time `db:"created_at"`
+	ID              string     `db:"id"`
+	Balance         int64      `db:"balance"`
+	Currency        string     `db:"currency"`
+	HeldAmount      int64      `db:"held_amount"`
+	Status          string     `db:"status"`
+	StatusReason    *string    `db:"status_reason"`
+	StatusChangedAt *time.Time `db:"status_changed_at"`
+	OverdraftLimit  *int64     `db:"overdraft_limit"`
+	OwnerID         *string    `db:"owner_id"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	CreateAt        time.Time  `db:"created_at"`
 }
 
 func (w Wallet) ToEntity() *entity.Wallet {
+	var ownerID string
+	if w.OwnerID != nil {
+		ownerID = *w.OwnerID
+	}
+	var statusReason string
+	if w.StatusReason != nil {
+		statusReason = *w.StatusReason
+	}
+
 	return &entity.Wallet{
-		ID:        w.ID,
-		Balance:   w.Balance,
-		UpdatedAt: w.UpdatedAt,
-		CreatedAt: w.CreateAt,
+		ID:              w.ID,
+		Balance:         entity.NewMoney(w.Balance, entity.Currency(w.Currency)),
+		Held:            w.HeldAmount,
+		Status:          entity.WalletStatus(w.Status),
+		StatusReason:    statusReason,
+		StatusChangedAt: w.StatusChangedAt,
+		OverdraftLimit:  w.OverdraftLimit,
+		OwnerID:         ownerID,
+		UpdatedAt:       w.UpdatedAt,
+		CreatedAt:       w.CreateAt,
 	}
 }
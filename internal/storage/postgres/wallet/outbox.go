@@ -0,0 +1,69 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/wallet/model"
+)
+
+// recordTransaction is a helper method that appends an entry to the wallet_transactions
+// ledger, writes the matching WalletCredited or WalletDebited event to the outbox and
+// stages the audit record of the balance change.
+func (r *Repository) recordTransaction(
+	ctx context.Context,
+	tx pgx.Tx,
+	t *entity.Transaction,
+	currency entity.Currency,
+) error {
+	if err := r.insertTransaction(ctx, tx, t); err != nil {
+		return err
+	}
+
+	eventType := entity.EventWalletCredited
+	if t.Amount < 0 {
+		eventType = entity.EventWalletDebited
+	}
+
+	err := r.insertEvent(ctx, tx, eventType, t.WalletID, model.BalanceChanged{
+		TransactionID: t.ID,
+		OperationType: string(t.OperationType),
+		Amount:        abs(t.Amount),
+		Currency:      string(currency),
+		BalanceAfter:  t.BalanceAfter,
+		ReferenceID:   t.ReferenceID,
+		OccurredAt:    t.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.stageBalanceAudit(ctx, tx, t)
+}
+
+// insertEvent is a helper method that writes an event to the outbox. The event is
+// published by the relay once the transaction commits.
+func (r *Repository) insertEvent(
+	ctx context.Context,
+	tx pgx.Tx,
+	eventType entity.EventType,
+	walletID string,
+	payload any,
+) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	query := `INSERT INTO outbox (event_type, wallet_id, payload) VALUES ($1, $2, $3)`
+
+	if _, err := tx.Exec(ctx, query, string(eventType), walletID, data); err != nil {
+		return fmt.Errorf("failed to write %s event: %w", eventType, err)
+	}
+
+	return nil
+}
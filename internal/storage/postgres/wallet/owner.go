@@ -0,0 +1,45 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+// AssignOwner is a method that makes the end user with the subject ownerID the owner
+// of the wallet, replacing its current owner. An empty ownerID leaves the wallet
+// without an owner.
+// If the wallet does not exist, it returns [repoErr.ErrWalletNotFound].
+func (r *Repository) AssignOwner(ctx context.Context, walletID, ownerID string) (*entity.Wallet, error) {
+	const op = "repository.wallet.AssignOwner"
+
+	var wallet *entity.Wallet
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		query := `UPDATE wallets SET owner_id = NULLIF($1, ''), updated_at = NOW() WHERE id = $2 RETURNING *`
+
+		var err error
+		wallet, err = r.queryWallet(ctx, tx, query, ownerID, walletID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repoErr.ErrWalletNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update wallet owner: %w", err)
+		}
+
+		return r.stageAudit(ctx, tx, entity.AuditRecord{
+			Action:   entity.AuditWalletAssignOwner,
+			WalletID: wallet.ID,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return wallet, nil
+}
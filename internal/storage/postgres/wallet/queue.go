@@ -0,0 +1,274 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/wallet/model"
+	postgresPkg "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
+)
+
+// EnqueueOperation is a method that stores a deposit or withdrawal to be applied later
+// by [Repository.ProcessQueuedOperation]. The wallet is checked to exist and the amount
+// is converted to the wallet currency, but the balance is not changed.
+// If operation.IdempotencyKey is set, it is stored in the same transaction; if the key
+// already exists, it returns [repoErr.ErrIdempotencyKeyExists] and nothing is queued.
+// If the wallet does not exist, it returns [repoErr.ErrWalletNotFound].
+// If the operation currency differs from the wallet currency, it returns [repoErr.ErrCurrencyMismatch].
+// If the wallet is frozen or closed, it returns [repoErr.ErrWalletFrozen] or [repoErr.ErrWalletClosed];
+// the status is checked again when the operation is applied.
+func (r *Repository) EnqueueOperation(ctx context.Context, operation entity.QueuedOperation) (*entity.QueuedOperation, error) {
+	const op = "repository.wallet.EnqueueOperation"
+
+	var queued *entity.QueuedOperation
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		if operation.IdempotencyKey != nil {
+			if err := r.insertIdempotencyKey(ctx, tx, operation.IdempotencyKey); err != nil {
+				return err
+			}
+		}
+
+		wallet, err := r.getByID(ctx, tx, operation.WalletID, false)
+		if err != nil {
+			return err
+		}
+		if err := checkStatus(wallet); err != nil {
+			return err
+		}
+
+		amount := inWalletCurrency(wallet, operation.Amount)
+		if amount.Currency != wallet.Currency() {
+			return repoErr.ErrCurrencyMismatch
+		}
+
+		query := `INSERT INTO operations (id, wallet_id, operation_type, amount, currency)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *`
+
+		queued, err = r.queryOperation(ctx, tx, query,
+			operation.ID, wallet.ID, string(operation.Type), amount.Amount, string(amount.Currency))
+		if err != nil {
+			return fmt.Errorf("failed to queue operation: %w", err)
+		}
+
+		return r.stageAudit(ctx, tx, entity.AuditRecord{
+			Action:     entity.AuditEnqueue,
+			WalletID:   queued.WalletID,
+			ResourceID: queued.ID,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return queued, nil
+}
+
+// GetQueuedOperation is a method that retrieves a queued operation by its ID.
+// If the operation is not found, it returns [repoErr.ErrOperationNotFound].
+func (r *Repository) GetQueuedOperation(ctx context.Context, operationID string) (*entity.QueuedOperation, error) {
+	const op = "repository.wallet.GetQueuedOperation"
+
+	operation, err := r.queryOperation(ctx, r.db, `SELECT * FROM operations WHERE id = $1`, operationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, repoErr.ErrOperationNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return operation, nil
+}
+
+// ProcessQueuedOperation is a method that applies the oldest pending operation the
+// same way [Repository.Operation] does and records the outcome.
+// The operation row is claimed with SKIP LOCKED, so several workers can process the
+// queue concurrently without applying an operation twice.
+// If the operation is rejected, e.g. the wallet has insufficient funds, its balance
+// changes are rolled back to a savepoint and the operation is marked as failed with
+// the reason. Other errors are counted as a failed attempt and returned, leaving the
+// operation pending, so it is retried later; once the operation has been attempted
+// as many times as the repository allows, it is marked as failed instead, so an
+// operation that can never be applied doesn't hold up the queue. Clients can read
+// the failure reason, so it is only [queuedInternalError] then, and the failed
+// operation is returned together with the error of its last attempt.
+// If there are no pending operations, it returns nil.
+func (r *Repository) ProcessQueuedOperation(ctx context.Context) (*entity.QueuedOperation, error) {
+	const op = "repository.wallet.ProcessQueuedOperation"
+
+	var (
+		processed *entity.QueuedOperation
+		// attemptErr is the error of an attempt that is retried later.
+		attemptErr error
+		// lastAttemptErr is the error of the attempt after which the operation failed.
+		lastAttemptErr error
+	)
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		query := `SELECT * FROM operations
+			WHERE status = 'pending'
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED`
+
+		operation, err := r.queryOperation(ctx, tx, query)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		transaction, err := r.applyQueued(ctx, tx, operation)
+		if rejection := rejectionOf(err); rejection != nil {
+			query = `UPDATE operations SET status = $1, failure_reason = $2, updated_at = NOW()
+				WHERE id = $3
+				RETURNING *`
+
+			processed, err = r.queryOperation(ctx, tx, query,
+				string(entity.QueuedOperationFailed), rejection.Error(), operation.ID)
+			if err != nil {
+				return fmt.Errorf("failed to update operation: %w", err)
+			}
+
+			return nil
+		}
+		if err != nil {
+			if operation.Attempts+1 < r.maxQueuedAttempts {
+				attemptErr = err
+
+				query = `UPDATE operations SET attempts = attempts + 1, updated_at = NOW() WHERE id = $1`
+				if _, err := tx.Exec(ctx, query, operation.ID); err != nil {
+					return fmt.Errorf("failed to count attempt: %w", err)
+				}
+
+				return nil
+			}
+
+			query = `UPDATE operations SET status = $1, failure_reason = $2, attempts = attempts + 1, updated_at = NOW()
+				WHERE id = $3
+				RETURNING *`
+
+			lastAttemptErr = err
+			processed, err = r.queryOperation(ctx, tx, query,
+				string(entity.QueuedOperationFailed), queuedInternalError, operation.ID)
+			if err != nil {
+				return fmt.Errorf("failed to update operation: %w", err)
+			}
+
+			return nil
+		}
+
+		query = `UPDATE operations SET status = $1, transaction_id = $2, updated_at = NOW()
+			WHERE id = $3
+			RETURNING *`
+
+		processed, err = r.queryOperation(ctx, tx, query,
+			string(entity.QueuedOperationCompleted), transaction.ID, operation.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update operation: %w", err)
+		}
+		processed.Transaction = transaction
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if attemptErr != nil {
+		return nil, fmt.Errorf("%s: %w", op, attemptErr)
+	}
+	if lastAttemptErr != nil {
+		return processed, fmt.Errorf("%s: gave up after %d attempts: %w", op, processed.Attempts, lastAttemptErr)
+	}
+
+	return processed, nil
+}
+
+// applyQueued is a helper method that applies a queued operation within a savepoint,
+// so its changes can be rolled back while the operation row stays locked.
+func (r *Repository) applyQueued(
+	ctx context.Context,
+	tx pgx.Tx,
+	operation *entity.QueuedOperation,
+) (transaction *entity.Transaction, err error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = savepoint.Rollback(ctx)
+		}
+	}()
+
+	wallet, err := r.getByID(ctx, savepoint, operation.WalletID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err = r.apply(ctx, savepoint, wallet, operation.Operation, cashAccount(operation.Amount.Amount), "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := savepoint.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// queryOperation is a helper method that runs a query returning exactly one
+// operation row. If the query returns no rows, it returns [pgx.ErrNoRows].
+func (r *Repository) queryOperation(
+	ctx context.Context,
+	q postgresPkg.Queryer,
+	query string,
+	args ...any,
+) (*entity.QueuedOperation, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	operation, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[model.QueuedOperation])
+	if err != nil {
+		return nil, err
+	}
+
+	return operation.ToEntity(), nil
+}
+
+// queuedInternalError is the failure reason of an operation given up on after
+// unexpected errors, which may contain SQL and aren't shown to clients.
+const queuedInternalError = "internal error"
+
+// rejections are the errors that mean an operation can never be applied as is,
+// so retrying it is pointless.
+var rejections = []error{
+	repoErr.ErrInsufficientFunds,
+	repoErr.ErrWalletNotFound,
+	repoErr.ErrCurrencyMismatch,
+	repoErr.ErrBalanceOverflow,
+	repoErr.ErrWalletFrozen,
+	repoErr.ErrWalletClosed,
+}
+
+// rejectionOf returns the rejection err wraps, or nil if it isn't one. Its message
+// is the failure reason, without the context err adds to it.
+func rejectionOf(err error) error {
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection) {
+			return rejection
+		}
+	}
+	return nil
+}
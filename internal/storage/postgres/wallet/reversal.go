@@ -0,0 +1,131 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/wallet/model"
+)
+
+// Reverse is a method that writes a compensating change for a past wallet transaction.
+// The reversal goes through the same path as [Repository.Operation]: the wallet row is
+// locked, the change is posted to the journal against the account the original change
+// was posted against, and the reversal is recorded with a reference to the original.
+// Several partial reversals are allowed as long as their total doesn't exceed the
+// original amount; the locked wallet row serializes them.
+// If reversal.IdempotencyKey is set, it is stored in the same transaction; if the key
+// already exists, it returns [repoErr.ErrIdempotencyKeyExists] and nothing is changed.
+// If the transaction is not found, it returns [repoErr.ErrTransactionNotFound].
+// If the transaction type can't be reversed, it returns [repoErr.ErrNotReversible].
+// If the amount exceeds the amount left to reverse, it returns [repoErr.ErrReversalAmountExceeded].
+// Reversing a deposit debits the wallet, so it returns [repoErr.ErrInsufficientFunds]
+// if the wallet doesn't have enough available funds.
+func (r *Repository) Reverse(ctx context.Context, reversal entity.Reversal) (*entity.Transaction, error) {
+	const op = "repository.wallet.Reverse"
+
+	var transaction *entity.Transaction
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		if reversal.IdempotencyKey != nil {
+			if err := r.insertIdempotencyKey(ctx, tx, reversal.IdempotencyKey); err != nil {
+				return err
+			}
+		}
+
+		original, err := r.getTransaction(ctx, tx, reversal.TransactionID)
+		if err != nil {
+			return err
+		}
+		if !original.OperationType.IsReversible() {
+			return repoErr.ErrNotReversible
+		}
+
+		wallet, err := r.getByID(ctx, tx, original.WalletID, true)
+		if err != nil {
+			return err
+		}
+
+		reversed, err := r.reversedAmount(ctx, tx, original.ID)
+		if err != nil {
+			return err
+		}
+		remaining := abs(original.Amount) - reversed
+
+		amount := inWalletCurrency(wallet, reversal.Amount)
+		if amount.Currency != wallet.Currency() {
+			return repoErr.ErrCurrencyMismatch
+		}
+		if amount.Amount == 0 {
+			amount.Amount = remaining
+		}
+		if remaining == 0 || amount.Amount > remaining {
+			return repoErr.ErrReversalAmountExceeded
+		}
+
+		// The reversal has the opposite sign of the original change.
+		if original.Amount > 0 {
+			amount = amount.Neg()
+		}
+
+		transaction, err = r.apply(ctx, tx, wallet, entity.Operation{
+			WalletID: wallet.ID,
+			Amount:   amount,
+			Type:     entity.OperationReversal,
+		}, cashAccount(original.Amount), original.ID)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return transaction, nil
+}
+
+// getTransaction is a helper method that retrieves a wallet transaction by its ID.
+// If the transaction is not found, it returns [repoErr.ErrTransactionNotFound].
+func (r *Repository) getTransaction(ctx context.Context, tx pgx.Tx, transactionID string) (*entity.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM wallet_transactions WHERE id = $1`
+
+	rows, err := tx.Query(ctx, query, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[model.Transaction])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repoErr.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t.ToEntity(), nil
+}
+
+// reversedAmount is a helper method that returns the total amount of the reversals
+// of a transaction.
+func (r *Repository) reversedAmount(ctx context.Context, tx pgx.Tx, transactionID string) (int64, error) {
+	query := `SELECT COALESCE(SUM(ABS(amount)), 0)::BIGINT FROM wallet_transactions
+		WHERE reference_id = $1 AND operation_type = $2`
+
+	var reversed int64
+	if err := tx.QueryRow(ctx, query, transactionID, string(entity.OperationReversal)).Scan(&reversed); err != nil {
+		return 0, fmt.Errorf("failed to get reversed amount: %w", err)
+	}
+
+	return reversed, nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
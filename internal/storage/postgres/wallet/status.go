@@ -0,0 +1,107 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+// ChangeStatus is a method that moves a wallet to change.Status and stores change.Reason
+// with it. A wallet being closed must have no active holds and, unless its balance is
+// zero, change.SweepToWalletID: the positive balance is transferred to that wallet in
+// the same transaction, and the transfer is returned. Both wallets are locked like
+// for a transfer.
+// If any of the wallets does not exist, it returns [repoErr.ErrWalletNotFound].
+// If the wallet can't move to the status, it returns [repoErr.ErrWalletStatusTransition].
+// If the wallet can't be closed because of its balance or holds, it returns [repoErr.ErrWalletNotEmpty].
+// If the wallet would be swept to itself, it returns [repoErr.ErrSameWallet].
+// If the wallet swept to is frozen or closed, it returns [repoErr.ErrWalletFrozen] or
+// [repoErr.ErrWalletClosed]; if it holds another currency, [repoErr.ErrCurrencyMismatch].
+func (r *Repository) ChangeStatus(
+	ctx context.Context,
+	change entity.WalletStatusChange,
+) (*entity.Wallet, *entity.TransferResult, error) {
+	const op = "repository.wallet.ChangeStatus"
+
+	var (
+		wallet *entity.Wallet
+		sweep  *entity.TransferResult
+	)
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		walletIDs := []string{change.WalletID}
+		if change.SweepToWalletID != "" {
+			walletIDs = append(walletIDs, change.SweepToWalletID)
+		}
+
+		wallets, err := r.lockWallets(ctx, tx, walletIDs...)
+		if err != nil {
+			return err
+		}
+		current := wallets[change.WalletID]
+
+		if !current.Status.CanChangeTo(change.Status) {
+			return repoErr.ErrWalletStatusTransition
+		}
+
+		if change.Status == entity.WalletStatusClosed {
+			sweep, err = r.sweep(ctx, tx, current, wallets[change.SweepToWalletID])
+			if err != nil {
+				return err
+			}
+		}
+
+		query := `UPDATE wallets
+			SET status = $1, status_reason = $2, status_changed_at = NOW(), updated_at = NOW()
+			WHERE id = $3
+			RETURNING *`
+
+		wallet, err = r.queryWallet(ctx, tx, query, string(change.Status), change.Reason, change.WalletID)
+		if err != nil {
+			return fmt.Errorf("failed to update wallet status: %w", err)
+		}
+
+		return r.stageAudit(ctx, tx, entity.AuditRecord{
+			Action:   entity.AuditStatusAction(change.Status),
+			WalletID: wallet.ID,
+		})
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return wallet, sweep, nil
+}
+
+// sweep is a helper method that empties a wallet being closed into the wallet to,
+// which is nil if none was given. A wallet with zero balance needs no sweep. A
+// wallet with active holds or a negative balance can't be emptied.
+func (r *Repository) sweep(
+	ctx context.Context,
+	tx pgx.Tx,
+	from *entity.Wallet,
+	to *entity.Wallet,
+) (*entity.TransferResult, error) {
+	if from.Held != 0 {
+		return nil, repoErr.ErrWalletNotEmpty
+	}
+	if from.Balance.Amount == 0 {
+		return nil, nil
+	}
+	if to == nil || from.Balance.IsNegative() {
+		return nil, repoErr.ErrWalletNotEmpty
+	}
+	if from.ID == to.ID {
+		return nil, repoErr.ErrSameWallet
+	}
+
+	if err := checkStatus(to); err != nil {
+		return nil, err
+	}
+
+	return r.transfer(ctx, tx, from, to, from.Balance)
+}
@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	"github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/wallet/model"
+)
+
+const transactionColumns = `id, wallet_id, amount, operation_type, balance_after, reference_id, entry_id, sequence, created_at`
+
+// Transactions is a method that returns wallet transactions matching the filter,
+// ordered from newest to oldest. At most filter.Limit transactions are returned.
+func (r *Repository) Transactions(ctx context.Context, filter entity.TransactionFilter) ([]entity.Transaction, error) {
+	const op = "repository.wallet.Transactions"
+
+	var (
+		conds = []string{"wallet_id = $1"}
+		args  = []any{filter.WalletID}
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.OperationType != "" {
+		conds = append(conds, "operation_type = "+arg(string(filter.OperationType)))
+	}
+	if !filter.From.IsZero() {
+		conds = append(conds, "created_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conds = append(conds, "created_at < "+arg(filter.To))
+	}
+	if filter.After != nil {
+		conds = append(conds, fmt.Sprintf("(created_at, id) < (%s, %s)",
+			arg(filter.After.CreatedAt), arg(filter.After.ID)))
+	}
+
+	query := `SELECT ` + transactionColumns + ` FROM wallet_transactions WHERE ` +
+		strings.Join(conds, " AND ") +
+		` ORDER BY created_at DESC, id DESC LIMIT ` + arg(filter.Limit)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	txs, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.Transaction])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res := make([]entity.Transaction, 0, len(txs))
+	for _, t := range txs {
+		res = append(res, *t.ToEntity())
+	}
+
+	return res, nil
+}
+
+// LastSequence is a method that returns the sequence of the last transaction of the
+// wallet, or zero if it has none.
+func (r *Repository) LastSequence(ctx context.Context, walletID string) (int64, error) {
+	const op = "repository.wallet.LastSequence"
+
+	query := `SELECT COALESCE(MAX(sequence), 0) FROM wallet_transactions WHERE wallet_id = $1`
+
+	rows, err := r.db.Query(ctx, query, walletID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	sequence, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sequence, nil
+}
+
+// insertTransaction is a helper method that appends an entry to the wallet_transactions
+// ledger. The generated ID, sequence and creation time are written back to the given
+// transaction. The wallet row must be locked, so the sequence follows the last one
+// of the wallet.
+func (r *Repository) insertTransaction(ctx context.Context, tx pgx.Tx, t *entity.Transaction) error {
+	query := `INSERT INTO wallet_transactions (wallet_id, amount, operation_type, balance_after, reference_id, entry_id, sequence)
+		VALUES ($1, $2, $3, $4, $5, $6,
+			(SELECT COALESCE(MAX(sequence), 0) + 1 FROM wallet_transactions WHERE wallet_id = $1))
+		RETURNING id, sequence, created_at`
+
+	return tx.QueryRow(ctx, query,
+		t.WalletID, t.Amount, string(t.OperationType), t.BalanceAfter, nullable(t.ReferenceID), nullable(t.EntryID),
+	).Scan(&t.ID, &t.Sequence, &t.CreatedAt)
+}
+
+// nullable returns nil for an empty string, so it is stored as NULL.
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+// Transfer is a method that moves funds from one wallet to another in a single transaction.
+// Both wallet rows are locked in ascending ID order, so concurrent transfers in opposite
+// directions can't deadlock. A single journal entry moves the funds between the wallet
+// accounts, and a debit and a credit record are written to the wallet_transactions
+// ledger, the credit record references the debit one.
+// If transfer.IdempotencyKey is set, it is stored in the same transaction; if the key
+// already exists, it returns [repoErr.ErrIdempotencyKeyExists] and nothing is changed.
+// If any of the wallets does not exist, it returns [repoErr.ErrWalletNotFound].
+// If the wallets or the amount are in different currencies, it returns [repoErr.ErrCurrencyMismatch].
+// If the source wallet doesn't have enough available funds, it returns [repoErr.ErrInsufficientFunds].
+// If any of the wallets is frozen or closed, it returns [repoErr.ErrWalletFrozen] or [repoErr.ErrWalletClosed].
+// If both IDs refer to the same wallet, it returns [repoErr.ErrSameWallet].
+func (r *Repository) Transfer(ctx context.Context, transfer entity.Transfer) (*entity.TransferResult, error) {
+	const op = "repository.wallet.Transfer"
+
+	var result *entity.TransferResult
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		if transfer.IdempotencyKey != nil {
+			if err := r.insertIdempotencyKey(ctx, tx, transfer.IdempotencyKey); err != nil {
+				return err
+			}
+		}
+
+		wallets, err := r.lockWallets(ctx, tx, transfer.FromWalletID, transfer.ToWalletID)
+		if err != nil {
+			return err
+		}
+		from, to := wallets[transfer.FromWalletID], wallets[transfer.ToWalletID]
+
+		if err := checkStatus(from); err != nil {
+			return err
+		}
+		if err := checkStatus(to); err != nil {
+			return err
+		}
+
+		result, err = r.transfer(ctx, tx, from, to, transfer.Amount)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// transfer is a helper method that moves the amount from one wallet locked for update
+// to another within a transaction, checking the available funds of the source wallet
+// but not the status of the wallets. It returns the debit and credit records.
+// The wallets must be different rows: updating one row through two copies would
+// leave only the credit applied.
+func (r *Repository) transfer(
+	ctx context.Context,
+	tx pgx.Tx,
+	from *entity.Wallet,
+	to *entity.Wallet,
+	amount entity.Money,
+) (*entity.TransferResult, error) {
+	if from.ID == to.ID {
+		return nil, repoErr.ErrSameWallet
+	}
+
+	amount = inWalletCurrency(from, amount)
+
+	fromBalance, err := addToBalance(from, amount.Neg())
+	if err != nil {
+		return nil, err
+	}
+	toBalance, err := addToBalance(to, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkFunds(from, fromBalance); err != nil {
+		return nil, err
+	}
+
+	if err := r.updateBalance(ctx, tx, from.ID, fromBalance); err != nil {
+		return nil, err
+	}
+	if err := r.updateBalance(ctx, tx, to.ID, toBalance); err != nil {
+		return nil, err
+	}
+
+	entryID, err := r.postEntry(ctx, tx, entity.JournalEntry{
+		Kind:     entity.OperationTransfer,
+		Currency: amount.Currency,
+		Postings: []entity.Posting{
+			{AccountID: from.ID, Amount: -amount.Amount},
+			{AccountID: to.ID, Amount: amount.Amount},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	debit := &entity.Transaction{
+		WalletID:      from.ID,
+		Amount:        -amount.Amount,
+		OperationType: entity.OperationTransferOut,
+		BalanceAfter:  fromBalance.Amount,
+		EntryID:       entryID,
+	}
+	if err := r.recordTransaction(ctx, tx, debit, amount.Currency); err != nil {
+		return nil, fmt.Errorf("failed to record debit transaction: %w", err)
+	}
+
+	credit := &entity.Transaction{
+		WalletID:      to.ID,
+		Amount:        amount.Amount,
+		OperationType: entity.OperationTransferIn,
+		BalanceAfter:  toBalance.Amount,
+		ReferenceID:   debit.ID,
+		EntryID:       entryID,
+	}
+	if err := r.recordTransaction(ctx, tx, credit, amount.Currency); err != nil {
+		return nil, fmt.Errorf("failed to record credit transaction: %w", err)
+	}
+
+	return &entity.TransferResult{Debit: debit, Credit: credit}, nil
+}
+
+// lockWallets is a helper method that locks the given wallets for update in ascending
+// ID order. The fixed order guarantees that two transactions locking the same set of
+// wallets never wait on each other in a cycle.
+func (r *Repository) lockWallets(ctx context.Context, tx pgx.Tx, walletIDs ...string) (map[string]*entity.Wallet, error) {
+	ids := slices.Clone(walletIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	wallets := make(map[string]*entity.Wallet, len(ids))
+	for _, id := range ids {
+		wallet, err := r.getByID(ctx, tx, id, true)
+		if err != nil {
+			return nil, err
+		}
+		wallets[id] = wallet
+	}
+
+	return wallets, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -13,33 +14,288 @@ import (
 	postgresPkg "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
 )
 
+const uniqueViolationCode = "23505"
+
+// DefaultMaxQueuedAttempts is how many times a queued operation is attempted after
+// unexpected errors by default.
+const DefaultMaxQueuedAttempts = 5
+
 type DB interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 }
 
+// Metrics records the timings of balance operations.
+type Metrics interface {
+	ObserveLockWait(operationType entity.OperationType, d time.Duration)
+	ObserveTransaction(operationType entity.OperationType, d time.Duration)
+}
+
+// AuditStamp returns the audit record with who made the change in ctx filled in.
+type AuditStamp func(ctx context.Context, record entity.AuditRecord) entity.AuditRecord
+
 type Repository struct {
-	db DB
+	db         DB
+	metrics    Metrics
+	auditStamp AuditStamp
+
+	defaultOverdraftLimit int64
+	defaultCurrency       entity.Currency
+	maxQueuedAttempts     int
+}
+
+type Option func(*Repository)
+
+// WithDefaultOverdraftLimit sets the overdraft limit used for wallets without their own limit.
+func WithDefaultOverdraftLimit(limit int64) Option {
+	return func(r *Repository) {
+		r.defaultOverdraftLimit = limit
+	}
+}
+
+// WithDefaultCurrency sets the currency of wallets created without one.
+func WithDefaultCurrency(currency entity.Currency) Option {
+	return func(r *Repository) {
+		r.defaultCurrency = currency
+	}
+}
+
+// WithMaxQueuedAttempts sets how many times a queued operation is attempted after
+// unexpected errors before it is marked as failed.
+func WithMaxQueuedAttempts(attempts int) Option {
+	return func(r *Repository) {
+		r.maxQueuedAttempts = attempts
+	}
+}
+
+// WithMetrics records how long [Repository.Operation] waits for the wallet row lock
+// and how long its transaction takes.
+func WithMetrics(metrics Metrics) Option {
+	return func(r *Repository) {
+		r.metrics = metrics
+	}
 }
 
-func New(db DB) *Repository {
-	return &Repository{
-		db: db,
+// WithAudit records wallet creations, status changes, balance changes, queued
+// operations and hold creations and releases in the audit log, in the transaction
+// of the change. stamp fills in the actor and request of every record.
+func WithAudit(stamp AuditStamp) Option {
+	return func(r *Repository) {
+		r.auditStamp = stamp
 	}
 }
 
+func New(db DB, opts ...Option) *Repository {
+	r := &Repository{
+		db:                db,
+		defaultCurrency:   entity.CurrencyRUB,
+		maxQueuedAttempts: DefaultMaxQueuedAttempts,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
 // Operation is a method that performs a deposit or withdrawal operation on a wallet.
-// If amount is positive, it performs a deposit; if negative, it performs a withdrawal.
+// If operation.Amount is positive, it performs a deposit; if negative, it performs a withdrawal.
+// The change is posted to the journal as a balanced entry against the cash-in or
+// cash-out system account, and the cached wallet balance is updated accordingly.
+// Every operation is recorded in the wallet_transactions ledger within the same
+// transaction as the balance update, and the recorded entry is returned. A
+// WalletCredited or WalletDebited event is written to the outbox in that transaction too.
+// If operation.IdempotencyKey is set, it is stored in the same transaction; if the key
+// already exists, it returns [repoErr.ErrIdempotencyKeyExists] and nothing is changed.
 // If wallet with the given ID does not exist, it returns [repoErr.ErrWalletNotFound].
-// Note: If balance becomes negative after the operation, it will still be updated.
+// If the operation currency differs from the wallet currency, it returns
+// [repoErr.ErrCurrencyMismatch]; if the new balance doesn't fit into int64, it
+// returns [repoErr.ErrBalanceOverflow].
+// If a withdrawal would take the available balance, i.e. the balance minus the funds
+// reserved by holds, below the wallet overdraft limit, it returns [repoErr.ErrInsufficientFunds].
+// If the wallet is frozen or closed, it returns [repoErr.ErrWalletFrozen] or [repoErr.ErrWalletClosed].
+// The limit and the status are checked while the wallet row is locked.
 // Safe for concurrent use, as it uses a transaction to ensure atomicity.
-func (r *Repository) Operation(ctx context.Context, walletID string, amount int64) (err error) {
-	const op = "repository.wallet.Deposit"
+func (r *Repository) Operation(ctx context.Context, operation entity.Operation) (*entity.Transaction, error) {
+	const op = "repository.wallet.Operation"
+
+	var transaction *entity.Transaction
+
+	start := time.Now()
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		// The key is inserted before the wallet row is locked, so a concurrent retry
+		// waits on the key and fails fast once the first request commits.
+		if operation.IdempotencyKey != nil {
+			if err := r.insertIdempotencyKey(ctx, tx, operation.IdempotencyKey); err != nil {
+				return err
+			}
+		}
+
+		lockStart := time.Now()
+		wallet, err := r.getByID(ctx, tx, operation.WalletID, true)
+		if err != nil {
+			return err
+		}
+		if r.metrics != nil {
+			r.metrics.ObserveLockWait(operation.Type, time.Since(lockStart))
+		}
+
+		operation.Amount = inWalletCurrency(wallet, operation.Amount)
+
+		transaction, err = r.apply(ctx, tx, wallet, operation, cashAccount(operation.Amount.Amount), "")
+
+		return err
+	})
+	if r.metrics != nil {
+		r.metrics.ObserveTransaction(operation.Type, time.Since(start))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return transaction, nil
+}
+
+// apply is a helper method that applies the operation to a wallet locked for update
+// within a transaction. The change is posted to the journal against the counter account
+// and recorded in the wallet_transactions ledger with the given reference; the recorded
+// entry is returned. The idempotency key of the operation is not stored.
+// If the wallet is frozen or closed, nothing is changed.
+func (r *Repository) apply(
+	ctx context.Context,
+	tx pgx.Tx,
+	wallet *entity.Wallet,
+	operation entity.Operation,
+	counterAccount string,
+	referenceID string,
+) (*entity.Transaction, error) {
+	amount := operation.Amount
+
+	if err := checkStatus(wallet); err != nil {
+		return nil, err
+	}
+
+	balance, err := addToBalance(wallet, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount.IsNegative() {
+		if err := r.checkFunds(wallet, balance); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.updateBalance(ctx, tx, wallet.ID, balance); err != nil {
+		return nil, err
+	}
+
+	entryID, err := r.postEntry(ctx, tx, entity.JournalEntry{
+		Kind:     operation.Type,
+		Currency: amount.Currency,
+		Postings: []entity.Posting{
+			{AccountID: wallet.ID, Amount: amount.Amount},
+			{AccountID: counterAccount, Amount: -amount.Amount},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &entity.Transaction{
+		WalletID:      wallet.ID,
+		Amount:        amount.Amount,
+		OperationType: operation.Type,
+		BalanceAfter:  balance.Amount,
+		ReferenceID:   referenceID,
+		EntryID:       entryID,
+	}
+
+	if err := r.recordTransaction(ctx, tx, transaction, amount.Currency); err != nil {
+		return nil, fmt.Errorf("failed to record transaction: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// Create is a method that creates a new wallet with zero balance together with
+// its ledger account and writes a WalletCreated event to the outbox. If w.Balance has no currency, the default currency is used.
+// If a wallet with the given ID already exists, it returns [repoErr.ErrWalletAlreadyExists].
+func (r *Repository) Create(ctx context.Context, w entity.Wallet) (*entity.Wallet, error) {
+	const op = "repository.wallet.Create"
+
+	var wallet *entity.Wallet
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		currency := w.Currency()
+		if currency == "" {
+			currency = r.defaultCurrency
+		}
+
+		var ownerID *string
+		if w.OwnerID != "" {
+			ownerID = &w.OwnerID
+		}
+
+		query := `INSERT INTO wallets (id, currency, overdraft_limit, owner_id) VALUES ($1, $2, $3, $4) RETURNING *`
 
+		var err error
+		wallet, err = r.queryWallet(ctx, tx, query, w.ID, string(currency), w.OverdraftLimit, ownerID)
+		if err != nil {
+			return err
+		}
+
+		accountQuery := `INSERT INTO ledger_accounts (id, kind) VALUES ($1, $2)`
+
+		if _, err := tx.Exec(ctx, accountQuery, wallet.ID, string(entity.AccountKindWallet)); err != nil {
+			return err
+		}
+
+		err = r.insertEvent(ctx, tx, entity.EventWalletCreated, wallet.ID, model.WalletCreated{
+			Currency:       string(wallet.Currency()),
+			OverdraftLimit: wallet.OverdraftLimit,
+			OccurredAt:     wallet.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		return r.stageAudit(ctx, tx, entity.AuditRecord{
+			Action:   entity.AuditWalletCreate,
+			WalletID: wallet.ID,
+		})
+	})
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%s: %w", op, repoErr.ErrWalletAlreadyExists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return wallet, nil
+}
+
+// GetByID is a method that retrieves a wallet by its ID.
+// If the wallet is not found, it returns [repoErr.ErrWalletNotFound].
+func (r *Repository) GetByID(ctx context.Context, walletID string) (*entity.Wallet, error) {
+	const op = "repository.wallet.Balance"
+
+	wallet, err := r.getByID(ctx, r.db, walletID, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return wallet, nil
+}
+
+// inTx is a helper method that runs fn within a database transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+func (r *Repository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) (err error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return err
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -47,42 +303,55 @@ func (r *Repository) Operation(ctx context.Context, walletID string, amount int6
 			panic(p)
 		} else if err != nil {
 			if rbErr := tx.Rollback(ctx); rbErr != nil {
-				err = fmt.Errorf("%s: rollback failed: %v, original error: %w", op, rbErr, err)
+				err = fmt.Errorf("rollback failed: %v, original error: %w", rbErr, err)
 			}
 		} else {
 			if commitErr := tx.Commit(ctx); commitErr != nil {
-				err = fmt.Errorf("%s: commit failed: %w", op, commitErr)
+				err = fmt.Errorf("commit failed: %w", commitErr)
 			}
 		}
 	}()
 
-	wallet, err := r.getByID(ctx, tx, walletID, true)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
+	return fn(tx)
+}
 
-	balance := wallet.Balance + amount
+// checkFunds is a helper method that returns [repoErr.ErrInsufficientFunds] if the
+// new balance minus the funds reserved by holds is below the overdraft limit of the wallet.
+func (r *Repository) checkFunds(wallet *entity.Wallet, balance entity.Money) error {
+	limit := r.defaultOverdraftLimit
+	if wallet.OverdraftLimit != nil {
+		limit = *wallet.OverdraftLimit
+	}
 
-	query := `UPDATE wallets SET balance = $1, updated_at = NOW() WHERE id = $2`
-	_, err = tx.Exec(ctx, query, balance, walletID)
-	if err != nil {
-		return fmt.Errorf("%s: failed to update wallet balance: %w", op, err)
+	if balance.Amount-wallet.Held < -limit {
+		return repoErr.ErrInsufficientFunds
 	}
 
 	return nil
 }
 
-// GetByID is a method that retrieves a wallet by its ID.
-// If the wallet is not found, it returns [repoErr.ErrWalletNotFound].
-func (r *Repository) GetByID(ctx context.Context, walletID string) (*entity.Wallet, error) {
-	const op = "repository.wallet.Balance"
+// checkStatus returns [repoErr.ErrWalletFrozen] or [repoErr.ErrWalletClosed] if the
+// balance of the wallet may not change.
+func checkStatus(wallet *entity.Wallet) error {
+	switch wallet.Status {
+	case entity.WalletStatusFrozen:
+		return repoErr.ErrWalletFrozen
+	case entity.WalletStatusClosed:
+		return repoErr.ErrWalletClosed
+	default:
+		return nil
+	}
+}
 
-	wallet, err := r.getByID(ctx, r.db, walletID, false)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+// updateBalance is a helper method that sets the wallet balance within a transaction.
+func (r *Repository) updateBalance(ctx context.Context, tx pgx.Tx, walletID string, balance entity.Money) error {
+	query := `UPDATE wallets SET balance = $1, updated_at = NOW() WHERE id = $2`
+
+	if _, err := tx.Exec(ctx, query, balance.Amount, walletID); err != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", err)
 	}
 
-	return wallet, nil
+	return nil
 }
 
 // getByID is a helper method that retrieves a wallet by its ID.
@@ -100,19 +369,64 @@ func (r *Repository) getByID(
 		query += " FOR UPDATE"
 	}
 
-	rows, err := q.Query(ctx, query, walletID)
+	wallet, err := r.queryWallet(ctx, q, query, walletID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repoErr.ErrWalletNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet, nil
+}
+
+// queryWallet is a helper method that runs a query returning exactly one wallet row.
+// If the query returns no rows, it returns [pgx.ErrNoRows].
+func (r *Repository) queryWallet(
+	ctx context.Context,
+	q postgresPkg.Queryer,
+	query string,
+	args ...any,
+) (*entity.Wallet, error) {
+	rows, err := q.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	wallet, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[model.Wallet])
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, repoErr.ErrWalletNotFound
-	}
 	if err != nil {
 		return nil, err
 	}
 
 	return wallet.ToEntity(), nil
 }
+
+// inWalletCurrency returns the amount in the wallet currency if the amount has no currency.
+func inWalletCurrency(wallet *entity.Wallet, amount entity.Money) entity.Money {
+	if amount.Currency == "" {
+		amount.Currency = wallet.Currency()
+	}
+	return amount
+}
+
+// addToBalance returns the wallet balance increased by amount.
+// If the currencies differ, it returns [repoErr.ErrCurrencyMismatch]; if the sum
+// doesn't fit into int64, it returns [repoErr.ErrBalanceOverflow].
+func addToBalance(wallet *entity.Wallet, amount entity.Money) (entity.Money, error) {
+	balance, err := wallet.Balance.Add(amount)
+	if errors.Is(err, entity.ErrCurrencyMismatch) {
+		return entity.Money{}, repoErr.ErrCurrencyMismatch
+	}
+	if errors.Is(err, entity.ErrAmountOverflow) {
+		return entity.Money{}, repoErr.ErrBalanceOverflow
+	}
+
+	return balance, err
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
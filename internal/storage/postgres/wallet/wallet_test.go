@@ -1,10 +1,14 @@
 package wallet
 
 import (
+	"context"
 	"errors"
+	"math"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/require"
 
@@ -12,17 +16,70 @@ import (
 	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
 )
 
-var walletColumns = []string{"id", "balance", "updated_at", "created_at"}
+const testDefaultOverdraftLimit = 100
+
+var testEntryID = "test-entry-id"
+
+var (
+	noReference      *string
+	noEntry          *string
+	noOverdraftLimit *int64
+	noOwner          *string
+	noReason         *string
+	noStatusChange   *time.Time
+)
+
+var (
+	walletColumns     = []string{"id", "balance", "currency", "held_amount", "status", "overdraft_limit", "owner_id", "status_reason", "status_changed_at", "updated_at", "created_at"}
+	insertedTxColumns = []string{"id", "sequence", "created_at"}
+	holdColumns       = []string{
+		"id", "wallet_id", "amount", "currency", "captured_amount", "status",
+		"transaction_id", "expires_at", "created_at", "updated_at",
+	}
+	operationColumns = []string{
+		"id", "wallet_id", "operation_type", "amount", "currency", "status",
+		"failure_reason", "transaction_id", "created_at", "updated_at", "attempts",
+	}
+)
 
 type mockBehavior func(mock pgxmock.PgxPoolIface)
 
+// operationType returns the operation type matching the sign of the amount.
+func operationType(amount int64) entity.OperationType {
+	if amount < 0 {
+		return entity.OperationWithdraw
+	}
+	return entity.OperationDeposit
+}
+
+func rub(amount int64) entity.Money {
+	return entity.NewMoney(amount, entity.CurrencyRUB)
+}
+
+// expectJournalEntry expects a journal entry with the given postings to be written.
+func expectJournalEntry(mock pgxmock.PgxPoolIface, kind string, accounts []string, amounts []int64) {
+	mock.ExpectQuery(`INSERT INTO journal_entries \(kind, currency\) VALUES \(\$1, \$2\) RETURNING id`).
+		WithArgs(kind, "RUB").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(testEntryID))
+	mock.ExpectExec(`INSERT INTO journal_postings \(entry_id, account_id, amount\)`).
+		WithArgs(testEntryID, accounts, amounts).
+		WillReturnResult(pgxmock.NewResult("INSERT", int64(len(accounts))))
+}
+
+// expectEvent expects an event about the wallet to be written to the outbox.
+func expectEvent(mock pgxmock.PgxPoolIface, eventType entity.EventType, walletID string) {
+	mock.ExpectExec(`INSERT INTO outbox \(event_type, wallet_id, payload\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs(string(eventType), walletID, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+}
+
 func setupTest(t *testing.T) (pgxmock.PgxPoolIface, *Repository) {
 	t.Helper()
 
 	mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
 	require.NoError(t, err)
 
-	repo := New(mock)
+	repo := New(mock, WithDefaultOverdraftLimit(testDefaultOverdraftLimit))
 
 	return mock, repo
 }
@@ -32,15 +89,22 @@ func TestOperation(t *testing.T) {
 
 	const getQuery = `SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`
 	const updateQuery = `UPDATE wallets SET balance = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const insertTxQuery = `INSERT INTO wallet_transactions \(wallet_id, amount, operation_type, balance_after, reference_id, entry_id, sequence\)`
+	const insertKeyQuery = `INSERT INTO idempotency_keys \(client_id, key, fingerprint, status_code, response\)`
 
 	updErr := errors.New("update error")
+	insErr := errors.New("insert error")
+	zeroOverdraftLimit := int64(0)
 
 	tests := []struct {
-		name          string
-		walletID      string
-		amount        int64
-		mockBehavior  mockBehavior
-		expectedError error
+		name                string
+		walletID            string
+		amount              int64
+		currency            entity.Currency
+		idempotencyKey      *entity.IdempotencyKey
+		mockBehavior        mockBehavior
+		expectedTransaction *entity.Transaction
+		expectedError       error
 	}{
 		{
 			name:     "Deposit",
@@ -51,12 +115,27 @@ func TestOperation(t *testing.T) {
 				mock.ExpectQuery(getQuery).
 					WithArgs("test-wallet-id").
 					WillReturnRows(pgxmock.NewRows(walletColumns).
-						AddRow("test-wallet-id", 100, time.Time{}, time.Time{}))
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
 				mock.ExpectExec(updateQuery).
 					WithArgs(int64(200), "test-wallet-id").
 					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "deposit", []string{"test-wallet-id", entity.AccountCashIn}, []int64{100, -100})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(100), "deposit", int64(200), noReference, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).
+						AddRow("test-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletCredited, "test-wallet-id")
 				mock.ExpectCommit()
 			},
+			expectedTransaction: &entity.Transaction{
+				ID:            "test-tx-id",
+				WalletID:      "test-wallet-id",
+				Amount:        100,
+				OperationType: entity.OperationDeposit,
+				BalanceAfter:  200,
+				EntryID:       testEntryID,
+				Sequence:      1,
+			},
 			expectedError: nil,
 		},
 		{
@@ -68,14 +147,88 @@ func TestOperation(t *testing.T) {
 				mock.ExpectQuery(getQuery).
 					WithArgs("test-wallet-id").
 					WillReturnRows(pgxmock.NewRows(walletColumns).
-						AddRow("test-wallet-id", 100, time.Time{}, time.Time{}))
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
 				mock.ExpectExec(updateQuery).
 					WithArgs(int64(50), "test-wallet-id").
 					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "withdraw", []string{"test-wallet-id", entity.AccountCashOut}, []int64{-50, 50})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(-50), "withdraw", int64(50), noReference, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).
+						AddRow("test-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletDebited, "test-wallet-id")
 				mock.ExpectCommit()
 			},
+			expectedTransaction: &entity.Transaction{
+				ID:            "test-tx-id",
+				WalletID:      "test-wallet-id",
+				Amount:        -50,
+				OperationType: entity.OperationWithdraw,
+				BalanceAfter:  50,
+				EntryID:       testEntryID,
+				Sequence:      1,
+			},
 			expectedError: nil,
 		},
+		{
+			name:     "WithdrawWithinDefaultOverdraft",
+			walletID: "test-wallet-id",
+			amount:   -150,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(-50), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "withdraw", []string{"test-wallet-id", entity.AccountCashOut}, []int64{-150, 150})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(-150), "withdraw", int64(-50), noReference, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).
+						AddRow("test-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletDebited, "test-wallet-id")
+				mock.ExpectCommit()
+			},
+			expectedTransaction: &entity.Transaction{
+				ID:            "test-tx-id",
+				WalletID:      "test-wallet-id",
+				Amount:        -150,
+				OperationType: entity.OperationWithdraw,
+				BalanceAfter:  -50,
+				EntryID:       testEntryID,
+				Sequence:      1,
+			},
+		},
+		{
+			name:     "InsufficientFunds",
+			walletID: "test-wallet-id",
+			amount:   -150,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", &zeroOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrInsufficientFunds,
+		},
+		{
+			name:     "WalletFrozen",
+			walletID: "test-wallet-id",
+			amount:   100,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "frozen", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletFrozen,
+		},
 		{
 			name:     "WalletNotFound",
 			walletID: "non-existent-wallet-id",
@@ -98,7 +251,7 @@ func TestOperation(t *testing.T) {
 				mock.ExpectQuery(getQuery).
 					WithArgs("test-wallet-id").
 					WillReturnRows(pgxmock.NewRows(walletColumns).
-						AddRow("test-wallet-id", 100, time.Time{}, time.Time{}))
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
 				mock.ExpectExec(updateQuery).
 					WithArgs(int64(150), "test-wallet-id").
 					WillReturnError(updErr)
@@ -106,6 +259,205 @@ func TestOperation(t *testing.T) {
 			},
 			expectedError: updErr,
 		},
+		{
+			name:     "InsertTransactionError",
+			walletID: "test-wallet-id",
+			amount:   50,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(150), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "deposit", []string{"test-wallet-id", entity.AccountCashIn}, []int64{50, -50})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(50), "deposit", int64(150), noReference, &testEntryID).
+					WillReturnError(insErr)
+				mock.ExpectRollback()
+			},
+			expectedError: insErr,
+		},
+		{
+			name:           "WithIdempotencyKey",
+			walletID:       "test-wallet-id",
+			amount:         100,
+			idempotencyKey: &entity.IdempotencyKey{ClientID: "key:test", Key: "key", Fingerprint: "fp", StatusCode: 200, Response: []byte("{}")},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(insertKeyQuery).
+					WithArgs("key:test", "key", "fp", 200, []byte("{}")).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(200), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "deposit", []string{"test-wallet-id", entity.AccountCashIn}, []int64{100, -100})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(100), "deposit", int64(200), noReference, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).
+						AddRow("test-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletCredited, "test-wallet-id")
+				mock.ExpectCommit()
+			},
+			expectedTransaction: &entity.Transaction{
+				ID:            "test-tx-id",
+				WalletID:      "test-wallet-id",
+				Amount:        100,
+				OperationType: entity.OperationDeposit,
+				BalanceAfter:  200,
+				EntryID:       testEntryID,
+				Sequence:      1,
+			},
+		},
+		{
+			name:     "CurrencyMismatch",
+			walletID: "test-wallet-id",
+			amount:   100,
+			currency: entity.CurrencyEUR,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrCurrencyMismatch,
+		},
+		{
+			name:     "BalanceOverflow",
+			walletID: "test-wallet-id",
+			amount:   1,
+			currency: entity.CurrencyRUB,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", int64(math.MaxInt64), "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrBalanceOverflow,
+		},
+		{
+			name:           "IdempotencyKeyExists",
+			walletID:       "test-wallet-id",
+			amount:         100,
+			idempotencyKey: &entity.IdempotencyKey{ClientID: "key:test", Key: "key", Fingerprint: "fp", StatusCode: 200, Response: []byte("{}")},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(insertKeyQuery).
+					WithArgs("key:test", "key", "fp", 200, []byte("{}")).
+					WillReturnError(&pgconn.PgError{Code: uniqueViolationCode})
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrIdempotencyKeyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			transaction, err := repo.Operation(t.Context(), entity.Operation{
+				WalletID:         tt.walletID,
+				Amount:           entity.NewMoney(tt.amount, tt.currency),
+				Type:             operationType(tt.amount),
+				OperationOptions: entity.OperationOptions{IdempotencyKey: tt.idempotencyKey},
+			})
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedTransaction, transaction, "expected transaction to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, transaction, "expected transaction to be nil")
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	const query = `INSERT INTO wallets \(id, currency, overdraft_limit, owner_id\) VALUES \(\$1, \$2, \$3, \$4\) RETURNING \*`
+
+	tests := []struct {
+		name           string
+		walletID       string
+		ownerID        string
+		mockBehavior   mockBehavior
+		expectedWallet *entity.Wallet
+		expectedError  error
+	}{
+		{
+			name:     "Ok",
+			walletID: "test-wallet-id",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).
+					WithArgs("test-wallet-id", "RUB", noOverdraftLimit, noOwner).
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 0, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(`INSERT INTO ledger_accounts \(id, kind\) VALUES \(\$1, \$2\)`).
+					WithArgs("test-wallet-id", "wallet").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(mock, entity.EventWalletCreated, "test-wallet-id")
+				mock.ExpectCommit()
+			},
+			expectedWallet: &entity.Wallet{
+				ID:      "test-wallet-id",
+				Balance: entity.NewMoney(0, entity.CurrencyRUB),
+				Status:  entity.WalletStatusActive,
+			},
+		},
+		{
+			name:     "OkWithOwner",
+			walletID: "test-wallet-id",
+			ownerID:  "test-owner-id",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				ownerID := "test-owner-id"
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).
+					WithArgs("test-wallet-id", "RUB", noOverdraftLimit, &ownerID).
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 0, "RUB", 0, "active", noOverdraftLimit, &ownerID, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(`INSERT INTO ledger_accounts \(id, kind\) VALUES \(\$1, \$2\)`).
+					WithArgs("test-wallet-id", "wallet").
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				expectEvent(mock, entity.EventWalletCreated, "test-wallet-id")
+				mock.ExpectCommit()
+			},
+			expectedWallet: &entity.Wallet{
+				ID:      "test-wallet-id",
+				Balance: entity.NewMoney(0, entity.CurrencyRUB),
+				Status:  entity.WalletStatusActive,
+				OwnerID: "test-owner-id",
+			},
+		},
+		{
+			name:     "AlreadyExists",
+			walletID: "test-wallet-id",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).
+					WithArgs("test-wallet-id", "RUB", noOverdraftLimit, noOwner).
+					WillReturnError(&pgconn.PgError{Code: uniqueViolationCode})
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletAlreadyExists,
+		},
 	}
 
 	for _, tt := range tests {
@@ -116,13 +468,15 @@ func TestOperation(t *testing.T) {
 
 			tt.mockBehavior(mock)
 
-			err := repo.Operation(t.Context(), tt.walletID, tt.amount)
+			wallet, err := repo.Create(t.Context(), entity.Wallet{ID: tt.walletID, OwnerID: tt.ownerID})
 
 			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
 			if tt.expectedError == nil {
 				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedWallet, wallet, "expected wallet to match")
 			} else {
 				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, wallet, "expected wallet to be nil")
 			}
 		})
 	}
@@ -147,11 +501,12 @@ func TestGetByID(t *testing.T) {
 				mock.ExpectQuery(query).
 					WithArgs("test-wallet-id").
 					WillReturnRows(pgxmock.NewRows(walletColumns).
-						AddRow("test-wallet-id", 100, time.Time{}, time.Time{}))
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
 			},
 			expectedWallet: &entity.Wallet{
 				ID:        "test-wallet-id",
-				Balance:   100,
+				Balance:   entity.NewMoney(100, entity.CurrencyRUB),
+				Status:    entity.WalletStatusActive,
 				UpdatedAt: time.Time{},
 				CreatedAt: time.Time{},
 			},
@@ -190,3 +545,1578 @@ func TestGetByID(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactions(t *testing.T) {
+	t.Parallel()
+
+	txColumns := []string{"id", "wallet_id", "amount", "operation_type", "balance_after", "reference_id", "entry_id", "sequence", "created_at"}
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	queryErr := errors.New("query error")
+
+	tests := []struct {
+		name          string
+		filter        entity.TransactionFilter
+		mockBehavior  mockBehavior
+		expectedTxs   []entity.Transaction
+		expectedError error
+	}{
+		{
+			name:   "WithoutFilters",
+			filter: entity.TransactionFilter{WalletID: "test-wallet-id", Limit: 2},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(`FROM wallet_transactions WHERE wallet_id = \$1 `+
+					`ORDER BY created_at DESC, id DESC LIMIT \$2`).
+					WithArgs("test-wallet-id", 2).
+					WillReturnRows(pgxmock.NewRows(txColumns).
+						AddRow("tx-2", "test-wallet-id", int64(-50), "withdraw", int64(50), noReference, noEntry, int64(2), createdAt).
+						AddRow("tx-1", "test-wallet-id", int64(100), "deposit", int64(100), noReference, noEntry, int64(1), createdAt))
+			},
+			expectedTxs: []entity.Transaction{
+				{ID: "tx-2", WalletID: "test-wallet-id", Amount: -50, OperationType: entity.OperationWithdraw, BalanceAfter: 50, Sequence: 2, CreatedAt: createdAt},
+				{ID: "tx-1", WalletID: "test-wallet-id", Amount: 100, OperationType: entity.OperationDeposit, BalanceAfter: 100, Sequence: 1, CreatedAt: createdAt},
+			},
+		},
+		{
+			name: "WithAllFilters",
+			filter: entity.TransactionFilter{
+				WalletID:      "test-wallet-id",
+				OperationType: entity.OperationDeposit,
+				From:          createdAt.Add(-time.Hour),
+				To:            createdAt.Add(time.Hour),
+				After:         &entity.TransactionCursor{CreatedAt: createdAt, ID: "tx-3"},
+				Limit:         10,
+			},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(`WHERE wallet_id = \$1 AND operation_type = \$2 AND created_at >= \$3 `+
+					`AND created_at < \$4 AND \(created_at, id\) < \(\$5, \$6\) `+
+					`ORDER BY created_at DESC, id DESC LIMIT \$7`).
+					WithArgs("test-wallet-id", "deposit", createdAt.Add(-time.Hour), createdAt.Add(time.Hour),
+						createdAt, "tx-3", 10).
+					WillReturnRows(pgxmock.NewRows(txColumns))
+			},
+			expectedTxs: []entity.Transaction{},
+		},
+		{
+			name:   "QueryError",
+			filter: entity.TransactionFilter{WalletID: "test-wallet-id", Limit: 2},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(`FROM wallet_transactions`).
+					WithArgs("test-wallet-id", 2).
+					WillReturnError(queryErr)
+			},
+			expectedError: queryErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			txs, err := repo.Transactions(t.Context(), tt.filter)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedTxs, txs, "expected transactions to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+		})
+	}
+}
+
+func TestLastSequence(t *testing.T) {
+	t.Parallel()
+
+	queryErr := errors.New("query error")
+
+	tests := []struct {
+		name             string
+		mockBehavior     mockBehavior
+		expectedSequence int64
+		expectedError    error
+	}{
+		{
+			name: "Ok",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(`SELECT COALESCE\(MAX\(sequence\), 0\) FROM wallet_transactions WHERE wallet_id = \$1`).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows([]string{"coalesce"}).AddRow(int64(7)))
+			},
+			expectedSequence: 7,
+		},
+		{
+			name: "QueryError",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(`FROM wallet_transactions`).
+					WithArgs("test-wallet-id").
+					WillReturnError(queryErr)
+			},
+			expectedError: queryErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			sequence, err := repo.LastSequence(t.Context(), "test-wallet-id")
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedSequence, sequence, "expected sequence to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+		})
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	t.Parallel()
+
+	const getQuery = `SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`
+	const updateQuery = `UPDATE wallets SET balance = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const insertTxQuery = `INSERT INTO wallet_transactions`
+
+	tests := []struct {
+		name           string
+		transfer       entity.Transfer
+		mockBehavior   mockBehavior
+		expectedResult *entity.TransferResult
+		expectedError  error
+	}{
+		{
+			name:     "LocksInAscendingOrder",
+			transfer: entity.Transfer{FromWalletID: "b-wallet", ToWalletID: "a-wallet", Amount: entity.NewMoney(30, "")},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 10, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectQuery(getQuery).
+					WithArgs("b-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("b-wallet", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(70), "b-wallet").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(40), "a-wallet").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "transfer", []string{"b-wallet", "a-wallet"}, []int64{-30, 30})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("b-wallet", int64(-30), "transfer_out", int64(70), noReference, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("debit-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletDebited, "b-wallet")
+				debitID := "debit-tx-id"
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("a-wallet", int64(30), "transfer_in", int64(40), &debitID, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("credit-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletCredited, "a-wallet")
+				mock.ExpectCommit()
+			},
+			expectedResult: &entity.TransferResult{
+				Debit: &entity.Transaction{
+					ID:            "debit-tx-id",
+					WalletID:      "b-wallet",
+					Amount:        -30,
+					OperationType: entity.OperationTransferOut,
+					BalanceAfter:  70,
+					EntryID:       testEntryID,
+					Sequence:      1,
+				},
+				Credit: &entity.Transaction{
+					ID:            "credit-tx-id",
+					WalletID:      "a-wallet",
+					Amount:        30,
+					OperationType: entity.OperationTransferIn,
+					BalanceAfter:  40,
+					ReferenceID:   "debit-tx-id",
+					EntryID:       testEntryID,
+					Sequence:      1,
+				},
+			},
+		},
+		{
+			name:     "InsufficientFunds",
+			transfer: entity.Transfer{FromWalletID: "a-wallet", ToWalletID: "b-wallet", Amount: entity.NewMoney(111, "")},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 10, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectQuery(getQuery).
+					WithArgs("b-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("b-wallet", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrInsufficientFunds,
+		},
+		{
+			name: "SameWalletInDifferentCase",
+			transfer: entity.Transfer{
+				FromWalletID: "AAAAAAAA-2b2b-4c4c-8d8d-0e0e1f2a3b4c",
+				ToWalletID:   "aaaaaaaa-2b2b-4c4c-8d8d-0e0e1f2a3b4c",
+				Amount:       entity.NewMoney(30, ""),
+			},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				for _, id := range []string{"AAAAAAAA-2b2b-4c4c-8d8d-0e0e1f2a3b4c", "aaaaaaaa-2b2b-4c4c-8d8d-0e0e1f2a3b4c"} {
+					mock.ExpectQuery(getQuery).
+						WithArgs(id).
+						WillReturnRows(pgxmock.NewRows(walletColumns).
+							AddRow("aaaaaaaa-2b2b-4c4c-8d8d-0e0e1f2a3b4c", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				}
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrSameWallet,
+		},
+		{
+			name:     "WalletNotFound",
+			transfer: entity.Transfer{FromWalletID: "a-wallet", ToWalletID: "b-wallet", Amount: entity.NewMoney(30, "")},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 10, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectQuery(getQuery).
+					WithArgs("b-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			result, err := repo.Transfer(t.Context(), tt.transfer)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedResult, result, "expected result to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, result, "expected result to be nil")
+			}
+		})
+	}
+}
+
+func TestTransfer_StagesAudit(t *testing.T) {
+	t.Parallel()
+
+	const getQuery = `SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`
+	const updateQuery = `UPDATE wallets SET balance = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const insertTxQuery = `INSERT INTO wallet_transactions`
+
+	balance := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name          string
+		stageError    error
+		expectedError bool
+	}{
+		{
+			name: "Staged",
+		},
+		{
+			name:          "StagingFails",
+			stageError:    errors.New("insert error"),
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+			require.NoError(t, err)
+
+			repo := New(mock, WithAudit(func(_ context.Context, record entity.AuditRecord) entity.AuditRecord {
+				record.Actor = "key:test-key-id"
+				record.RequestID = "test-request-id"
+				return record
+			}))
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(getQuery).
+				WithArgs("a-wallet").
+				WillReturnRows(pgxmock.NewRows(walletColumns).
+					AddRow("a-wallet", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+			mock.ExpectQuery(getQuery).
+				WithArgs("b-wallet").
+				WillReturnRows(pgxmock.NewRows(walletColumns).
+					AddRow("b-wallet", 0, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+			mock.ExpectExec(updateQuery).
+				WithArgs(int64(70), "a-wallet").
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			mock.ExpectExec(updateQuery).
+				WithArgs(int64(30), "b-wallet").
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			expectJournalEntry(mock, "transfer", []string{"a-wallet", "b-wallet"}, []int64{-30, 30})
+			mock.ExpectQuery(insertTxQuery).
+				WithArgs("a-wallet", int64(-30), "transfer_out", int64(70), noReference, &testEntryID).
+				WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("debit-tx-id", int64(1), time.Time{}))
+			expectEvent(mock, entity.EventWalletDebited, "a-wallet")
+			walletID := "a-wallet"
+			stage := mock.ExpectExec(`INSERT INTO audit_pending`).
+				WithArgs("wallet.transfer_out", "key:test-key-id", "", "test-request-id", &walletID,
+					"debit-tx-id", balance(100), balance(70))
+			if tt.stageError != nil {
+				stage.WillReturnError(tt.stageError)
+				mock.ExpectRollback()
+			} else {
+				stage.WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				debitID := "debit-tx-id"
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("b-wallet", int64(30), "transfer_in", int64(30), &debitID, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("credit-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletCredited, "b-wallet")
+				creditWalletID := "b-wallet"
+				mock.ExpectExec(`INSERT INTO audit_pending`).
+					WithArgs("wallet.transfer_in", "key:test-key-id", "", "test-request-id", &creditWalletID,
+						"credit-tx-id", balance(0), balance(30)).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			}
+
+			_, err = repo.Transfer(t.Context(), entity.Transfer{
+				FromWalletID: "a-wallet",
+				ToWalletID:   "b-wallet",
+				Amount:       entity.NewMoney(30, ""),
+			})
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError {
+				require.Error(t, err, "expected the transfer to fail without its audit records")
+				return
+			}
+			require.NoError(t, err, "expected no error")
+		})
+	}
+}
+
+func TestChangeStatus(t *testing.T) {
+	t.Parallel()
+
+	const getQuery = `SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`
+	const statusQuery = `UPDATE wallets\s+SET status = \$1, status_reason = \$2, status_changed_at = NOW\(\), updated_at = NOW\(\)\s+WHERE id = \$3`
+	const updateQuery = `UPDATE wallets SET balance = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const insertTxQuery = `INSERT INTO wallet_transactions`
+
+	reason := "suspicious activity"
+	changedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		change         entity.WalletStatusChange
+		mockBehavior   mockBehavior
+		expectedWallet *entity.Wallet
+		expectedSweep  *entity.TransferResult
+		expectedError  error
+	}{
+		{
+			name:   "Freeze",
+			change: entity.WalletStatusChange{WalletID: "a-wallet", Status: entity.WalletStatusFrozen, Reason: reason},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectQuery(statusQuery).
+					WithArgs("frozen", reason, "a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 100, "RUB", 0, "frozen", noOverdraftLimit, noOwner, &reason, &changedAt, time.Time{}, time.Time{}))
+				mock.ExpectCommit()
+			},
+			expectedWallet: &entity.Wallet{
+				ID:              "a-wallet",
+				Balance:         rub(100),
+				Status:          entity.WalletStatusFrozen,
+				StatusReason:    reason,
+				StatusChangedAt: &changedAt,
+			},
+		},
+		{
+			name:   "ClosedIsFinal",
+			change: entity.WalletStatusChange{WalletID: "a-wallet", Status: entity.WalletStatusActive, Reason: reason},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 0, "RUB", 0, "closed", noOverdraftLimit, noOwner, &reason, &changedAt, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletStatusTransition,
+		},
+		{
+			name:   "CloseWithBalance",
+			change: entity.WalletStatusChange{WalletID: "a-wallet", Status: entity.WalletStatusClosed, Reason: reason},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletNotEmpty,
+		},
+		{
+			name: "CloseWithHolds",
+			change: entity.WalletStatusChange{
+				WalletID:        "a-wallet",
+				Status:          entity.WalletStatusClosed,
+				Reason:          reason,
+				SweepToWalletID: "b-wallet",
+			},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 100, "RUB", 40, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectQuery(getQuery).
+					WithArgs("b-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("b-wallet", 0, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletNotEmpty,
+		},
+		{
+			name: "CloseWithSweep",
+			change: entity.WalletStatusChange{
+				WalletID:        "a-wallet",
+				Status:          entity.WalletStatusClosed,
+				Reason:          reason,
+				SweepToWalletID: "b-wallet",
+			},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 100, "RUB", 0, "frozen", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectQuery(getQuery).
+					WithArgs("b-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("b-wallet", 10, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(0), "a-wallet").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(110), "b-wallet").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "transfer", []string{"a-wallet", "b-wallet"}, []int64{-100, 100})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("a-wallet", int64(-100), "transfer_out", int64(0), noReference, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("debit-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletDebited, "a-wallet")
+				debitID := "debit-tx-id"
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("b-wallet", int64(100), "transfer_in", int64(110), &debitID, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("credit-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletCredited, "b-wallet")
+				mock.ExpectQuery(statusQuery).
+					WithArgs("closed", reason, "a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 0, "RUB", 0, "closed", noOverdraftLimit, noOwner, &reason, &changedAt, time.Time{}, time.Time{}))
+				mock.ExpectCommit()
+			},
+			expectedWallet: &entity.Wallet{
+				ID:              "a-wallet",
+				Balance:         rub(0),
+				Status:          entity.WalletStatusClosed,
+				StatusReason:    reason,
+				StatusChangedAt: &changedAt,
+			},
+			expectedSweep: &entity.TransferResult{
+				Debit: &entity.Transaction{
+					ID:            "debit-tx-id",
+					WalletID:      "a-wallet",
+					Amount:        -100,
+					OperationType: entity.OperationTransferOut,
+					BalanceAfter:  0,
+					EntryID:       testEntryID,
+					Sequence:      1,
+				},
+				Credit: &entity.Transaction{
+					ID:            "credit-tx-id",
+					WalletID:      "b-wallet",
+					Amount:        100,
+					OperationType: entity.OperationTransferIn,
+					BalanceAfter:  110,
+					ReferenceID:   "debit-tx-id",
+					EntryID:       testEntryID,
+					Sequence:      1,
+				},
+			},
+		},
+		{
+			name: "SweepToItself",
+			change: entity.WalletStatusChange{
+				WalletID:        "aaaaaaaa-2b2b-4c4c-8d8d-0e0e1f2a3b4c",
+				Status:          entity.WalletStatusClosed,
+				Reason:          reason,
+				SweepToWalletID: "AAAAAAAA-2b2b-4c4c-8d8d-0e0e1f2a3b4c",
+			},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				for _, id := range []string{"AAAAAAAA-2b2b-4c4c-8d8d-0e0e1f2a3b4c", "aaaaaaaa-2b2b-4c4c-8d8d-0e0e1f2a3b4c"} {
+					mock.ExpectQuery(getQuery).
+						WithArgs(id).
+						WillReturnRows(pgxmock.NewRows(walletColumns).
+							AddRow("aaaaaaaa-2b2b-4c4c-8d8d-0e0e1f2a3b4c", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				}
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrSameWallet,
+		},
+		{
+			name: "SweepToClosedWallet",
+			change: entity.WalletStatusChange{
+				WalletID:        "a-wallet",
+				Status:          entity.WalletStatusClosed,
+				Reason:          reason,
+				SweepToWalletID: "b-wallet",
+			},
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("a-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("a-wallet", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectQuery(getQuery).
+					WithArgs("b-wallet").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("b-wallet", 0, "RUB", 0, "closed", noOverdraftLimit, noOwner, &reason, &changedAt, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			wallet, sweep, err := repo.ChangeStatus(t.Context(), tt.change)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedWallet, wallet, "expected wallet to match")
+				require.Equal(t, tt.expectedSweep, sweep, "expected sweep to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, wallet, "expected wallet to be nil")
+				require.Nil(t, sweep, "expected sweep to be nil")
+			}
+		})
+	}
+}
+
+func TestChangeStatus_StagesAudit(t *testing.T) {
+	t.Parallel()
+
+	mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+
+	repo := New(mock, WithAudit(func(_ context.Context, record entity.AuditRecord) entity.AuditRecord {
+		record.Actor = entity.AuditActorSystem
+		return record
+	}))
+
+	reason := "suspicious activity"
+	walletID := "a-wallet"
+	var noBalance *int64
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`).
+		WithArgs(walletID).
+		WillReturnRows(pgxmock.NewRows(walletColumns).
+			AddRow(walletID, 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+	mock.ExpectQuery(`UPDATE wallets\s+SET status = \$1`).
+		WithArgs("frozen", reason, walletID).
+		WillReturnRows(pgxmock.NewRows(walletColumns).
+			AddRow(walletID, 100, "RUB", 0, "frozen", noOverdraftLimit, noOwner, &reason, noStatusChange, time.Time{}, time.Time{}))
+	mock.ExpectExec(`INSERT INTO audit_pending`).
+		WithArgs("wallet.freeze", entity.AuditActorSystem, "", "", &walletID, "", noBalance, noBalance).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	_, _, err = repo.ChangeStatus(t.Context(), entity.WalletStatusChange{
+		WalletID: walletID,
+		Status:   entity.WalletStatusFrozen,
+		Reason:   reason,
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+	require.NoError(t, err, "expected no error")
+}
+
+func TestAssignOwner(t *testing.T) {
+	t.Parallel()
+
+	const query = `UPDATE wallets SET owner_id = NULLIF\(\$1, ''\)`
+
+	walletID := "a-wallet"
+	ownerID := "user-1"
+	var noBalance *int64
+
+	tests := []struct {
+		name           string
+		mockBehavior   mockBehavior
+		expectedWallet *entity.Wallet
+		expectedError  error
+	}{
+		{
+			name: "Success",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).
+					WithArgs(ownerID, walletID).
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow(walletID, 100, "RUB", 0, "active", noOverdraftLimit, &ownerID, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(`INSERT INTO audit_pending`).
+					WithArgs("wallet.assign_owner", entity.AuditActorSystem, "", "", &walletID, "", noBalance, noBalance).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectCommit()
+			},
+			expectedWallet: &entity.Wallet{
+				ID:      walletID,
+				Balance: rub(100),
+				Status:  entity.WalletStatusActive,
+				OwnerID: ownerID,
+			},
+		},
+		{
+			name: "WalletNotFound",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).
+					WithArgs(ownerID, walletID).
+					WillReturnRows(pgxmock.NewRows(walletColumns))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+			require.NoError(t, err)
+
+			repo := New(mock, WithAudit(func(_ context.Context, record entity.AuditRecord) entity.AuditRecord {
+				record.Actor = entity.AuditActorSystem
+				return record
+			}))
+
+			tt.mockBehavior(mock)
+
+			wallet, err := repo.AssignOwner(t.Context(), walletID, ownerID)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			require.Equal(t, tt.expectedWallet, wallet, "expected wallet to match")
+		})
+	}
+}
+
+func TestTrialBalance(t *testing.T) {
+	t.Parallel()
+
+	systemColumns := []string{"id", "code", "kind", "currency", "balance"}
+
+	tests := []struct {
+		name           string
+		mockBehavior   mockBehavior
+		expectedResult *entity.TrialBalance
+		expectedError  error
+	}{
+		{
+			name: "Consistent",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`).
+					WillReturnResult(pgxmock.NewResult("SET", 0))
+				mock.ExpectQuery(`FROM ledger_accounts a`).
+					WillReturnRows(pgxmock.NewRows(systemColumns).
+						AddRow(entity.AccountCashIn, "cash_in", entity.AccountKindSystem, "RUB", int64(-300)).
+						AddRow(entity.AccountCashOut, "cash_out", entity.AccountKindSystem, "RUB", int64(100)))
+				mock.ExpectQuery(`WHERE a.kind = 'wallet'`).
+					WillReturnRows(pgxmock.NewRows([]string{"currency", "sum"}).AddRow("RUB", int64(200)))
+				mock.ExpectQuery(`FROM wallets w`).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "sum"}))
+				mock.ExpectCommit()
+			},
+			expectedResult: &entity.TrialBalance{
+				SystemAccounts: []entity.AccountBalance{
+					{AccountID: entity.AccountCashIn, Code: "cash_in", Kind: entity.AccountKindSystem, Balance: rub(-300)},
+					{AccountID: entity.AccountCashOut, Code: "cash_out", Kind: entity.AccountKindSystem, Balance: rub(100)},
+				},
+				WalletLiabilities: []entity.Money{rub(200)},
+				Totals:            []entity.Money{rub(0)},
+				Mismatches:        []entity.BalanceMismatch{},
+			},
+		},
+		{
+			name: "CachedBalanceMismatch",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`).
+					WillReturnResult(pgxmock.NewResult("SET", 0))
+				mock.ExpectQuery(`FROM ledger_accounts a`).
+					WillReturnRows(pgxmock.NewRows(systemColumns).
+						AddRow(entity.AccountCashIn, "cash_in", entity.AccountKindSystem, "RUB", int64(-100)))
+				mock.ExpectQuery(`WHERE a.kind = 'wallet'`).
+					WillReturnRows(pgxmock.NewRows([]string{"currency", "sum"}).AddRow("RUB", int64(100)))
+				mock.ExpectQuery(`FROM wallets w`).
+					WillReturnRows(pgxmock.NewRows([]string{"id", "currency", "balance", "sum"}).
+						AddRow("test-wallet-id", "RUB", int64(150), int64(100)))
+				mock.ExpectCommit()
+			},
+			expectedResult: &entity.TrialBalance{
+				SystemAccounts: []entity.AccountBalance{
+					{AccountID: entity.AccountCashIn, Code: "cash_in", Kind: entity.AccountKindSystem, Balance: rub(-100)},
+				},
+				WalletLiabilities: []entity.Money{rub(100)},
+				Totals:            []entity.Money{rub(0)},
+				Mismatches: []entity.BalanceMismatch{
+					{WalletID: "test-wallet-id", CachedBalance: rub(150), LedgerBalance: rub(100)},
+				},
+			},
+		},
+		{
+			name: "QueryError",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`).
+					WillReturnResult(pgxmock.NewResult("SET", 0))
+				mock.ExpectQuery(`FROM ledger_accounts a`).
+					WillReturnError(errors.New("query error"))
+				mock.ExpectRollback()
+			},
+			expectedError: errors.New("repository.wallet.TrialBalance: query error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			result, err := repo.TrialBalance(t.Context())
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedResult, result, "expected result to match")
+			} else {
+				require.EqualError(t, err, tt.expectedError.Error(), "expected error to match")
+				require.Nil(t, result, "expected result to be nil")
+			}
+		})
+	}
+}
+
+func TestPostEntry_Unbalanced(t *testing.T) {
+	t.Parallel()
+
+	mock, repo := setupTest(t)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err := repo.inTx(t.Context(), func(tx pgx.Tx) error {
+		_, err := repo.postEntry(t.Context(), tx, entity.JournalEntry{
+			Kind:     entity.OperationDeposit,
+			Currency: entity.CurrencyRUB,
+			Postings: []entity.Posting{
+				{AccountID: "test-wallet-id", Amount: 100},
+				{AccountID: entity.AccountCashIn, Amount: -99},
+			},
+		})
+		return err
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+	require.ErrorIs(t, err, repoErr.ErrUnbalancedEntry, "expected error to match")
+}
+
+func TestCreateHold(t *testing.T) {
+	t.Parallel()
+
+	const getQuery = `SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`
+	const updateHeldQuery = `UPDATE wallets SET held_amount = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const insertQuery = `INSERT INTO holds \(wallet_id, amount, currency, expires_at\)`
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	zeroOverdraftLimit := int64(0)
+
+	tests := []struct {
+		name          string
+		amount        int64
+		mockBehavior  mockBehavior
+		expectedHold  *entity.Hold
+		expectedError error
+	}{
+		{
+			name:   "Ok",
+			amount: 60,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 20, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(updateHeldQuery).
+					WithArgs(int64(80), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectQuery(insertQuery).
+					WithArgs("test-wallet-id", int64(60), "RUB", expiresAt).
+					WillReturnRows(pgxmock.NewRows(holdColumns).
+						AddRow("test-hold-id", "test-wallet-id", 60, "RUB", 0, "active", noReference, expiresAt, time.Time{}, time.Time{}))
+				mock.ExpectCommit()
+			},
+			expectedHold: &entity.Hold{
+				ID:        "test-hold-id",
+				WalletID:  "test-wallet-id",
+				Amount:    rub(60),
+				Status:    entity.HoldStatusActive,
+				ExpiresAt: expiresAt,
+			},
+		},
+		{
+			name:   "InsufficientFundsWithOtherHolds",
+			amount: 60,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 50, "active", &zeroOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrInsufficientFunds,
+		},
+		{
+			name:   "WalletNotFound",
+			amount: 60,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			hold, err := repo.CreateHold(t.Context(), entity.Hold{
+				WalletID:  "test-wallet-id",
+				Amount:    entity.NewMoney(tt.amount, ""),
+				ExpiresAt: expiresAt,
+			})
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedHold, hold, "expected hold to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, hold, "expected hold to be nil")
+			}
+		})
+	}
+}
+
+func TestCaptureHold(t *testing.T) {
+	t.Parallel()
+
+	const holdWalletQuery = `SELECT wallet_id FROM holds WHERE id = \$1`
+	const getQuery = `SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`
+	const lockHoldQuery = `SELECT \* FROM holds WHERE id = \$1 FOR UPDATE`
+	const updateQuery = `UPDATE wallets SET balance = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const updateHeldQuery = `UPDATE wallets SET held_amount = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const insertTxQuery = `INSERT INTO wallet_transactions`
+	const updateHoldQuery = `UPDATE holds\s+SET status = \$1, captured_amount = \$2, transaction_id = \$3`
+
+	expiresAt := time.Now().Add(time.Hour)
+	expiredAt := time.Now().Add(-time.Hour)
+
+	// expectLocks expects the hold and its wallet to be locked. The wallet holds
+	// 100 with 60 reserved by the hold.
+	expectLocks := func(mock pgxmock.PgxPoolIface, status string, expiresAt time.Time) {
+		mock.ExpectQuery(holdWalletQuery).
+			WithArgs("test-hold-id").
+			WillReturnRows(pgxmock.NewRows([]string{"wallet_id"}).AddRow("test-wallet-id"))
+		mock.ExpectQuery(getQuery).
+			WithArgs("test-wallet-id").
+			WillReturnRows(pgxmock.NewRows(walletColumns).
+				AddRow("test-wallet-id", 100, "RUB", 60, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+		mock.ExpectQuery(lockHoldQuery).
+			WithArgs("test-hold-id").
+			WillReturnRows(pgxmock.NewRows(holdColumns).
+				AddRow("test-hold-id", "test-wallet-id", 60, "RUB", 0, status, noReference, expiresAt, time.Time{}, time.Time{}))
+	}
+
+	tests := []struct {
+		name          string
+		amount        int64
+		mockBehavior  mockBehavior
+		expectedHold  *entity.Hold
+		expectedError error
+	}{
+		{
+			name:   "Partial",
+			amount: 40,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectLocks(mock, "active", expiresAt)
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(60), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectExec(updateHeldQuery).
+					WithArgs(int64(0), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "capture", []string{"test-wallet-id", entity.AccountCashOut}, []int64{-40, 40})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(-40), "capture", int64(60), noReference, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("test-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletDebited, "test-wallet-id")
+				txID := "test-tx-id"
+				mock.ExpectQuery(updateHoldQuery).
+					WithArgs("captured", int64(40), "test-tx-id", "test-hold-id").
+					WillReturnRows(pgxmock.NewRows(holdColumns).
+						AddRow("test-hold-id", "test-wallet-id", 60, "RUB", 40, "captured", &txID, expiresAt, time.Time{}, time.Time{}))
+				mock.ExpectCommit()
+			},
+			expectedHold: &entity.Hold{
+				ID:             "test-hold-id",
+				WalletID:       "test-wallet-id",
+				Amount:         rub(60),
+				CapturedAmount: 40,
+				Status:         entity.HoldStatusCaptured,
+				TransactionID:  "test-tx-id",
+				Transaction: &entity.Transaction{
+					ID:            "test-tx-id",
+					WalletID:      "test-wallet-id",
+					Amount:        -40,
+					OperationType: entity.OperationCapture,
+					BalanceAfter:  60,
+					EntryID:       testEntryID,
+					Sequence:      1,
+				},
+				ExpiresAt: expiresAt,
+			},
+		},
+		{
+			name:   "AmountExceeded",
+			amount: 61,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectLocks(mock, "active", expiresAt)
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrHoldAmountExceeded,
+		},
+		{
+			name:   "Released",
+			amount: 0,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectLocks(mock, "released", expiresAt)
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrHoldNotActive,
+		},
+		{
+			name:   "Expired",
+			amount: 0,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectLocks(mock, "active", expiredAt)
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrHoldNotActive,
+		},
+		{
+			name:   "NotFound",
+			amount: 0,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(holdWalletQuery).
+					WithArgs("test-hold-id").
+					WillReturnRows(pgxmock.NewRows([]string{"wallet_id"}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrHoldNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			hold, err := repo.CaptureHold(t.Context(), entity.HoldCapture{
+				HoldID: "test-hold-id",
+				Amount: entity.NewMoney(tt.amount, ""),
+			})
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedHold, hold, "expected hold to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, hold, "expected hold to be nil")
+			}
+		})
+	}
+}
+
+func TestReleaseHold(t *testing.T) {
+	t.Parallel()
+
+	mock, repo := setupTest(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT wallet_id FROM holds WHERE id = \$1`).
+		WithArgs("test-hold-id").
+		WillReturnRows(pgxmock.NewRows([]string{"wallet_id"}).AddRow("test-wallet-id"))
+	mock.ExpectQuery(`SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`).
+		WithArgs("test-wallet-id").
+		WillReturnRows(pgxmock.NewRows(walletColumns).
+			AddRow("test-wallet-id", 100, "RUB", 80, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+	mock.ExpectQuery(`SELECT \* FROM holds WHERE id = \$1 FOR UPDATE`).
+		WithArgs("test-hold-id").
+		WillReturnRows(pgxmock.NewRows(holdColumns).
+			AddRow("test-hold-id", "test-wallet-id", 60, "RUB", 0, "active", noReference, expiresAt, time.Time{}, time.Time{}))
+	mock.ExpectExec(`UPDATE wallets SET held_amount = \$1`).
+		WithArgs(int64(20), "test-wallet-id").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`UPDATE holds SET status = \$1, updated_at = NOW\(\) WHERE id = \$2 RETURNING \*`).
+		WithArgs("released", "test-hold-id").
+		WillReturnRows(pgxmock.NewRows(holdColumns).
+			AddRow("test-hold-id", "test-wallet-id", 60, "RUB", 0, "released", noReference, expiresAt, time.Time{}, time.Time{}))
+	mock.ExpectCommit()
+
+	hold, err := repo.ReleaseHold(t.Context(), "test-hold-id")
+
+	require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+	require.NoError(t, err, "expected no error")
+	require.Equal(t, entity.HoldStatusReleased, hold.Status, "expected hold to be released")
+}
+
+func TestReverse(t *testing.T) {
+	t.Parallel()
+
+	const getTxQuery = `FROM wallet_transactions WHERE id = \$1`
+	const getQuery = `SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`
+	const reversedQuery = `SELECT COALESCE\(SUM\(ABS\(amount\)\), 0\)::BIGINT FROM wallet_transactions\s+` +
+		`WHERE reference_id = \$1 AND operation_type = \$2`
+	const updateQuery = `UPDATE wallets SET balance = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const insertTxQuery = `INSERT INTO wallet_transactions`
+
+	txColumns := []string{"id", "wallet_id", "amount", "operation_type", "balance_after", "reference_id", "entry_id", "sequence", "created_at"}
+	originalID := "test-original-id"
+
+	// expectOriginal expects the original transaction, its wallet and the amount
+	// already reversed to be read. The wallet holds 100.
+	expectOriginal := func(mock pgxmock.PgxPoolIface, amount int64, operationType string, reversed int64) {
+		mock.ExpectQuery(getTxQuery).
+			WithArgs(originalID).
+			WillReturnRows(pgxmock.NewRows(txColumns).
+				AddRow(originalID, "test-wallet-id", amount, operationType, int64(100), noReference, &testEntryID, int64(1), time.Time{}))
+		mock.ExpectQuery(getQuery).
+			WithArgs("test-wallet-id").
+			WillReturnRows(pgxmock.NewRows(walletColumns).
+				AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+		mock.ExpectQuery(reversedQuery).
+			WithArgs(originalID, "reversal").
+			WillReturnRows(pgxmock.NewRows([]string{"sum"}).AddRow(reversed))
+	}
+
+	tests := []struct {
+		name                string
+		amount              int64
+		mockBehavior        mockBehavior
+		expectedTransaction *entity.Transaction
+		expectedError       error
+	}{
+		{
+			name:   "PartialDeposit",
+			amount: 50,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectOriginal(mock, 100, "deposit", 30)
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(50), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "reversal", []string{"test-wallet-id", entity.AccountCashIn}, []int64{-50, 50})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(-50), "reversal", int64(50), &originalID, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("test-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletDebited, "test-wallet-id")
+				mock.ExpectCommit()
+			},
+			expectedTransaction: &entity.Transaction{
+				ID:            "test-tx-id",
+				WalletID:      "test-wallet-id",
+				Amount:        -50,
+				OperationType: entity.OperationReversal,
+				BalanceAfter:  50,
+				ReferenceID:   originalID,
+				EntryID:       testEntryID,
+				Sequence:      1,
+			},
+		},
+		{
+			name:   "RestOfWithdraw",
+			amount: 0,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectOriginal(mock, -80, "withdraw", 0)
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(180), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "reversal", []string{"test-wallet-id", entity.AccountCashOut}, []int64{80, -80})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(80), "reversal", int64(180), &originalID, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow("test-tx-id", int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletCredited, "test-wallet-id")
+				mock.ExpectCommit()
+			},
+			expectedTransaction: &entity.Transaction{
+				ID:            "test-tx-id",
+				WalletID:      "test-wallet-id",
+				Amount:        80,
+				OperationType: entity.OperationReversal,
+				BalanceAfter:  180,
+				ReferenceID:   originalID,
+				EntryID:       testEntryID,
+				Sequence:      1,
+			},
+		},
+		{
+			name:   "AmountExceeded",
+			amount: 71,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectOriginal(mock, 100, "deposit", 30)
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrReversalAmountExceeded,
+		},
+		{
+			name:   "AlreadyReversed",
+			amount: 0,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectOriginal(mock, -80, "withdraw", 80)
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrReversalAmountExceeded,
+		},
+		{
+			name:   "NotReversible",
+			amount: 0,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getTxQuery).
+					WithArgs(originalID).
+					WillReturnRows(pgxmock.NewRows(txColumns).
+						AddRow(originalID, "test-wallet-id", int64(-50), "transfer_out", int64(50), noReference, &testEntryID, int64(1), time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrNotReversible,
+		},
+		{
+			name:   "NotFound",
+			amount: 0,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getTxQuery).
+					WithArgs(originalID).
+					WillReturnRows(pgxmock.NewRows(txColumns))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrTransactionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			transaction, err := repo.Reverse(t.Context(), entity.Reversal{
+				TransactionID: originalID,
+				Amount:        entity.NewMoney(tt.amount, ""),
+			})
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedTransaction, transaction, "expected transaction to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, transaction, "expected transaction to be nil")
+			}
+		})
+	}
+}
+
+func TestEnqueueOperation(t *testing.T) {
+	t.Parallel()
+
+	const getQuery = `SELECT.*FROM wallets WHERE id = \$1`
+	const insertQuery = `INSERT INTO operations \(id, wallet_id, operation_type, amount, currency\)`
+
+	tests := []struct {
+		name              string
+		amount            entity.Money
+		mockBehavior      mockBehavior
+		expectedOperation *entity.QueuedOperation
+		expectedError     error
+	}{
+		{
+			name:   "Withdraw",
+			amount: entity.NewMoney(-50, ""),
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectQuery(insertQuery).
+					WithArgs("test-operation-id", "test-wallet-id", "withdraw", int64(-50), "RUB").
+					WillReturnRows(pgxmock.NewRows(operationColumns).
+						AddRow("test-operation-id", "test-wallet-id", "withdraw", int64(-50), "RUB", "pending",
+							noReference, noReference, time.Time{}, time.Time{}, 0))
+				mock.ExpectCommit()
+			},
+			expectedOperation: &entity.QueuedOperation{
+				ID: "test-operation-id",
+				Operation: entity.Operation{
+					WalletID: "test-wallet-id",
+					Amount:   rub(-50),
+					Type:     entity.OperationWithdraw,
+				},
+				Status: entity.QueuedOperationPending,
+			},
+		},
+		{
+			name:   "CurrencyMismatch",
+			amount: entity.NewMoney(-50, entity.CurrencyEUR),
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrCurrencyMismatch,
+		},
+		{
+			name:   "WalletNotFound",
+			amount: entity.NewMoney(-50, ""),
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWalletNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			operation, err := repo.EnqueueOperation(t.Context(), entity.QueuedOperation{
+				ID: "test-operation-id",
+				Operation: entity.Operation{
+					WalletID: "test-wallet-id",
+					Amount:   tt.amount,
+					Type:     entity.OperationWithdraw,
+				},
+			})
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+				require.Equal(t, tt.expectedOperation, operation, "expected operation to match")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+				require.Nil(t, operation, "expected operation to be nil")
+			}
+		})
+	}
+}
+
+func TestProcessQueuedOperation(t *testing.T) {
+	t.Parallel()
+
+	const claimQuery = `SELECT \* FROM operations\s+WHERE status = 'pending'.*FOR UPDATE SKIP LOCKED`
+	const getQuery = `SELECT.*FROM wallets WHERE id = \$1 FOR UPDATE`
+	const updateQuery = `UPDATE wallets SET balance = \$1, updated_at = NOW\(\) WHERE id = \$2`
+	const insertTxQuery = `INSERT INTO wallet_transactions`
+	const completeQuery = `UPDATE operations SET status = \$1, transaction_id = \$2`
+	const failQuery = `UPDATE operations SET status = \$1, failure_reason = \$2`
+	const attemptQuery = `UPDATE operations SET attempts = attempts \+ 1, updated_at = NOW\(\) WHERE id = \$1`
+
+	queryErr := errors.New("query error")
+	countErr := errors.New("count error")
+	gaveUpReason := queuedInternalError
+	zeroOverdraftLimit := int64(0)
+	txID := "test-tx-id"
+	reason := repoErr.ErrInsufficientFunds.Error()
+
+	// expectClaim expects a pending withdrawal of 150, attempted the given number of times, to be claimed.
+	expectClaim := func(mock pgxmock.PgxPoolIface, attempts int) {
+		mock.ExpectQuery(claimQuery).
+			WillReturnRows(pgxmock.NewRows(operationColumns).
+				AddRow("test-operation-id", "test-wallet-id", "withdraw", int64(-150), "RUB", "pending",
+					noReference, noReference, time.Time{}, time.Time{}, attempts))
+	}
+
+	tests := []struct {
+		name              string
+		mockBehavior      mockBehavior
+		expectedOperation *entity.QueuedOperation
+		expectedError     error
+	}{
+		{
+			name: "Completed",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectClaim(mock, 0)
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 200, "RUB", 0, "active", noOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectExec(updateQuery).
+					WithArgs(int64(50), "test-wallet-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				expectJournalEntry(mock, "withdraw", []string{"test-wallet-id", entity.AccountCashOut}, []int64{-150, 150})
+				mock.ExpectQuery(insertTxQuery).
+					WithArgs("test-wallet-id", int64(-150), "withdraw", int64(50), noReference, &testEntryID).
+					WillReturnRows(pgxmock.NewRows(insertedTxColumns).AddRow(txID, int64(1), time.Time{}))
+				expectEvent(mock, entity.EventWalletDebited, "test-wallet-id")
+				mock.ExpectCommit()
+				mock.ExpectQuery(completeQuery).
+					WithArgs("completed", txID, "test-operation-id").
+					WillReturnRows(pgxmock.NewRows(operationColumns).
+						AddRow("test-operation-id", "test-wallet-id", "withdraw", int64(-150), "RUB", "completed",
+							noReference, &txID, time.Time{}, time.Time{}, 0))
+				mock.ExpectCommit()
+			},
+			expectedOperation: &entity.QueuedOperation{
+				ID: "test-operation-id",
+				Operation: entity.Operation{
+					WalletID: "test-wallet-id",
+					Amount:   rub(-150),
+					Type:     entity.OperationWithdraw,
+				},
+				Status:        entity.QueuedOperationCompleted,
+				TransactionID: txID,
+				Transaction: &entity.Transaction{
+					ID:            txID,
+					WalletID:      "test-wallet-id",
+					Amount:        -150,
+					OperationType: entity.OperationWithdraw,
+					BalanceAfter:  50,
+					EntryID:       testEntryID,
+					Sequence:      1,
+				},
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectClaim(mock, 0)
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnRows(pgxmock.NewRows(walletColumns).
+						AddRow("test-wallet-id", 100, "RUB", 0, "active", &zeroOverdraftLimit, noOwner, noReason, noStatusChange, time.Time{}, time.Time{}))
+				mock.ExpectRollback()
+				mock.ExpectQuery(failQuery).
+					WithArgs("failed", reason, "test-operation-id").
+					WillReturnRows(pgxmock.NewRows(operationColumns).
+						AddRow("test-operation-id", "test-wallet-id", "withdraw", int64(-150), "RUB", "failed",
+							&reason, noReference, time.Time{}, time.Time{}, 0))
+				mock.ExpectCommit()
+			},
+			expectedOperation: &entity.QueuedOperation{
+				ID: "test-operation-id",
+				Operation: entity.Operation{
+					WalletID: "test-wallet-id",
+					Amount:   rub(-150),
+					Type:     entity.OperationWithdraw,
+				},
+				Status:        entity.QueuedOperationFailed,
+				FailureReason: reason,
+			},
+		},
+		{
+			name: "EmptyQueue",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(claimQuery).
+					WillReturnRows(pgxmock.NewRows(operationColumns))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "QueryErrorLeavesOperationPending",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectClaim(mock, 0)
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnError(queryErr)
+				mock.ExpectRollback()
+				mock.ExpectExec(attemptQuery).
+					WithArgs("test-operation-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+			expectedError: queryErr,
+		},
+		{
+			name: "QueryErrorOnLastAttemptFailsOperation",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectClaim(mock, DefaultMaxQueuedAttempts-1)
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnError(queryErr)
+				mock.ExpectRollback()
+				mock.ExpectQuery(failQuery).
+					WithArgs("failed", gaveUpReason, "test-operation-id").
+					WillReturnRows(pgxmock.NewRows(operationColumns).
+						AddRow("test-operation-id", "test-wallet-id", "withdraw", int64(-150), "RUB", "failed",
+							&gaveUpReason, noReference, time.Time{}, time.Time{}, DefaultMaxQueuedAttempts))
+				mock.ExpectCommit()
+			},
+			expectedOperation: &entity.QueuedOperation{
+				ID: "test-operation-id",
+				Operation: entity.Operation{
+					WalletID: "test-wallet-id",
+					Amount:   rub(-150),
+					Type:     entity.OperationWithdraw,
+				},
+				Status:        entity.QueuedOperationFailed,
+				FailureReason: gaveUpReason,
+				Attempts:      DefaultMaxQueuedAttempts,
+			},
+			// The error of the last attempt is only returned, not stored.
+			expectedError: queryErr,
+		},
+		{
+			name: "CountingAttemptFails",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				expectClaim(mock, 0)
+				mock.ExpectBegin()
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-wallet-id").
+					WillReturnError(queryErr)
+				mock.ExpectRollback()
+				mock.ExpectExec(attemptQuery).
+					WithArgs("test-operation-id").
+					WillReturnError(countErr)
+				mock.ExpectRollback()
+			},
+			expectedError: countErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			operation, err := repo.ProcessQueuedOperation(t.Context())
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError == nil {
+				require.NoError(t, err, "expected no error")
+			} else {
+				require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			}
+			require.Equal(t, tt.expectedOperation, operation, "expected operation to match")
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"time"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+)
+
+type Webhook struct {
+	ID         string    `db:"id"`
+	URL        string    `db:"url"`
+	Secret     string    `db:"secret"`
+	EventTypes []string  `db:"event_types"`
+	WalletIDs  []string  `db:"wallet_ids"`
+	Active     bool      `db:"active"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func (w Webhook) ToEntity() *entity.Webhook {
+	eventTypes := make([]entity.EventType, 0, len(w.EventTypes))
+	for _, t := range w.EventTypes {
+		eventTypes = append(eventTypes, entity.EventType(t))
+	}
+
+	return &entity.Webhook{
+		ID:         w.ID,
+		URL:        w.URL,
+		Secret:     w.Secret,
+		EventTypes: eventTypes,
+		WalletIDs:  w.WalletIDs,
+		Active:     w.Active,
+		CreatedAt:  w.CreatedAt,
+	}
+}
+
+type WebhookDelivery struct {
+	ID             string     `db:"id"`
+	WebhookID      string     `db:"webhook_id"`
+	EventID        string     `db:"event_id"`
+	EventType      string     `db:"event_type"`
+	Payload        []byte     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LastStatusCode *int       `db:"last_status_code"`
+	LastError      *string    `db:"last_error"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+func (d WebhookDelivery) ToEntity() *entity.WebhookDelivery {
+	var lastStatusCode int
+	if d.LastStatusCode != nil {
+		lastStatusCode = *d.LastStatusCode
+	}
+	var lastError string
+	if d.LastError != nil {
+		lastError = *d.LastError
+	}
+
+	return &entity.WebhookDelivery{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventID:        d.EventID,
+		EventType:      entity.EventType(d.EventType),
+		Payload:        d.Payload,
+		Status:         entity.WebhookDeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: lastStatusCode,
+		LastError:      lastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
+
+// DueWebhookDelivery is a claimed delivery joined with its webhook endpoint.
+type DueWebhookDelivery struct {
+	WebhookDelivery
+
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+func (d DueWebhookDelivery) ToEntity() entity.DueWebhookDelivery {
+	return entity.DueWebhookDelivery{
+		WebhookDelivery: *d.WebhookDelivery.ToEntity(),
+		URL:             d.URL,
+		Secret:          d.Secret,
+	}
+}
+
+type WebhookAttempt struct {
+	DeliveryID  string    `db:"delivery_id"`
+	StatusCode  *int      `db:"status_code"`
+	Error       *string   `db:"error"`
+	DurationMs  int64     `db:"duration_ms"`
+	AttemptedAt time.Time `db:"attempted_at"`
+}
+
+func (a WebhookAttempt) ToEntity() entity.WebhookAttempt {
+	var statusCode int
+	if a.StatusCode != nil {
+		statusCode = *a.StatusCode
+	}
+	var errMsg string
+	if a.Error != nil {
+		errMsg = *a.Error
+	}
+
+	return entity.WebhookAttempt{
+		DeliveryID:  a.DeliveryID,
+		StatusCode:  statusCode,
+		Error:       errMsg,
+		Duration:    time.Duration(a.DurationMs) * time.Millisecond,
+		AttemptedAt: a.AttemptedAt,
+	}
+}
@@ -0,0 +1,401 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+	"github.com/passwordhash/asynchronous-wallet/internal/storage/postgres/webhook/model"
+	postgresPkg "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
+)
+
+const deliveryFields = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, delivered_at, created_at, updated_at`
+
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+type Repository struct {
+	db DB
+}
+
+func New(db DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Create is a method that registers a webhook.
+func (r *Repository) Create(ctx context.Context, webhook entity.Webhook) (*entity.Webhook, error) {
+	const op = "repository.webhook.Create"
+
+	eventTypes := make([]string, 0, len(webhook.EventTypes))
+	for _, t := range webhook.EventTypes {
+		eventTypes = append(eventTypes, string(t))
+	}
+	walletIDs := webhook.WalletIDs
+	if walletIDs == nil {
+		walletIDs = []string{}
+	}
+
+	query := `INSERT INTO webhooks (url, secret, event_types, wallet_ids)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *`
+
+	created, err := r.queryWebhook(ctx, r.db, query, webhook.URL, webhook.Secret, eventTypes, walletIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// GetByID is a method that retrieves a webhook by its ID, including a deactivated one.
+// If the webhook is not found, it returns [repoErr.ErrWebhookNotFound].
+func (r *Repository) GetByID(ctx context.Context, webhookID string) (*entity.Webhook, error) {
+	const op = "repository.webhook.GetByID"
+
+	webhook, err := r.queryWebhook(ctx, r.db, `SELECT * FROM webhooks WHERE id = $1`, webhookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, repoErr.ErrWebhookNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+// Deactivate is a method that stops queuing events for a webhook. Deliveries that
+// are already queued are still attempted.
+// If the webhook is not found, it returns [repoErr.ErrWebhookNotFound].
+func (r *Repository) Deactivate(ctx context.Context, webhookID string) error {
+	const op = "repository.webhook.Deactivate"
+
+	tag, err := r.db.Exec(ctx, `UPDATE webhooks SET active = FALSE WHERE id = $1`, webhookID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, repoErr.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries is a method that queues the event for every active webhook
+// whose filters it passes, with payload as the request body. An event is queued
+// for a webhook only once, so it is safe to call again with the same event.
+// It returns the number of queued deliveries.
+func (r *Repository) EnqueueDeliveries(ctx context.Context, event entity.Event, payload []byte) (int64, error) {
+	const op = "repository.webhook.EnqueueDeliveries"
+
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM webhooks
+		WHERE active
+			AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+			AND (cardinality(wallet_ids) = 0 OR $4::uuid = ANY(wallet_ids))
+		ON CONFLICT (webhook_id, event_id) DO NOTHING`
+
+	tag, err := r.db.Exec(ctx, query, event.ID, string(event.Type), payload, event.WalletID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDueDeliveries is a method that claims up to limit pending deliveries whose
+// next attempt is due, the longest waiting first. The next attempt of a claimed
+// delivery is moved lease into the future, so other workers skip it while it is
+// being attempted and pick it up again if the worker crashes before recording the result.
+func (r *Repository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]entity.DueWebhookDelivery, error) {
+	const op = "repository.webhook.ClaimDueDeliveries"
+
+	query := `WITH claimed AS (
+			UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2::interval, updated_at = NOW()
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT c.*, w.url, w.secret FROM claimed c JOIN webhooks w ON w.id = c.webhook_id
+		ORDER BY c.created_at`
+
+	rows, err := r.db.Query(ctx, query, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	claimed, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.DueWebhookDelivery])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries := make([]entity.DueWebhookDelivery, 0, len(claimed))
+	for _, d := range claimed {
+		deliveries = append(deliveries, d.ToEntity())
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt is a method that stores the outcome of a delivery attempt and moves
+// the delivery to the given status. A pending delivery is attempted again at nextAttemptAt.
+func (r *Repository) RecordAttempt(
+	ctx context.Context,
+	attempt entity.WebhookAttempt,
+	status entity.WebhookDeliveryStatus,
+	nextAttemptAt time.Time,
+) error {
+	const op = "repository.webhook.RecordAttempt"
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		attemptQuery := `INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, duration_ms, attempted_at)
+			VALUES ($1, $2, $3, $4, $5)`
+
+		_, err := tx.Exec(ctx, attemptQuery,
+			attempt.DeliveryID,
+			nullableInt(attempt.StatusCode),
+			nullableString(attempt.Error),
+			attempt.Duration.Milliseconds(),
+			attempt.AttemptedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record attempt: %w", err)
+		}
+
+		deliveryQuery := `UPDATE webhook_deliveries
+			SET status = $1, attempts = attempts + 1, next_attempt_at = $2,
+				last_status_code = $3, last_error = $4,
+				delivered_at = CASE WHEN $1 = 'delivered' THEN NOW() END,
+				updated_at = NOW()
+			WHERE id = $5`
+
+		tag, err := tx.Exec(ctx, deliveryQuery,
+			string(status),
+			nextAttemptAt,
+			nullableInt(attempt.StatusCode),
+			nullableString(attempt.Error),
+			attempt.DeliveryID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update delivery: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return repoErr.ErrWebhookDeliveryNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Deliveries is a method that returns deliveries of a webhook matching the filter,
+// the newest first. At most filter.Limit deliveries are returned.
+func (r *Repository) Deliveries(ctx context.Context, filter entity.WebhookDeliveryFilter) ([]entity.WebhookDelivery, error) {
+	const op = "repository.webhook.Deliveries"
+
+	query := `SELECT ` + deliveryFields + ` FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, filter.WebhookID, string(filter.Status), filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	found, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.WebhookDelivery])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries := make([]entity.WebhookDelivery, 0, len(found))
+	for _, d := range found {
+		deliveries = append(deliveries, *d.ToEntity())
+	}
+
+	return deliveries, nil
+}
+
+// GetDelivery is a method that retrieves a delivery of a webhook together with its
+// attempts, the oldest first.
+// If the delivery is not found, it returns [repoErr.ErrWebhookDeliveryNotFound].
+func (r *Repository) GetDelivery(
+	ctx context.Context,
+	webhookID string,
+	deliveryID string,
+) (*entity.WebhookDelivery, []entity.WebhookAttempt, error) {
+	const op = "repository.webhook.GetDelivery"
+
+	query := `SELECT ` + deliveryFields + ` FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2`
+
+	delivery, err := r.queryDelivery(ctx, r.db, query, deliveryID, webhookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, fmt.Errorf("%s: %w", op, repoErr.ErrWebhookDeliveryNotFound)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	attemptsQuery := `SELECT delivery_id, status_code, error, duration_ms, attempted_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY id`
+
+	rows, err := r.db.Query(ctx, attemptsQuery, deliveryID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	found, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.WebhookAttempt])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	attempts := make([]entity.WebhookAttempt, 0, len(found))
+	for _, a := range found {
+		attempts = append(attempts, a.ToEntity())
+	}
+
+	return delivery, attempts, nil
+}
+
+// Redeliver is a method that moves a dead delivery back to pending with a fresh
+// attempt budget, so it is attempted again right away.
+// If the delivery is not found, it returns [repoErr.ErrWebhookDeliveryNotFound];
+// if it is not dead, it returns [repoErr.ErrWebhookDeliveryNotDead].
+func (r *Repository) Redeliver(ctx context.Context, webhookID, deliveryID string) (*entity.WebhookDelivery, error) {
+	const op = "repository.webhook.Redeliver"
+
+	var redelivered *entity.WebhookDelivery
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		query := `SELECT ` + deliveryFields + ` FROM webhook_deliveries
+			WHERE id = $1 AND webhook_id = $2
+			FOR UPDATE`
+
+		delivery, err := r.queryDelivery(ctx, tx, query, deliveryID, webhookID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repoErr.ErrWebhookDeliveryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if delivery.Status != entity.WebhookDeliveryDead {
+			return repoErr.ErrWebhookDeliveryNotDead
+		}
+
+		query = `UPDATE webhook_deliveries
+			SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+			WHERE id = $1
+			RETURNING ` + deliveryFields
+
+		redelivered, err = r.queryDelivery(ctx, tx, query, deliveryID)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return redelivered, nil
+}
+
+// inTx is a helper method that runs fn within a database transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+func (r *Repository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("rollback failed: %v, original error: %w", rbErr, err)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// queryWebhook is a helper method that runs a query returning exactly one webhook row.
+// If the query returns no rows, it returns [pgx.ErrNoRows].
+func (r *Repository) queryWebhook(
+	ctx context.Context,
+	q postgresPkg.Queryer,
+	query string,
+	args ...any,
+) (*entity.Webhook, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhook, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[model.Webhook])
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook.ToEntity(), nil
+}
+
+// queryDelivery is a helper method that runs a query returning exactly one delivery row.
+// If the query returns no rows, it returns [pgx.ErrNoRows].
+func (r *Repository) queryDelivery(
+	ctx context.Context,
+	q postgresPkg.Queryer,
+	query string,
+	args ...any,
+) (*entity.WebhookDelivery, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delivery, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[model.WebhookDelivery])
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery.ToEntity(), nil
+}
+
+func nullableInt(n int) *int {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
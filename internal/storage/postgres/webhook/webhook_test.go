@@ -0,0 +1,302 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/entity"
+	repoErr "github.com/passwordhash/asynchronous-wallet/internal/storage/errors"
+)
+
+var deliveryColumns = []string{
+	"id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts", "next_attempt_at",
+	"last_status_code", "last_error", "delivered_at", "created_at", "updated_at",
+}
+
+var (
+	noStatusCode *int
+	noError      *string
+	notDelivered *time.Time
+)
+
+func setupTest(t *testing.T) (pgxmock.PgxPoolIface, *Repository) {
+	t.Helper()
+
+	mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+
+	return mock, New(mock)
+}
+
+func TestGetByID(t *testing.T) {
+	t.Parallel()
+
+	const getQuery = `SELECT \* FROM webhooks WHERE id = \$1`
+
+	webhookColumns := []string{"id", "url", "secret", "event_types", "wallet_ids", "active", "created_at"}
+
+	tests := []struct {
+		name            string
+		mockBehavior    func(mock pgxmock.PgxPoolIface)
+		expectedWebhook *entity.Webhook
+		expectedError   error
+	}{
+		{
+			name: "Success",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-webhook-id").
+					WillReturnRows(pgxmock.NewRows(webhookColumns).
+						AddRow("test-webhook-id", "https://example.com/hook", "secret",
+							[]string{"WalletCredited"}, []string{}, true, time.Time{}))
+			},
+			expectedWebhook: &entity.Webhook{
+				ID:         "test-webhook-id",
+				URL:        "https://example.com/hook",
+				Secret:     "secret",
+				EventTypes: []entity.EventType{entity.EventWalletCredited},
+				WalletIDs:  []string{},
+				Active:     true,
+			},
+		},
+		{
+			name: "NotFound",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(getQuery).
+					WithArgs("test-webhook-id").
+					WillReturnRows(pgxmock.NewRows(webhookColumns))
+			},
+			expectedError: repoErr.ErrWebhookNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			webhook, err := repo.GetByID(t.Context(), "test-webhook-id")
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+			require.Equal(t, tt.expectedWebhook, webhook, "expected webhook to match")
+		})
+	}
+}
+
+func TestEnqueueDeliveries(t *testing.T) {
+	t.Parallel()
+
+	mock, repo := setupTest(t)
+
+	event := entity.Event{ID: "test-event-id", Type: entity.EventWalletDebited, WalletID: "test-wallet-id"}
+	payload := []byte(`{"id":"test-event-id"}`)
+
+	mock.ExpectExec(`INSERT INTO webhook_deliveries \(webhook_id, event_id, event_type, payload\)\s+`+
+		`SELECT id, \$1, \$2, \$3 FROM webhooks\s+WHERE active.*ON CONFLICT \(webhook_id, event_id\) DO NOTHING`).
+		WithArgs("test-event-id", "WalletDebited", payload, "test-wallet-id").
+		WillReturnResult(pgxmock.NewResult("INSERT", 2))
+
+	queued, err := repo.EnqueueDeliveries(t.Context(), event, payload)
+
+	require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+	require.NoError(t, err, "expected no error")
+	require.EqualValues(t, 2, queued, "expected queued deliveries count to match")
+}
+
+func TestRecordAttempt(t *testing.T) {
+	t.Parallel()
+
+	const attemptQuery = `INSERT INTO webhook_delivery_attempts`
+	const deliveryQuery = `UPDATE webhook_deliveries\s+SET status = \$1, attempts = attempts \+ 1`
+
+	nextAttemptAt := time.Now().Add(time.Minute)
+	statusCode := 500
+	errMsg := "unexpected status code 500"
+
+	tests := []struct {
+		name          string
+		attempt       entity.WebhookAttempt
+		status        entity.WebhookDeliveryStatus
+		mockBehavior  func(mock pgxmock.PgxPoolIface)
+		expectedError error
+	}{
+		{
+			name:    "Delivered",
+			attempt: entity.WebhookAttempt{DeliveryID: "test-delivery-id", StatusCode: 200, Duration: 15 * time.Millisecond},
+			status:  entity.WebhookDeliveryDelivered,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				okCode := 200
+				mock.ExpectBegin()
+				mock.ExpectExec(attemptQuery).
+					WithArgs("test-delivery-id", &okCode, noError, int64(15), pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(deliveryQuery).
+					WithArgs("delivered", nextAttemptAt, &okCode, noError, "test-delivery-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Failed",
+			attempt: entity.WebhookAttempt{
+				DeliveryID: "test-delivery-id",
+				StatusCode: statusCode,
+				Error:      errMsg,
+				Duration:   time.Second,
+			},
+			status: entity.WebhookDeliveryPending,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(attemptQuery).
+					WithArgs("test-delivery-id", &statusCode, &errMsg, int64(1000), pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(deliveryQuery).
+					WithArgs("pending", nextAttemptAt, &statusCode, &errMsg, "test-delivery-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:    "NoResponse",
+			attempt: entity.WebhookAttempt{DeliveryID: "test-delivery-id", Error: errMsg},
+			status:  entity.WebhookDeliveryDead,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(attemptQuery).
+					WithArgs("test-delivery-id", noStatusCode, &errMsg, int64(0), pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(deliveryQuery).
+					WithArgs("dead", nextAttemptAt, noStatusCode, &errMsg, "test-delivery-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:    "DeliveryNotFound",
+			attempt: entity.WebhookAttempt{DeliveryID: "test-delivery-id", Error: errMsg},
+			status:  entity.WebhookDeliveryPending,
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectExec(attemptQuery).
+					WithArgs("test-delivery-id", noStatusCode, &errMsg, int64(0), pgxmock.AnyArg()).
+					WillReturnResult(pgxmock.NewResult("INSERT", 1))
+				mock.ExpectExec(deliveryQuery).
+					WithArgs("pending", nextAttemptAt, noStatusCode, &errMsg, "test-delivery-id").
+					WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWebhookDeliveryNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			err := repo.RecordAttempt(t.Context(), tt.attempt, tt.status, nextAttemptAt)
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			require.ErrorIs(t, err, tt.expectedError, "expected error to match")
+		})
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	t.Parallel()
+
+	const lockQuery = `FROM webhook_deliveries\s+WHERE id = \$1 AND webhook_id = \$2\s+FOR UPDATE`
+	const updateQuery = `UPDATE webhook_deliveries\s+SET status = 'pending', attempts = 0`
+
+	deliveryRow := func(status string, attempts int) *pgxmock.Rows {
+		return pgxmock.NewRows(deliveryColumns).
+			AddRow("test-delivery-id", "test-webhook-id", "test-event-id", "WalletCreated", []byte(`{}`),
+				status, attempts, time.Time{}, noStatusCode, noError, notDelivered, time.Time{}, time.Time{})
+	}
+
+	tests := []struct {
+		name             string
+		mockBehavior     func(mock pgxmock.PgxPoolIface)
+		expectedAttempts int
+		expectedError    error
+	}{
+		{
+			name: "Success",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs("test-delivery-id", "test-webhook-id").
+					WillReturnRows(deliveryRow("dead", 8))
+				mock.ExpectQuery(updateQuery).
+					WithArgs("test-delivery-id").
+					WillReturnRows(deliveryRow("pending", 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "NotDead",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs("test-delivery-id", "test-webhook-id").
+					WillReturnRows(deliveryRow("delivered", 1))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWebhookDeliveryNotDead,
+		},
+		{
+			name: "NotFound",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs("test-delivery-id", "test-webhook-id").
+					WillReturnRows(pgxmock.NewRows(deliveryColumns))
+				mock.ExpectRollback()
+			},
+			expectedError: repoErr.ErrWebhookDeliveryNotFound,
+		},
+		{
+			name: "DBError",
+			mockBehavior: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).
+					WithArgs("test-delivery-id", "test-webhook-id").
+					WillReturnError(errors.New("db error"))
+				mock.ExpectRollback()
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock, repo := setupTest(t)
+
+			tt.mockBehavior(mock)
+
+			delivery, err := repo.Redeliver(t.Context(), "test-webhook-id", "test-delivery-id")
+
+			require.NoError(t, mock.ExpectationsWereMet(), "expectations were not met")
+			if tt.expectedError != nil {
+				require.ErrorContains(t, err, tt.expectedError.Error(), "expected error to match")
+				return
+			}
+			require.NoError(t, err, "expected no error")
+			require.Equal(t, entity.WebhookDeliveryPending, delivery.Status, "expected delivery to be pending")
+			require.Zero(t, delivery.Attempts, "expected attempts to be reset")
+		})
+	}
+}
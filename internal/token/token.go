@@ -0,0 +1,102 @@
+// Package token verifies the JWT bearer tokens end users authenticate with.
+package token
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+)
+
+// asymmetricMethods are the algorithms accepted for tokens verified with a JWK Set.
+// HMAC is left out, so a public key of the set can't be used as a shared secret.
+var asymmetricMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Verifier checks the signature, issuer, audience and lifetime of tokens.
+type Verifier struct {
+	keyfunc func(ctx context.Context) jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+// New returns a verifier of tokens signed with the keys of the JWK Set at
+// cfg.JWKSURL or in cfg.JWKSFile, or with cfg.StaticKey. Keys fetched from the
+// URL are refreshed in the background until ctx is done.
+func New(ctx context.Context, cfg config.JWTConfig) (*Verifier, error) {
+	const op = "token.New"
+
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, fmt.Errorf("%s: issuer and audience are required", op)
+	}
+
+	sources := 0
+	for _, s := range []string{cfg.JWKSURL, cfg.JWKSFile, cfg.StaticKey} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("%s: exactly one of jwks_url, jwks_file and static_key must be set", op)
+	}
+
+	v := &Verifier{}
+	methods := asymmetricMethods
+
+	switch {
+	case cfg.JWKSURL != "":
+		k, err := keyfunc.NewDefaultCtx(ctx, []string{cfg.JWKSURL})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		v.keyfunc = k.KeyfuncCtx
+	case cfg.JWKSFile != "":
+		raw, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		k, err := keyfunc.NewJWKSetJSON(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		v.keyfunc = k.KeyfuncCtx
+	default:
+		key := []byte(cfg.StaticKey)
+		v.keyfunc = func(context.Context) jwt.Keyfunc {
+			return func(*jwt.Token) (any, error) { return key, nil }
+		}
+		methods = []string{jwt.SigningMethodHS256.Alg()}
+	}
+
+	v.parser = jwt.NewParser(
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithExpirationRequired(),
+	)
+
+	return v, nil
+}
+
+// Verify returns the subject of the token, the end user it was issued to.
+func (v *Verifier) Verify(ctx context.Context, raw string) (string, error) {
+	const op = "token.Verify"
+
+	var claims jwt.RegisteredClaims
+	if _, err := v.parser.ParseWithClaims(raw, &claims, v.keyfunc(ctx)); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if claims.Subject == "" {
+		return "", fmt.Errorf("%s: token has no subject", op)
+	}
+
+	return claims.Subject, nil
+}
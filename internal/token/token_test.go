@@ -0,0 +1,205 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "wallet"
+	testSecret   = "test-secret"
+)
+
+func validClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "user-1",
+		Issuer:    testIssuer,
+		Audience:  jwt.ClaimStrings{testAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims jwt.RegisteredClaims) string {
+	t.Helper()
+
+	raw, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+
+	return raw
+}
+
+func TestVerify_StaticKey(t *testing.T) {
+	t.Parallel()
+
+	v, err := New(t.Context(), config.JWTConfig{
+		StaticKey: testSecret,
+		Issuer:    testIssuer,
+		Audience:  testAudience,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		token       func() string
+		wantSubject string
+		wantErr     bool
+	}{
+		{
+			name:        "Valid",
+			token:       func() string { return sign(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims()) },
+			wantSubject: "user-1",
+		},
+		{
+			name:    "Wrong key",
+			token:   func() string { return sign(t, jwt.SigningMethodHS256, []byte("other-secret"), validClaims()) },
+			wantErr: true,
+		},
+		{
+			name: "Wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims.Issuer = "https://other.example.com"
+				return sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims.Audience = jwt.ClaimStrings{"other"}
+				return sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Expired",
+			token: func() string {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				return sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Without expiry",
+			token: func() string {
+				claims := validClaims()
+				claims.ExpiresAt = nil
+				return sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Without subject",
+			token: func() string {
+				claims := validClaims()
+				claims.Subject = ""
+				return sign(t, jwt.SigningMethodHS256, []byte(testSecret), claims)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Unsigned",
+			token:   func() string { return sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims()) },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject, err := v.Verify(t.Context(), tt.token())
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSubject, subject)
+		})
+	}
+}
+
+func TestVerify_JWKSFile(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "EC",
+			"crv": "P-256",
+			"kid": "test-key",
+			"alg": "ES256",
+			"use": "sig",
+			"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	v, err := New(t.Context(), config.JWTConfig{
+		JWKSFile: path,
+		Issuer:   testIssuer,
+		Audience: testAudience,
+	})
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, validClaims())
+	token.Header["kid"] = "test-key"
+	raw, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	subject, err := v.Verify(t.Context(), raw)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", subject)
+
+	// A token signed with the public key as an HMAC secret must not pass.
+	_, err = v.Verify(t.Context(), sign(t, jwt.SigningMethodHS256, jwks, validClaims()))
+	assert.Error(t, err)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  config.JWTConfig
+	}{
+		{name: "No key source", cfg: config.JWTConfig{Issuer: testIssuer, Audience: testAudience}},
+		{
+			name: "Several key sources",
+			cfg:  config.JWTConfig{StaticKey: testSecret, JWKSFile: "jwks.json", Issuer: testIssuer, Audience: testAudience},
+		},
+		{name: "No audience", cfg: config.JWTConfig{StaticKey: testSecret, Issuer: testIssuer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(t.Context(), tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
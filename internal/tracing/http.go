@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTPMiddleware starts a server span for every request, named after the route
+// template. If the request has a traceparent header, the span continues that trace.
+func (t *Tracing) HTTPMiddleware() gin.HandlerFunc {
+	tracer := t.provider.Tracer(instrumentationName)
+
+	return func(c *gin.Context) {
+		ctx := t.propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		name := c.Request.Method
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.URLPath(c.Request.URL.Path),
+		}
+		if route := c.FullPath(); route != "" {
+			name += " " + route
+			attrs = append(attrs, semconv.HTTPRoute(route))
+		}
+
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogHandler adds the trace and span IDs of the span in the context to the records
+// logged with one, e.g. by [slog.Logger.InfoContext].
+type LogHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler returns a handler that passes the records to h with the trace IDs added.
+func NewLogHandler(h slog.Handler) *LogHandler {
+	return &LogHandler{Handler: h}
+}
+
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		record.AddAttrs(
+			slog.String("traceID", spanCtx.TraceID().String()),
+			slog.String("spanID", spanCtx.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithGroup(name)}
+}
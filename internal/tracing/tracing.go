@@ -0,0 +1,102 @@
+// Package tracing sets up OpenTelemetry tracing of the service: the span exporter,
+// W3C trace context propagation of HTTP requests and trace IDs in log records.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+)
+
+const (
+	serviceName = "asynchronous-wallet"
+	// instrumentationName names the tracer of the spans started by this package.
+	instrumentationName = "github.com/passwordhash/asynchronous-wallet/internal/tracing"
+)
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// Tracing holds the tracer provider of the service and the propagator of the
+// trace context of incoming requests.
+type Tracing struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	shutdown   func(ctx context.Context) error
+}
+
+// New returns tracing exporting spans with the configured exporter. With the none
+// exporter spans aren't recorded, but the trace context of incoming requests is
+// still propagated, so log records carry the trace IDs of the callers.
+func New(ctx context.Context, cfg config.TracingConfig) (*Tracing, error) {
+	const op = "tracing.New"
+
+	t := &Tracing{
+		provider:   noop.NewTracerProvider(),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		shutdown:   func(context.Context) error { return nil },
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone:
+		return t, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("%s: unknown exporter %q", op, cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	t.provider = provider
+	t.shutdown = provider.Shutdown
+
+	return t, nil
+}
+
+// TracerProvider returns the provider the components of the service get their tracers from.
+func (t *Tracing) TracerProvider() trace.TracerProvider {
+	return t.provider
+}
+
+// Shutdown exports the spans that are still buffered and stops the exporter.
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	return t.shutdown(ctx)
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/passwordhash/asynchronous-wallet/internal/config"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceparent = "00-" + testTraceID + "-00f067aa0ba902b7-01"
+)
+
+// setupTest returns tracing that records the ended spans.
+func setupTest(t *testing.T) (*Tracing, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	return &Tracing{
+		provider:   provider,
+		propagator: propagation.TraceContext{},
+		shutdown:   provider.Shutdown,
+	}, recorder
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	tr, recorder := setupTest(t)
+
+	var handlerSpan trace.SpanContext
+	r := gin.New()
+	r.Use(tr.HTTPMiddleware())
+	r.GET("/api/v1/wallets/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/42", nil)
+	req.Header.Set("traceparent", testTraceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+
+	span := spans[0]
+	assert.Equal(t, "GET /api/v1/wallets/:id", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, testTraceID, span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID())
+}
+
+func TestNew_NoneExporter(t *testing.T) {
+	tr, err := New(context.Background(), config.TracingConfig{Exporter: ExporterNone})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	var handlerSpan trace.SpanContext
+	r := gin.New()
+	r.Use(tr.HTTPMiddleware())
+	r.GET("/health", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("traceparent", testTraceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Spans aren't recorded, but the trace of the caller is still propagated.
+	assert.Equal(t, testTraceID, handlerSpan.TraceID().String())
+	assert.NoError(t, tr.Shutdown(context.Background()))
+}
+
+func TestNew_UnknownExporter(t *testing.T) {
+	_, err := New(context.Background(), config.TracingConfig{Exporter: "jaeger"})
+
+	assert.Error(t, err)
+}
+
+func TestLogHandler(t *testing.T) {
+	tr, _ := setupTest(t)
+
+	var buf bytes.Buffer
+	log := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("op", "test")
+
+	ctx, span := tr.TracerProvider().Tracer("test").Start(context.Background(), "test")
+	log.InfoContext(ctx, "traced")
+	span.End()
+	log.Info("untraced")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var traced, untraced map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &traced))
+	require.NoError(t, json.Unmarshal(lines[1], &untraced))
+
+	assert.Equal(t, span.SpanContext().TraceID().String(), traced["traceID"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), traced["spanID"])
+	assert.Equal(t, "test", traced["op"])
+	assert.NotContains(t, untraced, "traceID")
+}
@@ -0,0 +1,36 @@
+// Package migrations embeds the database migrations, so the service knows the
+// schema version it was built for.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed postgres/*.up.sql
+var postgres embed.FS
+
+// Version returns the version of the latest PostgreSQL migration, the number
+// its file name starts with.
+func Version() (int64, error) {
+	names, err := fs.Glob(postgres, "postgres/*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(name, "postgres/"), "_")
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %q: %w", name, err)
+		}
+		latest = max(latest, version)
+	}
+
+	return latest, nil
+}
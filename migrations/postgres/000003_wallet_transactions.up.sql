@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    wallet_id UUID NOT NULL REFERENCES wallets (id),
+    amount BIGINT NOT NULL,
+    operation_type TEXT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS wallet_transactions_wallet_id_created_at_idx
+    ON wallet_transactions (wallet_id, created_at DESC, id DESC);
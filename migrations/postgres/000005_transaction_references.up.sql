@@ -0,0 +1,2 @@
+ALTER TABLE wallet_transactions
+    ADD COLUMN IF NOT EXISTS reference_id UUID REFERENCES wallet_transactions (id);
@@ -0,0 +1 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
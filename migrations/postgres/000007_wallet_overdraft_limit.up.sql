@@ -0,0 +1,3 @@
+-- NULL means the wallet uses the overdraft limit configured for the service.
+ALTER TABLE wallets
+    ADD COLUMN IF NOT EXISTS overdraft_limit BIGINT CHECK (overdraft_limit >= 0);
@@ -0,0 +1,8 @@
+ALTER TABLE wallet_transactions DROP COLUMN IF EXISTS entry_id;
+
+DROP TABLE IF EXISTS journal_postings;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS ledger_accounts;
+
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+DROP FUNCTION IF EXISTS reject_journal_modification();
@@ -0,0 +1,87 @@
+-- Accounts of the double-entry ledger: one per wallet plus system accounts
+-- that represent money entering and leaving the service.
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id UUID PRIMARY KEY NOT NULL,
+    code TEXT UNIQUE,
+    kind TEXT NOT NULL CHECK (kind IN ('wallet', 'system')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO ledger_accounts (id, code, kind) VALUES
+('00000000-0000-0000-0000-000000000001', 'cash_in', 'system'),
+('00000000-0000-0000-0000-000000000002', 'cash_out', 'system'),
+('00000000-0000-0000-0000-000000000003', 'fees', 'system'),
+('00000000-0000-0000-0000-000000000004', 'opening_balance', 'system')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO ledger_accounts (id, kind)
+SELECT id, 'wallet' FROM wallets
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    kind TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS journal_postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES journal_entries (id),
+    account_id UUID NOT NULL REFERENCES ledger_accounts (id),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS journal_postings_entry_id_idx ON journal_postings (entry_id);
+CREATE INDEX IF NOT EXISTS journal_postings_account_id_idx ON journal_postings (account_id);
+
+-- Postings of a journal entry must sum to zero. The check is deferred to commit,
+-- so the postings of an entry can be written by separate statements.
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM journal_postings WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER journal_postings_balanced
+    AFTER INSERT ON journal_postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- The journal is append-only: mistakes are fixed by compensating entries.
+CREATE OR REPLACE FUNCTION reject_journal_modification() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER journal_entries_append_only
+    BEFORE UPDATE OR DELETE ON journal_entries
+    FOR EACH ROW EXECUTE FUNCTION reject_journal_modification();
+
+CREATE TRIGGER journal_postings_append_only
+    BEFORE UPDATE OR DELETE ON journal_postings
+    FOR EACH ROW EXECUTE FUNCTION reject_journal_modification();
+
+ALTER TABLE wallet_transactions
+    ADD COLUMN IF NOT EXISTS entry_id UUID REFERENCES journal_entries (id);
+
+-- Existing balances have no history, so they are brought into the journal
+-- by opening entries against the opening_balance account.
+DO $$
+DECLARE
+    w RECORD;
+    e UUID;
+BEGIN
+    FOR w IN SELECT id, balance FROM wallets WHERE balance <> 0 LOOP
+        INSERT INTO journal_entries (kind) VALUES ('opening_balance') RETURNING id INTO e;
+        INSERT INTO journal_postings (entry_id, account_id, amount) VALUES
+            (e, w.id, w.balance),
+            (e, '00000000-0000-0000-0000-000000000004', -w.balance);
+    END LOOP;
+END;
+$$;
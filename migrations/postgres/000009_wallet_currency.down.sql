@@ -0,0 +1,2 @@
+ALTER TABLE journal_entries DROP COLUMN IF EXISTS currency;
+ALTER TABLE wallets DROP COLUMN IF EXISTS currency;
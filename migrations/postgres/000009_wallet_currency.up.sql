@@ -0,0 +1,11 @@
+-- Wallets created before currencies were introduced hold roubles.
+ALTER TABLE wallets
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB'
+        CHECK (currency IN ('RUB', 'EUR', 'USD'));
+ALTER TABLE wallets ALTER COLUMN currency DROP DEFAULT;
+
+-- All postings of a journal entry are in the entry currency, so an entry whose
+-- postings sum to zero is balanced in that currency.
+ALTER TABLE journal_entries
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE journal_entries ALTER COLUMN currency DROP DEFAULT;
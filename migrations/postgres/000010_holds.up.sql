@@ -0,0 +1,20 @@
+-- Sum of the active holds of the wallet. The available balance is balance - held_amount.
+ALTER TABLE wallets
+    ADD COLUMN IF NOT EXISTS held_amount BIGINT NOT NULL DEFAULT 0 CHECK (held_amount >= 0);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    wallet_id UUID NOT NULL REFERENCES wallets (id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    captured_amount BIGINT NOT NULL DEFAULT 0 CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'released', 'expired')),
+    -- The wallet transaction written when the hold is captured.
+    transaction_id UUID REFERENCES wallet_transactions (id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS holds_wallet_id_idx ON holds (wallet_id);
+CREATE INDEX IF NOT EXISTS holds_active_expires_at_idx ON holds (expires_at) WHERE status = 'active';
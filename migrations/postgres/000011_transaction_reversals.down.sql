@@ -0,0 +1 @@
+DROP INDEX IF EXISTS wallet_transactions_reference_id_idx;
@@ -0,0 +1,5 @@
+-- Reversals reference the reversed transaction. The index is used to sum up
+-- the amount already reversed.
+CREATE INDEX IF NOT EXISTS wallet_transactions_reference_id_idx
+    ON wallet_transactions (reference_id)
+    WHERE reference_id IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Deposits and withdrawals accepted in async mode. They are applied by a background
+-- worker; the row records the outcome for status polling.
+CREATE TABLE IF NOT EXISTS operations (
+    id UUID PRIMARY KEY NOT NULL,
+    wallet_id UUID NOT NULL REFERENCES wallets (id),
+    operation_type TEXT NOT NULL CHECK (operation_type IN ('deposit', 'withdraw')),
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed')),
+    failure_reason TEXT,
+    -- The wallet transaction written when the operation is completed.
+    transaction_id UUID REFERENCES wallet_transactions (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS operations_pending_created_at_idx ON operations (created_at) WHERE status = 'pending';
@@ -0,0 +1,15 @@
+-- Wallet domain events written in the same transaction as the change they describe.
+-- The relay publishes them in id order and marks them as sent.
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    event_type TEXT NOT NULL,
+    wallet_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unsent_idx ON outbox (id) WHERE sent_at IS NULL;
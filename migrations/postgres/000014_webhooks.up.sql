@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    -- Empty arrays mean no filter.
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    wallet_ids UUID[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES webhooks (id),
+    event_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INT,
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- The outbox relay delivers events at least once; an event is queued for a webhook only once.
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries (id),
+    status_code INT,
+    error TEXT,
+    duration_ms INT NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_attempts_delivery_id_idx ON webhook_delivery_attempts (delivery_id);
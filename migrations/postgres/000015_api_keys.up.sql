@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    -- SHA-256 of the key; the key itself is shown only when it is created or rotated.
+    key_hash BYTEA NOT NULL UNIQUE,
+    permission TEXT NOT NULL CHECK (permission IN ('read', 'read_write')),
+    -- An empty array means all wallets.
+    wallet_ids UUID[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rotated_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
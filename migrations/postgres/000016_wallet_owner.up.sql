@@ -0,0 +1,6 @@
+-- The subject of the token of the end user who owns the wallet. NULL means the
+-- wallet has no owner and is only reachable with API keys.
+ALTER TABLE wallets
+    ADD COLUMN IF NOT EXISTS owner_id TEXT;
+
+CREATE INDEX IF NOT EXISTS wallets_owner_id_idx ON wallets (owner_id) WHERE owner_id IS NOT NULL;
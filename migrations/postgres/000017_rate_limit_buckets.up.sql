@@ -0,0 +1,7 @@
+-- Token buckets of the postgres rate limit backend, shared by all instances.
+-- Unlogged: losing the buckets in a crash only resets the limits.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+    key TEXT PRIMARY KEY NOT NULL,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,33 @@
+-- Append-only record of who changed what. Every record holds the SHA-256 hash of
+-- the previous one, so deleted, reordered or modified records break the chain.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    source_ip TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    wallet_id UUID,
+    resource_id TEXT NOT NULL DEFAULT '',
+    balance_before BIGINT,
+    balance_after BIGINT,
+    created_at TIMESTAMPTZ NOT NULL,
+    -- Empty for the first record.
+    prev_hash BYTEA NOT NULL,
+    hash BYTEA NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_wallet_id_idx ON audit_log (wallet_id) WHERE wallet_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,4 @@
+ALTER TABLE wallets
+    DROP CONSTRAINT IF EXISTS wallets_status_check,
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS status_reason;
@@ -0,0 +1,6 @@
+-- Frozen wallets reject balance changes until they are unfrozen, closed ones for good.
+-- The reason and time of the latest status change are kept with the wallet.
+ALTER TABLE wallets
+    ADD COLUMN IF NOT EXISTS status_reason TEXT,
+    ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ,
+    ADD CONSTRAINT wallets_status_check CHECK (status IN ('active', 'frozen', 'closed'));
@@ -0,0 +1,25 @@
+-- Records are staged in the transaction of the change they record and chained
+-- into audit_log by the sequencer afterwards, so a committed change always has its
+-- record and changes of different wallets don't wait for each other to append.
+CREATE TABLE IF NOT EXISTS audit_pending (
+    id BIGSERIAL PRIMARY KEY,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    source_ip TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    wallet_id UUID,
+    resource_id TEXT NOT NULL DEFAULT '',
+    balance_before BIGINT,
+    balance_after BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The hash of the newest record of audit_log. Its row lock serializes the sequencers.
+CREATE TABLE IF NOT EXISTS audit_head (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    hash BYTEA NOT NULL
+);
+
+INSERT INTO audit_head (hash)
+VALUES (COALESCE((SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1), ''::BYTEA))
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+-- An event that fails to publish is retried with backoff instead of holding back
+-- every event after it, and is moved to the dead state after too many attempts.
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS failed_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS outbox_unsent_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (id) WHERE sent_at IS NULL AND failed_at IS NULL;
+-- Finds the earlier events of a wallet that wait for a retry, which hold back its later events.
+CREATE INDEX IF NOT EXISTS outbox_pending_wallet_idx ON outbox (wallet_id, id)
+    WHERE sent_at IS NULL AND failed_at IS NULL;
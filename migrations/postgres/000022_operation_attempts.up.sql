@@ -0,0 +1,3 @@
+-- Attempts that failed with an unexpected error. An operation is marked as failed
+-- after too many of them, so it can't hold up the queue for good.
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS wallet_transactions_wallet_id_sequence_idx;
+
+ALTER TABLE wallet_transactions DROP COLUMN IF EXISTS sequence;
@@ -0,0 +1,17 @@
+-- Numbers the transactions of every wallet in the order they were committed, so
+-- balance changes can be streamed in that order. A transaction is numbered while
+-- its wallet row is locked, so the numbers of a wallet have no gaps.
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS sequence BIGINT;
+
+UPDATE wallet_transactions t
+SET sequence = numbered.sequence
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY wallet_id ORDER BY created_at, id) AS sequence
+    FROM wallet_transactions
+) numbered
+WHERE t.id = numbered.id;
+
+ALTER TABLE wallet_transactions ALTER COLUMN sequence SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS wallet_transactions_wallet_id_sequence_idx
+    ON wallet_transactions (wallet_id, sequence);
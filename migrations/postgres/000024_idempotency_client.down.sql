@@ -0,0 +1,9 @@
+-- Keys used by several clients are only kept once.
+DELETE FROM idempotency_keys a
+USING idempotency_keys b
+WHERE a.key = b.key AND a.client_id > b.client_id;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS client_id;
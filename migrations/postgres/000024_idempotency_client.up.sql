@@ -0,0 +1,7 @@
+-- Idempotency keys are chosen by clients, so they are scoped to the client that
+-- sent them: two clients using the same key don't see each other's responses.
+-- Existing keys keep an empty client and simply expire.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS client_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (client_id, key);
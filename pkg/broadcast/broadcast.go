@@ -0,0 +1,363 @@
+// Package broadcast fans values out to in-process subscribers of a key, keeping a
+// short history per key so that a subscriber can resume after reconnecting.
+package broadcast
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a value published under a key. IDs are opaque and unique within the
+// broadcaster; they are valid positions to resume from until the process restarts.
+type Message[T any] struct {
+	ID    string
+	Value T
+}
+
+// Subscription receives the messages published under its key.
+type Subscription[T any] struct {
+	// C receives the messages published after Subscribe returned. It is closed when
+	// the subscription is closed or dropped.
+	C <-chan Message[T]
+	// Missed are the messages published after the position the subscriber resumed
+	// from, the oldest first.
+	Missed []Message[T]
+	// Resumed reports whether Missed is complete. It is false if no position was
+	// given or the position is unknown or too old.
+	Resumed bool
+	// Position is the ID of the last message published under the key when the
+	// subscription was made. Resuming from it skips only messages already received.
+	Position string
+
+	c       chan Message[T]
+	b       *Broadcaster[T]
+	key     string
+	dropped bool
+}
+
+// Close stops the subscription. It is safe to call more than once.
+func (s *Subscription[T]) Close() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	s.b.unsubscribe(s)
+}
+
+// Dropped reports whether the subscription was closed because the subscriber didn't
+// keep up. It is only meaningful after C is closed.
+func (s *Subscription[T]) Dropped() bool {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	return s.dropped
+}
+
+type topic[T any] struct {
+	// since is the sequence number after which every message of the key is in recent.
+	since  uint64
+	recent []Message[T]
+	seqs   []uint64
+	subs   map[*Subscription[T]]struct{}
+	// idleSince is when the last subscriber left; it is zero while there are subscribers.
+	idleSince time.Time
+
+	// next is the position the next value published in order should have; zero
+	// until the first one is delivered.
+	next int64
+	// held are the values published in order ahead of a missing one, by position.
+	held []heldValue[T]
+	// gapTimer delivers the held values when the missing one takes too long.
+	gapTimer *time.Timer
+}
+
+type heldValue[T any] struct {
+	position int64
+	value    T
+}
+
+// DefaultGapTimeout is how long values published in order wait for a missing
+// earlier value by default.
+const DefaultGapTimeout = time.Second
+
+// Option configures a [Broadcaster].
+type Option func(*options)
+
+type options struct {
+	gapTimeout time.Duration
+}
+
+// WithGapTimeout sets how long values published with [Broadcaster.PublishInOrder]
+// are held back while an earlier value of their key is missing.
+func WithGapTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.gapTimeout = d
+	}
+}
+
+// Broadcaster delivers every published value to the current subscribers of its key.
+// Publish never blocks: a subscriber whose buffer is full is dropped.
+// The history of a key is kept while it has subscribers and for the retention
+// period after the last one leaves.
+type Broadcaster[T any] struct {
+	mu     sync.Mutex
+	epoch  string
+	seq    uint64
+	topics map[string]*topic[T]
+	closed bool
+
+	history    int
+	bufferSize int
+	retention  time.Duration
+	gapTimeout time.Duration
+}
+
+// New returns a broadcaster that keeps the last history messages of every key and
+// buffers up to bufferSize messages for every subscriber.
+func New[T any](history, bufferSize int, retention time.Duration, opts ...Option) *Broadcaster[T] {
+	o := options{gapTimeout: DefaultGapTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Broadcaster[T]{
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		topics:     make(map[string]*topic[T]),
+		history:    max(history, 0),
+		bufferSize: max(bufferSize, 1),
+		retention:  retention,
+		gapTimeout: o.gapTimeout,
+	}
+}
+
+// Publish delivers the value to the subscribers of the key and returns the message.
+// Values published under a key nobody listens to, or listened to recently, are not kept.
+func (b *Broadcaster[T]) Publish(key string, value T) Message[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.deliver(b.topics[key], value)
+}
+
+// PublishInOrder publishes the value like [Broadcaster.Publish], but delivers the
+// values of a key by their position, which numbers them without gaps, e.g. when
+// they are published concurrently in an order other than the one they were made
+// in. A value ahead of a missing one is held back until the missing value is
+// published, or for the gap timeout at most; then the held values are delivered
+// and the missing one is given up on. A value behind the last one delivered is
+// delivered right away.
+func (b *Broadcaster[T]) PublishInOrder(key string, position int64, value T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[key]
+	if !ok {
+		// Nobody listens, so there is no order to keep.
+		b.deliver(nil, value)
+		return
+	}
+
+	if t.next != 0 && position > t.next {
+		i, _ := slices.BinarySearchFunc(t.held, position, func(h heldValue[T], p int64) int {
+			return cmp.Compare(h.position, p)
+		})
+		t.held = slices.Insert(t.held, i, heldValue[T]{position: position, value: value})
+
+		if t.gapTimer == nil {
+			var timer *time.Timer
+			timer = time.AfterFunc(b.gapTimeout, func() {
+				b.mu.Lock()
+				defer b.mu.Unlock()
+
+				// The values were delivered in the meantime, and maybe held again.
+				if t.gapTimer == timer {
+					b.releaseHeld(t, true)
+				}
+			})
+			t.gapTimer = timer
+		}
+		return
+	}
+
+	b.deliver(t, value)
+	t.next = max(t.next, position+1)
+	b.releaseHeld(t, false)
+}
+
+// SetNextPosition sets the position of the next value of the key published in order,
+// e.g. to one past the last value made before a subscription started, so that the
+// first value published isn't taken as the start if an earlier one is missing. It
+// does nothing if nobody listens to the key or values were published in order
+// under it already.
+func (b *Broadcaster[T]) SetNextPosition(key string, position int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t, ok := b.topics[key]; ok && t.next == 0 {
+		t.next = position
+	}
+}
+
+// releaseHeld delivers the held values of the topic that are no longer ahead of a
+// missing one, or all of them. It must be called with b.mu held.
+func (b *Broadcaster[T]) releaseHeld(t *topic[T], all bool) {
+	for len(t.held) > 0 && (all || t.held[0].position <= t.next) {
+		h := t.held[0]
+		t.held = t.held[1:]
+
+		b.deliver(t, h.value)
+		t.next = max(t.next, h.position+1)
+	}
+
+	if len(t.held) == 0 && t.gapTimer != nil {
+		t.gapTimer.Stop()
+		t.gapTimer = nil
+	}
+}
+
+// deliver sends the value to the subscribers of the topic, which is nil if the key
+// has none, and adds it to the history. It must be called with b.mu held.
+func (b *Broadcaster[T]) deliver(t *topic[T], value T) Message[T] {
+	b.seq++
+	msg := Message[T]{ID: b.id(b.seq), Value: value}
+
+	if t == nil {
+		return msg
+	}
+
+	t.recent = append(t.recent, msg)
+	t.seqs = append(t.seqs, b.seq)
+	if over := len(t.recent) - b.history; over > 0 {
+		t.since = t.seqs[over-1]
+		t.recent = append(t.recent[:0:0], t.recent[over:]...)
+		t.seqs = append(t.seqs[:0:0], t.seqs[over:]...)
+	}
+
+	for sub := range t.subs {
+		select {
+		case sub.c <- msg:
+		default:
+			sub.dropped = true
+			b.unsubscribe(sub)
+		}
+	}
+
+	return msg
+}
+
+// Subscribe subscribes to the messages published under the key. If lastID is the ID
+// of a message of the key that is still in its history, or a subscription position,
+// the messages published after it are returned in Missed.
+func (b *Broadcaster[T]) Subscribe(key, lastID string) *Subscription[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := make(chan Message[T], b.bufferSize)
+	sub := &Subscription[T]{
+		C:        c,
+		Position: b.id(b.seq),
+		c:        c,
+		b:        b,
+		key:      key,
+	}
+
+	if b.closed {
+		close(c)
+		return sub
+	}
+
+	b.prune()
+
+	t, ok := b.topics[key]
+	if !ok {
+		t = &topic[T]{
+			since: b.seq,
+			subs:  make(map[*Subscription[T]]struct{}),
+		}
+		b.topics[key] = t
+	}
+
+	if seq, ok := b.parseID(lastID); ok && seq >= t.since && seq <= b.seq {
+		sub.Resumed = true
+		for i, s := range t.seqs {
+			if s > seq {
+				sub.Missed = append(sub.Missed, t.recent[i])
+			}
+		}
+	}
+
+	t.subs[sub] = struct{}{}
+	t.idleSince = time.Time{}
+
+	return sub
+}
+
+// Close closes every subscription and the ones made later, e.g. to end long-lived
+// streams on shutdown.
+func (b *Broadcaster[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, t := range b.topics {
+		for sub := range t.subs {
+			b.unsubscribe(sub)
+		}
+		if t.gapTimer != nil {
+			t.gapTimer.Stop()
+			t.gapTimer = nil
+		}
+	}
+	b.topics = make(map[string]*topic[T])
+	b.closed = true
+}
+
+// unsubscribe removes the subscription from its topic and closes its channel.
+// It must be called with b.mu held.
+func (b *Broadcaster[T]) unsubscribe(sub *Subscription[T]) {
+	t, ok := b.topics[sub.key]
+	if !ok {
+		return
+	}
+	if _, ok := t.subs[sub]; !ok {
+		return
+	}
+
+	delete(t.subs, sub)
+	close(sub.c)
+
+	if len(t.subs) == 0 {
+		t.idleSince = time.Now()
+	}
+}
+
+// prune forgets the history of keys nobody has listened to for the retention period.
+// It must be called with b.mu held.
+func (b *Broadcaster[T]) prune() {
+	for key, t := range b.topics {
+		if len(t.subs) == 0 && time.Since(t.idleSince) > b.retention {
+			delete(b.topics, key)
+		}
+	}
+}
+
+func (b *Broadcaster[T]) id(seq uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// parseID returns the sequence number of an ID issued by this broadcaster.
+func (b *Broadcaster[T]) parseID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
@@ -0,0 +1,227 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish_DeliversToSubscribersOfKey(t *testing.T) {
+	t.Parallel()
+
+	b := New[int](10, 10, time.Minute)
+
+	sub := b.Subscribe("wallet-1", "")
+	defer sub.Close()
+	other := b.Subscribe("wallet-2", "")
+	defer other.Close()
+
+	msg := b.Publish("wallet-1", 42)
+
+	require.Equal(t, msg, <-sub.C, "expected the subscriber to receive the message")
+	require.Empty(t, other.C, "expected subscribers of other keys to receive nothing")
+	require.False(t, sub.Resumed, "expected a subscription without a position not to be resumed")
+}
+
+func TestPublishInOrder(t *testing.T) {
+	t.Parallel()
+
+	// received returns the values the subscriber has received so far.
+	received := func(sub *Subscription[int]) []int {
+		var values []int
+		for {
+			select {
+			case msg := <-sub.C:
+				values = append(values, msg.Value)
+			default:
+				return values
+			}
+		}
+	}
+
+	t.Run("HoldsBackValuesAheadOfMissingOne", func(t *testing.T) {
+		t.Parallel()
+
+		b := New[int](10, 10, time.Minute, WithGapTimeout(time.Hour))
+		sub := b.Subscribe("wallet", "")
+		defer sub.Close()
+
+		b.PublishInOrder("wallet", 1, 1)
+		b.PublishInOrder("wallet", 3, 3)
+		b.PublishInOrder("wallet", 4, 4)
+		require.Equal(t, []int{1}, received(sub), "expected the values after the missing one to be held back")
+
+		b.PublishInOrder("wallet", 2, 2)
+		require.Equal(t, []int{2, 3, 4}, received(sub), "expected the held values once the missing one arrives")
+	})
+
+	t.Run("GivesUpOnMissingValue", func(t *testing.T) {
+		t.Parallel()
+
+		b := New[int](10, 10, time.Minute, WithGapTimeout(10*time.Millisecond))
+		sub := b.Subscribe("wallet", "")
+		defer sub.Close()
+
+		b.PublishInOrder("wallet", 1, 1)
+		b.PublishInOrder("wallet", 4, 4)
+		b.PublishInOrder("wallet", 3, 3)
+
+		var values []int
+		require.Eventually(t, func() bool {
+			values = append(values, received(sub)...)
+			return len(values) == 3
+		}, time.Second, time.Millisecond, "expected the held values after the gap timeout")
+		require.Equal(t, []int{1, 3, 4}, values)
+
+		b.PublishInOrder("wallet", 2, 2)
+		b.PublishInOrder("wallet", 5, 5)
+		require.Equal(t, []int{2, 5}, received(sub), "expected a late value and the next one right away")
+	})
+
+	t.Run("ResumesInDeliveryOrder", func(t *testing.T) {
+		t.Parallel()
+
+		b := New[int](10, 10, time.Minute, WithGapTimeout(time.Hour))
+		sub := b.Subscribe("wallet", "")
+		position := sub.Position
+		sub.Close()
+
+		b.PublishInOrder("wallet", 1, 1)
+		b.PublishInOrder("wallet", 3, 3)
+		b.PublishInOrder("wallet", 2, 2)
+
+		resumed := b.Subscribe("wallet", position)
+		defer resumed.Close()
+
+		var values []int
+		for _, msg := range resumed.Missed {
+			values = append(values, msg.Value)
+		}
+		require.Equal(t, []int{1, 2, 3}, values, "expected missed values in order")
+	})
+}
+
+func TestSubscribe_Resume(t *testing.T) {
+	t.Parallel()
+
+	b := New[int](3, 10, time.Minute)
+
+	first := b.Subscribe("wallet", "")
+	position := first.Position
+	m1 := b.Publish("wallet", 1)
+	m2 := b.Publish("wallet", 2)
+	first.Close()
+	m3 := b.Publish("wallet", 3)
+
+	tests := []struct {
+		name            string
+		lastID          string
+		expectedResumed bool
+		expectedMissed  []Message[int]
+	}{
+		{name: "FromMessage", lastID: m1.ID, expectedResumed: true, expectedMissed: []Message[int]{m2, m3}},
+		{name: "FromPosition", lastID: position, expectedResumed: true, expectedMissed: []Message[int]{m1, m2, m3}},
+		{name: "UpToDate", lastID: m3.ID, expectedResumed: true},
+		{name: "UnknownID", lastID: "other-1"},
+		{name: "Malformed", lastID: "garbage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := b.Subscribe("wallet", tt.lastID)
+			defer sub.Close()
+
+			require.Equal(t, tt.expectedResumed, sub.Resumed, "expected resumed to match")
+			require.Equal(t, tt.expectedMissed, sub.Missed, "expected missed messages to match")
+		})
+	}
+}
+
+func TestSubscribe_HistoryTrimmed(t *testing.T) {
+	t.Parallel()
+
+	b := New[int](2, 10, time.Minute)
+
+	sub := b.Subscribe("wallet", "")
+	defer sub.Close()
+
+	m1 := b.Publish("wallet", 1)
+	b.Publish("wallet", 2)
+	m3 := b.Publish("wallet", 3)
+
+	resumed := b.Subscribe("wallet", m1.ID)
+	defer resumed.Close()
+	require.True(t, resumed.Resumed, "expected the history after the position to be complete")
+	require.Len(t, resumed.Missed, 2, "expected the messages after the position")
+
+	tooOld := b.Subscribe("wallet", sub.Position)
+	defer tooOld.Close()
+	require.False(t, tooOld.Resumed, "expected a position before the history not to be resumed")
+
+	require.Equal(t, m3.ID, b.Subscribe("wallet", "").Position, "expected the position to be the last message")
+}
+
+func TestPublish_DropsSlowSubscriber(t *testing.T) {
+	t.Parallel()
+
+	b := New[int](10, 2, time.Minute)
+
+	slow := b.Subscribe("wallet", "")
+	fast := b.Subscribe("wallet", "")
+	defer fast.Close()
+
+	for i := range 3 {
+		b.Publish("wallet", i)
+		<-fast.C
+	}
+
+	var received int
+	for range slow.C {
+		received++
+	}
+
+	require.Equal(t, 2, received, "expected the buffered messages to be delivered before the channel is closed")
+	require.True(t, slow.Dropped(), "expected the slow subscriber to be dropped")
+	require.False(t, fast.Dropped(), "expected the fast subscriber to stay")
+
+	slow.Close()
+}
+
+func TestSubscribe_PrunesIdleKeys(t *testing.T) {
+	t.Parallel()
+
+	b := New[int](10, 10, 0)
+
+	sub := b.Subscribe("wallet", "")
+	msg := b.Publish("wallet", 1)
+	sub.Close()
+
+	// Subscribing prunes the idle key, so its history is gone.
+	resumed := b.Subscribe("wallet", msg.ID)
+	defer resumed.Close()
+
+	require.True(t, resumed.Resumed, "expected an up-to-date position to be resumed")
+	require.Empty(t, resumed.Missed, "expected no history")
+
+	b.Publish("other", 1)
+	require.NotContains(t, b.topics, "other", "expected keys without subscribers not to be kept")
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	b := New[int](10, 10, time.Minute)
+
+	sub := b.Subscribe("wallet", "")
+	b.Close()
+
+	_, ok := <-sub.C
+	require.False(t, ok, "expected open subscriptions to be closed")
+	require.False(t, sub.Dropped(), "expected a closed subscription not to be reported as dropped")
+
+	later := b.Subscribe("wallet", "")
+	_, ok = <-later.C
+	require.False(t, ok, "expected subscriptions made after close to be closed")
+	later.Close()
+}
@@ -0,0 +1,173 @@
+// Package executor runs tasks on a fixed set of worker goroutines, serializing
+// tasks that share a key.
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrQueueFull is returned when the queue of the worker the key maps to is full.
+	ErrQueueFull = errors.New("executor queue is full")
+	// ErrStopped is returned when a task is submitted after the executor was stopped.
+	ErrStopped = errors.New("executor is stopped")
+)
+
+// Task is a unit of work run by a worker.
+type Task func(ctx context.Context) error
+
+type job struct {
+	ctx  context.Context
+	task Task
+	done chan error
+}
+
+// Stats is a snapshot of the executor state.
+type Stats struct {
+	// QueueDepths is the number of tasks waiting in the queue of each worker.
+	QueueDepths []int `json:"queueDepths"`
+	Completed   int64 `json:"completed"`
+	// Rejected is the number of tasks rejected with [ErrQueueFull].
+	Rejected int64 `json:"rejected"`
+}
+
+// Executor hashes task keys onto worker goroutines. Tasks with the same key run
+// one at a time in the order they were submitted; tasks with different keys may
+// run concurrently. Every worker has a bounded queue, and a task is rejected
+// rather than queued when the queue is full.
+type Executor struct {
+	queues []chan job
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+
+	completed atomic.Int64
+	rejected  atomic.Int64
+}
+
+// New starts an executor with the given number of workers, each with a queue
+// of queueSize tasks. Both values are raised to 1 if they are lower.
+func New(workers, queueSize int) *Executor {
+	workers = max(workers, 1)
+	queueSize = max(queueSize, 1)
+
+	e := &Executor{
+		queues: make([]chan job, workers),
+	}
+
+	for i := range e.queues {
+		e.queues[i] = make(chan job, queueSize)
+
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.work(e.queues[i])
+		}()
+	}
+
+	return e
+}
+
+// Do runs the task on the worker the key maps to and waits for its result.
+// If the worker queue is full, it returns [ErrQueueFull] without running the task.
+// If ctx is done before the task starts, the task is skipped and ctx.Err() is returned;
+// if ctx is done while the task runs, Do returns ctx.Err() without waiting for it.
+func (e *Executor) Do(ctx context.Context, key string, task Task) error {
+	j := job{
+		ctx:  ctx,
+		task: task,
+		done: make(chan error, 1),
+	}
+
+	if err := e.submit(key, j); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the queue depths and task counters.
+func (e *Executor) Stats() Stats {
+	depths := make([]int, len(e.queues))
+	for i, q := range e.queues {
+		depths[i] = len(q)
+	}
+
+	return Stats{
+		QueueDepths: depths,
+		Completed:   e.completed.Load(),
+		Rejected:    e.rejected.Load(),
+	}
+}
+
+// Stop stops accepting tasks, waits for the queued ones to finish and stops the workers.
+func (e *Executor) Stop() {
+	e.mu.Lock()
+	if !e.stopped {
+		e.stopped = true
+		for _, q := range e.queues {
+			close(q)
+		}
+	}
+	e.mu.Unlock()
+
+	e.wg.Wait()
+}
+
+func (e *Executor) submit(key string, j job) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.stopped {
+		return ErrStopped
+	}
+
+	select {
+	case e.queues[e.shard(key)] <- j:
+		return nil
+	default:
+		e.rejected.Add(1)
+		return ErrQueueFull
+	}
+}
+
+func (e *Executor) work(queue <-chan job) {
+	for j := range queue {
+		if err := j.ctx.Err(); err != nil {
+			j.done <- err
+			continue
+		}
+
+		j.done <- run(j)
+		e.completed.Add(1)
+	}
+}
+
+// run runs the task of the job. A panic is returned as an error, so it doesn't
+// take the worker down with the tasks queued behind it.
+func run(j job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("executor: task panicked: %v", p)
+		}
+	}()
+
+	return j.task(j.ctx)
+}
+
+func (e *Executor) shard(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(e.queues)))
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo_SerializesTasksWithSameKey(t *testing.T) {
+	t.Parallel()
+
+	e := New(4, 100)
+	defer e.Stop()
+
+	var (
+		mu      sync.Mutex
+		running int
+		counter int
+		wg      sync.WaitGroup
+	)
+
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := e.Do(t.Context(), "wallet", func(ctx context.Context) error {
+				mu.Lock()
+				running++
+				assert.Equal(t, 1, running, "expected tasks with the same key not to overlap")
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				running--
+				counter++
+				mu.Unlock()
+
+				return nil
+			})
+			assert.NoError(t, err, "expected no error")
+		}()
+	}
+
+	wg.Wait()
+
+	require.Equal(t, 50, counter, "expected all tasks to run")
+	require.Equal(t, int64(50), e.Stats().Completed, "expected completed counter to match")
+}
+
+func TestDo_QueueFull(t *testing.T) {
+	t.Parallel()
+
+	e := New(1, 1)
+	defer e.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	// The first task occupies the worker, the second one fills the queue.
+	go func() {
+		_ = e.Do(t.Context(), "a", func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	queued := make(chan error, 1)
+	go func() {
+		queued <- e.Do(t.Context(), "a", func(ctx context.Context) error { return nil })
+	}()
+	require.Eventually(t, func() bool { return e.Stats().QueueDepths[0] == 1 },
+		time.Second, time.Millisecond, "expected the task to be queued")
+
+	err := e.Do(t.Context(), "a", func(ctx context.Context) error { return nil })
+	require.ErrorIs(t, err, ErrQueueFull, "expected queue to be full")
+	require.Equal(t, int64(1), e.Stats().Rejected, "expected rejected counter to match")
+
+	close(release)
+	require.NoError(t, <-queued, "expected queued task to run")
+}
+
+func TestDo_Panic(t *testing.T) {
+	t.Parallel()
+
+	e := New(1, 1)
+	defer e.Stop()
+
+	err := e.Do(t.Context(), "a", func(ctx context.Context) error { panic("boom") })
+	require.ErrorContains(t, err, "boom", "expected panic to be returned as an error")
+
+	err = e.Do(t.Context(), "a", func(ctx context.Context) error { return nil })
+	require.NoError(t, err, "expected worker to survive the panic")
+}
+
+func TestDo_Stopped(t *testing.T) {
+	t.Parallel()
+
+	e := New(1, 1)
+	e.Stop()
+
+	err := e.Do(t.Context(), "a", func(ctx context.Context) error { return nil })
+	require.ErrorIs(t, err, ErrStopped, "expected executor to be stopped")
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Option func(*pgxpool.Config)
@@ -16,6 +17,13 @@ func WithMaxConns(maxConns int32) Option {
 	}
 }
 
+// WithTracerProvider traces every query run on the pool with a tracer of the provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(cfg *pgxpool.Config) {
+		cfg.ConnConfig.Tracer = newQueryTracer(provider)
+	}
+}
+
 // NewPool creates a new PostgreSQL connection pool with the provided DSN and options.
 func NewPool(ctx context.Context, dsn string, opts ...Option) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(dsn)
@@ -43,3 +51,22 @@ func NewPool(ctx context.Context, dsn string, opts ...Option) (*pgxpool.Pool, er
 type Queryer interface {
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 }
+
+// SchemaVersion returns the version of the latest migration applied by golang-migrate
+// and whether it failed halfway, leaving the schema dirty.
+func SchemaVersion(ctx context.Context, q Queryer) (version int64, dirty bool, err error) {
+	rows, err := q.Query(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if err != nil {
+		return 0, false, err
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[struct {
+		Version int64
+		Dirty   bool
+	}])
+	if err != nil {
+		return 0, false, err
+	}
+
+	return row.Version, row.Dirty, nil
+}
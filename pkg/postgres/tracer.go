@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/passwordhash/asynchronous-wallet/pkg/postgres"
+
+// queryTracer starts a client span for every query run on a connection of the pool.
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+func newQueryTracer(provider trace.TracerProvider) *queryTracer {
+	return &queryTracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+func (t *queryTracer) TraceQueryStart(
+	ctx context.Context,
+	_ *pgx.Conn,
+	data pgx.TraceQueryStartData,
+) context.Context {
+	operation := operationName(data.SQL)
+
+	ctx, _ = t.tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+
+	return ctx
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	// No rows is an expected result of a lookup rather than a failed query.
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+}
+
+// operationName returns the first keyword of the query, e.g. SELECT or BEGIN.
+func operationName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
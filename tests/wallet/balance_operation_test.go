@@ -13,7 +13,11 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var u url.URL
+var (
+	u url.URL
+	// apiKey authenticates the requests; create one with cmd/apikey.
+	apiKey = os.Getenv("API_KEY")
+)
 
 func init() {
 	port := "8080"
@@ -42,7 +46,7 @@ type operationResp struct {
 }
 
 func TestBalanceOperation_Ok(t *testing.T) {
-	e := httpexpect.Default(t, u.String())
+	e := newExpect(t)
 
 	t.Run("Deposit Operation", func(t *testing.T) {
 		initialBalance := getBalance(t, e, walletID)
@@ -84,7 +88,7 @@ func TestBalanceOperation_Ok(t *testing.T) {
 }
 
 func TestBalanceOperation_Error(t *testing.T) {
-	e := httpexpect.Default(t, u.String())
+	e := newExpect(t)
 
 	t.Run("Deposit operation with negative amount", func(t *testing.T) {
 		t.Parallel()
@@ -152,7 +156,7 @@ func TestBalanceOperation_Error(t *testing.T) {
 }
 
 func TestBalanceOperation_EdgeCases(t *testing.T) {
-	e := httpexpect.Default(t, u.String())
+	e := newExpect(t)
 
 	t.Run("Minimum operation amount", func(t *testing.T) {
 		initialBalance := getBalance(t, e, walletID)
@@ -172,7 +176,7 @@ func TestBalanceOperation_EdgeCases(t *testing.T) {
 
 func TestBalanceOperation_Concurrent(t *testing.T) {
 	// Test 1000 rps/sec
-	e := httpexpect.Default(t, u.String())
+	e := newExpect(t)
 
 	const numOperationPairs = 500
 
@@ -206,6 +210,13 @@ func TestBalanceOperation_Concurrent(t *testing.T) {
 		"Final balance should match expected after concurrent operations")
 }
 
+// newExpect returns an httpexpect instance whose requests carry the API key.
+func newExpect(t *testing.T) *httpexpect.Expect {
+	return httpexpect.Default(t, u.String()).Builder(func(req *httpexpect.Request) {
+		req.WithHeader("X-API-Key", apiKey)
+	})
+}
+
 func mustSuccessOperationReq(t *testing.T, e *httpexpect.Expect, walletID, operationType string, amount int64) {
 	var resp operationResp
 	operationReq(e, walletID, operationType, amount).
@@ -4,12 +4,11 @@ import (
 	"sync"
 	"testing"
 
-	"github.com/gavv/httpexpect/v2"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestConcurrentOperations(t *testing.T) {
-	e := httpexpect.Default(t, u.String())
+	e := newExpect(t)
 
 	// Получаем начальный баланс
 	initialBalance := getBalance(t, e, walletID)